@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditEvent describes a verification for monitoring. It only carries
+// metadata: neither the characters nor the timings of the key, so audit
+// logs can be kept and shipped without protecting them like the secret.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// Method is "hash", "profile" or "samples".
+	Method   string `json:"method"`
+	Accepted bool   `json:"accepted"`
+	// Score against the best matching profile, or the fraction of samples
+	// matched, zero for hashes.
+	Score float64 `json:"score"`
+	// Length is the number of characters typed.
+	Length int `json:"length"`
+	// Reason a key was rejected before being matched, if it was.
+	Reason string `json:"reason,omitempty"`
+}
+
+// AuditFunc receives an AuditEvent for every verification. A nil AuditFunc
+// audits nothing.
+type AuditFunc func(AuditEvent)
+
+func (audit AuditFunc) record(event AuditEvent) {
+	if audit == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = now()
+	}
+	audit(event)
+}
+
+// JSONLinesAudit writes every event to w as a line of JSON. It is safe for
+// concurrent use, write errors are dropped so auditing never fails a
+// verification.
+func JSONLinesAudit(w io.Writer) AuditFunc {
+	mu := sync.Mutex{}
+	enc := json.NewEncoder(w)
+
+	return func(event AuditEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		enc.Encode(event)
+	}
+}
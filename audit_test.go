@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLinesAudit(t *testing.T) {
+	testMode = true
+	defer func() { testMode = false }()
+
+	buf := bytes.Buffer{}
+	audit := JSONLinesAudit(&buf)
+	audit.record(AuditEvent{Method: "profile", Accepted: true, Score: 0.75, Length: 8})
+	audit.record(AuditEvent{Method: "hash", Length: 3, Reason: "too regular"})
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("JSONLinesAudit wrote %q, want 2 lines", buf.String())
+	}
+
+	events := []AuditEvent{}
+	for _, line := range lines {
+		event := AuditEvent{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+
+	want := []AuditEvent{
+		{Time: now(), Method: "profile", Accepted: true, Score: 0.75, Length: 8},
+		{Time: now(), Method: "hash", Length: 3, Reason: "too regular"},
+	}
+	for i := range want {
+		if !events[i].Time.Equal(want[i].Time) {
+			t.Errorf("event %d at %v, want %v", i, events[i].Time, want[i].Time)
+		}
+		events[i].Time = want[i].Time
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestAuditKeepsTime(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	got := AuditEvent{}
+	AuditFunc(func(event AuditEvent) { got = event }).record(AuditEvent{Time: at})
+	if !got.Time.Equal(at) {
+		t.Errorf("recorded at %v, want %v", got.Time, at)
+	}
+
+	// A nil AuditFunc audits nothing, without panicking.
+	AuditFunc(nil).record(AuditEvent{})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Band is the range of timings accepted for a character, bounds included.
+type Band struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (b Band) Contains(timing time.Duration) bool {
+	return timing >= b.Min && timing <= b.Max
+}
+
+// WithinBands reports whether every timing of rk falls within the band of
+// its position. Otherwise it also returns the index of the first timing
+// out of its band, or of the first character without a band or
+// without a timing when their counts differ.
+func (rythmkey Rythmkey) WithinBands(bands []Band) (bool, int) {
+	for i, ct := range rythmkey {
+		if i >= len(bands) || !bands[i].Contains(ct.Timing) {
+			return false, i
+		}
+	}
+
+	if len(bands) != len(rythmkey) {
+		return false, len(rythmkey)
+	}
+
+	return true, -1
+}
+
+// Bands returns the band of every character of the profile: the range of
+// the timings it was enrolled with when k is zero, else its mean plus or
+// minus k standard deviations, never below zero.
+func (p Profile) Bands(k float64) ([]Band, error) {
+	if k == 0 && (len(p.Min) != len(p.Chars) || len(p.Max) != len(p.Chars)) {
+		return nil, errors.New("profile has no enrollment range, enroll again or give a band width")
+	}
+
+	bands := make([]Band, len(p.Chars))
+	for i := range bands {
+		low, high := p.Mean[i]-k*p.Stddev[i], p.Mean[i]+k*p.Stddev[i]
+		if k == 0 {
+			low, high = p.Min[i], p.Max[i]
+		}
+
+		bands[i] = Band{
+			Min: time.Duration(math.Round(math.Max(0, low) * float64(time.Millisecond))),
+			Max: time.Duration(math.Round(high * float64(time.Millisecond))),
+		}
+	}
+
+	return bands, nil
+}
+
+// BandScore returns the fraction of characters of rk within their band,
+// see Bands. The character sequences must be identical.
+func (p Profile) BandScore(rk Rythmkey, k float64) (float64, error) {
+	if err := checkChars(p.Chars, rk.Chars()); err != nil {
+		return 0, err
+	}
+
+	bands, err := p.Bands(k)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rk) == 0 {
+		return 0, errors.New("empty rythmkey")
+	}
+
+	within := 0
+	for i, ct := range rk {
+		if bands[i].Contains(ct.Timing) {
+			within++
+		}
+	}
+
+	return float64(within) / float64(len(rk)), nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithinBands(t *testing.T) {
+	bands := []Band{
+		{0, 0},
+		{100 * time.Millisecond, 140 * time.Millisecond},
+		{200 * time.Millisecond, 240 * time.Millisecond},
+	}
+
+	for _, tc := range []struct {
+		rks    string
+		within bool
+		index  int
+	}{
+		{"t0.at120.bt220.c", true, -1},
+		// Bounds are included.
+		{"t0.at100.bt240.c", true, -1},
+		// Exactly one band violated, either way.
+		{"t0.at141.bt220.c", false, 1},
+		{"t0.at120.bt199.c", false, 2},
+		{"t0.at120.b", false, 2},
+		{"t0.at120.bt220.ct10.d", false, 3},
+	} {
+		within, index := mustParse(t, tc.rks).WithinBands(bands)
+		if within != tc.within || index != tc.index {
+			t.Errorf("WithinBands(%s) = %t, %d, want %t, %d", tc.rks, within, index, tc.within, tc.index)
+		}
+	}
+}
+
+func TestProfileBands(t *testing.T) {
+	p := mustProfile(t, "t0.at100.bt200.c", "t0.at140.bt240.c")
+
+	bands, err := p.Bands(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Band{{0, 0}, {100 * time.Millisecond, 140 * time.Millisecond}, {200 * time.Millisecond, 240 * time.Millisecond}}
+	if !slices.Equal(bands, want) {
+		t.Errorf("Bands(0) = %v, want the enrolled ranges %v", bands, want)
+	}
+
+	bands, err = p.Bands(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, band := range bands {
+		low := time.Duration(math.Round(math.Max(0, p.Mean[i]-2*p.Stddev[i]) * float64(time.Millisecond)))
+		high := time.Duration(math.Round((p.Mean[i] + 2*p.Stddev[i]) * float64(time.Millisecond)))
+		if band.Min != low || band.Max != high {
+			t.Errorf("Bands(2)[%d] = %v, want mean ± 2 stddev, %v to %v", i, band, low, high)
+		}
+	}
+
+	// Older profiles have no enrolled range.
+	p.Min, p.Max = nil, nil
+	if _, err := p.Bands(0); err == nil {
+		t.Error("Bands(0) of a profile without range succeeded")
+	}
+}
+
+func TestBandScore(t *testing.T) {
+	p := mustProfile(t, "t0.at100.bt200.c", "t0.at140.bt240.c")
+
+	for _, tc := range []struct {
+		rks   string
+		score float64
+	}{
+		{"t0.at120.bt220.c", 1},
+		{"t0.at150.bt220.c", 2.0 / 3},
+		{"t5.at150.bt190.c", 0},
+	} {
+		score, err := p.BandScore(mustParse(t, tc.rks), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score != tc.score {
+			t.Errorf("BandScore(%s) = %v, want %v", tc.rks, score, tc.score)
+		}
+	}
+
+	if _, err := p.BandScore(mustParse(t, "t0.at120.bt220.x"), 0); err == nil {
+		t.Error("BandScore of other characters succeeded")
+	}
+}
+
+func TestVerifyBands(t *testing.T) {
+	dir := t.TempDir()
+	saveProfile(t, dir, "secret.json", mustProfile(t, "t0.at100.bt200.c", "t0.at140.bt240.c"))
+
+	for _, tc := range []struct {
+		rks  string
+		code int
+	}{
+		{"t0.at120.bt220.c", 0},
+		// The second character only is out of its band.
+		{"t0.at150.bt220.c", exitReject},
+	} {
+		t.Setenv(encodedInputEnv, tc.rks)
+
+		result := runApp(t, "verify", "--profiles-dir", dir, "--method", "bands")
+		if result.code != tc.code {
+			t.Errorf("verify --method bands %s exited %d, want %d: %v", tc.rks, result.code, tc.code, result.err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// BenchmarkResult summarizes the cost of verifying synthetic keys with a
+// matching method.
+type BenchmarkResult struct {
+	Method  string
+	Samples int
+	Total   time.Duration
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+}
+
+// PerSecond is the verification throughput of a single goroutine.
+func (r BenchmarkResult) PerSecond() float64 {
+	if r.Total <= 0 {
+		return 0
+	}
+
+	return float64(r.Samples) / r.Total.Seconds()
+}
+
+// syntheticSamples types the same random characters n times with timings
+// jittered around a common rhythm, as an enrolled user would.
+func syntheticSamples(rng *rand.Rand, n int, length int) []Rythmkey {
+	chars := make([]byte, length)
+	rhythm := make([]time.Duration, length)
+	for i := range chars {
+		chars[i] = byte('a' + rng.Intn(26))
+		rhythm[i] = time.Duration(80+rng.Intn(200)) * time.Millisecond
+	}
+
+	samples := make([]Rythmkey, n)
+	for s := range samples {
+		for i, c := range chars {
+			jitter := time.Duration(rng.Intn(41)-20) * time.Millisecond
+			samples[s].Add(c, rhythm[i]+jitter)
+		}
+	}
+
+	return samples
+}
+
+// Benchmark verifies samples synthetic keys of length characters with
+// method, "hash", "profile", "exact" or "tolerance", and measures the latency
+// of each verification. exact and tolerance compare to the first key
+// rather than to a hash or profile, see CompareExact.
+func Benchmark(method string, samples int, length int) (BenchmarkResult, error) {
+	if samples < 1 || length < 1 {
+		return BenchmarkResult{}, fmt.Errorf("samples and length must be positive")
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	keys := syntheticSamples(rng, samples, length)
+
+	var verify func(rk Rythmkey) error
+	switch method {
+	case "hash":
+		opts := HashOptions{Salt: defaultSalt}
+		hash, err := keys[0].HashWith(opts)
+		if err != nil {
+			return BenchmarkResult{}, err
+		}
+
+		verify = func(rk Rythmkey) error {
+			_, err := rk.VerifyHash(opts, hash)
+			return err
+		}
+	case "profile":
+		p, err := NewProfile(keys, time.Millisecond)
+		if err != nil {
+			return BenchmarkResult{}, err
+		}
+
+		verify = func(rk Rythmkey) error {
+			_, _, err := p.Verify(rk, defaultThreshold)
+			return err
+		}
+	case "exact":
+		opts := HashOptions{Salt: defaultSalt}
+		verify = func(rk Rythmkey) error {
+			_, err := CompareExact(keys[0], rk, opts)
+			return err
+		}
+	case "tolerance":
+		tolerance := AbsoluteTolerance(50 * time.Millisecond)
+		verify = func(rk Rythmkey) error {
+			Compare(keys[0], rk, tolerance)
+			return nil
+		}
+	default:
+		return BenchmarkResult{}, fmt.Errorf("unknown method %q", method)
+	}
+
+	latencies := make([]time.Duration, 0, samples)
+	for _, rk := range keys {
+		start := time.Now()
+		if err := verify(rk); err != nil {
+			return BenchmarkResult{}, err
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+
+	return summarize(method, latencies), nil
+}
+
+// summarize totals the latencies of method and picks their percentiles,
+// the nearest measured latency below each.
+func summarize(method string, latencies []time.Duration) BenchmarkResult {
+	result := BenchmarkResult{Method: method, Samples: len(latencies)}
+	for _, took := range latencies {
+		result.Total += took
+	}
+
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	percentile := func(p int) time.Duration {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+	result.P50, result.P90, result.P99 = percentile(50), percentile(90), percentile(99)
+
+	return result
+}
+
+func WriteBenchmark(w io.Writer, results []BenchmarkResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "method\tsamples\tverifications/s\tp50\tp90\tp99")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%.0f\t%s\t%s\t%s\n", r.Method, r.Samples, r.PerSecond(), r.P50, r.P90, r.P99)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	hundred := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		hundred = append(hundred, time.Duration(i)*time.Millisecond)
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(hundred), func(i, j int) {
+		hundred[i], hundred[j] = hundred[j], hundred[i]
+	})
+
+	for _, tc := range []struct {
+		name      string
+		latencies []time.Duration
+		want      BenchmarkResult
+	}{
+		{"hundred", hundred, BenchmarkResult{Samples: 100, Total: 5050 * time.Millisecond, P50: 50 * time.Millisecond, P90: 90 * time.Millisecond, P99: 99 * time.Millisecond}},
+		// Percentiles round down to a measured latency.
+		{"three", []time.Duration{3 * time.Millisecond, time.Millisecond, 2 * time.Millisecond}, BenchmarkResult{Samples: 3, Total: 6 * time.Millisecond, P50: 2 * time.Millisecond, P90: 2 * time.Millisecond, P99: 2 * time.Millisecond}},
+		{"one", []time.Duration{time.Millisecond}, BenchmarkResult{Samples: 1, Total: time.Millisecond, P50: time.Millisecond, P90: time.Millisecond, P99: time.Millisecond}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			latencies := slices.Clone(tc.latencies)
+			tc.want.Method = "hash"
+
+			if result := summarize("hash", latencies); result != tc.want {
+				t.Errorf("summarize = %+v, want %+v", result, tc.want)
+			}
+			if !slices.Equal(latencies, tc.latencies) {
+				t.Errorf("summarize reordered the latencies to %v", latencies)
+			}
+		})
+	}
+}
+
+func TestBenchmarkResultPerSecond(t *testing.T) {
+	for _, tc := range []struct {
+		result BenchmarkResult
+		want   float64
+	}{
+		{BenchmarkResult{Samples: 100, Total: 5050 * time.Millisecond}, 100 / 5.05},
+		{BenchmarkResult{Samples: 4, Total: 2 * time.Second}, 2},
+		{BenchmarkResult{Samples: 4}, 0},
+	} {
+		if perSecond := tc.result.PerSecond(); perSecond != tc.want {
+			t.Errorf("PerSecond of %d samples in %s = %v, want %v", tc.result.Samples, tc.result.Total, perSecond, tc.want)
+		}
+	}
+}
+
+func TestWriteBenchmark(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := WriteBenchmark(buf, []BenchmarkResult{
+		{Method: "hash", Samples: 100, Total: 5050 * time.Millisecond, P50: 50 * time.Millisecond, P90: 90 * time.Millisecond, P99: 99 * time.Millisecond},
+		{Method: "exact", Samples: 4, Total: 2 * time.Millisecond, P50: 500 * time.Microsecond, P90: 500 * time.Microsecond, P99: 600 * time.Microsecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "method  samples  verifications/s  p50    p90    p99\n" +
+		"hash    100      20               50ms   90ms   99ms\n" +
+		"exact   4        2000             500µs  500µs  600µs\n"
+	if buf.String() != want {
+		t.Errorf("WriteBenchmark =\n%s\nwant\n%s", buf, want)
+	}
+}
+
+func TestBenchmark(t *testing.T) {
+	for _, method := range benchmarkMethods {
+		result, err := Benchmark(method, 10, 8)
+		if err != nil {
+			t.Errorf("Benchmark(%q): %v", method, err)
+			continue
+		}
+		if result.Method != method || result.Samples != 10 || result.P50 > result.P90 || result.P90 > result.P99 {
+			t.Errorf("Benchmark(%q) = %+v", method, result)
+		}
+	}
+
+	for _, tc := range []struct {
+		method          string
+		samples, length int
+	}{
+		{"hash", 0, 8},
+		{"hash", 10, 0},
+		{"scrypt", 10, 8},
+	} {
+		if _, err := Benchmark(tc.method, tc.samples, tc.length); err == nil {
+			t.Errorf("Benchmark(%q, %d, %d) succeeded", tc.method, tc.samples, tc.length)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// A bundle is bundleMagic, a random scrypt salt, the AES-GCM nonce and the
+// sealed JSON profile. The header is authenticated along with the profile.
+var bundleMagic = []byte("rkbundle1")
+
+const bundleSaltSize = 16
+
+var errBadBundle = errors.New("invalid bundle or wrong password")
+
+func bundleKey(password string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
+}
+
+func bundleAEAD(password string, salt []byte) (cipher.AEAD, error) {
+	key, err := bundleKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Export encrypts p with a key derived from password into a bundle that
+// Import opens back.
+func (p Profile) Export(password string) ([]byte, error) {
+	if password == "" {
+		return nil, errors.New("a password is required")
+	}
+
+	plain, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, bundleSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := bundleAEAD(password, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append(append(append([]byte{}, bundleMagic...), salt...), nonce...)
+	return aead.Seal(header, nonce, plain, header), nil
+}
+
+// ImportProfile decrypts a bundle made by Profile.Export. A wrong password
+// and a tampered bundle both fail authentication and can't be told apart.
+func ImportProfile(bundle []byte, password string) (Profile, error) {
+	if !bytes.HasPrefix(bundle, bundleMagic) {
+		return Profile{}, errors.New("not a profile bundle")
+	}
+
+	rest := bundle[len(bundleMagic):]
+	if len(rest) < bundleSaltSize {
+		return Profile{}, errBadBundle
+	}
+	salt := rest[:bundleSaltSize]
+
+	aead, err := bundleAEAD(password, salt)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	headerSize := len(bundleMagic) + bundleSaltSize + aead.NonceSize()
+	if len(bundle) < headerSize {
+		return Profile{}, errBadBundle
+	}
+
+	header := bundle[:headerSize]
+	nonce := header[len(bundleMagic)+bundleSaltSize:]
+
+	plain, err := aead.Open(nil, nonce, bundle[headerSize:], header)
+	if err != nil {
+		return Profile{}, errBadBundle
+	}
+
+	p := Profile{}
+	if err := json.Unmarshal(plain, &p); err != nil {
+		return Profile{}, err
+	}
+
+	return p, p.Validate()
+}
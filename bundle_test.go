@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	p := mustProfile(t, "t0.at100.bt90.c", "t0.at110.bt100.c")
+
+	bundle, err := p.Export("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := ImportProfile(bundle, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(imported, p) {
+		t.Errorf("ImportProfile = %+v, want %+v", imported, p)
+	}
+}
+
+func TestBundleWrongPassword(t *testing.T) {
+	bundle, err := mustProfile(t, "t0.at100.b").Export("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ImportProfile(bundle, "hunter3"); !errors.Is(err, errBadBundle) {
+		t.Errorf("ImportProfile with a wrong password = %v, want %v", err, errBadBundle)
+	}
+}
+
+func TestBundleTampered(t *testing.T) {
+	bundle, err := mustProfile(t, "t0.at100.b").Export("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A flipped bit in the salt, the nonce and the sealed profile.
+	for _, i := range []int{len(bundleMagic), len(bundleMagic) + bundleSaltSize, len(bundle) - 1} {
+		tampered := append([]byte{}, bundle...)
+		tampered[i] ^= 1
+
+		if _, err := ImportProfile(tampered, "hunter2"); !errors.Is(err, errBadBundle) {
+			t.Errorf("ImportProfile with byte %d flipped = %v, want %v", i, err, errBadBundle)
+		}
+	}
+
+	for _, truncated := range [][]byte{bundle[:len(bundleMagic)+4], bundle[:len(bundle)-20]} {
+		if _, err := ImportProfile(truncated, "hunter2"); err == nil {
+			t.Errorf("ImportProfile of a bundle truncated to %d bytes succeeded", len(truncated))
+		}
+	}
+
+	if _, err := ImportProfile([]byte(`{"chars":"ab"}`), "hunter2"); err == nil {
+		t.Error("ImportProfile of a plain profile succeeded")
+	}
+}
+
+func TestBundleRequiresPassword(t *testing.T) {
+	if _, err := mustProfile(t, "t0.at100.b").Export(""); err == nil {
+		t.Error("exported a bundle without a password")
+	}
+}
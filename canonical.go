@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// canonicalVersion identifies the canonicalEncode format. The bytes it
+// produces are what gets hashed, changing them in any way breaks every
+// stored hash: a new format must get a new version and leave this one be.
+const canonicalVersion = 1
+
+// canonicalEncode is the frozen hashing input of a quantized rythmkey,
+// version 1: for each character, a 't', its timing as a base 10 count of
+// milliseconds without leading zeros, then the character byte. With
+// SkipFirstTiming the first character is written as 't' and its byte only,
+// with RhythmOnly the character bytes are left out. With Modifiers every
+// character is followed by an 'm' and its modifiers mask in base 10.
+//
+// Unlike Encode, which is meant for humans and may evolve, this must never
+// change.
+func canonicalEncode(rk Rythmkey, opts HashOptions) []byte {
+	canonical := []byte{}
+
+	for i, ct := range rk {
+		canonical = append(canonical, 't')
+		if i > 0 || !opts.SkipFirstTiming {
+			canonical = strconv.AppendInt(canonical, int64(ct.Timing/time.Millisecond), 10)
+		}
+
+		if !opts.RhythmOnly {
+			canonical = append(canonical, ct.Char)
+		}
+
+		if opts.Modifiers {
+			canonical = strconv.AppendInt(append(canonical, 'm'), int64(ct.Modifiers), 10)
+		}
+	}
+
+	return canonical
+}
+
+// canonicalStructure is the prefix of the hashing input with
+// BindStructure: an 'n' and the number of characters, then a 'd' and the
+// total duration of the key quantized like its timings, then a ':'. Only
+// the raw total is quantized, so keys quantizing to the same timings but
+// captured with different overall durations don't collide.
+func canonicalStructure(rk Rythmkey, salt int) []byte {
+	total := Rythmkey{{Timing: rk.TotalDuration()}}.Quantize(salt, false)[0].Timing
+
+	structure := strconv.AppendInt([]byte{'n'}, int64(rk.Len()), 10)
+	structure = append(structure, 'd')
+	structure = strconv.AppendInt(structure, int64(total/time.Millisecond), 10)
+	return append(structure, ':')
+}
+
+// canonicalContext is the prefix of the hashing input with a Context: a
+// 'c', the length of the context, a ':' then the context itself, so no
+// context can be read as the start of another.
+func canonicalContext(context string) []byte {
+	prefix := strconv.AppendInt([]byte{'c'}, int64(len(context)), 10)
+	prefix = append(prefix, ':')
+	return append(prefix, context...)
+}
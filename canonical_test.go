@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The canonical bytes and digests below are frozen with version 1 of the
+// encoding: if they change, every stored hash breaks.
+func TestCanonicalEncodePinned(t *testing.T) {
+	rk := mustParse(t, "t0.at123.bt7.1t2000. ").Quantize(20, false)
+
+	for _, tc := range []struct {
+		opts HashOptions
+		want string
+	}{
+		{HashOptions{}, "t20at140bt201t2020 "},
+		{HashOptions{SkipFirstTiming: true}, "tat140bt201t2020 "},
+		{HashOptions{RhythmOnly: true}, "t20t140t20t2020"},
+		{HashOptions{Modifiers: true}, "t20am0t140bm0t201m0t2020 m0"},
+	} {
+		if got := string(canonicalEncode(rk, tc.opts)); got != tc.want {
+			t.Errorf("canonicalEncode(%+v) = %q, want %q", tc.opts, got, tc.want)
+		}
+	}
+}
+
+func TestHashInputPinned(t *testing.T) {
+	rk := mustParse(t, "t0.at123.bt7.c")
+
+	input, err := rk.HashInput(HashOptions{Salt: 20, BindStructure: true, Context: "login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := string(input), "c5:loginn3d140:t20at140bt20c"; got != want {
+		t.Errorf("HashInput = %q, want %q", got, want)
+	}
+}
+
+func TestHashPinned(t *testing.T) {
+	digest, err := mustParse(t, "t0.at123.bt7.c").Digest(HashOptions{Salt: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := hex.EncodeToString(digest), "8e222d0df224636b5c15b82b9e17b7b8f35fd083a42f8caa987185d370eea1e1"; got != want {
+		t.Errorf("digest = %s, want %s", got, want)
+	}
+}
+
+func TestBindStructure(t *testing.T) {
+	// Both quantize to t20at140bt20c, over 128 and 156 milliseconds.
+	a, b := mustParse(t, "t0.at121.bt7.c"), mustParse(t, "t0.at138.bt18.c")
+
+	for _, bind := range []bool{false, true} {
+		opts := HashOptions{Salt: 20, BindStructure: bind}
+
+		hashA, err := a.HashWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashB, err := b.HashWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if collide := hashA == hashB; collide == bind {
+			t.Errorf("with BindStructure %t, hashes collide %t, want %t", bind, collide, !bind)
+		}
+	}
+
+	if got, want := string(canonicalStructure(b, 20)), "n3d160:"; got != want {
+		t.Errorf("canonicalStructure = %q, want %q", got, want)
+	}
+}
+
+func TestHashContext(t *testing.T) {
+	rk := mustParse(t, "t0.at123.bt7.c")
+
+	digests := map[string]string{}
+	for _, context := range []string{"", "login", "sudo", "c5:login"} {
+		digest, err := rk.HashWith(HashOptions{Salt: 20, Context: context})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for other, d := range digests {
+			if d == digest {
+				t.Errorf("contexts %q and %q hash alike", context, other)
+			}
+		}
+		digests[context] = digest
+	}
+
+	// A context can't spill into the key, or into another context.
+	if got, want := string(canonicalContext("a:b")), "c3:a:b"; got != want {
+		t.Errorf("canonicalContext = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// The values each choice flag accepts. Flags describe their choices from
+// these, and the capabilities command lists them, so both stay in sync.
+var (
+	compareMethods    = []string{"tolerance", "rank", "curve", "exact"}
+	benchmarkMethods  = []string{"hash", "profile", "exact", "tolerance"}
+	hashAlgorithms    = []string{"sha256"}
+	hashEncodings     = []string{"hex", "base64", "base32"}
+	reportFormats     = []string{"text", "json"}
+	parseFormats      = []string{"text", "timeline", "table", "vector", "msgpack"}
+	parseInputFormats = []string{"encoded", "msgpack"}
+	parseStrategies   = []string{ParseAuto, ParseDelimited, ParseGreedy, ParseMinimal}
+	controlByteModes  = []string{ControlRejectNUL, ControlReject, ControlRecord}
+	importFormats     = []string{"cmu"}
+	keySources        = []string{"tty", "evdev"}
+	tabModes          = []string{"data", "separator"}
+	keyboardLayouts   = []string{"qwerty"}
+	norms             = []string{"l1", "l2", "linf"}
+	matchModes        = []string{MatchFlight, MatchDwell, MatchBoth}
+	templateShells    = []string{"bash", "zsh"}
+)
+
+// Capabilities lists the values supported for every choice the commands
+// offer.
+type Capabilities struct {
+	CompareMethods    []string `json:"compare_methods"`
+	VerifyMethods     []string `json:"verify_methods"`
+	Matchers          []string `json:"matchers"`
+	BenchmarkMethods  []string `json:"benchmark_methods"`
+	HashAlgorithms    []string `json:"hash_algorithms"`
+	HashEncodings     []string `json:"hash_encodings"`
+	Units             []string `json:"units"`
+	KeySources        []string `json:"key_sources"`
+	TabModes          []string `json:"tab_modes"`
+	KeyboardLayouts   []string `json:"keyboard_layouts"`
+	Norms             []string `json:"norms"`
+	MatchModes        []string `json:"match_modes"`
+	TemplateShells    []string `json:"template_shells"`
+	ReportFormats     []string `json:"report_formats"`
+	ParseFormats      []string `json:"parse_formats"`
+	ParseInputFormats []string `json:"parse_input_formats"`
+	ParseStrategies   []string `json:"parse_strategies"`
+	ControlByteModes  []string `json:"control_byte_modes"`
+	ImportFormats     []string `json:"import_formats"`
+}
+
+func SupportedCapabilities() Capabilities {
+	names := []string{}
+	for _, u := range units {
+		names = append(names, u.Name)
+	}
+
+	return Capabilities{
+		CompareMethods:    compareMethods,
+		VerifyMethods:     Matchers(),
+		Matchers:          Matchers(),
+		BenchmarkMethods:  benchmarkMethods,
+		HashAlgorithms:    hashAlgorithms,
+		HashEncodings:     hashEncodings,
+		Units:             names,
+		KeySources:        keySources,
+		TabModes:          tabModes,
+		KeyboardLayouts:   keyboardLayouts,
+		Norms:             norms,
+		MatchModes:        matchModes,
+		TemplateShells:    templateShells,
+		ReportFormats:     reportFormats,
+		ParseFormats:      parseFormats,
+		ParseInputFormats: parseInputFormats,
+		ParseStrategies:   parseStrategies,
+		ControlByteModes:  controlByteModes,
+		ImportFormats:     importFormats,
+	}
+}
+
+func (c Capabilities) WriteText(w io.Writer) error {
+	for _, dimension := range []struct {
+		name   string
+		values []string
+	}{
+		{"compare methods", c.CompareMethods},
+		{"verify methods", c.VerifyMethods},
+		{"matchers", c.Matchers},
+		{"benchmark methods", c.BenchmarkMethods},
+		{"hash algorithms", c.HashAlgorithms},
+		{"hash encodings", c.HashEncodings},
+		{"units", c.Units},
+		{"key sources", c.KeySources},
+		{"tab modes", c.TabModes},
+		{"keyboard layouts", c.KeyboardLayouts},
+		{"norms", c.Norms},
+		{"match modes", c.MatchModes},
+		{"template shells", c.TemplateShells},
+		{"report formats", c.ReportFormats},
+		{"parse formats", c.ParseFormats},
+		{"parse input formats", c.ParseInputFormats},
+		{"parse strategies", c.ParseStrategies},
+		{"control byte modes", c.ControlByteModes},
+		{"import formats", c.ImportFormats},
+	} {
+		_, err := io.WriteString(w, dimension.name+": "+strings.Join(dimension.values, ", ")+"\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c Capabilities) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c)
+}
+
+// choices formats values for a flag usage, like "(text, json)".
+func choices(values []string) string {
+	return "(" + strings.Join(values, ", ") + ")"
+}
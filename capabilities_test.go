@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// capabilityLists returns every dimension of SupportedCapabilities by its
+// JSON name.
+func capabilityLists(t *testing.T) map[string][]string {
+	t.Helper()
+
+	data, err := json.Marshal(SupportedCapabilities())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lists := map[string][]string{}
+	if err := json.Unmarshal(data, &lists); err != nil {
+		t.Fatal(err)
+	}
+
+	return lists
+}
+
+func TestCapabilitiesNotEmpty(t *testing.T) {
+	lists := capabilityLists(t)
+	if len(lists) != reflect.TypeOf(Capabilities{}).NumField() {
+		t.Errorf("%d dimensions serialized, want one per field of Capabilities", len(lists))
+	}
+
+	for name, values := range lists {
+		if len(values) == 0 {
+			t.Errorf("%s is empty", name)
+		}
+
+		for i, value := range values {
+			if value == "" || slices.Contains(values[:i], value) {
+				t.Errorf("%s lists %q, empty or twice", name, value)
+			}
+		}
+	}
+}
+
+// commandFlags returns the usage of every flag of the commands, walking
+// subcommands.
+func commandFlags(commands []*cli.Command, path string, usages map[string]string) {
+	for _, command := range commands {
+		for _, f := range command.Flags {
+			if usage, ok := f.(cli.DocGenerationFlag); ok {
+				usages[path+command.Name+" --"+f.Names()[0]] = usage.GetUsage()
+			}
+		}
+		commandFlags(command.Subcommands, path+command.Name+" ", usages)
+	}
+}
+
+// Flags list their choices from the registries rather than spelling them
+// out, so a choice listed by a flag is always one capabilities lists too.
+func TestFlagChoicesFromRegistries(t *testing.T) {
+	registries := [][]string{}
+	for _, values := range capabilityLists(t) {
+		registries = append(registries, values)
+	}
+
+	app := newApp()
+	usages := map[string]string{}
+	for _, f := range app.Flags {
+		if usage, ok := f.(cli.DocGenerationFlag); ok {
+			usages["--"+f.Names()[0]] = usage.GetUsage()
+		}
+	}
+	commandFlags(app.Commands, "", usages)
+
+	choiceList := regexp.MustCompile(`\(([a-z0-9_-]+(, [a-z0-9_-]+)+)\)`)
+	checked := 0
+	for flag, usage := range usages {
+		for _, match := range choiceList.FindAllStringSubmatch(usage, -1) {
+			values := strings.Split(match[1], ", ")
+			if !slices.ContainsFunc(registries, func(registry []string) bool { return slices.Equal(registry, values) }) {
+				t.Errorf("%s lists the choices %v of no registry", flag, values)
+			}
+			checked++
+		}
+	}
+
+	if checked == 0 {
+		t.Error("no flag lists its choices")
+	}
+}
+
+func TestCapabilitiesListsFormats(t *testing.T) {
+	for _, format := range reportFormats {
+		result := runApp(t, "capabilities", "--format", format)
+		if result.err != nil {
+			t.Errorf("capabilities --format %s failed: %v", format, result.err)
+		}
+	}
+
+	for _, format := range parseFormats {
+		result := runApp(t, "parse", "--rythmkey", "t0.at120.b", "--format", format)
+		if result.err != nil {
+			t.Errorf("parse --format %s failed: %v", format, result.err)
+		}
+	}
+
+	t.Setenv(encodedInputEnv, "t0.at125.bt85.c")
+	for _, method := range compareMethods {
+		result := runApp(t, "compare", "--rythmkey", "t0.at120.bt80.c", "--method", method)
+		if result.err != nil {
+			t.Errorf("compare --method %s failed: %v", method, result.err)
+		}
+	}
+
+	buf := bytes.Buffer{}
+	if err := SupportedCapabilities().WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(capabilityLists(t)) {
+		t.Errorf("WriteText wrote %d lines, want one per dimension", lines)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CharMap replaces captured characters before they're encoded or hashed,
+// to make some keys equivalent. The same map must be used at enroll and
+// verify time.
+type CharMap map[byte]byte
+
+// parseMapChar reads a single byte, or a quoted Go character such as '\t'
+// or ':', from the start of s and returns what follows it.
+func parseMapChar(s string) (byte, string, error) {
+	if strings.HasPrefix(s, "'") {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return 0, "", fmt.Errorf("bad quoted character %s", s)
+		}
+
+		value, _, tail, err := strconv.UnquoteChar(quoted[1:], '\'')
+		if err != nil || tail != "'" || value > 0xff {
+			return 0, "", fmt.Errorf("bad quoted character %s", quoted)
+		}
+
+		return byte(value), s[len(quoted):], nil
+	}
+
+	if len(s) == 0 {
+		return 0, "", fmt.Errorf("missing character")
+	}
+
+	return s[0], s[1:], nil
+}
+
+// ParseCharMap reads one from:to pair per line, ignoring blank lines and
+// lines starting with #. Any byte can be mapped to any other, '\n'
+// included as it is data under another terminator: mapping to the
+// terminator is refused along with the other capture options.
+func ParseCharMap(r io.Reader) (CharMap, error) {
+	cm := CharMap{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		from, rest, err := parseMapChar(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		if !strings.HasPrefix(rest, ":") {
+			return nil, fmt.Errorf("line %d: expected from:to", n)
+		}
+
+		to, rest, err := parseMapChar(rest[1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		if rest != "" {
+			return nil, fmt.Errorf("line %d: trailing data %q", n, rest)
+		}
+
+		cm[from] = to
+	}
+
+	return cm, scanner.Err()
+}
+
+func LoadCharMap(path string) (CharMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseCharMap(f)
+}
+
+// Remap returns a copy of the key with its characters replaced per cm,
+// which must be zeroed like the key.
+func (rythmkey Rythmkey) Remap(cm CharMap) Rythmkey {
+	rk := make(Rythmkey, len(rythmkey))
+	for i, ct := range rythmkey {
+		c := ct.Char
+		if to, ok := cm[c]; ok {
+			c = to
+		}
+		rk[i] = &CharTiming{Timing: ct.Timing, Char: c, Modifiers: ct.Modifiers}
+	}
+
+	return rk
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const foldCase = "# fold the first letters to lowercase\nA:a\nB:b\nC:c\n"
+
+func TestCharMapFoldsHash(t *testing.T) {
+	cm, err := ParseCharMap(strings.NewReader(foldCase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upper := mustParse(t, "t0.At120.Bt80.c")
+	lower := mustParse(t, "t0.at120.bt80.c")
+
+	hash := func(rk Rythmkey) string {
+		h, err := rk.Hash(20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	if hash(upper) == hash(lower) {
+		t.Fatal("upper and lower case keys hash the same without a char map")
+	}
+
+	if hash(upper.Remap(cm)) != hash(lower) {
+		t.Error("the folded upper case key doesn't hash like the lower case one")
+	}
+
+	if upper.Chars() != "ABc" {
+		t.Errorf("Remap changed the key to %q", upper.Chars())
+	}
+}
+
+func TestParseCharMap(t *testing.T) {
+	cm, err := ParseCharMap(strings.NewReader("'\\t':' '\n':':'\\n'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cm['\t'] != ' ' || cm[':'] != '\n' || len(cm) != 2 {
+		t.Errorf("ParseCharMap = %q", cm)
+	}
+
+	for _, bad := range []string{"a", "a:", "ab:c", "a:bc", "'ab':c"} {
+		if _, err := ParseCharMap(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseCharMap(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestReadOptionsCharMapTerminator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "map")
+	if err := os.WriteFile(path, []byte("::'\\n'\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readOptions(flagsContext(t, captureFlags(), "--char-map", path)); err == nil || !strings.Contains(err.Error(), "terminator") {
+		t.Errorf("mapping a character to the terminating newline: %v, want it refused", err)
+	}
+
+	ro, err := readOptions(flagsContext(t, captureFlags(), "--char-map", path, "--terminator-key", `\x04`))
+	if err != nil {
+		t.Fatalf("mapping to a newline that is data: %v", err)
+	}
+	if ro.CharMap[':'] != '\n' {
+		t.Errorf("char map = %q", ro.CharMap)
+	}
+}
+
+func TestReadRemapZeroesOriginal(t *testing.T) {
+	scriptStdin(t, "ABc\n")
+
+	raw := Rythmkey{}
+	ro := ReadOptions{CharMap: CharMap{'A': 'a', 'B': 'b'}}
+	rawCapture(&ro, &raw)
+
+	rk := Rythmkey{}
+	if err := rk.ReadWith(ro); err != nil {
+		t.Fatal(err)
+	}
+
+	if rk.Chars() != "abc" {
+		t.Errorf("captured %q, want %q", rk.Chars(), "abc")
+	}
+	requireZeroed(t, "key before remapping", raw)
+}
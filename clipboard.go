@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// clipboardCommands print the system clipboard, the first one found being
+// used: macOS, Wayland, X11 and Windows ones.
+var clipboardCommands = [][]string{
+	{"pbpaste"},
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-o"},
+	{"xsel", "--clipboard", "--output"},
+	{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"},
+}
+
+// copyCommands set the system clipboard to their input, in the same order.
+var copyCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard", "-i"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+var errNoClipboard = errors.New("no clipboard available, install one of pbpaste, wl-paste, xclip or xsel")
+
+// readClipboard returns the text of the clipboard. It is a variable so a
+// fake clipboard can stand in for the system one.
+var readClipboard = func() (string, error) {
+	for _, command := range clipboardCommands {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+
+		out, err := exec.Command(path, command[1:]...).Output()
+		if err != nil {
+			return "", err
+		}
+
+		return string(out), nil
+	}
+
+	return "", errNoClipboard
+}
+
+// writeClipboard sets the text of the clipboard, a variable like
+// readClipboard.
+var writeClipboard = func(text string) error {
+	for _, command := range copyCommands {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+
+		cmd := exec.Command(path, command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+
+	return errNoClipboard
+}
+
+func toClipboardFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "to-clipboard",
+		Value: false,
+		Usage: "copy the hash to the clipboard instead of printing it, keeping it out of the terminal scrollback",
+	}
+}
+
+// outputHash prints hash, or copies it to the clipboard with
+// --to-clipboard, only confirming it on stderr.
+func outputHash(cCtx *cli.Context, hash string) error {
+	if !cCtx.Bool("to-clipboard") {
+		printOutput(hash)
+		return nil
+	}
+
+	if err := writeClipboard(hash); err != nil {
+		return fmt.Errorf("can't copy the hash to the clipboard: %w", err)
+	}
+
+	fmt.Fprintln(os.Stderr, "hash copied to the clipboard")
+	return nil
+}
+
+func clipboardFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "from-clipboard",
+		Value: false,
+		Usage: "read the encoded rythmkey from the clipboard instead of --rythmkey, keeping it out of the shell history",
+	}
+}
+
+// rythmkeyFromClipboard, a Before hook, fills --rythmkey from the
+// clipboard with --from-clipboard, whitespace trimmed since copying
+// often picks up a trailing newline. One of them is required.
+func rythmkeyFromClipboard(cCtx *cli.Context) error {
+	if !cCtx.Bool("from-clipboard") {
+		if !cCtx.IsSet("rythmkey") {
+			return errors.New("--rythmkey or --from-clipboard is required")
+		}
+		return nil
+	}
+
+	if cCtx.IsSet("rythmkey") {
+		return errors.New("--rythmkey and --from-clipboard are exclusive")
+	}
+
+	rks, err := readClipboard()
+	if err != nil {
+		return err
+	}
+	rks = strings.TrimSpace(rks)
+
+	if rks == "" {
+		return errors.New("clipboard is empty")
+	}
+
+	return cCtx.Set("rythmkey", rks)
+}
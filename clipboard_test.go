@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeClipboard stands in for the system clipboard for the duration of
+// the test, holding text, or failing with err.
+func fakeClipboard(t *testing.T, text string, err error) *string {
+	t.Helper()
+
+	read, write := readClipboard, writeClipboard
+	t.Cleanup(func() { readClipboard, writeClipboard = read, write })
+
+	readClipboard = func() (string, error) { return text, err }
+	writeClipboard = func(s string) error {
+		if err != nil {
+			return err
+		}
+		text = s
+		return nil
+	}
+
+	return &text
+}
+
+func TestFromClipboard(t *testing.T) {
+	const rks = "t0.at120.bt80.c"
+	digest, err := mustParse(t, rks).HashWith(HashOptions{Salt: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Copying often picks up a trailing newline.
+	for _, text := range []string{rks, rks + "\n", "  " + rks + "\r\n"} {
+		fakeClipboard(t, text, nil)
+
+		result := runApp(t, "hash", "--from-clipboard", "--salt", "20", "--bare")
+		if result.err != nil || result.stdout != digest {
+			t.Errorf("hash --from-clipboard of %q = %q, %v, want %s", text, result.stdout, result.err, digest)
+		}
+	}
+
+	fakeClipboard(t, rks, nil)
+	if result := runApp(t, "hash", "--from-clipboard", "--rythmkey", rks, "--salt", "20"); result.err == nil {
+		t.Error("--from-clipboard with --rythmkey succeeded")
+	}
+	if result := runApp(t, "hash", "--salt", "20"); result.err == nil {
+		t.Error("hash without --rythmkey nor --from-clipboard succeeded")
+	}
+
+	fakeClipboard(t, " \n", nil)
+	if result := runApp(t, "hash", "--from-clipboard", "--salt", "20"); result.err == nil || !strings.Contains(result.err.Error(), "empty") {
+		t.Errorf("hash --from-clipboard of an empty clipboard = %v, want it reported empty", result.err)
+	}
+
+	// Headless, without a clipboard.
+	fakeClipboard(t, "", errNoClipboard)
+	if result := runApp(t, "hash", "--from-clipboard", "--salt", "20"); !errors.Is(result.err, errNoClipboard) {
+		t.Errorf("hash --from-clipboard without a clipboard = %v, want %v", result.err, errNoClipboard)
+	}
+}
+
+func TestToClipboard(t *testing.T) {
+	const rks = "t0.at120.bt80.c"
+	digest, err := mustParse(t, rks).HashWith(HashOptions{Salt: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clipboard := fakeClipboard(t, "", nil)
+	result := runApp(t, "hash", "--rythmkey", rks, "--salt", "20", "--bare", "--to-clipboard")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if *clipboard != digest || result.stdout != "" {
+		t.Errorf("hash --to-clipboard copied %q and printed %q, want %s copied only", *clipboard, result.stdout, digest)
+	}
+
+	fakeClipboard(t, "", errNoClipboard)
+	if result := runApp(t, "hash", "--rythmkey", rks, "--salt", "20", "--to-clipboard"); !errors.Is(result.err, errNoClipboard) {
+		t.Errorf("hash --to-clipboard without a clipboard = %v, want %v", result.err, errNoClipboard)
+	}
+}
+
+func TestReadHashToClipboard(t *testing.T) {
+	scriptStdin(t, "abc\n")
+	printed := runApp(t, "read", "--hash")
+	if printed.err != nil {
+		t.Fatal(printed.err)
+	}
+	hash := strings.TrimSpace(printed.stdout)
+
+	clipboard := fakeClipboard(t, "", nil)
+	scriptStdin(t, "abc\n")
+	result := runApp(t, "read", "--hash", "--to-clipboard")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if *clipboard != hash || result.stdout != "" {
+		t.Errorf("read --hash --to-clipboard copied %q and printed %q, want %s copied only", *clipboard, result.stdout, hash)
+	}
+	if !strings.Contains(result.stderr, "copied") || strings.Contains(result.stderr, hash) {
+		t.Errorf("read --hash --to-clipboard wrote %q to stderr, want a confirmation without the hash", result.stderr)
+	}
+
+	for _, flags := range [][]string{{}, {"--hash", "--output-hash-only"}, {"--hash", "--tab-mode", "separator"}} {
+		scriptStdin(t, "abc\n")
+		if result := runApp(t, append([]string{"read", "--to-clipboard"}, flags...)...); result.err == nil {
+			t.Errorf("read --to-clipboard %v succeeded", flags)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CharDistance is the Levenshtein distance between the characters of both
+// keys, regardless of their timings.
+func (rythmkey Rythmkey) CharDistance(other Rythmkey) int {
+	a, b := rythmkey.Chars(), other.Chars()
+
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+const defaultTolerance = "50ms"
+
+// CompareReport summarizes how a typed key compares to a reference one.
+type CompareReport struct {
+	// Method is "tolerance", "rank", "curve" or "exact", see Compare,
+	// CompareRank, CompareCurve and CompareExact.
+	Method          string `json:"method"`
+	Length          int    `json:"length"`
+	TypedLength     int    `json:"typed_length"`
+	MatchingChars   int    `json:"matching_chars"`
+	WithinTolerance int    `json:"within_tolerance"`
+	// Correlation of the intervals, for the rank method.
+	Correlation float64 `json:"correlation"`
+	// CurveDistance of the timings in milliseconds, for the curve method.
+	CurveDistance float64 `json:"curve_distance_ms"`
+	CharDistance  int     `json:"char_distance"`
+	Match         bool    `json:"match"`
+	// Score with partial credit, for the tolerance method, see
+	// ScoreWithPenalties.
+	Score float64 `json:"score"`
+	// FailIndex is the first position whose character differs or, for the
+	// tolerance method, is typed out of tolerance, or the end of the
+	// shorter key if they only differ in length. -1 when there is none.
+	FailIndex int `json:"fail_index"`
+	// Norm the match was decided on and the NormDistance of the timings,
+	// in tolerances, see WithNorm.
+	Norm         string  `json:"norm,omitempty"`
+	NormDistance float64 `json:"norm_distance,omitempty"`
+	// Offset in milliseconds added to the typed intervals before
+	// comparing, see BestOffset.
+	Offset float64 `json:"offset_ms,omitempty"`
+	// distances of the matching characters in tolerances, for WithNorm.
+	distances []float64
+}
+
+// fail records a failing position, the first one only.
+func (report *CompareReport) fail(i int) {
+	if report.FailIndex < 0 {
+		report.FailIndex = i
+	}
+}
+
+// failLength records the end of the shorter key as failing when the
+// lengths differ.
+func (report *CompareReport) failLength() {
+	if report.Length != report.TypedLength {
+		report.fail(min(report.Length, report.TypedLength))
+	}
+}
+
+// ScoringConfig is what each error costs in ScoreWithPenalties, in
+// characters.
+type ScoringConfig struct {
+	// TimingMissPenalty for every matching character typed out of
+	// tolerance.
+	TimingMissPenalty float64
+	// CharMissPenalty for every character edit between both keys.
+	CharMissPenalty float64
+}
+
+var defaultScoringConfig = ScoringConfig{TimingMissPenalty: 1, CharMissPenalty: 2}
+
+// ScoreWithPenalties grades a comparison rather than telling whether the
+// keys match: starting from 1, each error deducts its penalty divided by
+// the reference length, down to 0. With a char penalty of 0 it is the
+// fraction of correctly typed characters within tolerance.
+func (report CompareReport) ScoreWithPenalties(config ScoringConfig) float64 {
+	if report.Length == 0 {
+		return 0
+	}
+
+	timingMisses := report.MatchingChars - report.WithinTolerance
+	penalty := float64(timingMisses)*config.TimingMissPenalty + float64(report.CharDistance)*config.CharMissPenalty
+
+	return max(0, 1-penalty/float64(report.Length))
+}
+
+// Compare counts the positions where rk and the reference share the same
+// character, and the ones among them timed within tolerance of each other,
+// the tolerance being computed from the reference timing. They match if all
+// their characters do and are within tolerance.
+func Compare(reference Rythmkey, rk Rythmkey, tolerance Tolerance) CompareReport {
+	return compareWithin(reference, rk, func(i int) time.Duration {
+		return tolerance(reference[i].Timing)
+	})
+}
+
+// CompareSloped compares like Compare with a tolerance growing along the
+// key, since the rhythm gets noisier the further it goes: slope is added
+// to the tolerance of every position after the first, i*slope at position
+// i. The slope is always a duration, added once tolerance is computed from
+// the reference timing, so with a percentage the growth doesn't depend on
+// the timing and with max or min it's added to the result.
+func CompareSloped(reference Rythmkey, rk Rythmkey, tolerance Tolerance, slope time.Duration) CompareReport {
+	return compareWithin(reference, rk, func(i int) time.Duration {
+		return tolerance(reference[i].Timing) + slope*time.Duration(i)
+	})
+}
+
+// CompareContext compares like Compare unless ctx is already done, in
+// which case it returns ctx.Err(). A comparison is too quick to be worth
+// interrupting once started.
+func CompareContext(ctx context.Context, reference Rythmkey, rk Rythmkey, tolerance Tolerance) (CompareReport, error) {
+	if err := ctx.Err(); err != nil {
+		return CompareReport{}, err
+	}
+
+	return Compare(reference, rk, tolerance), nil
+}
+
+// compareWithin compares like Compare with the tolerance of every position
+// of the reference.
+func compareWithin(reference Rythmkey, rk Rythmkey, tolerance func(i int) time.Duration) CompareReport {
+	report := CompareReport{
+		Method:       "tolerance",
+		Length:       reference.Len(),
+		TypedLength:  rk.Len(),
+		CharDistance: reference.CharDistance(rk),
+		FailIndex:    -1,
+	}
+
+	for i := 0; i < len(reference) && i < len(rk); i++ {
+		if reference[i].Char != rk[i].Char {
+			report.fail(i)
+			continue
+		}
+		report.MatchingChars++
+
+		d, t := reference[i].Timing-rk[i].Timing, tolerance(i)
+		report.distances = append(report.distances, float64(d)/float64(max(t, 1)))
+		if d <= t && d >= -t {
+			report.WithinTolerance++
+		} else {
+			report.fail(i)
+		}
+	}
+	report.failLength()
+
+	report.Match = len(reference) == len(rk) && report.WithinTolerance == len(reference)
+	report.Score = report.ScoreWithPenalties(defaultScoringConfig)
+	return report
+}
+
+func (report CompareReport) String() string {
+	verdict := "mismatch"
+	if report.Match {
+		verdict = "match"
+	}
+
+	if report.Method == "curve" {
+		return fmt.Sprintf("%d/%d characters typed, timing curve distance %.2fms: %s", report.TypedLength, report.Length, report.CurveDistance, verdict)
+	}
+
+	if report.Method == "exact" {
+		return fmt.Sprintf("%d/%d characters match, quantized: %s", report.MatchingChars, report.Length, verdict)
+	}
+
+	if report.Method == "rank" {
+		return fmt.Sprintf("%d/%d characters match, rank correlation %.2f: %s", report.MatchingChars, report.Length, report.Correlation, verdict)
+	}
+
+	if report.Norm != "" {
+		return fmt.Sprintf("%d/%d characters match, %d/%d within tolerance, %s distance %.2f: %s", report.MatchingChars, report.Length, report.WithinTolerance, report.Length, report.Norm, report.NormDistance, verdict)
+	}
+
+	return fmt.Sprintf("%d/%d characters match, %d/%d within tolerance, score %.2f: %s", report.MatchingChars, report.Length, report.WithinTolerance, report.Length, report.Score, verdict)
+}
+
+// Err returns nil for a match, or a *MismatchError telling why the keys
+// don't match.
+func (report CompareReport) Err() error {
+	switch {
+	case report.Match:
+		return nil
+	case report.Method == "curve":
+		return &MismatchError{Kind: TimingMismatch, Typed: report.TypedLength, Expected: report.Length, Index: -1}
+	case report.TypedLength != report.Length:
+		return &MismatchError{Kind: LengthMismatch, Typed: report.TypedLength, Expected: report.Length, Index: -1}
+	case report.MatchingChars != report.Length:
+		return &MismatchError{Kind: CharMismatch, Typed: report.TypedLength, Expected: report.Length, Index: -1}
+	}
+
+	return &MismatchError{Kind: TimingMismatch, Typed: report.TypedLength, Expected: report.Length, Index: -1}
+}
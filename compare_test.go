@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareReport(t *testing.T) {
+	reference := mustParse(t, "t0.at100.bt200.c")
+	tolerance := AbsoluteTolerance(50 * time.Millisecond)
+
+	for _, tc := range []struct {
+		typed     string
+		matching  int
+		within    int
+		distance  int
+		failIndex int
+		match     bool
+	}{
+		{"t0.at100.bt200.c", 3, 3, 0, -1, true},
+		{"t0.at150.bt150.c", 3, 3, 0, -1, true},
+		{"t0.at151.bt200.c", 3, 2, 0, 1, false},
+		{"t0.at100.xt200.c", 2, 2, 1, 1, false},
+		{"t0.at100.b", 2, 2, 1, 2, false},
+		{"t0.at100.bt200.ct10.d", 3, 3, 1, 3, false},
+	} {
+		report := Compare(reference, mustParse(t, tc.typed), tolerance)
+
+		if report.MatchingChars != tc.matching || report.WithinTolerance != tc.within || report.CharDistance != tc.distance || report.FailIndex != tc.failIndex || report.Match != tc.match {
+			t.Errorf("Compare(%s) = %+v, want %d matching, %d within, distance %d, failing at %d, match %t", tc.typed, report, tc.matching, tc.within, tc.distance, tc.failIndex, tc.match)
+		}
+	}
+}
+
+func TestCompareReportJSON(t *testing.T) {
+	report := Compare(mustParse(t, "t0.at100.b"), mustParse(t, "t0.at300.b"), AbsoluteTolerance(50*time.Millisecond))
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range []string{`"method":"tolerance"`, `"within_tolerance":1`, `"fail_index":1`, `"match":false`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("report %s lacks %s", data, field)
+		}
+	}
+
+	if got, want := report.String(), "2/2 characters match, 1/2 within tolerance, score 0.50: mismatch"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+}
+
+func TestScoreWithPenalties(t *testing.T) {
+	reference := mustParse(t, "t0.at100.bt100.ct100.dt100.e")
+	tolerance := AbsoluteTolerance(50 * time.Millisecond)
+	config := ScoringConfig{TimingMissPenalty: 1, CharMissPenalty: 2}
+
+	// Every further error lowers the score by its penalty over the 5
+	// characters of the reference.
+	for _, tc := range []struct {
+		typed string
+		score float64
+	}{
+		{"t0.at100.bt100.ct100.dt100.e", 1},
+		{"t0.at300.bt100.ct100.dt100.e", 0.8},
+		{"t0.at300.bt300.ct100.dt100.e", 0.6},
+		{"t0.at300.bt300.ct100.xt100.e", 0.2},
+		{"t0.at300.bt300.ct100.xt100.y", 0},
+	} {
+		score := Compare(reference, mustParse(t, tc.typed), tolerance).ScoreWithPenalties(config)
+		if math.Abs(score-tc.score) > 1e-9 {
+			t.Errorf("ScoreWithPenalties(%s) = %v, want %v", tc.typed, score, tc.score)
+		}
+	}
+
+	// Without a char penalty, the score is the fraction of characters
+	// typed right within tolerance.
+	report := Compare(reference, mustParse(t, "t0.at300.bt100.xt100.dt100.e"), tolerance)
+	if score := report.ScoreWithPenalties(ScoringConfig{TimingMissPenalty: 1}); math.Abs(score-0.8) > 1e-9 {
+		t.Errorf("ScoreWithPenalties without char penalty = %v, want 0.8", score)
+	}
+
+	if score := (CompareReport{}).ScoreWithPenalties(config); score != 0 {
+		t.Errorf("ScoreWithPenalties of an empty reference = %v, want 0", score)
+	}
+}
+
+func TestComparePenaltyFlags(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at300.bt100.xt100.dt100.e")
+
+	for _, tc := range []struct {
+		flags []string
+		score float64
+	}{
+		{nil, 0.4},
+		{[]string{"--timing-penalty", "0.5", "--char-penalty", "1"}, 0.7},
+		{[]string{"--char-penalty", "0"}, 0.8},
+	} {
+		result := runApp(t, append([]string{"compare", "--rythmkey", "t0.at100.bt100.ct100.dt100.e", "--tolerance", "50ms", "--format", "json"}, tc.flags...)...)
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+
+		report := CompareReport{}
+		if err := json.Unmarshal([]byte(result.stdout), &report); err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(report.Score-tc.score) > 1e-9 {
+			t.Errorf("compare %v scored %v, want %v", tc.flags, report.Score, tc.score)
+		}
+	}
+
+	if result := runApp(t, "compare", "--rythmkey", "t0.at100.b", "--char-penalty", "-1"); result.err == nil {
+		t.Error("compare --char-penalty -1 succeeded")
+	}
+}
+
+func TestCompareRepeats(t *testing.T) {
+	reference := mustParse(t, "t0.ht100.et100.lt100.lt100.o")
+	tolerance := AbsoluteTolerance(50 * time.Millisecond)
+
+	for _, tc := range []struct {
+		typed     string
+		within    int
+		failIndex int
+	}{
+		{"t0.ht100.et100.lt100.lt100.o", 5, -1},
+		// Either l off on its own.
+		{"t0.ht100.et300.lt100.lt100.o", 4, 2},
+		{"t0.ht100.et100.lt300.lt100.o", 4, 3},
+		// A single l is a missing character, not a merged repeat.
+		{"t0.ht100.et100.lt100.o", 3, 3},
+	} {
+		report := Compare(reference, mustParse(t, tc.typed), tolerance)
+		if report.WithinTolerance != tc.within || report.FailIndex != tc.failIndex {
+			t.Errorf("Compare(%s) = %d within, failing at %d, want %d, %d", tc.typed, report.WithinTolerance, report.FailIndex, tc.within, tc.failIndex)
+		}
+	}
+}
+
+// The tolerance at position i is the base tolerance of the reference
+// timing, a duration or a percentage of it, plus i slopes.
+func TestCompareSloped(t *testing.T) {
+	const slope = 10 * time.Millisecond
+	reference := mustParse(t, "t0.at100.bt100.ct100.d")
+
+	for _, s := range []string{"50ms", "50%", "max(20ms,50%)"} {
+		tolerance, err := ParseTolerance(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 1; i < len(reference); i++ {
+			effective := 50*time.Millisecond + slope*time.Duration(i)
+
+			typed := mustParse(t, reference.Encode())
+			typed[i].Timing += effective
+			if report := CompareSloped(reference, typed, tolerance, slope); !report.Match || report.distances[i] != -1 {
+				t.Errorf("%s: CompareSloped off by %v at %d = %+v, want a match at one tolerance", s, effective, i, report)
+			}
+
+			typed[i].Timing += time.Millisecond
+			if report := CompareSloped(reference, typed, tolerance, slope); report.Match || report.FailIndex != i {
+				t.Errorf("%s: CompareSloped off by %v at %d = %+v, want failing at %d", s, effective+time.Millisecond, i, report, i)
+			}
+		}
+	}
+
+	// Without a slope it's Compare.
+	typed := mustParse(t, "t0.at150.bt100.ct151.d")
+	if sloped, flat := CompareSloped(reference, typed, AbsoluteTolerance(50*time.Millisecond), 0), Compare(reference, typed, AbsoluteTolerance(50*time.Millisecond)); sloped.Match != flat.Match || sloped.FailIndex != flat.FailIndex {
+		t.Errorf("CompareSloped without a slope = %+v, Compare = %+v", sloped, flat)
+	}
+}
+
+// The same 65ms deviation fails at the second character, 60ms of
+// tolerance, and passes at the fourth, 80ms.
+func TestToleranceSlopeFlag(t *testing.T) {
+	const reference = "t0.at100.bt100.ct100.d"
+
+	for _, tc := range []struct {
+		typed string
+		match bool
+	}{
+		{"t0.at165.bt100.ct100.d", false},
+		{"t0.at100.bt100.ct165.d", true},
+	} {
+		t.Setenv(encodedInputEnv, tc.typed)
+
+		result := runApp(t, "compare", "--rythmkey", reference, "--tolerance", "50ms", "--tolerance-slope", "10ms", "--format", "json")
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+		report := CompareReport{}
+		if err := json.Unmarshal([]byte(result.stdout), &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.Match != tc.match {
+			t.Errorf("compare --tolerance-slope 10ms of %s matched %t, want %t", tc.typed, report.Match, tc.match)
+		}
+
+		code := map[bool]int{true: 0, false: exitReject}[tc.match]
+		if result := runApp(t, "verify", "--samples-dir", writeSamples(t, reference), "--tolerance", "50ms", "--tolerance-slope", "10ms", "--reject-delay", "0"); result.code != code {
+			t.Errorf("verify --tolerance-slope 10ms of %s exited %d, want %d: %v", tc.typed, result.code, code, result.err)
+		}
+	}
+
+	dir := t.TempDir()
+	saveProfile(t, dir, "user.json", mustProfile(t, reference))
+	for _, args := range [][]string{
+		{"verify", "--profiles-dir", dir, "--tolerance-slope", "10ms"},
+		{"verify", "--samples-dir", writeSamples(t, reference), "--method", "dtw", "--tolerance-slope", "10ms"},
+	} {
+		if result := runApp(t, args...); result.err == nil || !strings.Contains(result.err.Error(), "--tolerance-slope") {
+			t.Errorf("%v = %v, want a --tolerance-slope error", args, result.err)
+		}
+	}
+}
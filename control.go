@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+var (
+	errCaptureAborted = errors.New("capture aborted")
+	errCaptureTimeout = errors.New("capture timed out")
+	errFirstKeyIdle   = errors.New("no key pressed in time, capture aborted")
+	errCaptureTooLong = errors.New("capture exceeded its maximum total time, key discarded")
+)
+
+// captureReader is what a capture reads keystrokes from: input, or input
+// polled along with opts.Control, its timeouts and the cancellation of
+// ctx, see ReadOptions. release frees what polling needs once the capture
+// is over.
+func captureReader(ctx context.Context, input *os.File, opts ReadOptions) (r io.Reader, release func(), err error) {
+	if opts.Control == nil && opts.Timeout <= 0 && opts.FirstKeyTimeout <= 0 && opts.MaxTotalTime <= 0 && ctx.Done() == nil {
+		return input, func() {}, nil
+	}
+
+	return newPollReader(ctx, input, opts)
+}
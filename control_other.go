@@ -0,0 +1,14 @@
+//go:build !unix
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+func newPollReader(ctx context.Context, input *os.File, opts ReadOptions) (io.Reader, func(), error) {
+	return nil, nil, errors.New("a control descriptor, timeouts and cancellation aren't supported on this platform")
+}
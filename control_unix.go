@@ -0,0 +1,125 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pollReader polls its input along with an optional control descriptor:
+// a read fails with errCaptureAborted as soon as the control one has data
+// or is hung up, even between two keystrokes, with the error of its
+// context once it's done, or with a timeout error once a deadline passes.
+// The first key deadline is disarmed by the first byte read.
+type pollReader struct {
+	input   *os.File
+	control *os.File
+	// canceled is the read end of a pipe closed when ctx is done.
+	canceled *os.File
+	ctx      context.Context
+
+	deadline time.Time
+	firstKey time.Time
+	maxTotal time.Time
+}
+
+// newPollReader arms the deadlines of opts from now, right before the
+// capture starts.
+func newPollReader(ctx context.Context, input *os.File, opts ReadOptions) (*pollReader, func(), error) {
+	r := &pollReader{input: input, control: opts.Control, ctx: ctx}
+	release := func() {}
+
+	if ctx.Done() != nil {
+		canceled, cancel, err := os.Pipe()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stop := context.AfterFunc(ctx, func() { cancel.Close() })
+		r.canceled = canceled
+		release = func() {
+			if stop() {
+				cancel.Close()
+			}
+			canceled.Close()
+		}
+	}
+
+	start := time.Now()
+	if opts.Timeout > 0 {
+		r.deadline = start.Add(opts.Timeout)
+	}
+	if opts.FirstKeyTimeout > 0 {
+		r.firstKey = start.Add(opts.FirstKeyTimeout)
+	}
+	if opts.MaxTotalTime > 0 {
+		r.maxTotal = start.Add(opts.MaxTotalTime)
+	}
+
+	return r, release, nil
+}
+
+// wait returns the poll timeout in milliseconds until the nearest deadline
+// and the error it fails with, -1 if there is none.
+func (r *pollReader) wait() (int, error) {
+	timeout, err := -1, error(nil)
+	for _, d := range []struct {
+		at  time.Time
+		err error
+	}{{r.deadline, errCaptureTimeout}, {r.firstKey, errFirstKeyIdle}, {r.maxTotal, errCaptureTooLong}} {
+		if d.at.IsZero() {
+			continue
+		}
+
+		ms := int(max(0, time.Until(d.at).Milliseconds()))
+		if timeout < 0 || ms < timeout {
+			timeout, err = ms, d.err
+		}
+	}
+
+	return timeout, err
+}
+
+func (r *pollReader) Read(p []byte) (int, error) {
+	fds := []unix.PollFd{{Fd: int32(r.input.Fd()), Events: unix.POLLIN}}
+	if r.canceled != nil {
+		fds = append(fds, unix.PollFd{Fd: int32(r.canceled.Fd()), Events: unix.POLLIN})
+	}
+	if r.control != nil {
+		fds = append(fds, unix.PollFd{Fd: int32(r.control.Fd()), Events: unix.POLLIN})
+	}
+
+	timeout, timeoutErr := r.wait()
+
+	// An interrupted poll is retried by Capture like an interrupted read.
+	n, err := unix.Poll(fds, timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	if n == 0 {
+		return 0, timeoutErr
+	}
+
+	for _, fd := range fds[1:] {
+		if fd.Revents == 0 {
+			continue
+		}
+
+		if r.canceled != nil && fd.Fd == int32(r.canceled.Fd()) {
+			return 0, r.ctx.Err()
+		}
+		return 0, errCaptureAborted
+	}
+
+	c, err := r.input.Read(p)
+	if c > 0 {
+		r.firstKey = time.Time{}
+	}
+
+	return c, err
+}
@@ -0,0 +1,337 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pipe returns both ends of a pipe closed when the test ends.
+func pipe(t *testing.T) (*os.File, *os.File) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+
+	return r, w
+}
+
+// pollingReader polls input as opts tells until the test ends.
+func pollingReader(t *testing.T, input *os.File, opts ReadOptions) io.Reader {
+	t.Helper()
+
+	r, release, err := captureReader(context.Background(), input, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(release)
+
+	return r
+}
+
+// controlledReader polls input along with control until the test ends.
+func controlledReader(t *testing.T, input, control *os.File) (io.Reader, ReadOptions) {
+	t.Helper()
+
+	opts := ReadOptions{Control: control}
+	return pollingReader(t, input, opts), opts
+}
+
+func TestControlAbortsBetweenKeystrokes(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		abort func(w *os.File)
+	}{
+		{"data", func(w *os.File) { w.WriteString("x") }},
+		{"hang up", func(w *os.File) { w.Close() }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			input, keys := pipe(t)
+			control, abort := pipe(t)
+
+			// The abort only arrives after the first keystrokes, with
+			// the capture blocked waiting for the next one, which
+			// never comes.
+			typed := make(chan struct{})
+			go func() {
+				keys.WriteString("a")
+				time.Sleep(10 * time.Millisecond)
+				keys.WriteString("b")
+				time.Sleep(20 * time.Millisecond)
+				close(typed)
+				tc.abort(abort)
+			}()
+
+			r, opts := controlledReader(t, input, control)
+			done := make(chan error, 1)
+			go func() {
+				rk := Rythmkey{}
+				done <- rk.Capture(r, opts)
+			}()
+
+			select {
+			case err := <-done:
+				select {
+				case <-typed:
+				default:
+					t.Fatalf("capture returned %v before the abort", err)
+				}
+				if !errors.Is(err, errCaptureAborted) {
+					t.Errorf("capture = %v, want %v", err, errCaptureAborted)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("capture not aborted")
+			}
+		})
+	}
+}
+
+func TestControlIdle(t *testing.T) {
+	input, keys := pipe(t)
+	control, _ := pipe(t)
+
+	go func() {
+		for _, c := range "abc\n" {
+			keys.WriteString(string(c))
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	r, opts := controlledReader(t, input, control)
+	rk := Rythmkey{}
+	if err := rk.Capture(r, opts); err != nil {
+		t.Fatalf("capture with an idle control = %v", err)
+	}
+	if rk.Chars() != "abc" {
+		t.Errorf("capture with an idle control = %q, want %q", rk.Chars(), "abc")
+	}
+}
+
+func TestReadControlFD(t *testing.T) {
+	control, abort := pipe(t)
+	abort.WriteString("x")
+
+	// The command owns the descriptor it's given, and closes it once its
+	// file is collected.
+	fd, err := unix.Dup(int(control.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is typed, stdin stays open: only the control descriptor
+	// ends the capture.
+	input, keys := pipe(t)
+	defer keys.Close()
+	stdin := os.Stdin
+	os.Stdin, testMode = input, true
+	t.Cleanup(func() { os.Stdin, testMode = stdin, false })
+
+	result := runApp(t, "read", "--control-fd", fmt.Sprint(fd))
+	if result.err == nil || !strings.Contains(result.err.Error(), errCaptureAborted.Error()) {
+		t.Errorf("read --control-fd = %v, want %v", result.err, errCaptureAborted)
+	}
+}
+
+// typeAfter writes every key of keys to w, each after its delay.
+func typeAfter(w *os.File, keys string, delays ...time.Duration) {
+	go func() {
+		for i := range keys {
+			time.Sleep(delays[i])
+			w.WriteString(keys[i : i+1])
+		}
+	}()
+}
+
+func TestFirstKeyTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		opts   ReadOptions
+		keys   string
+		delays []time.Duration
+		err    error
+	}{
+		{
+			name: "fires before the first key",
+			opts: ReadOptions{FirstKeyTimeout: 20 * time.Millisecond},
+			err:  errFirstKeyIdle,
+		},
+		{
+			name:   "fires with the first key late",
+			opts:   ReadOptions{FirstKeyTimeout: 20 * time.Millisecond},
+			keys:   "a\n",
+			delays: []time.Duration{200 * time.Millisecond, 0},
+			err:    errFirstKeyIdle,
+		},
+		{
+			name:   "disarmed by the first key",
+			opts:   ReadOptions{FirstKeyTimeout: 30 * time.Millisecond},
+			keys:   "ab\n",
+			delays: []time.Duration{0, 100 * time.Millisecond, 0},
+		},
+		{
+			name:   "disarmed, the overall timeout still fires",
+			opts:   ReadOptions{FirstKeyTimeout: 30 * time.Millisecond, Timeout: 80 * time.Millisecond},
+			keys:   "ab\n",
+			delays: []time.Duration{0, 300 * time.Millisecond, 0},
+			err:    errCaptureTimeout,
+		},
+		{
+			name:   "within both",
+			opts:   ReadOptions{FirstKeyTimeout: 100 * time.Millisecond, Timeout: time.Second},
+			keys:   "ab\n",
+			delays: []time.Duration{0, 20 * time.Millisecond, 0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			input, keys := pipe(t)
+			typeAfter(keys, tc.keys, tc.delays...)
+
+			rk := Rythmkey{}
+			err := rk.Capture(pollingReader(t, input, tc.opts), tc.opts)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("capture = %v, want %v", err, tc.err)
+			}
+			if err == nil && rk.Chars() != "ab" {
+				t.Errorf("captured %q, want %q", rk.Chars(), "ab")
+			}
+		})
+	}
+}
+
+func TestReadFirstKeyTimeout(t *testing.T) {
+	input, keys := pipe(t)
+	defer keys.Close()
+	stdin := os.Stdin
+	os.Stdin, testMode = input, true
+	t.Cleanup(func() { os.Stdin, testMode = stdin, false })
+
+	result := runApp(t, "read", "--first-key-timeout", "20ms", "--timeout", "10s")
+	if result.err == nil || !strings.Contains(result.err.Error(), errFirstKeyIdle.Error()) {
+		t.Errorf("read --first-key-timeout = %v, want %v", result.err, errFirstKeyIdle)
+	}
+}
+
+// A capture aborted by --timeout or --first-key-timeout leaves cbreak
+// mode like any other.
+func TestTimeoutsRestoreCbreak(t *testing.T) {
+	for _, tc := range []struct {
+		opts    ReadOptions
+		wantErr error
+	}{
+		{ReadOptions{Timeout: 20 * time.Millisecond}, errCaptureTimeout},
+		{ReadOptions{FirstKeyTimeout: 20 * time.Millisecond, Timeout: 10 * time.Second}, errFirstKeyIdle},
+	} {
+		input, keys := pipe(t)
+		stdin := os.Stdin
+		os.Stdin = input
+		t.Cleanup(func() { os.Stdin = stdin })
+		left := stubCbreak(t)
+
+		rk := Rythmkey{}
+		if err := rk.ReadWith(tc.opts); !errors.Is(err, tc.wantErr) {
+			t.Errorf("ReadWith = %v, want %v", err, tc.wantErr)
+		}
+		if *left != 1 {
+			t.Errorf("capture failing with %v left cbreak %d times, want once", tc.wantErr, *left)
+		}
+		keys.Close()
+	}
+}
+
+// Canceling the context of a capture in progress, with keys typed and
+// more to come, fails it right away with context.Canceled, zeroing the
+// keys typed.
+func TestCaptureCanceledMidway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	input, keys := pipe(t)
+	r, release, err := captureReader(ctx, input, ReadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	typeAfter(keys, "ab", 0, 10*time.Millisecond)
+	time.AfterFunc(60*time.Millisecond, cancel)
+
+	start := time.Now()
+	rk := Rythmkey{}
+	err = rk.Capture(r, ReadOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("capture = %v, want %v", err, context.Canceled)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("capture returned %s after being canceled", took)
+	}
+	requireZeroed(t, "canceled capture", rk)
+}
+
+func TestReadContextCanceled(t *testing.T) {
+	input, keys := pipe(t)
+	defer keys.Close()
+	stdin := os.Stdin
+	os.Stdin, testMode = input, true
+	t.Cleanup(func() { os.Stdin, testMode = stdin, false })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	typeAfter(keys, "a", 0)
+
+	rk := Rythmkey{}
+	if err := rk.ReadContext(ctx, ReadOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReadContext = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Already done, nothing is read.
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := rk.ReadContext(canceled, ReadOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadContext of a canceled context = %v, want %v", err, context.Canceled)
+	}
+
+	reference := mustParse(t, "t0.at100.b")
+	if _, err := CompareContext(canceled, reference, reference, AbsoluteTolerance(time.Millisecond)); !errors.Is(err, context.Canceled) {
+		t.Errorf("CompareContext of a canceled context = %v", err)
+	}
+	if _, _, err := mustProfile(t, "t0.at100.b").VerifyContext(canceled, reference, defaultThreshold); !errors.Is(err, context.Canceled) {
+		t.Errorf("VerifyContext of a canceled context = %v", err)
+	}
+}
+
+// A capture canceled midway leaves cbreak mode before failing.
+func TestReadContextCanceledRestoresCbreak(t *testing.T) {
+	input, keys := pipe(t)
+	defer keys.Close()
+	stdin := os.Stdin
+	os.Stdin = input
+	t.Cleanup(func() { os.Stdin = stdin })
+	left := stubCbreak(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	typeAfter(keys, "a", 0)
+
+	rk := Rythmkey{}
+	if err := rk.ReadContext(ctx, ReadOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReadContext = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if *left != 1 {
+		t.Errorf("canceled capture left cbreak %d times, want once", *left)
+	}
+}
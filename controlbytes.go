@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+)
+
+// escapeMark replaces the '.' ending a timing when the character after it
+// is written as two lowercase hex digits rather than as its raw byte, see
+// needsEscape.
+const escapeMark = ','
+
+// needsEscape tells the bytes Encode escapes: every byte below 0x20 but
+// the tab, so NUL and the newline among them, and DEL, 0x7f. Written raw
+// they would break text handlers and line based files. The tab is kept
+// raw as the plain whitespace character it is.
+func needsEscape(c byte) bool {
+	return (c < 0x20 && c != '\t') || c == 0x7f
+}
+
+// What a capture does with control bytes, needsEscape ones: ControlRejectNUL
+// rejects NUL only, ControlReject all of them, and ControlRecord records
+// them like any other character.
+const (
+	ControlRejectNUL = "reject-nul"
+	ControlReject    = "reject"
+	ControlRecord    = "record"
+)
+
+// CheckControlBytes reports the first character of the key mode rejects,
+// with its position. An empty mode is ControlRejectNUL.
+func (rythmkey Rythmkey) CheckControlBytes(mode string) error {
+	if mode == ControlRecord {
+		return nil
+	}
+
+	for i, ct := range rythmkey {
+		switch {
+		case ct.Char == 0:
+			return fmt.Errorf("NUL byte at position %d, record it with --control-bytes %s", i, ControlRecord)
+		case mode == ControlReject && needsEscape(ct.Char):
+			return fmt.Errorf("control byte %s at position %d, record it with --control-bytes %s", escapeChar(ct.Char), i, ControlRecord)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckControlBytes(t *testing.T) {
+	for _, tc := range []struct {
+		chars    string
+		accepted []string
+	}{
+		{"abc", []string{"", ControlRejectNUL, ControlReject, ControlRecord}},
+		{"a\tb", []string{"", ControlRejectNUL, ControlReject, ControlRecord}},
+		{"a\x00b", []string{ControlRecord}},
+		{"a\x1bb", []string{"", ControlRejectNUL, ControlRecord}},
+		{"a\x7fb", []string{"", ControlRejectNUL, ControlRecord}},
+	} {
+		rk := Rythmkey{}
+		for i := range tc.chars {
+			rk.Add(tc.chars[i], 0)
+		}
+
+		for _, mode := range []string{"", ControlRejectNUL, ControlReject, ControlRecord} {
+			accept := slices.Contains(tc.accepted, mode)
+			if err := rk.CheckControlBytes(mode); (err == nil) != accept {
+				t.Errorf("CheckControlBytes(%q) of %q = %v, want accepting %t", mode, tc.chars, err, accept)
+			}
+		}
+	}
+}
+
+// A NUL fed through the scripted reader is rejected by default, the key
+// zeroed, and recorded and escaped when asked to.
+func TestReadNUL(t *testing.T) {
+	for _, tc := range []struct {
+		mode    string
+		input   string
+		encoded string
+	}{
+		{"", "a\x00b\n", ""},
+		{ControlRejectNUL, "a\x00b\n", ""},
+		{ControlReject, "a\x01b\n", ""},
+		{"", "a\x01b\n", "t0.at0,01t0.b"},
+		{ControlRecord, "a\x00b\n", "t0.at0,00t0.b"},
+	} {
+		scriptStdin(t, tc.input)
+
+		raw := Rythmkey{}
+		ro := ReadOptions{ControlBytes: tc.mode}
+		rawCapture(&ro, &raw)
+
+		rk := Rythmkey{}
+		err := rk.ReadWith(ro)
+		if tc.encoded == "" {
+			if err == nil {
+				t.Errorf("ReadWith(%q) of %q = %q, want an error", tc.mode, tc.input, rk.Encode())
+			}
+			requireZeroed(t, "rejected capture", raw)
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("ReadWith(%q) of %q = %v", tc.mode, tc.input, err)
+		}
+		if rk.Encode() != tc.encoded {
+			t.Errorf("ReadWith(%q) of %q = %s, want %s", tc.mode, tc.input, rk.Encode(), tc.encoded)
+		}
+		if parsed, err := ParseRythmkey(rk.Encode()); err != nil || parsed.Chars() != rk.Chars() {
+			t.Errorf("ParseRythmkey(%s) = %q, %v, want %q", rk.Encode(), parsed.Chars(), err, rk.Chars())
+		}
+	}
+}
+
+func TestReadControlBytesFlag(t *testing.T) {
+	scriptStdin(t, "a\x00b\n")
+	if result := runApp(t, "read"); result.err == nil || !strings.Contains(result.err.Error(), "NUL byte at position 1") {
+		t.Errorf("read of a NUL = %v, want it rejected", result.err)
+	}
+
+	scriptStdin(t, "a\x00b\n")
+	result := runApp(t, "read", "--control-bytes", ControlRecord)
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if got := strings.TrimSpace(result.stdout); got != "t0.at0,00t0.b" || strings.ContainsRune(result.stdout, 0) {
+		t.Errorf("read --control-bytes record = %q, want the NUL escaped", result.stdout)
+	}
+
+	scriptStdin(t, "ab\n")
+	if result := runApp(t, "read", "--control-bytes", "escape"); result.err == nil {
+		t.Error("read --control-bytes escape succeeded")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ResampleTimings returns the cumulative timing curve of the key, in
+// milliseconds, linearly interpolated at n points evenly spread from its
+// first character to its last. Keys of different lengths resampled to the
+// same n can then be compared point by point as continuous curves. A
+// single point is the total duration.
+func (rythmkey Rythmkey) ResampleTimings(n int) []float64 {
+	if n < 1 || len(rythmkey) == 0 {
+		return nil
+	}
+
+	cumulative := make([]float64, len(rythmkey))
+	total := 0.0
+	for i, ct := range rythmkey {
+		total += milliseconds(ct.Timing)
+		cumulative[i] = total
+	}
+
+	if n == 1 {
+		return []float64{total}
+	}
+
+	resampled := make([]float64, n)
+	for j := range resampled {
+		x := float64(j) * float64(len(cumulative)-1) / float64(n-1)
+		i := int(x)
+		if i >= len(cumulative)-1 {
+			resampled[j] = cumulative[len(cumulative)-1]
+			continue
+		}
+
+		frac := x - float64(i)
+		resampled[j] = cumulative[i] + frac*(cumulative[i+1]-cumulative[i])
+	}
+
+	return resampled
+}
+
+// CurveDistance is the mean distance, in milliseconds, between the
+// resampled timing curves of both keys, resampled at the length of the
+// longest one.
+func (rythmkey Rythmkey) CurveDistance(other Rythmkey) (float64, error) {
+	if len(rythmkey) == 0 || len(other) == 0 {
+		return 0, errors.New("empty rythmkey")
+	}
+
+	n := max(len(rythmkey), len(other))
+	a, b := rythmkey.ResampleTimings(n), other.ResampleTimings(n)
+
+	distance := 0.0
+	for i := range a {
+		distance += math.Abs(a[i] - b[i])
+	}
+
+	return distance / float64(n), nil
+}
+
+// CompareCurve compares the timing curves of both keys, see
+// CurveDistance, ignoring their characters: a key with a spurious or
+// missing keystroke still matches if its rhythm does. They match if the
+// distance is within tolerance of the mean interval of the reference.
+// Character counts are reported but play no part.
+func CompareCurve(reference Rythmkey, rk Rythmkey, tolerance Tolerance) (CompareReport, error) {
+	report := CompareReport{
+		Method:       "curve",
+		Length:       reference.Len(),
+		TypedLength:  rk.Len(),
+		CharDistance: reference.CharDistance(rk),
+		FailIndex:    -1,
+	}
+
+	for i := 0; i < len(reference) && i < len(rk); i++ {
+		if reference[i].Char == rk[i].Char {
+			report.MatchingChars++
+		} else {
+			report.fail(i)
+		}
+	}
+	report.failLength()
+
+	distance, err := reference.CurveDistance(rk)
+	if err != nil {
+		return CompareReport{}, err
+	}
+
+	meanInterval := reference.TotalDuration()
+	if len(reference) > 1 {
+		meanInterval /= time.Duration(len(reference) - 1)
+	}
+
+	report.CurveDistance = distance
+	report.Match = distance <= milliseconds(tolerance(meanInterval))
+	return report, nil
+}
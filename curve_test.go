@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestResampleTimings(t *testing.T) {
+	rk := mustParse(t, "t0.at100.bt100.c")
+
+	for _, tc := range []struct {
+		n         int
+		resampled []float64
+	}{
+		{3, []float64{0, 100, 200}},
+		{5, []float64{0, 50, 100, 150, 200}},
+		{2, []float64{0, 200}},
+		{1, []float64{200}},
+		{0, nil},
+	} {
+		if resampled := rk.ResampleTimings(tc.n); !slices.Equal(resampled, tc.resampled) {
+			t.Errorf("ResampleTimings(%d) = %v, want %v", tc.n, resampled, tc.resampled)
+		}
+	}
+
+	if resampled := (Rythmkey{}).ResampleTimings(3); resampled != nil {
+		t.Errorf("ResampleTimings of an empty key = %v, want none", resampled)
+	}
+}
+
+func TestCurveDistance(t *testing.T) {
+	reference := mustParse(t, "t0.at100.bt100.ct100.d")
+	// The same rhythm with a spurious keystroke splitting the last
+	// interval: both are resampled at 5 points, 0 75 150 225 300 against
+	// 0 100 200 250 300.
+	longer := mustParse(t, "t0.at100.bt100.ct50.xt50.d")
+
+	distance, err := reference.CurveDistance(longer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if distance != 20 {
+		t.Errorf("CurveDistance = %v, want 20", distance)
+	}
+
+	if distance, _ := reference.CurveDistance(reference); distance != 0 {
+		t.Errorf("CurveDistance to itself = %v, want 0", distance)
+	}
+
+	if _, err := reference.CurveDistance(Rythmkey{}); err == nil {
+		t.Error("CurveDistance to an empty key succeeded")
+	}
+}
+
+func TestCompareCurve(t *testing.T) {
+	reference := mustParse(t, "t0.at100.bt100.ct100.d")
+
+	for _, tc := range []struct {
+		typed string
+		match bool
+	}{
+		{"t0.at100.bt100.ct50.xt50.d", true},
+		// Characters play no part.
+		{"t0.wt100.xt100.yt100.z", true},
+		{"t0.at300.bt300.ct300.d", false},
+	} {
+		report, err := CompareCurve(reference, mustParse(t, tc.typed), AbsoluteTolerance(30*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if report.Match != tc.match || report.Method != "curve" {
+			t.Errorf("CompareCurve(%s) = %+v, want match %t", tc.typed, report, tc.match)
+		}
+	}
+}
+
+func TestCompareCurveCommand(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at100.bt100.ct50.xt50.d")
+
+	result := runApp(t, "compare", "--rythmkey", "t0.at100.bt100.ct100.d", "--method", "curve", "--tolerance", "30ms", "--format", "json")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	report := CompareReport{}
+	if err := json.Unmarshal([]byte(result.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Match || report.CurveDistance != 20 || report.TypedLength != 5 {
+		t.Errorf("compare --method curve = %+v, want a match at distance 20", report)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+)
+
+// TimingDelta is how much later, in milliseconds, a character was typed
+// than in the reference, negative when rushed.
+type TimingDelta struct {
+	Index int     `json:"index"`
+	Char  string  `json:"char"`
+	Delta float64 `json:"delta_ms"`
+}
+
+// Deltas returns the signed timing difference of every character of rk
+// with the reference, which must have the same characters.
+func Deltas(reference Rythmkey, rk Rythmkey) ([]TimingDelta, error) {
+	if err := checkChars(reference.Chars(), rk.Chars()); err != nil {
+		return nil, err
+	}
+
+	deltas := make([]TimingDelta, len(rk))
+	for i, ct := range rk {
+		deltas[i] = TimingDelta{
+			Index: i,
+			Char:  string([]byte{ct.Char}),
+			Delta: milliseconds(ct.Timing - reference[i].Timing),
+		}
+	}
+
+	return deltas, nil
+}
+
+// deltaBarWidth is the width of the bar of the largest delta, on either
+// side of the reference.
+const deltaBarWidth = 20
+
+// WriteDeltaBars writes a row per character with its delta and a bar
+// going left of the reference when rushed and right when lagging, scaled
+// to the largest delta.
+func WriteDeltaBars(w io.Writer, deltas []TimingDelta) error {
+	largest := 0.0
+	for _, d := range deltas {
+		largest = math.Max(largest, math.Abs(d.Delta))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "index\tchar\tdelta")
+	for _, d := range deltas {
+		n := 0
+		if largest > 0 {
+			n = int(math.Round(math.Abs(d.Delta) / largest * deltaBarWidth))
+		}
+
+		left, right := strings.Repeat(" ", deltaBarWidth), strings.Repeat(" ", deltaBarWidth)
+		if d.Delta < 0 {
+			left = strings.Repeat(" ", deltaBarWidth-n) + strings.Repeat("<", n)
+		} else {
+			right = strings.Repeat(">", n) + strings.Repeat(" ", deltaBarWidth-n)
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%+.2f\t%s|%s\n", d.Index, escapeChar(d.Char[0]), d.Delta, left, strings.TrimRight(right, " "))
+	}
+
+	return tw.Flush()
+}
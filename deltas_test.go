@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeltas(t *testing.T) {
+	reference := mustParse(t, "t0.at120.bt80.ct100.\xe9")
+
+	deltas, err := Deltas(reference, mustParse(t, "t0.at90.bt130.ct100.\xe9"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []TimingDelta{
+		{Index: 0, Char: "a", Delta: 0},
+		{Index: 1, Char: "b", Delta: -30},
+		{Index: 2, Char: "c", Delta: 50},
+		{Index: 3, Char: "\xe9", Delta: 0},
+	}
+	if !reflect.DeepEqual(deltas, want) {
+		t.Errorf("Deltas = %+v, want %+v", deltas, want)
+	}
+
+	for _, rks := range []string{"t0.at90.bt130.c", "t0.at90.bt130.xt100.\xe9"} {
+		if _, err := Deltas(reference, mustParse(t, rks)); err == nil {
+			t.Errorf("Deltas(%s) succeeded with other characters", rks)
+		}
+	}
+}
+
+func TestWriteDeltaBars(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := WriteDeltaBars(&buf, []TimingDelta{
+		{Index: 0, Char: "a", Delta: 0},
+		{Index: 1, Char: "b", Delta: -25},
+		{Index: 2, Char: "c", Delta: 50},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blank := strings.Repeat(" ", deltaBarWidth)
+	want := "index  char  delta\n" +
+		"0      'a'   +0.00   " + blank + "|\n" +
+		"1      'b'   -25.00  " + strings.Repeat(" ", deltaBarWidth/2) + strings.Repeat("<", deltaBarWidth/2) + "|\n" +
+		"2      'c'   +50.00  " + blank + "|" + strings.Repeat(">", deltaBarWidth) + "\n"
+	if buf.String() != want {
+		t.Errorf("WriteDeltaBars =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
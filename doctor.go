@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// stty runs against /dev/tty, BSD stty names it with -f and GNU stty with -F.
+func stty(args ...string) *exec.Cmd {
+	if testMode {
+		return &exec.Cmd{Err: errTestMode}
+	}
+
+	flag := "-f"
+	if runtime.GOOS == "linux" {
+		flag = "-F"
+	}
+
+	return exec.Command("stty", append([]string{flag, "/dev/tty"}, args...)...)
+}
+
+type Check struct {
+	Name string
+	Err  error
+	Hint string
+}
+
+func isTerminal(f *os.File) bool {
+	if testMode {
+		return false
+	}
+
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func checkStdinTerminal() error {
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("stdin is not a terminal")
+	}
+
+	return nil
+}
+
+func checkDevTTY() error {
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// checkRawMode enters the mode Read captures in and restores the saved
+// terminal state, whatever happens in between.
+func checkRawMode() error {
+	saved, err := stty("-g").Output()
+	if err != nil {
+		return fmt.Errorf("can't save terminal state: %w", err)
+	}
+	defer stty(strings.TrimSpace(string(saved))).Run()
+
+	err = stty("cbreak", "min", "1", "-echo").Run()
+	if err != nil {
+		return fmt.Errorf("can't enter raw mode: %w", err)
+	}
+
+	err = stty(strings.TrimSpace(string(saved))).Run()
+	if err != nil {
+		return fmt.Errorf("can't restore terminal state: %w", err)
+	}
+
+	return nil
+}
+
+// diagnostic is a check Diagnose runs, and the hint it reports on failure.
+type diagnostic struct {
+	Name string
+	Run  func() error
+	Hint string
+}
+
+// diagnostics are the checks Diagnose runs, in order. It's a variable so
+// tests can simulate a terminal passing or failing them.
+var diagnostics = []diagnostic{
+	{Name: "stdin is a terminal", Run: checkStdinTerminal, Hint: "run rythmkey directly in a terminal, not through a pipe or redirection"},
+	{Name: "/dev/tty can be opened", Run: checkDevTTY, Hint: "run rythmkey from an interactive session with a controlling terminal"},
+	{Name: "raw mode can be entered and restored", Run: checkRawMode, Hint: "make sure stty is installed and in PATH; if your terminal is broken run 'stty sane' or 'reset'"},
+}
+
+func Diagnose() []Check {
+	checks := []Check{}
+	for _, d := range diagnostics {
+		checks = append(checks, Check{Name: d.Name, Err: d.Run(), Hint: d.Hint})
+	}
+
+	return checks
+}
+
+func WriteDiagnosis(w io.Writer, checks []Check) bool {
+	fmt.Fprintf(w, "platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+
+	ok := true
+	for _, check := range checks {
+		if check.Err == nil {
+			fmt.Fprintf(w, "pass  %s\n", check.Name)
+			continue
+		}
+
+		ok = false
+		fmt.Fprintf(w, "FAIL  %s: %s\n      hint: %s\n", check.Name, check.Err, check.Hint)
+	}
+
+	return ok
+}
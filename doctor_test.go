@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+// stubDiagnostics replaces the checks Diagnose runs with ones failing
+// with errs, nil ones passing.
+func stubDiagnostics(t *testing.T, errs ...error) {
+	t.Helper()
+
+	saved := diagnostics
+	t.Cleanup(func() { diagnostics = saved })
+
+	diagnostics = nil
+	for i, err := range errs {
+		err := err
+		diagnostics = append(diagnostics, diagnostic{Name: saved[i].Name, Run: func() error { return err }, Hint: saved[i].Hint})
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	errTTY := errors.New("no such device")
+	stubDiagnostics(t, nil, errTTY, nil)
+
+	checks := Diagnose()
+	if len(checks) != 3 {
+		t.Fatalf("Diagnose = %+v, want 3 checks", checks)
+	}
+	for i, wantErr := range []error{nil, errTTY, nil} {
+		if checks[i].Name != diagnostics[i].Name || checks[i].Hint != diagnostics[i].Hint || checks[i].Err != wantErr {
+			t.Errorf("check %d = %+v, want %q failing with %v", i, checks[i], diagnostics[i].Name, wantErr)
+		}
+	}
+}
+
+func TestDoctorCommand(t *testing.T) {
+	platform := "platform: " + runtime.GOOS + "/" + runtime.GOARCH + "\n"
+
+	for _, tc := range []struct {
+		name       string
+		errs       []error
+		wantStdout string
+		wantCode   int
+	}{
+		{
+			name: "all passing",
+			errs: []error{nil, nil, nil},
+			wantStdout: platform +
+				"pass  stdin is a terminal\n" +
+				"pass  /dev/tty can be opened\n" +
+				"pass  raw mode can be entered and restored\n",
+		}, {
+			name: "some failing",
+			errs: []error{errors.New("stdin is not a terminal"), nil, errors.New("can't enter raw mode: exit status 1")},
+			wantStdout: platform +
+				"FAIL  stdin is a terminal: stdin is not a terminal\n" +
+				"      hint: run rythmkey directly in a terminal, not through a pipe or redirection\n" +
+				"pass  /dev/tty can be opened\n" +
+				"FAIL  raw mode can be entered and restored: can't enter raw mode: exit status 1\n" +
+				"      hint: make sure stty is installed and in PATH; if your terminal is broken run 'stty sane' or 'reset'\n",
+			wantCode: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stubDiagnostics(t, tc.errs...)
+
+			result := runApp(t, "doctor")
+			if result.stdout != tc.wantStdout {
+				t.Errorf("stdout = %q, want %q", result.stdout, tc.wantStdout)
+			}
+			if result.stderr != "" {
+				t.Errorf("stderr = %q, want nothing", result.stderr)
+			}
+			if result.code != tc.wantCode {
+				t.Errorf("exit code = %d, want %d", result.code, tc.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// DTWDistance is the dynamic time warping distance between the intervals
+// of both keys, the first timing left aside: the mean difference, in
+// milliseconds, along the alignment of the intervals that minimizes it,
+// where an interval of one key can pair with several consecutive ones of
+// the other. A hesitation shifted by a character costs less than compared
+// position by position. Both keys need at least one interval.
+//
+// The interval of a repeated character, like the second l of "hello", is
+// only paired once, with the interval of a repeat of the other key: the
+// time between repeats is a rhythm of its own, warping would merge it
+// with its neighbour's. Keys without as many repeats can't be aligned
+// that way and are an error.
+func (rythmkey Rythmkey) DTWDistance(other Rythmkey) (float64, error) {
+	if len(rythmkey) < 2 || len(other) < 2 {
+		return 0, errors.New("at least one interval is needed to align keys")
+	}
+
+	a, b := rythmkey[1:], other[1:]
+	repeatA, repeatB := repeats(rythmkey), repeats(other)
+
+	// cost is the total difference along the best alignment of a[:i+1]
+	// with b[:j+1], steps the number of pairs along it, a row at a time.
+	// A repeat paired twice, or with an interval that isn't one, costs
+	// infinitely.
+	cost, steps := make([]float64, len(b)), make([]int, len(b))
+	previousCost, previousSteps := make([]float64, len(b)), make([]int, len(b))
+	inf := math.Inf(1)
+	for i := range a {
+		for j := range b {
+			d := math.Abs(milliseconds(a[i].Timing - b[j].Timing))
+			if repeatA[i] != repeatB[j] {
+				d = inf
+			}
+
+			// Pairing a[i] again with b[j], or b[j] again with a[i].
+			left, up := cost[max(j-1, 0)], previousCost[j]
+			if repeatA[i] {
+				left = inf
+			}
+			if repeatB[j] {
+				up = inf
+			}
+
+			switch {
+			case i == 0 && j == 0:
+				cost[j], steps[j] = d, 1
+			case i == 0:
+				cost[j], steps[j] = left+d, steps[j-1]+1
+			case j == 0:
+				cost[j], steps[j] = up+d, previousSteps[j]+1
+			default:
+				best, n := previousCost[j-1], previousSteps[j-1]
+				if up < best {
+					best, n = up, previousSteps[j]
+				}
+				if left < best {
+					best, n = left, steps[j-1]
+				}
+				cost[j], steps[j] = best+d, n+1
+			}
+		}
+
+		cost, previousCost = previousCost, cost
+		steps, previousSteps = previousSteps, steps
+	}
+
+	last := len(b) - 1
+	if math.IsInf(previousCost[last], 1) {
+		return 0, errors.New("keys can't be aligned without merging a repeated character")
+	}
+
+	return previousCost[last] / float64(previousSteps[last]), nil
+}
+
+// repeats tells for every interval of the key, from its second character
+// on, whether it ends on a repeat of the character before.
+func repeats(rythmkey Rythmkey) []bool {
+	repeated := make([]bool, len(rythmkey)-1)
+	for i := range repeated {
+		repeated[i] = rythmkey[i+1].Char == rythmkey[i].Char
+	}
+
+	return repeated
+}
+
+// DTWMatcher matches samples typed with the same characters whose
+// intervals are within Tolerance of the mean interval of the reference
+// once aligned, see DTWDistance. Its score is 1 for identical intervals,
+// and one half at the tolerance.
+type DTWMatcher struct {
+	Tolerance Tolerance
+}
+
+func (m DTWMatcher) Match(reference, sample Rythmkey) (bool, float64, error) {
+	if checkChars(reference.Chars(), sample.Chars()) != nil {
+		return false, 0, nil
+	}
+
+	distance, err := reference.DTWDistance(sample)
+	if err != nil {
+		return false, 0, err
+	}
+
+	meanInterval := (reference.TotalDuration() - reference[0].Timing) / time.Duration(len(reference)-1)
+	allowed := milliseconds(m.Tolerance(meanInterval))
+	if allowed == 0 {
+		return distance == 0, 1 / (1 + distance), nil
+	}
+
+	return distance <= allowed, allowed / (allowed + distance), nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDTWDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		distance float64
+	}{
+		{"t0.at100.bt200.ct100.d", "t0.at100.bt200.ct100.d", 0},
+		// The first timing is left aside.
+		{"t0.at100.bt200.ct100.d", "t500.at100.bt200.ct100.d", 0},
+		{"t0.at100.bt200.ct100.d", "t0.at110.bt210.ct110.d", 10},
+		// A hesitation shifted by one character warps onto it, where
+		// position by position it would be 200ms off twice.
+		{"t0.at100.bt100.ct300.dt100.e", "t0.at100.bt300.ct100.dt100.e", 0},
+	} {
+		distance, err := mustParse(t, tc.a).DTWDistance(mustParse(t, tc.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if distance != tc.distance {
+			t.Errorf("DTWDistance(%s, %s) = %v, want %v", tc.a, tc.b, distance, tc.distance)
+		}
+	}
+
+	if _, err := mustParse(t, "t0.a").DTWDistance(mustParse(t, "t0.at100.b")); err == nil {
+		t.Error("DTWDistance of a single character succeeded")
+	}
+}
+
+func TestDTWMatcher(t *testing.T) {
+	m := DTWMatcher{Tolerance: AbsoluteTolerance(20 * time.Millisecond)}
+	reference := mustParse(t, "t0.at100.bt100.ct300.dt100.e")
+
+	for _, tc := range []struct {
+		sample string
+		match  bool
+		score  float64
+	}{
+		{"t0.at100.bt100.ct300.dt100.e", true, 1},
+		{"t0.at110.bt110.ct310.dt110.e", true, 20.0 / 30},
+		{"t0.at200.bt200.ct400.dt200.e", false, 20.0 / 120},
+		// Other characters never match.
+		{"t0.at100.bt100.ct300.dt100.x", false, 0},
+	} {
+		match, score, err := m.Match(reference, mustParse(t, tc.sample))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if match != tc.match || score != tc.score {
+			t.Errorf("Match(%s) = %t, %v, want %t, %v", tc.sample, match, score, tc.match, tc.score)
+		}
+	}
+}
+
+func TestDTWDistanceRepeats(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		distance float64
+	}{
+		{"t0.ht100.et100.lt100.lt100.o", "t0.ht100.et100.lt100.lt100.o", 0},
+		// A slow second l can't warp onto the intervals around it, it
+		// pairs with the other second l only: 200ms off over 4 intervals.
+		{"t0.ht100.et100.lt100.lt100.o", "t0.ht100.et100.lt300.lt100.o", 50},
+		// Neither can a hesitation moved from between both l to before
+		// them, like it would between other characters.
+		{"t0.ht100.et100.lt300.lt100.o", "t0.ht100.et300.lt100.lt100.o", 100},
+	} {
+		distance, err := mustParse(t, tc.a).DTWDistance(mustParse(t, tc.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if distance != tc.distance {
+			t.Errorf("DTWDistance(%s, %s) = %v, want %v", tc.a, tc.b, distance, tc.distance)
+		}
+	}
+
+	// A double letter typed single would have to merge with its repeat.
+	for _, tc := range [][2]string{
+		{"t0.ht100.et100.lt100.lt100.o", "t0.ht100.et100.lt100.o"},
+		{"t0.at100.at100.at100.a", "t0.at100.at100.a"},
+	} {
+		if _, err := mustParse(t, tc[0]).DTWDistance(mustParse(t, tc[1])); err == nil {
+			t.Errorf("DTWDistance(%s, %s) succeeded", tc[0], tc[1])
+		}
+	}
+}
+
+func TestDTWMatcherRepeats(t *testing.T) {
+	m := DTWMatcher{Tolerance: AbsoluteTolerance(20 * time.Millisecond)}
+	reference := mustParse(t, "t0.ht100.et100.lt100.lt100.o")
+
+	for _, tc := range []struct {
+		sample string
+		match  bool
+	}{
+		{"t0.ht105.et95.lt110.lt100.o", true},
+		{"t0.ht100.et100.lt300.lt100.o", false},
+		{"t0.ht100.et100.lt100.o", false},
+	} {
+		match, _, err := m.Match(reference, mustParse(t, tc.sample))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if match != tc.match {
+			t.Errorf("Match(%s) = %t, want %t", tc.sample, match, tc.match)
+		}
+	}
+}
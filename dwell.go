@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// What verify matches a key on: the flight times between key presses, the
+// timings of the key, how long every key is held, its dwell times, or
+// both. Only the evdev source sees keys released, see ReadOptions.Dwell.
+// A profile is verified on the one it was enrolled for, see Profile.MatchOn.
+const (
+	MatchFlight = "flight"
+	MatchDwell  = "dwell"
+	MatchBoth   = "both"
+)
+
+// parseDwells parses n comma separated durations, like 80ms,95ms.
+func parseDwells(s string, n int) ([]time.Duration, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != n {
+		return nil, fmt.Errorf("%d dwell times for %d characters", len(fields), n)
+	}
+
+	dwells := make([]time.Duration, n)
+	for i, field := range fields {
+		d, err := time.ParseDuration(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("negative dwell time %s", d)
+		}
+		dwells[i] = d
+	}
+
+	return dwells, nil
+}
+
+// selectDwell returns the dwell times at the positions of a key selected
+// with Rythmkey.Select, which checked them, zeroing dwell.
+func selectDwell(dwell []time.Duration, positions []int) []time.Duration {
+	selected := make([]time.Duration, len(positions))
+	for i, position := range positions {
+		selected[i] = dwell[position]
+	}
+	clear(dwell)
+
+	return selected
+}
+
+// AddDwell records in the profile the mean and standard deviation of the
+// dwell times of every character, one slice per sample enrolled. An
+// unknown dwell time, zero, fails: the profile would be matched on keys
+// held for no time.
+func (p *Profile) AddDwell(dwells [][]time.Duration) error {
+	if len(dwells) == 0 {
+		return errors.New("no dwell times to enroll")
+	}
+
+	p.Dwell = make([]float64, len(p.Chars))
+	p.DwellStddev = make([]float64, len(p.Chars))
+	for _, dwell := range dwells {
+		if len(dwell) != len(p.Chars) {
+			return fmt.Errorf("%d dwell times for %d characters, hold times are only captured with --source evdev", len(dwell), len(p.Chars))
+		}
+
+		for i, d := range dwell {
+			if d <= 0 {
+				return fmt.Errorf("unknown dwell time of character %d, released after the capture ended", i)
+			}
+			p.Dwell[i] += milliseconds(d)
+		}
+	}
+
+	for i := range p.Dwell {
+		p.Dwell[i] /= float64(len(dwells))
+
+		for _, dwell := range dwells {
+			d := milliseconds(dwell[i]) - p.Dwell[i]
+			p.DwellStddev[i] += d * d
+		}
+		p.DwellStddev[i] = math.Sqrt(p.DwellStddev[i] / float64(len(dwells)))
+	}
+
+	return nil
+}
+
+// DwellTolerance is the tolerance of the dwell time of character i, in
+// milliseconds, computed like Tolerance.
+func (p Profile) DwellTolerance(i int) float64 {
+	return math.Max(minTolerance, defaultToleranceFactor*p.DwellStddev[i])
+}
+
+// DwellScore returns the fraction of dwell times within the tolerance of
+// the profile, like Score does for timings. An unknown dwell time is out
+// of tolerance.
+func (p Profile) DwellScore(dwell []time.Duration) (float64, error) {
+	if len(p.Dwell) == 0 {
+		return 0, errors.New("profile has no dwell times, enroll it with --match dwell or both")
+	}
+	if len(dwell) != len(p.Dwell) {
+		return 0, fmt.Errorf("%d dwell times for %d characters", len(dwell), len(p.Dwell))
+	}
+
+	within := 0
+	for i, d := range dwell {
+		if d > 0 && math.Abs(milliseconds(d)-p.Dwell[i]) <= p.DwellTolerance(i) {
+			within++
+		}
+	}
+
+	return float64(within) / float64(len(dwell)), nil
+}
+
+// MatchScore scores rk, held for dwell, against the profile as mode tells:
+// on its timings, see Score, on its dwell times, see DwellScore, or on
+// both, the lower of the two scores so that both have to reach the
+// threshold. The character sequences must be identical.
+func (p Profile) MatchScore(rk Rythmkey, dwell []time.Duration, mode string) (float64, error) {
+	flight, err := p.Score(rk)
+	if err != nil {
+		return 0, err
+	}
+
+	switch mode {
+	case MatchFlight, "":
+		return flight, nil
+	case MatchDwell, MatchBoth:
+		held, err := p.DwellScore(dwell)
+		if err != nil {
+			return 0, err
+		}
+		if mode == MatchDwell {
+			return held, nil
+		}
+		return math.Min(flight, held), nil
+	default:
+		return 0, fmt.Errorf("unknown match mode %q", mode)
+	}
+}
+
+// checkMatchMode checks the profile was enrolled for matching on mode.
+func (p Profile) checkMatchMode(mode string) error {
+	enrolled := p.MatchOn
+	if enrolled == "" {
+		enrolled = MatchFlight
+	}
+
+	if enrolled != mode {
+		return fmt.Errorf("profile enrolled to match on %s, verify it with --match %s", enrolled, enrolled)
+	}
+
+	return nil
+}
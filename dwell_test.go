@@ -0,0 +1,219 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dwellProfile is enrolled from three samples of "abc" held about 80, 150
+// and 100ms, to match on both.
+func dwellProfile(t *testing.T) Profile {
+	t.Helper()
+
+	p := mustProfile(t, "t0.at100.bt200.c", "t0.at110.bt190.c", "t0.at90.bt210.c")
+	err := p.AddDwell([][]time.Duration{
+		{ms(80), ms(150), ms(100)},
+		{ms(85), ms(145), ms(105)},
+		{ms(75), ms(155), ms(95)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.MatchOn = MatchBoth
+
+	return p
+}
+
+func TestAddDwell(t *testing.T) {
+	p := dwellProfile(t)
+
+	if want := []float64{80, 150, 100}; !slices.Equal(p.Dwell, want) {
+		t.Errorf("dwell means = %v, want %v", p.Dwell, want)
+	}
+	if d := p.DwellStddev[0] - math.Sqrt(50.0/3); math.Abs(d) > 1e-9 {
+		t.Errorf("dwell stddev = %v, want %v", p.DwellStddev[0], math.Sqrt(50.0/3))
+	}
+	if err := p.Validate(); err != nil {
+		t.Errorf("Validate = %v", err)
+	}
+
+	for name, dwells := range map[string][][]time.Duration{
+		"none":      nil,
+		"too few":   {{ms(80), ms(150)}},
+		"unknown":   {{ms(80), 0, ms(100)}},
+		"one short": {{ms(80), ms(150), ms(100)}, {ms(80)}},
+	} {
+		p := mustProfile(t, "t0.at100.bt200.c")
+		if err := p.AddDwell(dwells); err == nil {
+			t.Errorf("AddDwell %s succeeded", name)
+		}
+	}
+}
+
+func TestValidateDwell(t *testing.T) {
+	for name, edit := range map[string]func(p *Profile){
+		"dwell without stddev": func(p *Profile) { p.DwellStddev = nil },
+		"negative dwell":       func(p *Profile) { p.Dwell[1] = -1 },
+		"unknown mode":         func(p *Profile) { p.MatchOn = "hold" },
+		"dwell mode, no dwell": func(p *Profile) { p.Dwell, p.DwellStddev = nil, nil },
+	} {
+		p := dwellProfile(t)
+		edit(&p)
+		if err := p.Validate(); err == nil {
+			t.Errorf("Validate of a profile with %s succeeded", name)
+		}
+	}
+}
+
+// Each sample matches the profile on flight times, dwell times, both or
+// neither, and every mode gives its own verdict.
+func TestMatchScoreModes(t *testing.T) {
+	p := dwellProfile(t)
+
+	for _, tc := range []struct {
+		name    string
+		typed   string
+		dwell   []time.Duration
+		matches map[string]bool
+	}{
+		{
+			name:    "flight only",
+			typed:   "t0.at105.bt195.c",
+			dwell:   []time.Duration{ms(200), ms(40), ms(250)},
+			matches: map[string]bool{MatchFlight: true, MatchDwell: false, MatchBoth: false},
+		},
+		{
+			name:    "dwell only",
+			typed:   "t0.at300.bt50.c",
+			dwell:   []time.Duration{ms(82), ms(148), ms(98)},
+			matches: map[string]bool{MatchFlight: false, MatchDwell: true, MatchBoth: false},
+		},
+		{
+			name:    "both",
+			typed:   "t0.at105.bt195.c",
+			dwell:   []time.Duration{ms(82), ms(148), ms(98)},
+			matches: map[string]bool{MatchFlight: true, MatchDwell: true, MatchBoth: true},
+		},
+		{
+			name:    "neither",
+			typed:   "t0.at300.bt50.c",
+			dwell:   []time.Duration{ms(200), ms(40), ms(250)},
+			matches: map[string]bool{MatchFlight: false, MatchDwell: false, MatchBoth: false},
+		},
+		{
+			// Released after the capture ended.
+			name:    "unknown dwell",
+			typed:   "t0.at105.bt195.c",
+			dwell:   []time.Duration{ms(82), ms(148), 0},
+			matches: map[string]bool{MatchFlight: true, MatchDwell: false, MatchBoth: false},
+		},
+	} {
+		for _, mode := range matchModes {
+			score, err := p.MatchScore(mustParse(t, tc.typed), tc.dwell, mode)
+			if err != nil {
+				t.Fatalf("%s: MatchScore(%s) = %v", tc.name, mode, err)
+			}
+			if match := score >= defaultThreshold; match != tc.matches[mode] {
+				t.Errorf("%s: MatchScore(%s) = %v, want matching %t", tc.name, mode, score, tc.matches[mode])
+			}
+		}
+	}
+
+	if _, err := p.MatchScore(mustParse(t, "t0.at105.bt195.x"), []time.Duration{ms(82), ms(148), ms(98)}, MatchDwell); err == nil {
+		t.Error("MatchScore of other characters on dwell times succeeded")
+	}
+	if _, err := p.MatchScore(mustParse(t, "t0.at105.bt195.c"), []time.Duration{ms(82)}, MatchBoth); err == nil {
+		t.Error("MatchScore with a dwell time missing succeeded")
+	}
+	if _, err := mustProfile(t, "t0.at100.bt200.c").MatchScore(mustParse(t, "t0.at105.bt195.c"), []time.Duration{ms(82), ms(148), ms(98)}, MatchDwell); err == nil {
+		t.Error("MatchScore on dwell times of a profile without them succeeded")
+	}
+}
+
+func TestReadDwell(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at100.bt200.c")
+	t.Setenv(dwellInputEnv, "80ms, 150ms,100ms")
+
+	dwell := []time.Duration{}
+	rk := Rythmkey{}
+	if err := rk.ReadWith(ReadOptions{Dwell: &dwell, Positions: []int{0, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{ms(80), ms(100)}; !slices.Equal(dwell, want) {
+		t.Errorf("dwell of positions 0 and 2 = %v, want %v", dwell, want)
+	}
+
+	// A failed capture leaves none.
+	rk = Rythmkey{}
+	if err := rk.ReadWith(ReadOptions{Dwell: &dwell, Forbid: []byte("b")}); err == nil || len(dwell) != 0 {
+		t.Errorf("failed capture = %v, dwell %v, want an error and no dwell", err, dwell)
+	}
+
+	for _, dwells := range []string{"80ms,150ms", "80ms,-1ms,100ms", "80,150,100"} {
+		t.Setenv(dwellInputEnv, dwells)
+		if err := rk.ReadWith(ReadOptions{Dwell: &dwell}); err == nil {
+			t.Errorf("capture held for %q succeeded", dwells)
+		}
+	}
+}
+
+func TestVerifyMatchFlag(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(encodedInputEnv, "t0.at100.bt200.c")
+	t.Setenv(dwellInputEnv, "80ms,150ms,100ms")
+
+	if result := runApp(t, "enroll", "--profile", filepath.Join(dir, "both.json"), "--match", MatchBoth); result.err != nil {
+		t.Fatal(result.err)
+	}
+	p, err := LoadProfile(filepath.Join(dir, "both.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MatchOn != MatchBoth || !slices.Equal(p.Dwell, []float64{80, 150, 100}) {
+		t.Errorf("enrolled to match on %q with dwell %v", p.MatchOn, p.Dwell)
+	}
+
+	for _, tc := range []struct {
+		typed, dwell string
+		accept       bool
+	}{
+		{"t0.at105.bt195.c", "85ms,145ms,95ms", true},
+		{"t0.at105.bt195.c", "200ms,40ms,250ms", false},
+		{"t0.at300.bt50.c", "85ms,145ms,95ms", false},
+	} {
+		t.Setenv(encodedInputEnv, tc.typed)
+		t.Setenv(dwellInputEnv, tc.dwell)
+
+		result := runApp(t, "verify", "--profiles-dir", dir, "--match", MatchBoth, "--reject-delay", "0")
+		if accept := result.err == nil && result.code == 0; accept != tc.accept {
+			t.Errorf("verify --match both of %s held %s accepted %t (%v), want %t", tc.typed, tc.dwell, accept, result.err, tc.accept)
+		}
+	}
+
+	// The mode must be the one enrolled.
+	if result := runApp(t, "verify", "--profiles-dir", dir); result.err == nil || !strings.Contains(result.err.Error(), "--match both") {
+		t.Errorf("verify --match flight of a profile enrolled on both = %v, want an error", result.err)
+	}
+
+	for _, args := range [][]string{
+		{"verify", "--profiles-dir", dir, "--match", "hold"},
+		{"verify", "--profiles-dir", dir, "--match", MatchBoth, "--method", "dtw"},
+		{"verify", "--profiles-dir", dir, "--match", MatchBoth, "--norm", "l2"},
+		{"enroll", "--profile", filepath.Join(dir, "raw.json"), "--match", MatchDwell, "--raw"},
+	} {
+		if result := runApp(t, args...); result.err == nil {
+			t.Errorf("%v succeeded", args)
+		}
+	}
+
+	// Without hold times there's nothing to enroll on.
+	os.Unsetenv(dwellInputEnv)
+	if result := runApp(t, "enroll", "--profile", filepath.Join(dir, "dwell.json"), "--match", MatchDwell); result.err == nil {
+		t.Error("enroll --match dwell without dwell times succeeded")
+	}
+}
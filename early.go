@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	errPrefixMismatch  = errors.New("the characters typed so far match no profile")
+	errToleranceBudget = errors.New("too many characters out of tolerance to reach the threshold")
+)
+
+// EarlyReject returns a ReadOptions.Check rejecting a capture as soon as no
+// profile can score it at threshold anymore: its characters so far aren't
+// the start of any profile's, or too many of them are already out of
+// tolerance for the rest of the key to make up for it.
+//
+// Rejecting early trades the constant time of a verdict for responsiveness:
+// when the rejection comes tells how many characters were right, and so
+// lets an attacker guess the key one character, or one rhythm, at a time.
+func EarlyReject(profiles []NamedProfile, threshold float64) func(Rythmkey) error {
+	return func(rk Rythmkey) error {
+		sequence := false
+		for _, np := range profiles {
+			p := np.Profile
+			if !isPrefix(rk, p.Chars) {
+				continue
+			}
+			sequence = true
+
+			out := 0
+			for i, ct := range rk {
+				if math.Abs(milliseconds(ct.Timing)-p.Mean[i]) > p.Tolerance(i) {
+					out++
+				}
+			}
+
+			if float64(len(p.Chars)-out)/float64(len(p.Chars)) >= threshold {
+				return nil
+			}
+		}
+
+		if !sequence {
+			return errPrefixMismatch
+		}
+		return errToleranceBudget
+	}
+}
+
+// isPrefix tells whether the characters of rk start chars, without making
+// a string of them.
+func isPrefix(rk Rythmkey, chars string) bool {
+	if len(rk) > len(chars) {
+		return false
+	}
+
+	for i, ct := range rk {
+		if ct.Char != chars[i] {
+			return false
+		}
+	}
+
+	return true
+}
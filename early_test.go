@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEarlyReject(t *testing.T) {
+	profiles := []NamedProfile{
+		{"abcd", mustProfile(t, "t0.at100.bt100.ct100.d", "t0.at110.bt90.ct110.d")},
+		{"abxy", mustProfile(t, "t0.at300.bt300.xt300.y", "t0.at310.bt290.xt310.y")},
+	}
+	check := EarlyReject(profiles, defaultThreshold)
+
+	for _, tc := range []struct {
+		typed string
+		err   error
+	}{
+		{"t0.a", nil},
+		{"t0.at100.b", nil},
+		// The start of the second profile.
+		{"t0.at300.bt300.x", nil},
+		{"t0.at0.x", errPrefixMismatch},
+		{"t0.at100.bt100.ct100.dt100.e", errPrefixMismatch},
+		// One of four characters out of tolerance already leaves 3/4 at
+		// best, below 0.8, against both profiles.
+		{"t0.at200.b", errToleranceBudget},
+		{"t0.at100.bt300.c", errToleranceBudget},
+	} {
+		if err := check(mustParse(t, tc.typed)); !errors.Is(err, tc.err) {
+			t.Errorf("EarlyReject(%s) = %v, want %v", tc.typed, err, tc.err)
+		}
+	}
+}
+
+// A wrong second character is rejected as it's typed, without waiting for
+// the rest of the key.
+func TestEarlyRejectSecondCharacter(t *testing.T) {
+	profiles := []NamedProfile{{"abcd", mustProfile(t, "t0.at100.bt100.ct100.d")}}
+	check := EarlyReject(profiles, defaultThreshold)
+
+	rejectedAt := 0
+	opts := ReadOptions{Check: func(rk Rythmkey) error {
+		err := check(rk)
+		if err != nil && rejectedAt == 0 {
+			rejectedAt = rk.Len()
+		}
+		return err
+	}}
+
+	scriptStdin(t, "axcd\n")
+	rk := Rythmkey{}
+	if err := rk.ReadWith(opts); !errors.Is(err, errPrefixMismatch) {
+		t.Fatalf("capture = %v, want %v", err, errPrefixMismatch)
+	}
+	if rejectedAt != 2 {
+		t.Errorf("rejected after %d characters, want 2", rejectedAt)
+	}
+}
+
+func TestVerifyEarlyReject(t *testing.T) {
+	dir := t.TempDir()
+	saveProfile(t, dir, "user.json", mustProfile(t, "t0.at0.bt0.ct0.d"))
+
+	// Rejected before the verdict delay.
+	scriptStdin(t, "axcd\n")
+	start := time.Now()
+	result := runApp(t, "verify", "--profiles-dir", dir, "--early-reject", "--reject-delay", "2s")
+	if result.code != exitWrongSequence || !strings.Contains(result.stderr, "reject: "+errPrefixMismatch.Error()) {
+		t.Errorf("verify --early-reject of axcd = exit %d, %q, want %d and the reject", result.code, result.stderr, exitWrongSequence)
+	}
+	if took := time.Since(start); took >= 2*time.Second {
+		t.Errorf("early reject took %s, waiting for the verdict delay", took)
+	}
+
+	scriptStdin(t, "abcd\n")
+	if result := runApp(t, "verify", "--profiles-dir", dir, "--early-reject", "--reject-delay", "0"); result.err != nil || result.code != 0 {
+		t.Errorf("verify --early-reject of abcd = %v, exit %d", result.err, result.code)
+	}
+
+	for _, args := range [][]string{
+		{"verify", "--profiles-dir", dir, "--early-reject", "--method", "dtw"},
+		{"verify", "--profiles-dir", dir, "--early-reject", "--smooth", "3"},
+		{"verify", "--samples-dir", writeSamples(t, "t0.at0.bt0.ct0.d"), "--early-reject"},
+	} {
+		scriptStdin(t, "abcd\n")
+		if result := runApp(t, args...); result.err == nil || !strings.Contains(result.err.Error(), "--early-reject") {
+			t.Errorf("%v = %v, want the --early-reject error", args, result.err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultEditStep = 10 * time.Millisecond
+
+var errEditCanceled = errors.New("edit canceled")
+
+// Editor adjusts the timings of a key one character at a time, the core of
+// the edit command without its terminal.
+type Editor struct {
+	Rythmkey Rythmkey
+	Cursor   int
+	Step     time.Duration
+}
+
+func NewEditor(rk Rythmkey, step time.Duration) *Editor {
+	if step <= 0 {
+		step = defaultEditStep
+	}
+
+	return &Editor{Rythmkey: rk, Step: step}
+}
+
+// Move the cursor by n characters, staying on the key.
+func (e *Editor) Move(n int) {
+	e.Cursor = max(0, min(len(e.Rythmkey)-1, e.Cursor+n))
+}
+
+// Adjust the timing under the cursor by n steps, never below zero.
+func (e *Editor) Adjust(n int) {
+	if len(e.Rythmkey) == 0 {
+		return
+	}
+
+	ct := e.Rythmkey[e.Cursor]
+	ct.Timing = max(0, ct.Timing+time.Duration(n)*e.Step)
+}
+
+// String shows every character with its timing, the one under the cursor
+// in brackets.
+func (e *Editor) String() string {
+	parts := make([]string, len(e.Rythmkey))
+	for i, ct := range e.Rythmkey {
+		parts[i] = fmt.Sprintf("%s(%s)", escapeChar(ct.Char), formatMilliseconds(ct.Timing))
+		if i == e.Cursor {
+			parts[i] = "[" + parts[i] + "]"
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Run edits from the key presses read from r, redrawing on w: left and
+// right arrows move the cursor, up and down adjust its timing, Enter
+// confirms and Escape cancels.
+func (e *Editor) Run(r io.Reader, w io.Writer) error {
+	buf := make([]byte, 8)
+	for {
+		fmt.Fprint(w, "\r\033[K"+e.String())
+
+		n, err := r.Read(buf)
+		if n == 0 && err != nil {
+			if err == io.EOF {
+				err = errEditCanceled
+			}
+			fmt.Fprintln(w)
+			return err
+		}
+
+		for keys := buf[:n]; len(keys) > 0; {
+			key := keys[:1]
+			if len(keys) >= 3 && keys[0] == '\033' && keys[1] == '[' {
+				key = keys[:3]
+			}
+			keys = keys[len(key):]
+
+			switch string(key) {
+			case "\033[D":
+				e.Move(-1)
+			case "\033[C":
+				e.Move(1)
+			case "\033[A":
+				e.Adjust(1)
+			case "\033[B":
+				e.Adjust(-1)
+			case "\n", "\r":
+				fmt.Fprintln(w)
+				return nil
+			case "\033":
+				fmt.Fprintln(w)
+				return errEditCanceled
+			}
+		}
+	}
+}
+
+// editInteractively runs the editor on the terminal, in cbreak mode.
+func editInteractively(e *Editor) error {
+	saveTerminal()
+	if err := stty("cbreak", "min", "1", "-echo").Run(); err != nil {
+		return fmt.Errorf("can't read key presses from the terminal: %w", err)
+	}
+	defer stty("-cbreak", "echo").Run()
+
+	fmt.Fprintln(os.Stderr, "left/right: select, up/down: adjust, enter: save, escape: cancel")
+	return e.Run(os.Stdin, os.Stderr)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEditorMoveAdjust(t *testing.T) {
+	e := NewEditor(mustParse(t, "t0.at120.bt15.c"), 0)
+	if e.Step != defaultEditStep {
+		t.Errorf("NewEditor step = %v, want the default %v", e.Step, defaultEditStep)
+	}
+
+	e.Move(-1)
+	e.Adjust(2)
+	e.Move(5)
+	e.Adjust(-1)
+	e.Move(-1)
+	e.Adjust(3)
+
+	if got, want := e.Rythmkey.Encode(), "t20.at150.bt5.c"; got != want {
+		t.Errorf("edited key = %s, want %s", got, want)
+	}
+
+	// Timings never go negative.
+	e.Move(1)
+	e.Adjust(-10)
+	if timing := e.Rythmkey[2].Timing; timing != 0 {
+		t.Errorf("timing adjusted below zero to %v", timing)
+	}
+
+	if got, want := e.String(), "'a'(20) 'b'(150) ['c'(0)]"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+
+	empty := NewEditor(Rythmkey{}, time.Millisecond)
+	empty.Move(1)
+	empty.Adjust(1)
+}
+
+// keyPresses delivers a key press a read, as a terminal in cbreak mode
+// does.
+type keyPresses []string
+
+func (k *keyPresses) Read(p []byte) (int, error) {
+	if len(*k) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, (*k)[0])
+	*k = (*k)[1:]
+	return n, nil
+}
+
+func TestEditorRun(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input io.Reader
+		rks   string
+		err   error
+	}{
+		{"saved", &keyPresses{"\033[C", "\033[A", "\033[A", "\033[D", "\033[B", "\n"}, "t0.at140.b", nil},
+		{"saved with enter", &keyPresses{"\033[A", "\r"}, "t10.at120.b", nil},
+		{"several keys a read", strings.NewReader("\033[C\033[Ax\n"), "t0.at130.b", nil},
+		{"canceled", &keyPresses{"\033[A", "\033"}, "", errEditCanceled},
+		{"end of input", &keyPresses{"\033[A"}, "", errEditCanceled},
+	} {
+		e := NewEditor(mustParse(t, "t0.at120.b"), 10*time.Millisecond)
+
+		err := e.Run(tc.input, io.Discard)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: Run = %v, want %v", tc.name, err, tc.err)
+			continue
+		}
+
+		if tc.err == nil && e.Rythmkey.Encode() != tc.rks {
+			t.Errorf("%s: edited key = %s, want %s", tc.name, e.Rythmkey.Encode(), tc.rks)
+		}
+	}
+}
+
+func TestEditorRunRedraws(t *testing.T) {
+	out := strings.Builder{}
+	e := NewEditor(mustParse(t, "t0.a"), 10*time.Millisecond)
+	if err := e.Run(&keyPresses{"\033[A", "\n"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "\r\033[K['a'(0)]\r\033[K['a'(10)]\n"; out.String() != want {
+		t.Errorf("Run drew %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ensembleMethods score a key against a profile between 0 and 1.
+var ensembleMethods = map[string]func(p Profile, rk Rythmkey) (float64, error){
+	"tolerance": Profile.Score,
+	"rank": func(p Profile, rk Rythmkey) (float64, error) {
+		correlation, err := p.Reference().RankCorrelation(rk)
+		var mismatch *MismatchError
+		if errors.As(err, &mismatch) {
+			return 0, err
+		}
+		if err != nil {
+			// Intervals that can't be ranked carry no pattern to match.
+			return 0, nil
+		}
+
+		return (correlation + 1) / 2, nil
+	},
+}
+
+const defaultEnsembleWeights = "tolerance=0.7,rank=0.3"
+
+// EnsembleWeights weigh the score of every method in an ensemble score.
+type EnsembleWeights map[string]float64
+
+// ParseEnsembleWeights parses comma separated method=weight pairs.
+func ParseEnsembleWeights(s string) (EnsembleWeights, error) {
+	weights := EnsembleWeights{}
+	total := 0.0
+
+	for _, pair := range strings.Split(s, ",") {
+		method, weight, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed weight %q, expected method=weight", pair)
+		}
+
+		if _, ok := ensembleMethods[method]; !ok {
+			return nil, fmt.Errorf("unknown ensemble method %q", method)
+		}
+
+		w, err := strconv.ParseFloat(weight, 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %q for %s", weight, method)
+		}
+
+		weights[method] = w
+		total += w
+	}
+
+	if total == 0 {
+		return nil, errors.New("ensemble weights sum to zero")
+	}
+
+	return weights, nil
+}
+
+// Reference is the key the profile means, each character typed at its
+// mean timing.
+func (p Profile) Reference() Rythmkey {
+	rk := make(Rythmkey, 0, len(p.Chars))
+	for i := range p.Chars {
+		rk = append(rk, &CharTiming{
+			Timing: time.Duration(p.Mean[i] * float64(time.Millisecond)),
+			Char:   p.Chars[i],
+		})
+	}
+
+	return rk
+}
+
+// EnsembleScore is the weighted mean of the scores of rk against p with
+// every weighted method, returned along with them.
+func (p Profile) EnsembleScore(rk Rythmkey, weights EnsembleWeights) (float64, map[string]float64, error) {
+	if err := checkChars(p.Chars, rk.Chars()); err != nil {
+		return 0, nil, err
+	}
+
+	methods := make([]string, 0, len(weights))
+	for method := range weights {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	components := map[string]float64{}
+	score, total := 0.0, 0.0
+	for _, method := range methods {
+		component, err := ensembleMethods[method](p, rk)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		components[method] = component
+		score += weights[method] * component
+		total += weights[method]
+	}
+
+	return score / total, components, nil
+}
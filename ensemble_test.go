@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// craftEnsemble replaces the ensemble methods by ones scoring every key
+// with the given scores.
+func craftEnsemble(t *testing.T, scores map[string]float64) {
+	t.Helper()
+
+	saved := ensembleMethods
+	t.Cleanup(func() { ensembleMethods = saved })
+
+	ensembleMethods = map[string]func(Profile, Rythmkey) (float64, error){}
+	for method, score := range scores {
+		score := score
+		ensembleMethods[method] = func(Profile, Rythmkey) (float64, error) { return score, nil }
+	}
+}
+
+func TestParseEnsembleWeights(t *testing.T) {
+	weights, err := ParseEnsembleWeights(" tolerance=0.7, rank=0.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weights) != 2 || weights["tolerance"] != 0.7 || weights["rank"] != 0.3 {
+		t.Errorf("ParseEnsembleWeights = %v", weights)
+	}
+
+	for _, s := range []string{"", "tolerance", "dtw=1", "tolerance=-1", "tolerance=x", "tolerance=0,rank=0"} {
+		if _, err := ParseEnsembleWeights(s); err == nil {
+			t.Errorf("ParseEnsembleWeights(%q) succeeded", s)
+		}
+	}
+}
+
+func TestEnsembleScoreCrafted(t *testing.T) {
+	craftEnsemble(t, map[string]float64{"high": 0.9, "low": 0.2})
+	p := mustProfile(t, "t0.at120.bt80.c")
+
+	for _, tc := range []struct {
+		weights string
+		score   float64
+	}{
+		{"high=1", 0.9},
+		{"high=1,low=1", 0.55},
+		{"high=3,low=1", 0.725},
+		{"high=0,low=2", 0.2},
+	} {
+		weights, err := ParseEnsembleWeights(tc.weights)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		score, components, err := p.EnsembleScore(mustParse(t, "t0.at120.bt80.c"), weights)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if math.Abs(score-tc.score) > 1e-9 {
+			t.Errorf("EnsembleScore(%s) = %v, want %v", tc.weights, score, tc.score)
+		}
+		for method := range weights {
+			if want := map[string]float64{"high": 0.9, "low": 0.2}[method]; components[method] != want {
+				t.Errorf("EnsembleScore(%s) scored %s %v, want %v", tc.weights, method, components[method], want)
+			}
+		}
+	}
+
+	if _, _, err := p.EnsembleScore(mustParse(t, "t0.at120.b"), EnsembleWeights{"high": 1}); mismatchKind(err) != LengthMismatch {
+		t.Errorf("EnsembleScore of a shorter key = %v, want a length mismatch", err)
+	}
+}
+
+func TestVerifyEnsemble(t *testing.T) {
+	craftEnsemble(t, map[string]float64{"high": 0.9, "low": 0.3})
+
+	dir := t.TempDir()
+	saveProfile(t, dir, "secret.json", mustProfile(t, "t0.at120.bt80.c"))
+	t.Setenv(encodedInputEnv, "t0.at120.bt80.c")
+
+	for _, tc := range []struct {
+		weights   string
+		threshold string
+		code      int
+	}{
+		// Scoring 0.6.
+		{"high=1,low=1", "0.5", 0},
+		{"high=1,low=1", "0.7", exitReject},
+		// Scoring 0.75.
+		{"high=3,low=1", "0.7", 0},
+		{"high=1,low=3", "0.7", exitReject},
+	} {
+		result := runApp(t, "--verbose", "verify", "--profiles-dir", dir, "--method", "ensemble", "--weights", tc.weights, "--threshold", tc.threshold)
+		if result.code != tc.code {
+			t.Errorf("ensemble %s over %s exited %d, want %d: %v %s", tc.weights, tc.threshold, result.code, tc.code, result.err, result.stderr)
+		}
+
+		if !strings.Contains(result.stderr, "ensemble score against secret") || !strings.Contains(result.stderr, "high:0.9") {
+			t.Errorf("ensemble %s didn't log its components: %q", tc.weights, result.stderr)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type LabeledSample struct {
+	Genuine  bool
+	Rythmkey Rythmkey
+}
+
+type Rates struct {
+	Threshold float64 `json:"threshold"`
+	FAR       float64 `json:"far"`
+	FRR       float64 `json:"frr"`
+}
+
+// ReadDataset reads one sample per line, a genuine or impostor label
+// followed by a space and the encoded rythmkey. Blank lines and lines
+// starting with # are ignored.
+func ReadDataset(r io.Reader) ([]LabeledSample, error) {
+	samples := []LabeledSample{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		label, rks, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing rythmkey", n)
+		}
+
+		sample := LabeledSample{}
+		switch label {
+		case "genuine":
+			sample.Genuine = true
+		case "impostor":
+		default:
+			return nil, fmt.Errorf("line %d: unknown label %q", n, label)
+		}
+
+		rk, err := ParseRythmkey(rks)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		sample.Rythmkey = rk
+
+		samples = append(samples, sample)
+	}
+
+	return samples, scanner.Err()
+}
+
+func LoadDataset(path string) ([]LabeledSample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadDataset(f)
+}
+
+// Scores returns the score of every sample against p, a sample typed with
+// other characters scoring -1 so no threshold accepts it.
+func (p Profile) Scores(samples []LabeledSample) []float64 {
+	scores := make([]float64, len(samples))
+	for i, sample := range samples {
+		score, err := p.Score(sample.Rythmkey)
+		if err != nil {
+			score = -1
+		}
+		scores[i] = score
+	}
+
+	return scores
+}
+
+func ComputeRates(samples []LabeledSample, scores []float64, threshold float64) Rates {
+	genuines, impostors := 0, 0
+	falseRejects, falseAccepts := 0, 0
+
+	for i, sample := range samples {
+		accepted := scores[i] >= threshold
+		if sample.Genuine {
+			genuines++
+			if !accepted {
+				falseRejects++
+			}
+		} else {
+			impostors++
+			if accepted {
+				falseAccepts++
+			}
+		}
+	}
+
+	rates := Rates{Threshold: threshold}
+	if impostors > 0 {
+		rates.FAR = float64(falseAccepts) / float64(impostors)
+	}
+	if genuines > 0 {
+		rates.FRR = float64(falseRejects) / float64(genuines)
+	}
+
+	return rates
+}
+
+func SweepRates(samples []LabeledSample, scores []float64, step float64) []Rates {
+	rates := []Rates{}
+	for i := 0; float64(i)*step <= 1+1e-9; i++ {
+		rates = append(rates, ComputeRates(samples, scores, float64(i)*step))
+	}
+
+	return rates
+}
+
+// OptimalThreshold returns the threshold misclassifying the fewest
+// samples, the highest one on ties. 1 is a candidate too, so impostors
+// scoring below every genuine sample, or without any genuine sample, are
+// all rejected.
+func OptimalThreshold(samples []LabeledSample, scores []float64) float64 {
+	candidates := append([]float64{1}, scores...)
+	sort.Float64s(candidates)
+
+	best, bestErrors := 1.0, len(samples)+1
+	for i := len(candidates) - 1; i >= 0; i-- {
+		threshold := candidates[i]
+		if threshold < 0 {
+			break
+		}
+
+		errors := 0
+		for j, sample := range samples {
+			if (scores[j] >= threshold) != sample.Genuine {
+				errors++
+			}
+		}
+
+		if errors < bestErrors {
+			best, bestErrors = threshold, errors
+		}
+	}
+
+	return best
+}
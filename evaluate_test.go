@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// evaluateDataset scores 1, 0.75 and 0.5 for the genuine samples and 0.5,
+// 0.25 and, typed with other characters, -1 for the impostors against
+// evaluateProfile.
+const evaluateDataset = `# enrolled user
+genuine t0.at100.bt100.ct100.d
+genuine t0.at100.bt100.ct300.d
+genuine t0.at100.bt300.ct300.d
+
+impostor t0.at100.bt300.ct300.d
+impostor t0.at300.bt300.ct300.d
+impostor t0.xt100.yt100.zt100.w
+`
+
+const evaluateProfile = "t0.at100.bt100.ct100.d"
+
+func TestComputeRates(t *testing.T) {
+	samples, err := ReadDataset(strings.NewReader(evaluateDataset))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scores := mustProfile(t, evaluateProfile).Scores(samples)
+	if want := []float64{1, 0.75, 0.5, 0.5, 0.25, -1}; !slices.Equal(scores, want) {
+		t.Fatalf("Scores = %v, want %v", scores, want)
+	}
+
+	for _, want := range []Rates{
+		{Threshold: 0, FAR: 2.0 / 3, FRR: 0},
+		{Threshold: 0.5, FAR: 1.0 / 3, FRR: 0},
+		{Threshold: 0.8, FAR: 0, FRR: 2.0 / 3},
+		{Threshold: 1, FAR: 0, FRR: 2.0 / 3},
+	} {
+		if rates := ComputeRates(samples, scores, want.Threshold); !ratesEqual(rates, want) {
+			t.Errorf("ComputeRates(%v) = %+v, want %+v", want.Threshold, rates, want)
+		}
+	}
+
+	// Without impostors or genuine samples the rate has nothing to count.
+	if rates := ComputeRates(samples[:3], scores[:3], 0.5); rates.FAR != 0 || rates.FRR != 0 {
+		t.Errorf("ComputeRates of genuine samples only = %+v", rates)
+	}
+	if rates := ComputeRates(samples[3:], scores[3:], 0.5); rates.FAR != 1.0/3 || rates.FRR != 0 {
+		t.Errorf("ComputeRates of impostors only = %+v", rates)
+	}
+}
+
+func TestSweepRates(t *testing.T) {
+	samples, err := ReadDataset(strings.NewReader(evaluateDataset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := mustProfile(t, evaluateProfile).Scores(samples)
+
+	want := []Rates{
+		{Threshold: 0, FAR: 2.0 / 3, FRR: 0},
+		{Threshold: 0.25, FAR: 2.0 / 3, FRR: 0},
+		{Threshold: 0.5, FAR: 1.0 / 3, FRR: 0},
+		{Threshold: 0.75, FAR: 0, FRR: 1.0 / 3},
+		{Threshold: 1, FAR: 0, FRR: 2.0 / 3},
+	}
+	rates := SweepRates(samples, scores, 0.25)
+	if len(rates) != len(want) {
+		t.Fatalf("SweepRates by 0.25 = %+v, want %d thresholds", rates, len(want))
+	}
+	for i := range want {
+		if !ratesEqual(rates[i], want[i]) {
+			t.Errorf("SweepRates by 0.25, threshold %d = %+v, want %+v", i, rates[i], want[i])
+		}
+	}
+
+	// A step that doesn't divide 1 stops below it.
+	if rates := SweepRates(samples, scores, 0.3); len(rates) != 4 || math.Abs(rates[3].Threshold-0.9) > 1e-9 {
+		t.Errorf("SweepRates by 0.3 = %+v, want 0 to 0.9", rates)
+	}
+}
+
+func TestLoadDataset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.txt")
+	if err := os.WriteFile(path, []byte(evaluateDataset), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := LoadDataset(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 6 || !samples[0].Genuine || samples[3].Genuine || samples[5].Rythmkey.Chars() != "xyzw" {
+		t.Errorf("LoadDataset = %+v", samples)
+	}
+
+	for _, tc := range []struct {
+		dataset, err string
+	}{
+		{"genuine t0.a\ngenuine\n", "line 2: missing rythmkey"},
+		{"# labels\nvisitor t0.a\n", `line 2: unknown label "visitor"`},
+		{"impostor t0.at.b\n", "line 1:"},
+	} {
+		if _, err := ReadDataset(strings.NewReader(tc.dataset)); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("ReadDataset(%q) = %v, want %q", tc.dataset, err, tc.err)
+		}
+	}
+
+	if _, err := LoadDataset(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadDataset of a missing file succeeded")
+	}
+}
+
+func TestEvaluateCommand(t *testing.T) {
+	dir := t.TempDir()
+	profile := saveProfile(t, dir, "user.json", mustProfile(t, evaluateProfile))
+	dataset := filepath.Join(dir, "dataset.txt")
+	if err := os.WriteFile(dataset, []byte(evaluateDataset), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runApp(t, "evaluate", "--profile", profile, "--dataset", dataset, "--threshold", "0.5")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if want := "threshold  FAR     FRR\n0.50       0.3333  0.0000\n"; result.stdout != want {
+		t.Errorf("evaluate --threshold 0.5 printed %q, want %q", result.stdout, want)
+	}
+
+	result = runApp(t, "evaluate", "--profile", profile, "--dataset", dataset, "--sweep", "0.5", "--format", "json")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	rates := []Rates{}
+	if err := json.Unmarshal([]byte(result.stdout), &rates); err != nil {
+		t.Fatal(err)
+	}
+	want := []Rates{{Threshold: 0, FAR: 2.0 / 3}, {Threshold: 0.5, FAR: 1.0 / 3}, {Threshold: 1, FRR: 2.0 / 3}}
+	if len(rates) != len(want) {
+		t.Fatalf("evaluate --sweep 0.5 = %+v, want %+v", rates, want)
+	}
+	for i := range want {
+		if !ratesEqual(rates[i], want[i]) {
+			t.Errorf("evaluate --sweep 0.5, threshold %d = %+v, want %+v", i, rates[i], want[i])
+		}
+	}
+}
+
+func ratesEqual(a, b Rates) bool {
+	return math.Abs(a.Threshold-b.Threshold) < 1e-9 && math.Abs(a.FAR-b.FAR) < 1e-9 && math.Abs(a.FRR-b.FRR) < 1e-9
+}
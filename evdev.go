@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// keyPress is a key pressed on an evdev device, with the modifiers held
+// at the time, and when it was released, zero if it wasn't before the
+// capture ended.
+type keyPress struct {
+	At        time.Time
+	Modifiers Modifiers
+	Code      uint16
+	Released  time.Time
+}
+
+// pressTimings turns the timestamps of consecutive key presses into the
+// timings of a key: zero for the first one, then the time elapsed since
+// the previous press, truncated to resolution.
+func pressTimings(presses []keyPress, resolution time.Duration) []time.Duration {
+	timings := make([]time.Duration, len(presses))
+	for i := 1; i < len(presses); i++ {
+		timings[i] = presses[i].At.Sub(presses[i-1].At).Truncate(resolution)
+	}
+
+	return timings
+}
+
+// pressDwells returns how long each key was held, truncated to
+// resolution, zero for the ones still held when the capture ended.
+func pressDwells(presses []keyPress, resolution time.Duration) []time.Duration {
+	dwells := make([]time.Duration, len(presses))
+	for i, press := range presses {
+		if !press.Released.IsZero() {
+			dwells[i] = press.Released.Sub(press.At).Truncate(resolution)
+		}
+	}
+
+	return dwells
+}
+
+// applyPresses replaces the timings of rk with the ones of presses, and
+// its modifiers with theirs if modifiers is set. The
+// terminator may have been pressed after the characters, any other count
+// means the characters and the presses can't be paired: a character typed
+// with a dead key or pasted, or a press on another keyboard.
+func applyPresses(rk Rythmkey, presses []keyPress, terminator []byte, resolution time.Duration, modifiers bool) error {
+	if len(presses) < len(rk) || len(presses) > len(rk)+len(terminator) {
+		return fmt.Errorf("%d key presses for %d characters", len(presses), len(rk))
+	}
+
+	for i, timing := range pressTimings(presses[:len(rk)], resolution) {
+		rk[i].Timing = timing
+		if modifiers {
+			rk[i].Modifiers = presses[i].Modifiers
+		}
+	}
+
+	return nil
+}
+
+// captureEvdev captures from input like Capture, but takes the
+// timings from the key presses of the evdev device, falling back to the
+// ones of the terminal with a warning when it can't. The dwell times go to
+// opts.Dwell, when set, once the presses are paired with the characters.
+func captureEvdev(rk *Rythmkey, input io.Reader, device string, opts ReadOptions) error {
+	recorder, err := openEvdev(device)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't read key presses from %s (%v), timing terminal reads instead\n", device, err)
+		return rk.Capture(input, opts)
+	}
+
+	err = rk.Capture(input, opts)
+	presses := recorder.stop()
+	if err != nil {
+		return err
+	}
+
+	if err := applyPresses(*rk, presses, opts.terminator(), opts.resolution(), opts.Modifiers); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't pair key presses with characters (%v), timing terminal reads instead\n", err)
+		return nil
+	}
+
+	if opts.Dwell != nil {
+		*opts.Dwell = pressDwells(presses[:len(*rk)], opts.resolution())
+	}
+
+	return nil
+}
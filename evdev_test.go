@@ -0,0 +1,88 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPressTimings(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	presses := []keyPress{
+		{At: start.Add(5 * time.Second)},
+		{At: start.Add(5*time.Second + 120*time.Millisecond + 700*time.Microsecond)},
+		{At: start.Add(5*time.Second + 200*time.Millisecond)},
+		// Presses a microsecond apart.
+		{At: start.Add(5*time.Second + 200*time.Millisecond + time.Microsecond)},
+	}
+
+	for _, tc := range []struct {
+		resolution time.Duration
+		timings    []time.Duration
+	}{
+		{time.Millisecond, []time.Duration{0, 120 * time.Millisecond, 79 * time.Millisecond, 0}},
+		{time.Microsecond, []time.Duration{0, 120700 * time.Microsecond, 79300 * time.Microsecond, time.Microsecond}},
+	} {
+		if timings := pressTimings(presses, tc.resolution); !slices.Equal(timings, tc.timings) {
+			t.Errorf("pressTimings(%v) = %v, want %v", tc.resolution, timings, tc.timings)
+		}
+	}
+
+	if timings := pressTimings(nil, time.Millisecond); len(timings) != 0 {
+		t.Errorf("pressTimings(nil) = %v, want none", timings)
+	}
+}
+
+func TestPressDwells(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	presses := []keyPress{
+		{At: start, Released: start.Add(80*time.Millisecond + 400*time.Microsecond)},
+		// Still held when the capture ended.
+		{At: start.Add(150 * time.Millisecond)},
+		{At: start.Add(250 * time.Millisecond), Released: start.Add(350 * time.Millisecond)},
+	}
+
+	if dwells, want := pressDwells(presses, time.Millisecond), []time.Duration{80 * time.Millisecond, 0, 100 * time.Millisecond}; !slices.Equal(dwells, want) {
+		t.Errorf("pressDwells = %v, want %v", dwells, want)
+	}
+}
+
+func TestApplyPresses(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	presses := []keyPress{
+		{At: start},
+		{At: start.Add(150 * time.Millisecond), Modifiers: ModShift},
+		{At: start.Add(250 * time.Millisecond)},
+		// The terminator.
+		{At: start.Add(900 * time.Millisecond)},
+	}
+
+	for _, tc := range []struct {
+		n         int
+		modifiers bool
+		encoded   string
+	}{
+		{3, false, "t0.at150.bt100.c"},
+		{4, false, "t0.at150.bt100.c"},
+		{3, true, "t0.a^1t150.bt100.c"},
+	} {
+		rk := mustParse(t, "t0.at10.bt10.c")
+		if err := applyPresses(rk, presses[:tc.n], []byte{'\n'}, time.Millisecond, tc.modifiers); err != nil {
+			t.Fatal(err)
+		}
+
+		if rk.Encode() != tc.encoded {
+			t.Errorf("applyPresses(%d presses, modifiers %t) = %s, want %s", tc.n, tc.modifiers, rk.Encode(), tc.encoded)
+		}
+	}
+
+	// A character without a press, or more presses than a terminator
+	// explains.
+	for _, n := range []int{2, 5} {
+		rk := mustParse(t, "t0.at10.bt10.c")
+		all := append(presses, keyPress{At: start.Add(time.Second)})
+		if err := applyPresses(rk, all[:n], []byte{'\n'}, time.Millisecond, false); err == nil || rk.Encode() != "t0.at10.bt10.c" {
+			t.Errorf("applyPresses(%d presses) = %v, %s, want an error leaving the timings", n, err, rk.Encode())
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// KeyEvent is a keystroke collected by a frontend doing its own capture.
+type KeyEvent struct {
+	Char rune
+	At   time.Time
+}
+
+// RythmkeyFromEvents builds a rythmkey from keystroke events, timing each
+// character from the previous one. Multi-byte characters are split into
+// their UTF-8 bytes, the trailing bytes having no timing of their own.
+func RythmkeyFromEvents(events []KeyEvent) Rythmkey {
+	rk := Rythmkey{}
+
+	for i, event := range events {
+		took := time.Duration(0)
+		if i > 0 {
+			took = event.At.Sub(events[i-1].At)
+		}
+
+		for j, c := range utf8.AppendRune(nil, event.Char) {
+			timing := took.Truncate(time.Millisecond)
+			if j > 0 {
+				timing = 0
+			}
+			rk.Add(c, timing)
+		}
+	}
+
+	return rk
+}
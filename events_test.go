@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRythmkeyFromEvents(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	events := []KeyEvent{
+		{Char: 'a', At: start},
+		{Char: 'b', At: start.Add(120*time.Millisecond + 400*time.Microsecond)},
+		{Char: 'é', At: start.Add(200 * time.Millisecond)},
+		{Char: 'c', At: start.Add(350 * time.Millisecond)},
+	}
+
+	rk := RythmkeyFromEvents(events)
+
+	if got, want := rk.Encode(), "t0.at120.bt79.\xc3t0.\xa9t150.c"; got != want {
+		t.Errorf("Encode = %q, want %q", got, want)
+	}
+
+	if rk.Chars() != "abéc" {
+		t.Errorf("Chars = %q, want %q", rk.Chars(), "abéc")
+	}
+}
+
+func TestRythmkeyFromNoEvents(t *testing.T) {
+	if rk := RythmkeyFromEvents(nil); rk.Len() != 0 {
+		t.Errorf("RythmkeyFromEvents(nil) = %v, want an empty key", rk)
+	}
+}
+
+func TestRythmkeyFromEventsRoundTrip(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	rk := RythmkeyFromEvents([]KeyEvent{{Char: 'ö', At: start}, {Char: 'k', At: start.Add(90 * time.Millisecond)}})
+
+	parsed := mustParse(t, rk.Encode())
+	if parsed.Encode() != rk.Encode() || parsed.Chars() != "ök" {
+		t.Errorf("round trip of %q = %q", rk.Encode(), parsed.Encode())
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// CompareExact matches keys that quantize to the same hashing input with
+// opts, the verdict VerifyHash gives the key against the hash of the
+// reference made with opts. Only the verdict, see matchExact, takes the
+// same time whether the keys match or not: the rest of the report, like
+// CharDistance and the failing length, branches on the characters that
+// differ and is meant for feedback, not for verification.
+func CompareExact(reference Rythmkey, rk Rythmkey, opts HashOptions) (CompareReport, error) {
+	report := CompareReport{
+		Method:       "exact",
+		Length:       reference.Len(),
+		TypedLength:  rk.Len(),
+		CharDistance: reference.CharDistance(rk),
+		FailIndex:    -1,
+	}
+
+	for i := 0; i < len(reference) && i < len(rk); i++ {
+		same := subtle.ConstantTimeByteEq(reference[i].Char, rk[i].Char)
+		report.MatchingChars += same
+
+		first := (1 - same) & subtle.ConstantTimeEq(int32(report.FailIndex), -1)
+		report.FailIndex = subtle.ConstantTimeSelect(first, i, report.FailIndex)
+	}
+	report.failLength()
+
+	match, err := matchExact(reference, rk, opts)
+	if err != nil {
+		return CompareReport{}, err
+	}
+
+	report.Match = match
+	return report, nil
+}
+
+// matchExact is the verdict of CompareExact alone, the fast path verify
+// takes with the exact method: the hashing inputs of both keys are
+// digested and the digests compared with crypto/subtle, so neither what
+// differs nor the length of the inputs shows in the time it takes.
+func matchExact(reference Rythmkey, rk Rythmkey, opts HashOptions) (bool, error) {
+	expected, err := reference.HashInput(opts)
+	if err != nil {
+		return false, err
+	}
+
+	typed, err := rk.HashInput(opts)
+	if err != nil {
+		return false, err
+	}
+
+	a, b := sha256.Sum256(expected), sha256.Sum256(typed)
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCompareExact(t *testing.T) {
+	reference := mustParse(t, "t0.at123.bt7.c")
+	opts := HashOptions{Salt: 20}
+
+	for _, tc := range []struct {
+		typed        string
+		match        bool
+		matching     int
+		charDistance int
+		failIndex    int
+	}{
+		{"t0.at125.bt10.c", true, 3, 0, -1},
+		// Same characters, another bucket.
+		{"t0.at300.bt10.c", false, 3, 0, -1},
+		{"t0.at125.xt10.c", false, 2, 1, 1},
+		{"t0.xt125.bt10.y", false, 1, 2, 0},
+		{"t0.at125.b", false, 2, 1, 2},
+	} {
+		report, err := CompareExact(reference, mustParse(t, tc.typed), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if report.Match != tc.match || report.MatchingChars != tc.matching || report.CharDistance != tc.charDistance || report.FailIndex != tc.failIndex {
+			t.Errorf("CompareExact(%s) = match %t, %d matching, distance %d, fail index %d, want %t, %d, %d, %d",
+				tc.typed, report.Match, report.MatchingChars, report.CharDistance, report.FailIndex,
+				tc.match, tc.matching, tc.charDistance, tc.failIndex)
+		}
+	}
+}
+
+// exactPairs returns a reference of length characters and keys typed
+// against it, some close enough to quantize the same, some not, some with
+// other characters.
+func exactPairs(length int) (Rythmkey, []Rythmkey) {
+	rng := rand.New(rand.NewSource(1))
+	keys := syntheticSamples(rng, 200, length)
+	for i, rk := range keys {
+		switch i % 4 {
+		case 1:
+			rk[i%length].Char++
+		case 2:
+			keys[i] = rk.Prefix(length - 2)
+		case 3:
+			// Copies of the reference, a bucket at most away.
+			keys[i] = keys[0].Prefix(length)
+			keys[i][i%length].Timing += time.Duration(i%3) * 5 * time.Millisecond
+		}
+	}
+
+	return keys[0], keys
+}
+
+// The fast path verify takes gives the verdict of the general comparison,
+// and of verifying against the hash of the reference.
+func TestExactFastPath(t *testing.T) {
+	reference, keys := exactPairs(8)
+	opts := HashOptions{Salt: 20}
+
+	hash, err := reference.HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches := 0
+	for _, rk := range keys {
+		report, err := CompareExact(reference, rk, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fast, score, err := ExactMatcher{Options: opts}.Match(reference, rk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hashed, err := rk.VerifyHash(opts, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fast != report.Match || hashed != report.Match {
+			t.Errorf("%s: fast path %t, general path %t, hash %t", rk.Encode(), fast, report.Match, hashed)
+		}
+		if want := map[bool]float64{true: 1, false: 0}[fast]; score != want {
+			t.Errorf("%s: fast path scored %v, want %v", rk.Encode(), score, want)
+		}
+		if report.Match {
+			matches++
+		}
+	}
+
+	// Both verdicts are exercised.
+	if matches == 0 || matches == len(keys) {
+		t.Errorf("%d of %d keys matched", matches, len(keys))
+	}
+}
+
+// The general path costs the edit distance of the characters on top of
+// the fast one, quadratic in their number.
+func BenchmarkExact(b *testing.B) {
+	reference, keys := exactPairs(32)
+	opts := HashOptions{Salt: 20}
+
+	b.Run("fast", func(b *testing.B) {
+		m := ExactMatcher{Options: opts}
+		for i := 0; i < b.N; i++ {
+			if _, _, err := m.Match(reference, keys[i%len(keys)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("general", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := CompareExact(reference, keys[i%len(keys)], opts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,62 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// feedFIFO creates a named pipe and writes s to it once readFIFO opens it.
+func feedFIFO(t *testing.T, s string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "input")
+	if err := unix.Mkfifo(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		// Opening blocks until the reader opens its end.
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		f.WriteString(s)
+		f.Close()
+	}()
+
+	return path
+}
+
+func TestReadFIFO(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		rks   string
+	}{
+		{"t0.at120.bt80.c\n", "t0.at120.bt80.c"},
+		{"t0.at120.bt80.c\nt0.d\n", "t0.at120.bt80.c"},
+		{"t0.at120.bt80.c", "t0.at120.bt80.c"},
+	} {
+		rk, err := readFIFO(feedFIFO(t, tc.input))
+		if err != nil {
+			t.Errorf("readFIFO(%q) failed: %v", tc.input, err)
+			continue
+		}
+
+		if rk.Encode() != tc.rks {
+			t.Errorf("readFIFO(%q) = %s, want %s", tc.input, rk.Encode(), tc.rks)
+		}
+	}
+}
+
+func TestReadFIFOInvalid(t *testing.T) {
+	for _, input := range []string{"", "\n", "t0.at-x.b\n"} {
+		if rk, err := readFIFO(feedFIFO(t, input)); err == nil {
+			t.Errorf("readFIFO(%q) = %s, want an error", input, rk.Encode())
+		}
+	}
+}
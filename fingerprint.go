@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const fingerprintGroups = 3
+
+// Fingerprint returns a short form of the hash of the key with salt and
+// default options, its first bytes as groups of uppercase hex like
+// "4F2A-9C01-77BE", for a user to recognize a key at a glance. It is for
+// humans only: far too short to verify a key with, it matches other keys
+// by chance, and being part of the digest it must be kept like the hash.
+func (rythmkey Rythmkey) Fingerprint(salt int) (string, error) {
+	digest, err := rythmkey.Digest(HashOptions{Salt: salt})
+	if err != nil {
+		return "", err
+	}
+
+	groups := make([]string, fingerprintGroups)
+	for i := range groups {
+		groups[i] = fmt.Sprintf("%02X%02X", digest[2*i], digest[2*i+1])
+	}
+
+	return strings.Join(groups, "-"), nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestFingerprintPinned(t *testing.T) {
+	rk := mustParse(t, "t0.at123.bt7.c")
+
+	fingerprint, err := rk.Fingerprint(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "8E22-2D0D-F224"; fingerprint != want {
+		t.Errorf("Fingerprint(20) = %s, want %s", fingerprint, want)
+	}
+
+	// The first bytes of the digest.
+	digest, err := rk.Digest(HashOptions{Salt: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.ReplaceAll(fingerprint, "-", ""); got != strings.ToUpper(hex.EncodeToString(digest[:6])) {
+		t.Errorf("Fingerprint(20) = %s, digest starts with %x", fingerprint, digest[:6])
+	}
+
+	// Within the same buckets, the same fingerprint, and another salt
+	// another one.
+	if other, err := mustParse(t, "t0.at125.bt10.c").Fingerprint(20); err != nil || other != fingerprint {
+		t.Errorf("Fingerprint(20) of a key in the same buckets = %s, %v, want %s", other, err, fingerprint)
+	}
+	if other, err := rk.Fingerprint(30); err != nil || other == fingerprint {
+		t.Errorf("Fingerprint(30) = %s, %v, want another fingerprint", other, err)
+	}
+}
+
+func TestReadFingerprint(t *testing.T) {
+	want, err := mustParse(t, "t0.at0.bt0.c").Fingerprint(defaultSalt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scriptStdin(t, "abc\n")
+	result := runApp(t, "read", "--fingerprint")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if got := strings.TrimSpace(result.stdout); got != want {
+		t.Errorf("read --fingerprint = %q, want %s", got, want)
+	}
+
+	for _, flag := range []string{"--hash", "--output-hash-only", "--checksum"} {
+		scriptStdin(t, "abc\n")
+		if result := runApp(t, "read", "--fingerprint", flag); result.err == nil {
+			t.Errorf("read --fingerprint %s succeeded", flag)
+		}
+	}
+}
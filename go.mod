@@ -2,7 +2,12 @@ module rythmkey
 
 go 1.21.5
 
-require github.com/urfave/cli/v2 v2.27.4
+require (
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	github.com/urfave/cli/v2 v2.27.4
+	golang.org/x/crypto v0.23.0
+	golang.org/x/sys v0.20.0
+)
 
 require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.4 // indirect
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// EncodeDigest renders the raw bytes of a digest in encoding, one of
+// hashEncodings, hex when empty.
+func EncodeDigest(digest []byte, encoding string) (string, error) {
+	switch encoding {
+	case "", "hex":
+		return hex.EncodeToString(digest), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(digest), nil
+	case "base32":
+		return base32.StdEncoding.EncodeToString(digest), nil
+	default:
+		return "", fmt.Errorf("unknown hash encoding %q", encoding)
+	}
+}
+
+// DecodeDigest returns the raw bytes of a digest rendered by EncodeDigest.
+func DecodeDigest(digest string, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "hex":
+		return hex.DecodeString(digest)
+	case "base64":
+		return base64.StdEncoding.DecodeString(digest)
+	case "base32":
+		return base32.StdEncoding.DecodeString(digest)
+	default:
+		return nil, fmt.Errorf("unknown hash encoding %q", encoding)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDigest(t *testing.T) {
+	digest := sha256.Sum256(nil)
+
+	for _, tc := range []struct {
+		encoding string
+		encoded  string
+	}{
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"hex", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"base64", "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+		{"base32", "4OYMIQUY7QOBJGX36TEJS35ZEQT24QPEMSNZGTFESWMRW6CSXBKQ===="},
+	} {
+		encoded, err := EncodeDigest(digest[:], tc.encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded != tc.encoded {
+			t.Errorf("EncodeDigest(%q) = %s, want %s", tc.encoding, encoded, tc.encoded)
+		}
+
+		decoded, err := DecodeDigest(encoded, tc.encoding)
+		if err != nil || !bytes.Equal(decoded, digest[:]) {
+			t.Errorf("DecodeDigest(%s, %q) = %x, %v, want the digest back", encoded, tc.encoding, decoded, err)
+		}
+	}
+
+	if _, err := EncodeDigest(digest[:], "base58"); err == nil {
+		t.Error("EncodeDigest in base58 succeeded")
+	}
+	if _, err := DecodeDigest("e3b0", "base58"); err == nil {
+		t.Error("DecodeDigest in base58 succeeded")
+	}
+	if _, err := DecodeDigest("not hex", "hex"); err == nil {
+		t.Error("DecodeDigest of invalid hex succeeded")
+	}
+}
+
+func TestHashEncodings(t *testing.T) {
+	const rks = "t0.at120.bt80.c"
+	hexDigest, err := mustParse(t, rks).HashWith(HashOptions{Salt: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encoding := range hashEncodings {
+		opts := HashOptions{Salt: 20, Encoding: encoding}
+		digest, err := mustParse(t, rks).HashWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The same digest bytes, only rendered differently.
+		raw, err := DecodeDigest(digest, encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded, _ := EncodeDigest(raw, "hex"); encoded != hexDigest {
+			t.Errorf("%s digest %s decodes to %s, want %s", encoding, digest, encoded, hexDigest)
+		}
+
+		// The parameters record the encoding, so it verifies.
+		params := NewHashParams(opts, digest).String()
+		if encoding != "hex" && !strings.Contains(params, "$e="+encoding+"$") {
+			t.Errorf("parameters %s don't record the %s encoding", params, encoding)
+		}
+
+		t.Setenv(encodedInputEnv, rks)
+		result := runApp(t, "hash", "--rythmkey", rks, "--salt", "20", "--hash-encoding", encoding)
+		if result.err != nil || result.stdout != params {
+			t.Errorf("hash --hash-encoding %s = %q, %v, want %s", encoding, result.stdout, result.err, params)
+		}
+		if result := runApp(t, "verify", "--hash", params); result.err != nil {
+			t.Errorf("verify of a %s hash = %v", encoding, result.err)
+		}
+	}
+}
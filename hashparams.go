@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const hashParamsPrefix = "$rk$"
+
+// HashParams is a digest along with everything needed to reproduce it,
+// formatted crypt style: $rk$sha256$v=1$s=20$u=ms$<hex>. A digest in
+// another encoding than hex records it as e=base64 or e=base32.
+type HashParams struct {
+	Algorithm string
+	Unit      string
+	Options   HashOptions
+	// SaltPhrase is set when the salt and key were derived from a phrase,
+	// which is never stored and must be provided again to verify.
+	SaltPhrase bool
+	// Context is set when the digest was made in a context, which isn't
+	// stored so a verifier must provide its own.
+	Context bool
+	// Pepper is set when the digest was peppered, see HashOptions.Pepper.
+	Pepper bool
+	// Fields is the number of keys combined by HashFields, 0 for a single
+	// key.
+	Fields int
+	Digest string
+}
+
+func NewHashParams(opts HashOptions, digest string) HashParams {
+	return HashParams{
+		Algorithm:  "sha256",
+		Unit:       "ms",
+		Options:    opts,
+		SaltPhrase: len(opts.Key) > 0,
+		Context:    opts.Context != "",
+		Pepper:     len(opts.Pepper) > 0,
+		Digest:     digest,
+	}
+}
+
+func IsParameterizedHash(hash string) bool {
+	return strings.HasPrefix(hash, hashParamsPrefix)
+}
+
+func (hp HashParams) String() string {
+	params := []string{
+		hp.Algorithm,
+		"v=" + strconv.Itoa(canonicalVersion),
+	}
+
+	if hp.SaltPhrase {
+		params = append(params, "sp=1")
+	} else {
+		params = append(params, "s="+strconv.Itoa(hp.Options.Salt))
+	}
+	params = append(params, "u="+hp.Unit)
+
+	if hp.Options.SkipFirstTiming {
+		params = append(params, "sf=1")
+	}
+
+	if hp.Options.Dither {
+		params = append(params, "d=1")
+	}
+
+	if hp.Options.RhythmOnly {
+		params = append(params, "r=1")
+	}
+
+	if hp.Options.BindStructure {
+		params = append(params, "b=1")
+	}
+
+	if hp.Context {
+		params = append(params, "c=1")
+	}
+
+	if hp.Pepper {
+		params = append(params, "p=1")
+	}
+
+	if hp.Options.Iterations > 1 {
+		params = append(params, "i="+strconv.Itoa(hp.Options.Iterations))
+	}
+
+	if hp.Options.Modifiers {
+		params = append(params, "m=1")
+	}
+
+	if hp.Fields > 1 {
+		params = append(params, "f="+strconv.Itoa(hp.Fields))
+	}
+
+	if encoding := hp.Options.Encoding; encoding != "" && encoding != "hex" {
+		params = append(params, "e="+encoding)
+	}
+
+	return hashParamsPrefix + strings.Join(params, "$") + "$" + hp.Digest
+}
+
+func ParseHashParams(hash string) (HashParams, error) {
+	if !IsParameterizedHash(hash) {
+		return HashParams{}, errors.New("hash has no parameters")
+	}
+
+	fields := strings.Split(strings.TrimPrefix(hash, hashParamsPrefix), "$")
+	if len(fields) < 2 {
+		return HashParams{}, errors.New("truncated hash parameters")
+	}
+
+	hp := HashParams{
+		Algorithm: fields[0],
+		Unit:      "ms",
+		Digest:    fields[len(fields)-1],
+	}
+
+	if !slices.Contains(hashAlgorithms, hp.Algorithm) {
+		return HashParams{}, fmt.Errorf("unsupported hash algorithm %q", hp.Algorithm)
+	}
+
+	if hp.Digest == "" {
+		return HashParams{}, errors.New("missing digest")
+	}
+
+	for _, param := range fields[1 : len(fields)-1] {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return HashParams{}, fmt.Errorf("malformed hash parameter %q", param)
+		}
+
+		switch key {
+		case "v":
+			if value != strconv.Itoa(canonicalVersion) {
+				return HashParams{}, fmt.Errorf("unsupported canonical encoding version %q", value)
+			}
+		case "s":
+			salt, err := strconv.Atoi(value)
+			if err != nil || salt < 1 {
+				return HashParams{}, fmt.Errorf("invalid salt %q", value)
+			}
+			hp.Options.Salt = salt
+		case "u":
+			if value != "ms" {
+				return HashParams{}, fmt.Errorf("unsupported unit %q", value)
+			}
+			hp.Unit = value
+		case "sf":
+			hp.Options.SkipFirstTiming = value == "1"
+		case "d":
+			hp.Options.Dither = value == "1"
+		case "sp":
+			hp.SaltPhrase = value == "1"
+		case "r":
+			hp.Options.RhythmOnly = value == "1"
+		case "b":
+			hp.Options.BindStructure = value == "1"
+		case "c":
+			hp.Context = value == "1"
+		case "p":
+			hp.Pepper = value == "1"
+		case "i":
+			iterations, err := strconv.Atoi(value)
+			if err != nil || iterations < 1 {
+				return HashParams{}, fmt.Errorf("invalid iterations %q", value)
+			}
+			hp.Options.Iterations = iterations
+		case "m":
+			hp.Options.Modifiers = value == "1"
+		case "f":
+			fields, err := strconv.Atoi(value)
+			if err != nil || fields < 2 {
+				return HashParams{}, fmt.Errorf("invalid field count %q", value)
+			}
+			hp.Fields = fields
+		case "e":
+			if !slices.Contains(hashEncodings, value) {
+				return HashParams{}, fmt.Errorf("unsupported hash encoding %q", value)
+			}
+			hp.Options.Encoding = value
+		default:
+			return HashParams{}, fmt.Errorf("unknown hash parameter %q", key)
+		}
+	}
+
+	if hp.Options.Salt == 0 && !hp.SaltPhrase {
+		return HashParams{}, errors.New("missing salt parameter")
+	}
+
+	return hp, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashParamsString(t *testing.T) {
+	hp := NewHashParams(HashOptions{Salt: 20, SkipFirstTiming: true}, "abcd")
+	if got, want := hp.String(), "$rk$sha256$v=1$s=20$u=ms$sf=1$abcd"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+}
+
+func TestHashParamsRoundTrip(t *testing.T) {
+	for _, opts := range []HashOptions{
+		{Salt: 20},
+		{Salt: 35, SkipFirstTiming: true, Dither: true},
+		{Salt: 20, RhythmOnly: true, BindStructure: true, Iterations: 1000},
+		{Salt: 20, Modifiers: true, Encoding: "base64"},
+	} {
+		hp := NewHashParams(opts, "abcd")
+
+		parsed, err := ParseHashParams(hp.String())
+		if err != nil {
+			t.Fatalf("ParseHashParams(%q): %v", hp.String(), err)
+		}
+
+		if !reflect.DeepEqual(parsed, hp) {
+			t.Errorf("ParseHashParams(%q) = %+v, want %+v", hp.String(), parsed, hp)
+		}
+	}
+}
+
+func TestParseHashParamsErrors(t *testing.T) {
+	for _, hash := range []string{
+		"abcd",
+		"$rk$abcd",
+		"$rk$md5$v=1$s=20$u=ms$abcd",
+		"$rk$sha256$v=2$s=20$u=ms$abcd",
+		"$rk$sha256$v=1$s=0$u=ms$abcd",
+		"$rk$sha256$v=1$u=ms$abcd",
+		"$rk$sha256$v=1$s=20$u=ms$",
+		"$rk$sha256$v=1$s=20$u=ms$z=1$abcd",
+		"$rk$sha256$v=1$s=20$u=ms$sf$abcd",
+	} {
+		if hp, err := ParseHashParams(hash); err == nil {
+			t.Errorf("ParseHashParams(%q) = %+v, want an error", hash, hp)
+		}
+	}
+}
+
+func TestVerifyParameterizedHash(t *testing.T) {
+	rk := mustParse(t, "t0.at120.bt80.c")
+	opts := HashOptions{Salt: 25, SkipFirstTiming: true}
+
+	digest, err := rk.HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hp, err := ParseHashParams(NewHashParams(opts, digest).String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := rk.VerifyHash(hp.Options, hp.Digest); err != nil || !ok {
+		t.Errorf("VerifyHash = %t, %v, want a match", ok, err)
+	}
+}
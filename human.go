@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// minHumanVariation is the coefficient of variation of the intervals below
+// which a key is deemed generated. Even very regular typists stay well
+// above it, it only catches near-perfect cadences.
+const minHumanVariation = 0.03
+
+// LooksHuman flags keys whose intervals are all identical or vary too
+// little to have been typed, returning why. Keys with less than three
+// intervals can't be judged and always pass.
+func (rythmkey Rythmkey) LooksHuman() (bool, string) {
+	if len(rythmkey) < 4 {
+		return true, ""
+	}
+
+	intervals := make([]float64, 0, len(rythmkey)-1)
+	for _, ct := range rythmkey[1:] {
+		intervals = append(intervals, float64(ct.Timing))
+	}
+
+	identical := true
+	for _, interval := range intervals[1:] {
+		if interval != intervals[0] {
+			identical = false
+			break
+		}
+	}
+	if identical {
+		return false, "all intervals are identical"
+	}
+
+	mean, variance := meanVariance(intervals)
+	if mean > 0 && math.Sqrt(variance)/mean < minHumanVariation {
+		return false, "intervals vary implausibly little"
+	}
+
+	return true, ""
+}
+
+func meanVariance(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+
+	return mean, variance / float64(len(values))
+}
+
+// IntervalVariance is the variance of the intervals of the key, in square
+// milliseconds, zero without at least two intervals.
+func (rythmkey Rythmkey) IntervalVariance() float64 {
+	if len(rythmkey) < 2 {
+		return 0
+	}
+
+	intervals := make([]float64, 0, len(rythmkey)-1)
+	for _, ct := range rythmkey[1:] {
+		intervals = append(intervals, milliseconds(ct.Timing))
+	}
+
+	_, variance := meanVariance(intervals)
+	return variance
+}
+
+// CheckVariance is a hard gate against flattened rhythms, unlike
+// LooksHuman: it fails keys whose intervals vary less than minimum square
+// milliseconds, too regular to have been typed by the enrolled user.
+func (rythmkey Rythmkey) CheckVariance(minimum float64) error {
+	if variance := rythmkey.IntervalVariance(); variance < minimum {
+		return fmt.Errorf("timing variance %.1fms² below the minimum of %.1fms²", variance, minimum)
+	}
+
+	return nil
+}
+
+// defaultZeroTimingThreshold is the fraction of zero intervals above which
+// a capture is warned about: the typing was faster than the resolution and
+// the key is about to degrade into a plain password.
+const defaultZeroTimingThreshold = 0.5
+
+// ZeroTimingFraction is the fraction of the intervals, the first timing
+// left aside, that are zero. Keys with no interval have none.
+func (rythmkey Rythmkey) ZeroTimingFraction() float64 {
+	if len(rythmkey) < 2 {
+		return 0
+	}
+
+	zero := 0
+	for _, ct := range rythmkey[1:] {
+		if ct.Timing == 0 {
+			zero++
+		}
+	}
+
+	return float64(zero) / float64(len(rythmkey)-1)
+}
+
+// coarseDeliveryMinIntervals is the number of non-zero intervals below
+// which the delivery of input can't be judged.
+const coarseDeliveryMinIntervals = 4
+
+// coarseGranularity is the common divisor of the intervals, in
+// milliseconds, from which input is deemed delivered in batches.
+const coarseGranularity = 10 * time.Millisecond
+
+// CoarseDelivery flags keys whose intervals look like the input was
+// delivered in batches rather than as typed, as over a laggy SSH session:
+// mostly the same value, or all multiples of a coarse granularity. It
+// returns why.
+func (rythmkey Rythmkey) CoarseDelivery() (bool, string) {
+	counts := map[time.Duration]int{}
+	intervals := []time.Duration{}
+	for _, ct := range rythmkey[min(1, len(rythmkey)):] {
+		if ct.Timing > 0 {
+			intervals = append(intervals, ct.Timing)
+			counts[ct.Timing]++
+		}
+	}
+
+	if len(intervals) < coarseDeliveryMinIntervals {
+		return false, ""
+	}
+
+	for timing, count := range counts {
+		if count*2 > len(intervals) {
+			return true, fmt.Sprintf("%d of %d intervals are exactly %s", count, len(intervals), timing)
+		}
+	}
+
+	gcd := intervals[0]
+	for _, interval := range intervals[1:] {
+		a, b := gcd, interval
+		for b != 0 {
+			a, b = b, a%b
+		}
+		gcd = a
+	}
+
+	if gcd >= coarseGranularity {
+		return true, fmt.Sprintf("all intervals are multiples of %s", gcd)
+	}
+
+	return false, ""
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLooksHuman(t *testing.T) {
+	for _, tc := range []struct {
+		rks   string
+		human bool
+	}{
+		{"t0.pt120.at80.st95.st140.w", true},
+		{"t0.pt100.at100.st100.st100.w", false},
+		{"t0.pt100.at101.st100.st101.w", false},
+		{"t0.pt100.at100.s", true},
+		{"t0.p", true},
+	} {
+		human, reason := mustParse(t, tc.rks).LooksHuman()
+		if human != tc.human {
+			t.Errorf("LooksHuman(%s) = %t (%s), want %t", tc.rks, human, reason, tc.human)
+		}
+
+		if !human && reason == "" {
+			t.Errorf("LooksHuman(%s) gave no reason", tc.rks)
+		}
+	}
+}
+
+func TestZeroTimingFraction(t *testing.T) {
+	for _, tc := range []struct {
+		rks      string
+		fraction float64
+	}{
+		{"t0.at0.bt0.c", 1},
+		{"t120.at0.bt0.c", 1},
+		{"t0.at0.bt90.ct0.d", 2.0 / 3},
+		{"t0.at80.bt90.c", 0},
+		{"t0.a", 0},
+	} {
+		if fraction := mustParse(t, tc.rks).ZeroTimingFraction(); fraction != tc.fraction {
+			t.Errorf("ZeroTimingFraction(%s) = %v, want %v", tc.rks, fraction, tc.fraction)
+		}
+	}
+}
+
+func TestCoarseDelivery(t *testing.T) {
+	for _, tc := range []struct {
+		rks    string
+		coarse bool
+	}{
+		// Mostly the same interval.
+		{"t0.pt40.at40.st40.st95.wt40.o", true},
+		// All multiples of 50ms.
+		{"t0.pt50.at100.st150.st50.wt200.o", true},
+		{"t0.pt120.at80.st95.st140.wt63.o", false},
+		// Too few intervals to tell.
+		{"t0.pt40.at40.s", false},
+		{"t0.pt0.at0.st0.st0.wt0.o", false},
+	} {
+		coarse, reason := mustParse(t, tc.rks).CoarseDelivery()
+		if coarse != tc.coarse {
+			t.Errorf("CoarseDelivery(%s) = %t (%s), want %t", tc.rks, coarse, reason, tc.coarse)
+		}
+
+		if coarse && reason == "" {
+			t.Errorf("CoarseDelivery(%s) gave no reason", tc.rks)
+		}
+	}
+}
+
+func TestIntervalVariance(t *testing.T) {
+	for _, tc := range []struct {
+		rks      string
+		variance float64
+	}{
+		{"t0.pt120.at80.st95.st140.w", 529.6875},
+		{"t0.pt100.at100.st100.st100.w", 0},
+		{"t0.pt90.at110.s", 100},
+		{"t0.pt100.a", 0},
+		{"t0.p", 0},
+	} {
+		if variance := mustParse(t, tc.rks).IntervalVariance(); math.Abs(variance-tc.variance) > 1e-9 {
+			t.Errorf("IntervalVariance(%s) = %v, want %v", tc.rks, variance, tc.variance)
+		}
+	}
+}
+
+func TestCheckVariance(t *testing.T) {
+	for _, tc := range []struct {
+		rks string
+		ok  bool
+	}{
+		{"t0.pt120.at80.st95.st140.w", true},
+		{"t0.pt100.at100.st100.st100.w", false},
+		{"t0.pt100.at101.st100.st101.w", false},
+		{"t0.pt90.at110.s", true},
+	} {
+		err := mustParse(t, tc.rks).CheckVariance(100)
+		if (err == nil) != tc.ok {
+			t.Errorf("CheckVariance(%s, 100) = %v, want ok %t", tc.rks, err, tc.ok)
+		}
+	}
+}
+
+func TestVerifyMinVariance(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		samples []string
+		typed   string
+		reject  bool
+	}{
+		{"flat", []string{"t0.pt100.at100.st100.st100.w", "t0.pt101.at100.st99.st100.w", "t0.pt99.at100.st101.st100.w"}, "t0.pt100.at100.st100.st100.w", true},
+		{"natural", []string{"t0.pt120.at80.st95.st140.w", "t0.pt125.at75.st95.st135.w", "t0.pt115.at85.st95.st145.w"}, "t0.pt120.at80.st95.st140.w", false},
+	} {
+		dir := t.TempDir()
+		saveProfile(t, dir, "user.json", mustProfile(t, tc.samples...))
+		t.Setenv(encodedInputEnv, tc.typed)
+
+		// Both match their profile without the gate.
+		if result := runApp(t, "verify", "--profiles-dir", dir); result.err != nil || result.code != 0 {
+			t.Fatalf("verify %s without --min-variance = %d, %v, want an accept", tc.name, result.code, result.err)
+		}
+
+		result := runApp(t, "verify", "--profiles-dir", dir, "--min-variance", "100")
+		if tc.reject && (result.code != exitReject || result.err == nil || !strings.Contains(result.err.Error(), "variance")) {
+			t.Errorf("verify --min-variance of a %s key = %d, %v, want a variance reject", tc.name, result.code, result.err)
+		}
+		if !tc.reject && (result.err != nil || result.code != 0) {
+			t.Errorf("verify --min-variance of a %s key = %d, %v, want an accept", tc.name, result.code, result.err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportedKey is a key typed in a keystroke dynamics dataset, along with
+// who typed it.
+type ImportedKey struct {
+	Subject  string
+	Session  string
+	Rep      string
+	Rythmkey Rythmkey
+	// Dwell is how long every key was held, zero when the dataset doesn't
+	// tell. Keys only carry the flight times between presses.
+	Dwell []time.Duration
+}
+
+// cmuKeyNames are the key names of the CMU benchmark columns that aren't
+// the character itself.
+var cmuKeyNames = map[string]byte{
+	"period": '.', "comma": ',', "space": ' ',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+}
+
+// cmuChar returns the character of a CMU key name, like "t", "five" or
+// "Shift.r". Return ends the password and has no character.
+func cmuChar(name string) (byte, bool, error) {
+	if name == "Return" {
+		return 0, false, nil
+	}
+
+	if c, ok := cmuKeyNames[name]; ok {
+		return c, true, nil
+	}
+
+	shifted, ok := strings.CutPrefix(name, "Shift.")
+	if len(shifted) == 1 && ok {
+		return strings.ToUpper(shifted)[0], true, nil
+	}
+
+	if len(name) == 1 {
+		return name[0], true, nil
+	}
+
+	return 0, false, fmt.Errorf("unknown key %q", name)
+}
+
+// cmuSeconds parses a timing in seconds, missing when empty or NA.
+func cmuSeconds(field string) (time.Duration, bool, error) {
+	if field == "" || field == "NA" {
+		return 0, false, nil
+	}
+
+	seconds, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return time.Duration(math.Round(seconds * float64(time.Second))), true, nil
+}
+
+// ReadCMU reads the CSV of the CMU keystroke dynamics benchmark: a subject,
+// sessionIndex and rep column, then for every key in typing order an
+// H.<key> column, the time it was held, and for every key after the first
+// DD.<previous>.<key>, from the press of the previous key to its own, and
+// UD.<previous>.<key>, from the previous release to its press, all in
+// seconds. The DD times become the timings of the keys. One missing is
+// rebuilt from the H of the previous key and the UD, a missing H only
+// leaves its dwell unknown. The final Return isn't part of the keys.
+func ReadCMU(r io.Reader) ([]ImportedKey, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+
+	columns := map[string]int{}
+	keys := []string{}
+	for i, name := range header {
+		columns[name] = i
+		if key, ok := strings.CutPrefix(name, "H."); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, name := range []string{"subject", "sessionIndex", "rep"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %s column", name)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no H.<key> column")
+	}
+
+	imported := []ImportedKey{}
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		key := ImportedKey{
+			Subject: record[columns["subject"]],
+			Session: record[columns["sessionIndex"]],
+			Rep:     record[columns["rep"]],
+		}
+
+		column := func(name string) (time.Duration, bool, error) {
+			i, ok := columns[name]
+			if !ok {
+				return 0, false, nil
+			}
+
+			d, ok, err := cmuSeconds(record[i])
+			if err != nil {
+				return 0, false, fmt.Errorf("line %d: %s: %w", line, name, err)
+			}
+			return d, ok, nil
+		}
+
+		for i, name := range keys {
+			c, ok, err := cmuChar(name)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+
+			dwell, _, err := column("H." + name)
+			if err != nil {
+				return nil, err
+			}
+
+			timing := time.Duration(0)
+			if i > 0 {
+				pair := keys[i-1] + "." + name
+				dd, ok, err := column("DD." + pair)
+				if err != nil {
+					return nil, err
+				}
+
+				if !ok {
+					ud, udOK, err := column("UD." + pair)
+					if err != nil {
+						return nil, err
+					}
+
+					held, heldOK, err := column("H." + keys[i-1])
+					if err != nil {
+						return nil, err
+					}
+
+					if !udOK || !heldOK {
+						return nil, fmt.Errorf("line %d: no timing for %s", line, pair)
+					}
+					dd = held + ud
+				}
+				timing = dd
+			}
+
+			key.Rythmkey.Add(c, timing)
+			key.Dwell = append(key.Dwell, dwell)
+		}
+
+		imported = append(imported, key)
+	}
+
+	return imported, nil
+}
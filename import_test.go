@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cmuSample follows the columns of the CMU benchmark for the password
+// ".tR5", the second rep missing a DD time and two H times.
+const cmuSample = `subject,sessionIndex,rep,H.period,DD.period.t,UD.period.t,H.t,DD.t.Shift.r,UD.t.Shift.r,H.Shift.r,DD.Shift.r.five,UD.Shift.r.five,H.five,DD.five.Return,UD.five.Return,H.Return
+s002,1,1,0.1491,0.3979,0.2488,0.1069,0.1674,0.0605,0.1169,0.2212,0.1043,0.0742,0.2,0.1258,0.08
+s002,1,2,NA,0.3451,0.2212,0.0814,NA,0.0643,0.1017,0.2013,0.1196,NA,0.2,0.1,0.08
+s003,2,1,0.0939,0.5546,0.4607,0.0934,0.1839,0.0905,0.0907,0.3,0.2093,0.1,0.25,0.15,0.09
+`
+
+func ms(f float64) time.Duration {
+	return time.Duration(f * float64(time.Millisecond))
+}
+
+func TestReadCMU(t *testing.T) {
+	keys, err := ReadCMU(strings.NewReader(cmuSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		subject, session, rep string
+		timings, dwell        []time.Duration
+	}{
+		{"s002", "1", "1", []time.Duration{0, ms(397.9), ms(167.4), ms(221.2)}, []time.Duration{ms(149.1), ms(106.9), ms(116.9), ms(74.2)}},
+		// DD.t.Shift.r rebuilt from H.t and UD.t.Shift.r.
+		{"s002", "1", "2", []time.Duration{0, ms(345.1), ms(145.7), ms(201.3)}, []time.Duration{0, ms(81.4), ms(101.7), 0}},
+		{"s003", "2", "1", []time.Duration{0, ms(554.6), ms(183.9), ms(300)}, []time.Duration{ms(93.9), ms(93.4), ms(90.7), ms(100)}},
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("ReadCMU read %d keys, want %d", len(keys), len(want))
+	}
+
+	for i, key := range keys {
+		w := want[i]
+		if key.Subject != w.subject || key.Session != w.session || key.Rep != w.rep {
+			t.Errorf("key %d is subject %s session %s rep %s, want %s %s %s", i, key.Subject, key.Session, key.Rep, w.subject, w.session, w.rep)
+		}
+
+		// The final Return isn't a character.
+		if key.Rythmkey.Chars() != ".tR5" {
+			t.Errorf("key %d typed %q, want %q", i, key.Rythmkey.Chars(), ".tR5")
+		}
+
+		timings := []time.Duration{}
+		for _, ct := range key.Rythmkey {
+			timings = append(timings, ct.Timing)
+		}
+		if !reflect.DeepEqual(timings, w.timings) {
+			t.Errorf("key %d timings = %v, want %v", i, timings, w.timings)
+		}
+		if !reflect.DeepEqual(key.Dwell, w.dwell) {
+			t.Errorf("key %d dwell = %v, want %v", i, key.Dwell, w.dwell)
+		}
+	}
+}
+
+func TestReadCMUInvalid(t *testing.T) {
+	for name, csv := range map[string]string{
+		"empty":          "",
+		"no subject":     "sessionIndex,rep,H.a\n1,1,0.1\n",
+		"no key":         "subject,sessionIndex,rep\ns002,1,1\n",
+		"unknown key":    "subject,sessionIndex,rep,H.F1\ns002,1,1,0.1\n",
+		"bad number":     "subject,sessionIndex,rep,H.a\ns002,1,1,fast\n",
+		"no timing":      "subject,sessionIndex,rep,H.a,DD.a.b,UD.a.b,H.b\ns002,1,1,0.1,NA,NA,0.1\n",
+		"no dwell to UD": "subject,sessionIndex,rep,H.a,DD.a.b,UD.a.b,H.b\ns002,1,1,NA,NA,0.1,0.1\n",
+		"short record":   "subject,sessionIndex,rep,H.a\ns002,1\n",
+	} {
+		if keys, err := ReadCMU(strings.NewReader(csv)); err == nil {
+			t.Errorf("ReadCMU(%s) = %d keys, want an error", name, len(keys))
+		}
+	}
+}
+
+func TestImportCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "DSL-StrongPasswordData.csv")
+	if err := os.WriteFile(path, []byte(cmuSample), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runApp(t, "import", "--file", path, "--genuine", "s002")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(result.stdout), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("import wrote %q, want 3 lines", result.stdout)
+	}
+	for i, label := range []string{"genuine", "genuine", "impostor"} {
+		got, encoded, _ := strings.Cut(lines[i], " ")
+		if got != label {
+			t.Errorf("line %d labeled %s, want %s", i, got, label)
+		}
+		if rk, err := ParseRythmkey(encoded); err != nil || rk.Chars() != ".tR5" {
+			t.Errorf("line %d is %q, want a key of .tR5", i, encoded)
+		}
+	}
+
+	if result := runApp(t, "import", "--file", path, "--format", "greyc"); result.err == nil {
+		t.Error("import --format greyc succeeded")
+	}
+}
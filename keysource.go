@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+// KeySource captures the characters of a key typed on input along with
+// their timings, each source measuring them its own way, see
+// ReadOptions.Source. A capture failing is zeroed before its error is
+// returned.
+type KeySource interface {
+	Capture(rk *Rythmkey, input io.Reader, opts ReadOptions) error
+}
+
+// ttySource times the reads of the terminal, see Rythmkey.Capture.
+type ttySource struct{}
+
+func (ttySource) Capture(rk *Rythmkey, input io.Reader, opts ReadOptions) error {
+	return rk.Capture(input, opts)
+}
+
+// evdevSource reads the characters from the terminal but times the key
+// presses of the evdev Device, see captureEvdev.
+type evdevSource struct {
+	Device string
+}
+
+func (s evdevSource) Capture(rk *Rythmkey, input io.Reader, opts ReadOptions) error {
+	return captureEvdev(rk, input, s.Device, opts)
+}
+
+// scriptedSource reads the characters from input but gives them Timings
+// in order instead of measuring them, zero past the end. Zero timings are
+// what a terminal that can't be put in cbreak mode gets: input only
+// arrives once a line is complete, so the characters are kept rather than
+// reporting how fast the line was buffered. Test mode uses them too.
+type scriptedSource struct {
+	Timings []time.Duration
+}
+
+func (s scriptedSource) Capture(rk *Rythmkey, input io.Reader, opts ReadOptions) error {
+	if err := rk.Capture(input, opts); err != nil {
+		return err
+	}
+
+	for i, ct := range *rk {
+		ct.Timing = 0
+		if i < len(s.Timings) {
+			ct.Timing = s.Timings[i]
+		}
+	}
+
+	return nil
+}
+
+// keySource returns the source opts captures from, scripted with zero
+// timings in test mode.
+func (opts ReadOptions) keySource() KeySource {
+	switch {
+	case testMode:
+		return scriptedSource{}
+	case opts.Source == "evdev":
+		return evdevSource{Device: opts.EvdevDevice}
+	default:
+		return ttySource{}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScriptedSource(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		timings []time.Duration
+		want    string
+	}{
+		{"zero", nil, "t0.at0.bt0.c"},
+		{"scripted", []time.Duration{0, 120 * time.Millisecond, 80 * time.Millisecond}, "t0.at120.bt80.c"},
+		{"short script", []time.Duration{0, 120 * time.Millisecond}, "t0.at120.bt0.c"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rk := Rythmkey{}
+			if err := (scriptedSource{Timings: tc.timings}).Capture(&rk, pacedBytes("abc\n", time.Millisecond), ReadOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if got := rk.Encode(); got != tc.want {
+				t.Errorf("Capture = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	rk := Rythmkey{}
+	if err := (scriptedSource{}).Capture(&rk, scriptedBytes("abcdef\n"), ReadOptions{MaxLength: 3}); err == nil {
+		t.Errorf("Capture past --max-length = %q, want an error", rk.Encode())
+	}
+	requireZeroed(t, "capture past --max-length", rk)
+}
+
+func TestTTYSource(t *testing.T) {
+	const delay = 20 * time.Millisecond
+
+	rk := Rythmkey{}
+	if err := (ttySource{}).Capture(&rk, pacedBytes("ab\n", delay), ReadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if rk.Len() != 2 || rk[0].Char != 'a' || rk[1].Char != 'b' || rk[1].Timing < delay {
+		t.Errorf("Capture = %q, want ab %s apart at least", rk.Encode(), delay)
+	}
+}
+
+func TestKeySource(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		opts     ReadOptions
+		testMode bool
+		want     KeySource
+	}{
+		{"default", ReadOptions{}, false, ttySource{}},
+		{"tty", ReadOptions{Source: "tty"}, false, ttySource{}},
+		{"evdev", ReadOptions{Source: "evdev", EvdevDevice: "/dev/input/event3"}, false, evdevSource{Device: "/dev/input/event3"}},
+		{"test mode", ReadOptions{Source: "evdev", EvdevDevice: "/dev/input/event3"}, true, scriptedSource{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testMode = tc.testMode
+			defer func() { testMode = false }()
+
+			// scriptedSource isn't comparable.
+			if source, want := fmt.Sprintf("%#v", tc.opts.keySource()), fmt.Sprintf("%#v", tc.want); source != want {
+				t.Errorf("keySource = %s, want %s", source, want)
+			}
+		})
+	}
+}
+
+// Without the device the terminal reads are timed instead.
+func TestEvdevSourceFallback(t *testing.T) {
+	rk := Rythmkey{}
+	source := evdevSource{Device: filepath.Join(t.TempDir(), "event3")}
+	if err := source.Capture(&rk, scriptedBytes("ab\n"), ReadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if rk.Len() != 2 || rk[0].Char != 'a' || rk[1].Char != 'b' {
+		t.Errorf("Capture = %q, want ab", rk.Encode())
+	}
+}
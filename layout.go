@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// defaultReachPerKey is how much more a timing may differ for every key
+// width between a character and the one before it.
+const defaultReachPerKey = 10 * time.Millisecond
+
+// Layout places the keys of a keyboard, in key widths, so a comparison can
+// allow far reaches to vary more than neighbouring keys. A layout only
+// loosens the tolerance of a comparison and never changes the timings:
+// keys enrolled and verified with a layout must always be compared with
+// the same one.
+type Layout struct {
+	Name string
+	keys map[byte][2]float64
+}
+
+// newLayout builds a layout from its rows, top to bottom, each with its
+// horizontal offset and both the unshifted and shifted characters of its
+// keys.
+func newLayout(name string, rows []struct {
+	offset           float64
+	unshifted, shift string
+}) Layout {
+	l := Layout{Name: name, keys: map[byte][2]float64{}}
+	for y, row := range rows {
+		for x := range row.unshifted {
+			position := [2]float64{row.offset + float64(x), float64(y)}
+			l.keys[row.unshifted[x]] = position
+			l.keys[row.shift[x]] = position
+		}
+	}
+
+	return l
+}
+
+var qwertyLayout = newLayout("qwerty", []struct {
+	offset           float64
+	unshifted, shift string
+}{
+	{0, "`1234567890-=", "~!@#$%^&*()_+"},
+	{1.5, "qwertyuiop[]\\", "QWERTYUIOP{}|"},
+	{1.75, "asdfghjkl;'", "ASDFGHJKL:\""},
+	{2.25, "zxcvbnm,./", "ZXCVBNM<>?"},
+})
+
+var layouts = map[string]Layout{
+	qwertyLayout.Name: qwertyLayout,
+}
+
+func LayoutByName(name string) (Layout, error) {
+	l, ok := layouts[name]
+	if !ok {
+		return Layout{}, fmt.Errorf("unknown layout %q", name)
+	}
+
+	return l, nil
+}
+
+// Distance between the keys of two characters in key widths, 0 if either
+// isn't on the layout.
+func (l Layout) Distance(a byte, b byte) float64 {
+	pa, ok := l.keys[a]
+	pb, okb := l.keys[b]
+	if !ok || !okb {
+		return 0
+	}
+
+	return math.Hypot(pa[0]-pb[0], pa[1]-pb[1])
+}
+
+// Reach is the tolerance added to the i-th character of rk, perKey for
+// every key width from the previous character. The first character has
+// none.
+func (l Layout) Reach(rk Rythmkey, i int, perKey time.Duration) time.Duration {
+	if i == 0 {
+		return 0
+	}
+
+	return time.Duration(l.Distance(rk[i-1].Char, rk[i].Char) * float64(perKey))
+}
+
+// CompareLayout compares like CompareSloped, the tolerance of every
+// character also widened by its reach on l from the previous one of the
+// reference.
+func CompareLayout(reference Rythmkey, rk Rythmkey, tolerance Tolerance, l Layout, perKey time.Duration, slope time.Duration) CompareReport {
+	return compareWithin(reference, rk, func(i int) time.Duration {
+		return tolerance(reference[i].Timing) + slope*time.Duration(i) + l.Reach(reference, i, perKey)
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLayoutDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     byte
+		distance float64
+	}{
+		{'q', 'w', 1},
+		{'q', 'p', 9},
+		{'q', 'Q', 0},
+		{'Q', 'p', 9},
+		{'a', 'q', math.Hypot(0.25, 1)},
+		{'1', 'z', math.Hypot(1.25, 3)},
+		{'q', ' ', 0},
+		{'\t', 'q', 0},
+	} {
+		if d := qwertyLayout.Distance(tc.a, tc.b); math.Abs(d-tc.distance) > 1e-9 {
+			t.Errorf("Distance(%s, %s) = %v, want %v", escapeChar(tc.a), escapeChar(tc.b), d, tc.distance)
+		}
+	}
+}
+
+func TestLayoutByName(t *testing.T) {
+	for _, name := range keyboardLayouts {
+		if l, err := LayoutByName(name); err != nil || l.Name != name {
+			t.Errorf("LayoutByName(%q) = %v, %v", name, l.Name, err)
+		}
+	}
+
+	if _, err := LayoutByName("dvorak"); err == nil {
+		t.Error("LayoutByName(dvorak) succeeded")
+	}
+}
+
+func TestLayoutReach(t *testing.T) {
+	rk := mustParse(t, "t0.qt100.pt100.o")
+	for i, want := range []time.Duration{0, 90 * time.Millisecond, 10 * time.Millisecond} {
+		if reach := qwertyLayout.Reach(rk, i, defaultReachPerKey); reach != want {
+			t.Errorf("Reach(%d) = %s, want %s", i, reach, want)
+		}
+	}
+}
+
+func TestCompareLayout(t *testing.T) {
+	tolerance := AbsoluteTolerance(50 * time.Millisecond)
+
+	for _, tc := range []struct {
+		reference, typed string
+		raw, layout      bool
+	}{
+		// q to p is a far reach, 80ms slower is allowed.
+		{"t0.qt100.p", "t0.qt180.p", false, true},
+		// q to w isn't.
+		{"t0.qt100.w", "t0.qt180.w", false, false},
+		{"t0.qt100.pt100.o", "t0.qt180.pt100.o", false, true},
+		{"t0.qt100.pt100.o", "t0.qt100.pt180.o", false, false},
+		{"t0.qt100.p", "t0.qt100.p", true, true},
+	} {
+		reference, typed := mustParse(t, tc.reference), mustParse(t, tc.typed)
+
+		if report := Compare(reference, typed, tolerance); report.Match != tc.raw {
+			t.Errorf("Compare(%s, %s) matched %v, want %v", tc.reference, tc.typed, report.Match, tc.raw)
+		}
+		if report := CompareLayout(reference, typed, tolerance, qwertyLayout, defaultReachPerKey, 0); report.Match != tc.layout {
+			t.Errorf("CompareLayout(%s, %s) matched %v, want %v", tc.reference, tc.typed, report.Match, tc.layout)
+		}
+	}
+}
+
+func TestCompareLayoutFlag(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.qt180.p")
+
+	for _, tc := range []struct {
+		flags []string
+		match bool
+	}{
+		{nil, false},
+		{[]string{"--layout", "qwerty"}, true},
+		{[]string{"--layout", "qwerty", "--reach-per-key", "1ms"}, false},
+	} {
+		result := runApp(t, append([]string{"compare", "--rythmkey", "t0.qt100.p", "--tolerance", "50ms", "--format", "json"}, tc.flags...)...)
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+
+		report := CompareReport{}
+		if err := json.Unmarshal([]byte(result.stdout), &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.Match != tc.match {
+			t.Errorf("compare %v matched %v, want %v", tc.flags, report.Match, tc.match)
+		}
+	}
+
+	if result := runApp(t, "compare", "--rythmkey", "t0.qt100.p", "--layout", "dvorak"); result.err == nil {
+		t.Error("compare --layout dvorak succeeded")
+	}
+}
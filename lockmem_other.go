@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+var lockMemory = func() error {
+	return errors.New("locking memory isn't supported on this platform")
+}
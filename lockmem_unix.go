@@ -0,0 +1,26 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockMemory disables core dumps and locks every page of the process, the
+// current ones and the ones it'll map, in memory so they're never swapped
+// to disk. Locking usually requires CAP_IPC_LOCK or a large enough
+// RLIMIT_MEMLOCK. It is a variable so tests can simulate a refusal
+// without locking the test process.
+var lockMemory = func() error {
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{}); err != nil {
+		return fmt.Errorf("can't disable core dumps: %w", err)
+	}
+
+	if err := unix.Mlockall(unix.MCL_CURRENT | unix.MCL_FUTURE); err != nil {
+		return fmt.Errorf("can't lock memory: %w", err)
+	}
+
+	return nil
+}
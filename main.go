@@ -1,265 +1,3216 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
 	"crypto/sha256"
-	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
-	"os/exec"
+	"slices"
 	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
+	"unicode/utf8"
 
 	"github.com/urfave/cli/v2"
 )
 
 type CharTiming struct {
-	Timing time.Duration
-	Char   byte
+	Timing    time.Duration
+	Char      byte
+	Modifiers Modifiers
 }
 
 func ParseRythmkey(rks string) (Rythmkey, error) {
-	if len(rks) == 0 {
-		return nil, errors.New("empty rythmkey")
+	result, err := ParseDetailed(rks)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Rythmkey, nil
+}
+
+// ParseLenient parses like ParseRythmkey but accepts timings with leading
+// zeros, normalizing them: the key encodes back to its canonical form,
+// which no longer matches a checksum computed over the original text.
+func ParseLenient(rks string) (Rythmkey, error) {
+	result, err := parseDetailed(rks, time.Millisecond, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Rythmkey, nil
+}
+
+// 0     x     y           z
+// t<timing>.<c>t<timing>.<c>
+//
+// The character is the raw byte typed and can be anything but the
+// terminator ending the capture, '\n' by default: spaces, tabs and other
+// whitespace are plain characters and never trimmed. The '.' ending every
+// timing keeps a digit character apart from it, keys encoded before it
+// are read greedily, see ParseAuto. The character
+// is always the single byte after the '.', so a 't', '^', '!' or '.'
+// typed is data, even "ttttt" can't desync the parser. A control
+// character is written ",<hex>" instead of ".<c>", see needsEscape, so
+// that the encoded form is printable text. An optional
+// "!<crc32>" suffix, see EncodeChecksum, guards the whole encoded form
+// against corruption.
+// A character typed with modifiers held is preceded by a "^<mask>", see
+// Modifiers.
+// Timings have no leading zeros, so a key encodes back to the exact text
+// it was parsed from: ParseRythmkey rejects "t007.a" and ParseLenient reads
+// it as "t7.a".
+//
+// A repeated character, like the "ll" of "hello", is as many CharTimings,
+// the second timed from the first like any other pair. Nothing merges
+// them: comparisons and matching methods pair the characters of two keys
+// by position, and warping ones never across a repeat, see DTWDistance,
+// so each repeat is matched on its own.
+type Rythmkey []*CharTiming
+
+// ReadOptions configure how a rythmkey is captured.
+type ReadOptions struct {
+	// Resolution timings are truncated to, milliseconds when unset.
+	// Finer resolutions keep fast typing distinguishable for matching,
+	// hashing always quantizes to milliseconds. Timings are measured with
+	// the monotonic clock whatever the resolution, nanoseconds keeping the
+	// exact deltas at the cost of up to 6 more digits per character in the
+	// encoded form.
+	Resolution time.Duration
+	// MaxLength of the key, a longer input is an error. Defaults to
+	// defaultMaxLength to bound memory against pasted or runaway input.
+	MaxLength int
+	// Echo receives a '*' for every character captured, after its timing
+	// has been taken so the feedback never inflates the measure.
+	Echo io.Writer
+	// OnTiming receives every captured timing, before smoothing, after the
+	// mask has been echoed.
+	OnTiming func(time.Duration)
+	// Check, when set, is called with the key captured so far after every
+	// character. The first error it returns fails the capture once it is
+	// over: the rest of the key is still read, so it doesn't end up typed
+	// in the shell, but it isn't checked and the key is discarded. Check
+	// sees the raw timings, before smoothing, remapping or selecting
+	// positions, and isn't called by evdev captures.
+	Check func(Rythmkey) error
+	// LiveWindow, when positive, shows after the mask of a prompted
+	// capture the mean of the last LiveWindow intervals typed. It is only
+	// displayed, see TrailingSmoother.
+	LiveWindow int
+	// Smooth the captured timings over this odd sized window, see
+	// Rythmkey.Smooth. Keys and profiles compared or verified together
+	// must be captured with the same window.
+	Smooth int
+	// RequireMonotonic fails the capture if a timing is negative or the
+	// first one isn't zero, see Rythmkey.CheckMonotonic.
+	RequireMonotonic bool
+	// CharMap remaps the captured characters, see CharMap.
+	CharMap CharMap
+	// PauseKey, when not zero, toggles a pause: the time spent paused is
+	// left out of the next timing and the key itself isn't recorded.
+	// Typing a character while paused resumes. Matching only sees the
+	// active typing, so pauses don't have to be reproduced.
+	PauseKey byte
+	// Terminator ends the capture, '\n' when unset. With another
+	// terminator '\n' is recorded as any other character. The terminator
+	// itself is never recorded, so it can't be part of the key.
+	Terminator []byte
+	// InputFIFO, when set, is a named pipe an encoded key is read from,
+	// up to a newline, instead of capturing from the terminal. Opening it
+	// blocks until a writer opens it too.
+	InputFIFO string
+	// Pairing, when set, has the key captured on another device: a token
+	// is written to stderr for the user to hand to it, and the capture it
+	// submits for the token is read as a line of stdin, see Pairing.
+	Pairing *Pairing
+	// MeasureReaction times the first character from the start of the
+	// capture, right after the prompt is displayed, instead of zeroing it.
+	// The reaction time is then part of the key and of its hash unless the
+	// first timing is skipped: keys must be captured consistently with or
+	// without it.
+	MeasureReaction bool
+	// Source of the timings, "tty" when unset, see KeySource. With "evdev"
+	// the characters are still read from the terminal but the timings are
+	// taken from the kernel timestamps of the key presses of EvdevDevice,
+	// unaffected by how late the read loop gets scheduled. See openEvdev
+	// for the permissions it requires.
+	Source      string
+	EvdevDevice string
+	// Control, when set, aborts the capture with errCaptureAborted as soon
+	// as it has data to read or its writer closes it, restoring the
+	// terminal, so a parent process can cancel a capture in progress. It
+	// is polled along with the terminal and isn't supported with evdev.
+	Control *os.File
+	// Modifiers records the modifiers held for every character, see
+	// Modifiers. Only the evdev source sees them, a terminal doesn't
+	// report them and they're left at none.
+	Modifiers bool
+	// Dwell, when set, receives how long every key was held, see
+	// MatchDwell. Only the evdev source sees keys released, the others
+	// leave it empty, as does a capture that fails.
+	Dwell *[]time.Duration
+	// Timeout aborts the capture once it has lasted this long, and
+	// FirstKeyTimeout once this long has passed without the first key
+	// being pressed, assuming nobody is there: it's disarmed by the first
+	// key. Both fail the capture, restoring the terminal, and are
+	// disabled when zero.
+	Timeout         time.Duration
+	FirstKeyTimeout time.Duration
+	// MaxTotalTime caps the whole capture and fails closed, discarding
+	// the key, when it's exceeded: a terminal capture is aborted once it
+	// has lasted this long, and a key from any source, a named pipe or
+	// evdev included, whose timings add up to more is rejected. Disabled
+	// when zero.
+	MaxTotalTime time.Duration
+	// LockMemory disables core dumps and locks the memory of the process
+	// before capturing, so the key is neither dumped nor swapped to disk.
+	// It only covers this process: the terminal and the garbage collector's
+	// copies made before locking are out of its reach. A failure to lock
+	// is only a warning.
+	LockMemory bool
+	// Positions, when set, keeps only the characters at these indices, see
+	// Rythmkey.Select, to leave out consistently noisy ones. Keys and
+	// profiles compared or verified together must be captured with the
+	// same positions.
+	Positions []int
+	// ReadOverhead is subtracted from every timing but the first, down to
+	// zero. A key typed while the capture loop is busy with the previous
+	// one is read as soon as the loop gets back to reading, the read
+	// returning right away: its timing is then at least the loop's own
+	// latency, which inflates the fastest keystrokes. See
+	// measureReadOverhead to estimate it.
+	ReadOverhead time.Duration
+	// Forbid rejects captures holding any of these characters, after
+	// CharMap, see Rythmkey.CheckForbidden.
+	Forbid []byte
+	// ControlBytes is what to do with NUL and the other control bytes
+	// captured, ControlRejectNUL when empty, see CheckControlBytes. They
+	// are escaped when encoded, see needsEscape.
+	ControlBytes string
+}
+
+const defaultMaxLength = 4096
+
+func (opts ReadOptions) terminator() []byte {
+	if len(opts.Terminator) == 0 {
+		return []byte{'\n'}
+	}
+
+	return opts.Terminator
+}
+
+// terminated tells whether c completes the terminator, after the rest of
+// it was recorded at the end of rk.
+func terminated(rk Rythmkey, c byte, terminator []byte) bool {
+	if c != terminator[len(terminator)-1] || len(rk) < len(terminator)-1 {
+		return false
+	}
+
+	tail := rk[len(rk)-len(terminator)+1:]
+	for i, ct := range tail {
+		if ct.Char != terminator[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (opts ReadOptions) resolution() time.Duration {
+	if opts.Resolution <= 0 {
+		return time.Millisecond
+	}
+
+	return opts.Resolution
+}
+
+func (rk *Rythmkey) Read() error {
+	return rk.ReadWith(ReadOptions{})
+}
+
+// inputEnv and encodedInputEnv let scripts and tests provide the key
+// without a terminal or stdin: the former holds plain characters, all timed
+// zero, the latter an encoded key with its timings. They are only looked
+// at when set.
+const (
+	inputEnv        = "RYTHMKEY_INPUT"
+	encodedInputEnv = "RYTHMKEY_INPUT_ENCODED"
+	// dwellInputEnv holds the comma separated dwell times of the key of
+	// encodedInputEnv, like 80ms,95ms, see ReadOptions.Dwell.
+	dwellInputEnv = "RYTHMKEY_INPUT_DWELL"
+)
+
+func (rk *Rythmkey) ReadWith(opts ReadOptions) error {
+	return rk.ReadContext(context.Background(), opts)
+}
+
+// ReadContext reads like ReadWith until ctx is done, the capture then
+// failing with ctx.Err() as soon as it is, restoring the terminal. Only
+// terminal captures are interrupted, a named pipe or evdev one is only
+// canceled before it starts.
+func (rk *Rythmkey) ReadContext(ctx context.Context, opts ReadOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if opts.LockMemory {
+		if err := lockMemory(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v, the key may end up in swap or a core dump\n", err)
+		}
+	}
+
+	crk, err := readInput(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	crk, err = finishCapture(crk, opts)
+	if err != nil {
+		if opts.Dwell != nil {
+			clear(*opts.Dwell)
+			*opts.Dwell = nil
+		}
+		return err
+	}
+
+	if opts.Dwell != nil && len(*opts.Dwell) > 0 && len(opts.Positions) > 0 {
+		*opts.Dwell = selectDwell(*opts.Dwell, opts.Positions)
+	}
+
+	*rk = append(*rk, crk...)
+	return nil
+}
+
+// finishCapture checks and transforms crk, just captured, as opts tell.
+// crk and the copies made along the way are zeroed, whether it fails or
+// not: only the key returned is left.
+func finishCapture(crk Rythmkey, opts ReadOptions) (Rythmkey, error) {
+	if opts.MaxTotalTime > 0 && crk.TotalDuration() > opts.MaxTotalTime {
+		crk.Zero()
+		return nil, errCaptureTooLong
+	}
+
+	if len(opts.CharMap) > 0 {
+		remapped := crk.Remap(opts.CharMap)
+		crk.Zero()
+		crk = remapped
+	}
+
+	if opts.Smooth > 1 {
+		smoothed := crk.Smooth(opts.Smooth)
+		crk.Zero()
+		crk = smoothed
+		for _, ct := range crk {
+			ct.Timing = ct.Timing.Truncate(opts.resolution())
+		}
+	}
+
+	if opts.RequireMonotonic {
+		if err := crk.CheckMonotonic(); err != nil {
+			crk.Zero()
+			return nil, err
+		}
+	}
+
+	if len(opts.Forbid) > 0 {
+		if err := crk.CheckForbidden(opts.Forbid); err != nil {
+			crk.Zero()
+			return nil, err
+		}
+	}
+
+	if err := crk.CheckControlBytes(opts.ControlBytes); err != nil {
+		crk.Zero()
+		return nil, err
+	}
+
+	if len(opts.Positions) > 0 {
+		selected, err := crk.Select(opts.Positions)
+		crk.Zero()
+		if err != nil {
+			return nil, err
+		}
+		crk = selected
+	}
+
+	return crk, nil
+}
+
+func readInput(ctx context.Context, opts ReadOptions) (Rythmkey, error) {
+	if rks, ok := os.LookupEnv(encodedInputEnv); ok {
+		rk, err := ParseRythmkey(rks)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", encodedInputEnv, err)
+		}
+
+		if dwells, ok := os.LookupEnv(dwellInputEnv); ok && opts.Dwell != nil {
+			*opts.Dwell, err = parseDwells(dwells, rk.Len())
+			if err != nil {
+				rk.Zero()
+				return nil, fmt.Errorf("%s: %w", dwellInputEnv, err)
+			}
+		}
+
+		return rk, nil
+	}
+
+	if opts.Pairing != nil {
+		return readPaired(opts.Pairing, os.Stdin, os.Stderr)
+	}
+
+	if opts.InputFIFO != "" {
+		return readFIFO(opts.InputFIFO)
+	}
+
+	if chars, ok := os.LookupEnv(inputEnv); ok {
+		chars, _, _ = strings.Cut(chars, string(opts.terminator()))
+
+		rk := Rythmkey{}
+		for i := 0; i < len(chars); i++ {
+			rk.Add(chars[i], 0)
+		}
+
+		return rk, nil
+	}
+
+	input, release, err := captureReader(ctx, os.Stdin, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	source := opts.keySource()
+	if !testMode {
+		saveTerminal()
+		if err := enterCbreak(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: can't read keystrokes as they are typed (%v), timings are lost and the key only holds its characters\n", err)
+			source = scriptedSource{}
+		} else {
+			defer leaveCbreak()
+		}
 	}
 
 	rk := Rythmkey{}
+	if err := source.Capture(&rk, input, opts); err != nil {
+		rk.Zero()
+		return nil, err
+	}
+
+	return rk, nil
+}
+
+// enterCbreak makes the terminal deliver keystrokes as they are typed,
+// without echoing them, and leaveCbreak restores it however the capture
+// ends. enterCbreak only fails for a terminal, pipes don't need it. They
+// are variables so tests can simulate a terminal that refuses and check
+// it's restored.
+var (
+	enterCbreak = func() error {
+		if err := stty("cbreak", "min", "1").Run(); err != nil && isTerminal(os.Stdin) {
+			return err
+		}
+		stty("-echo").Run()
+
+		return nil
+	}
+	leaveCbreak = func() {
+		stty("-cbreak", "echo").Run()
+	}
+)
+
+// readFIFO reads an encoded key terminated by a newline, or by the writer
+// closing its end, from the named pipe at path.
+func readFIFO(path string) (Rythmkey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	if line == "" {
+		return nil, fmt.Errorf("%s: no rythmkey before end of input", path)
+	}
 
-	if rks[0] != 't' {
-		return nil, errors.New("rythmkey char timing must start with a t")
+	rk, err := ParseRythmkey(line)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
 
-	var ct *CharTiming
+	return rk, nil
+}
+
+// CheckMonotonic reports a key whose first timing isn't zero or with a
+// negative interval, which would mean the clock went backwards. Captures
+// take their timings from the monotonic clock time.Now carries, so this is
+// a guard against corrupted or forged keys.
+func (rythmkey Rythmkey) CheckMonotonic() error {
+	for i, ct := range rythmkey {
+		if i == 0 && ct.Timing != 0 {
+			return fmt.Errorf("first timing is %s instead of zero", ct.Timing)
+		}
+
+		if ct.Timing < 0 {
+			return fmt.Errorf("negative timing %s at character %d", ct.Timing, i)
+		}
+	}
 
-	for i := 0; i < len(rks); i++ {
-		c := rks[i]
+	return nil
+}
 
-		if ct == nil && c != 't' {
-			return nil, errors.New("bad chartiming start")
+// CheckForbidden reports the first character of the key that is one of
+// forbidden, with its position.
+func (rythmkey Rythmkey) CheckForbidden(forbidden []byte) error {
+	for i, ct := range rythmkey {
+		if bytes.IndexByte(forbidden, ct.Char) >= 0 {
+			return fmt.Errorf("forbidden character %s at position %d", escapeChar(ct.Char), i)
 		}
+	}
+
+	return nil
+}
+
+// Capture records characters from r until the terminator or EOF. Each timing
+// is the time between the returns of two consecutive reads, taken before
+// anything else is done with the byte, less ReadOverhead. Reads that
+// return no byte or are interrupted by a signal are retried. A capture
+// failing is zeroed before its error is returned.
+func (rk *Rythmkey) Capture(r io.Reader, opts ReadOptions) error {
+	resolution := opts.resolution()
+
+	maxLength := opts.MaxLength
+	if maxLength <= 0 {
+		maxLength = defaultMaxLength
+	}
 
-		if ct == nil && c == 't' {
-			i += 1
+	terminator := opts.terminator()
 
-			ct = &CharTiming{}
-			j := 0
-			for ; j < len(rks); j++ {
-				if rks[i+j] >= '0' && rks[i+j] <= '9' {
-					continue
-				}
+	buf := make([]byte, 1)
+	defer func() { buf[0] = 0 }()
 
-				timing, err := strconv.ParseInt(rks[i:i+j], 10, 64)
-				if err != nil {
-					return nil, err
-				}
+	last := time.Time{}
+	if opts.MeasureReaction {
+		last = time.Now()
+	}
+	paused := time.Duration(0)
+	pausedAt := time.Time{}
+	rejected := error(nil)
+	for {
+		c, err := r.Read(buf)
+		for c == 0 && (err == nil || errors.Is(err, syscall.EINTR)) {
+			c, err = r.Read(buf)
+		}
+		now := time.Now()
 
-				ct.Timing = time.Duration(timing)
+		if c != 1 {
+			if err == io.EOF {
 				break
 			}
+			rk.Zero()
+			return err
+		}
+
+		if terminated(*rk, buf[0], terminator) {
+			*rk = (*rk)[:len(*rk)-len(terminator)+1]
+			break
+		}
+
+		if opts.PauseKey != 0 && buf[0] == opts.PauseKey {
+			if pausedAt.IsZero() {
+				pausedAt = now
+			} else {
+				paused += now.Sub(pausedAt)
+				pausedAt = time.Time{}
+			}
 
-			if i+j > len(rks) {
-				return nil, errors.New("missing data after timing")
+			if err == io.EOF {
+				break
 			}
+			continue
+		}
+
+		if !pausedAt.IsZero() {
+			paused += now.Sub(pausedAt)
+			pausedAt = time.Time{}
+		}
+
+		if len(*rk) >= maxLength {
+			rk.Zero()
+			return fmt.Errorf("rythmkey longer than %d characters", maxLength)
+		}
+
+		took := time.Duration(0)
+		if !last.IsZero() {
+			took = max(0, now.Sub(last)-paused-opts.ReadOverhead)
+		}
+		last = now
+		paused = 0
+
+		if opts.Echo != nil {
+			fmt.Fprint(opts.Echo, "*")
+		}
+		if opts.OnTiming != nil {
+			opts.OnTiming(took)
+		}
+
+		verbose.Printf("get char [%c] %+v in %+v (micro: %d, milli:%s, dec:%d, hex:%X)", buf[0], c, took.Microseconds(), took.Milliseconds(), took, took, took)
+		if len(*rk) == 0 && opts.MeasureReaction {
+			*rk = append(*rk, &CharTiming{Timing: took.Truncate(resolution), Char: buf[0]})
+		} else {
+			rk.Add(buf[0], took.Truncate(resolution))
+		}
+
+		if opts.Check != nil && rejected == nil {
+			rejected = opts.Check(*rk)
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if rejected != nil {
+		rk.Zero()
+		return rejected
+	}
+
+	return nil
+}
+
+func (rythmkey Rythmkey) Encode() string {
+	encoded := ""
+
+	unit := rythmkey.unit()
+	if unit != time.Millisecond {
+		encoded = unitName(unit) + ":"
+	}
+
+	for _, ct := range rythmkey {
+		if ct.Modifiers != 0 {
+			encoded += string(modifiersMark) + strconv.Itoa(int(ct.Modifiers))
+		}
+		encoded += "t" + strconv.FormatInt(int64(ct.Timing/unit), 10)
+		if needsEscape(ct.Char) {
+			encoded += fmt.Sprintf("%c%02x", escapeMark, ct.Char)
+		} else {
+			encoded += string(delimiterMark) + string([]byte{ct.Char})
+		}
+	}
+
+	return encoded
+}
+
+// Add appends a character typed timing after the previous one. The first
+// character of a key always has a zero timing and a negative timing is
+// stored as zero.
+func (rk *Rythmkey) Add(char byte, timing time.Duration) {
+	if len(*rk) == 0 || timing < 0 {
+		timing = 0
+	}
+
+	*rk = append(*rk, &CharTiming{Timing: timing, Char: char})
+}
+
+// Len is the number of characters of the key. A character is a byte: a
+// multi-byte UTF-8 character is as many characters as it has bytes, each
+// with its own timing, see RythmkeyFromEvents and RuneCount.
+func (rythmkey Rythmkey) Len() int {
+	return len(rythmkey)
+}
+
+// RuneCount is the number of UTF-8 characters of the key, bytes that
+// aren't valid UTF-8 counting for one each.
+func (rythmkey Rythmkey) RuneCount() int {
+	return utf8.RuneCountInString(rythmkey.Chars())
+}
+
+// Prefix returns the first n characters of the key, all of them when n
+// is zero or more than its length.
+func (rythmkey Rythmkey) Prefix(n int) Rythmkey {
+	if n <= 0 || n >= rythmkey.Len() {
+		return rythmkey
+	}
+
+	return rythmkey[:n]
+}
+
+// Select returns a new key of the characters at indices, in their order,
+// each keeping its timing: the time since the character typed before it,
+// selected or not. Indices must be within the key and distinct.
+func (rythmkey Rythmkey) Select(indices []int) (Rythmkey, error) {
+	rk := make(Rythmkey, 0, len(indices))
+	seen := map[int]bool{}
+	for _, i := range indices {
+		if i < 0 || i >= rythmkey.Len() {
+			return nil, fmt.Errorf("position %d out of a %d characters rythmkey", i, rythmkey.Len())
+		}
+		if seen[i] {
+			return nil, fmt.Errorf("position %d selected twice", i)
+		}
+		seen[i] = true
+
+		rk = append(rk, &CharTiming{Timing: rythmkey[i].Timing, Char: rythmkey[i].Char, Modifiers: rythmkey[i].Modifiers})
+	}
+
+	return rk, nil
+}
+
+// TotalDuration is the time the key spans, from its first character to
+// its last. It is zero for an empty key.
+func (rythmkey Rythmkey) TotalDuration() time.Duration {
+	total := time.Duration(0)
+	for _, ct := range rythmkey {
+		total += ct.Timing
+	}
+
+	return total
+}
+
+// Concat returns a new key made of rk followed by other, the first timing
+// of other being replaced by junction. Both keys are left untouched.
+func (rythmkey Rythmkey) Concat(other Rythmkey, junction time.Duration) Rythmkey {
+	rk := make(Rythmkey, 0, len(rythmkey)+len(other))
+	for _, ct := range rythmkey {
+		rk = append(rk, &CharTiming{Timing: ct.Timing, Char: ct.Char, Modifiers: ct.Modifiers})
+	}
+
+	for i, ct := range other {
+		timing := ct.Timing
+		if i == 0 {
+			timing = junction
+		}
+		rk = append(rk, &CharTiming{Timing: timing, Char: ct.Char, Modifiers: ct.Modifiers})
+	}
+
+	return rk
+}
+
+// Split returns the fields of the key separated by sep, the separators
+// left out and the first timing of every field zeroed as if it had been
+// captured on its own. A key without sep is a single field. The fields
+// are new keys, rk is left untouched.
+func (rythmkey Rythmkey) Split(sep byte) []Rythmkey {
+	fields := []Rythmkey{{}}
+	for _, ct := range rythmkey {
+		if ct.Char == sep {
+			fields = append(fields, Rythmkey{})
+			continue
+		}
+
+		timing := ct.Timing
+		if len(fields[len(fields)-1]) == 0 {
+			timing = 0
+		}
+		fields[len(fields)-1] = append(fields[len(fields)-1], &CharTiming{Timing: timing, Char: ct.Char, Modifiers: ct.Modifiers})
+	}
+
+	return fields
+}
+
+// Zero overwrites the characters and timings of the key once it's no
+// longer needed. It only narrows the time the secret sits in memory: the
+// garbage collector may have copied it and the strings built from it, like
+// its encoded form, are immutable and can't be wiped.
+func (rythmkey Rythmkey) Zero() {
+	for _, ct := range rythmkey {
+		ct.Char = 0
+		ct.Timing = 0
+		ct.Modifiers = 0
+	}
+}
+
+// Chars returns the typed characters, the plaintext of the key, multi-byte
+// characters back in one piece since each of their bytes is a character.
+func (rythmkey Rythmkey) Chars() string {
+	chars := make([]byte, len(rythmkey))
+	for i, ct := range rythmkey {
+		chars[i] = ct.Char
+	}
+
+	return string(chars)
+}
+
+// defaultSalt is the salt of the hashing flags and matchers when none is
+// given.
+const defaultSalt = 20
+
+// HashOptions tweak how a rythmkey is turned into a digest. Any of them
+// changes the digest, so the same options must be used to create a hash
+// and to verify it.
+type HashOptions struct {
+	Salt int
+	// SkipFirstTiming leaves out the timing of the first character, which
+	// is always zero, so only genuine inter-key intervals are hashed.
+	SkipFirstTiming bool
+	// Dither is experimental: it shifts each timing by an offset derived
+	// from its position before quantizing, so the bucket boundaries of a
+	// character don't line up with the ones of its neighbours.
+	Dither bool
+	// Key, when set, makes the digest an HMAC keyed with it, see
+	// DeriveSaltPhrase.
+	Key []byte
+	// RhythmOnly hashes the timings alone, for a secret tapped on any key.
+	// It has far less entropy than a rythmkey and a plain password.
+	RhythmOnly bool
+	// Iterations the digest is hashed again for, stretching the cost of
+	// each offline guess as much as the cost of every verification. Zero
+	// means a single pass.
+	Iterations int
+	// BindStructure prefixes the hashing input with the number of
+	// characters and the total duration of the key, see
+	// canonicalStructure. It changes the digest and must match between
+	// hashing and verifying.
+	BindStructure bool
+	// Context separates the digests of different applications: the same
+	// key hashes differently in every context, which must match between
+	// hashing and verifying. It isn't a secret.
+	Context string
+	// Pepper, when set, is a secret mixed as an HMAC key into every digest
+	// along with Key. Unlike the salt it is never stored with the hash,
+	// it lives in the environment of the verifier only, see pepperEnv.
+	Pepper []byte
+	// Modifiers hashes the modifiers held for every character along with
+	// it, see Modifiers. It changes the digest and must match between
+	// hashing and verifying, as must the modifiers.
+	Modifiers bool
+	// Encoding renders the digest, one of hashEncodings, hex when empty. It
+	// only changes how the digest is written, not its bytes.
+	Encoding string
+}
+
+// pepperEnv holds the pepper of the hashes made and verified, see
+// HashOptions.Pepper.
+const pepperEnv = "RYTHMKEY_PEPPER"
+
+// Quantize rounds every timing, in milliseconds, up to the next multiple
+// of salt.
+func (rythmkey Rythmkey) Quantize(salt int, dither bool) Rythmkey {
+	rk := Rythmkey{}
+	for i, bucket := range rythmkey.BucketIndices(salt, dither) {
+		saltedTiming := time.Duration(bucket*salt) * time.Millisecond
+		rk = append(rk, &CharTiming{Char: rythmkey[i].Char, Timing: saltedTiming, Modifiers: rythmkey[i].Modifiers})
+	}
+
+	return rk
+}
+
+// BucketIndices returns the bucket of every timing under salt, the
+// multiple of salt Quantize rounds it up to. A timing close to the bound
+// of its bucket flips to the next one with little jitter.
+func (rythmkey Rythmkey) BucketIndices(salt int, dither bool) []int {
+	buckets := make([]int, len(rythmkey))
+	for i, ct := range rythmkey {
+		ms := int(ct.Timing / time.Millisecond)
+		if dither {
+			ms += ditherOffset(i, salt)
+		}
+
+		buckets[i] = (ms + salt) / salt
+	}
+
+	return buckets
+}
+
+// WriteBuckets writes a row per character with its raw and quantized
+// timings in milliseconds, its bucket and its margin: how many
+// milliseconds faster or slower it could have been typed without leaving
+// its bucket.
+func (rythmkey Rythmkey) WriteBuckets(w io.Writer, salt int, dither bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "index\tchar\traw\tquantized\tbucket\tmargin")
+	for i, bucket := range rythmkey.BucketIndices(salt, dither) {
+		shifted := milliseconds(rythmkey[i].Timing)
+		if dither {
+			shifted += float64(ditherOffset(i, salt))
+		}
+		margin := math.Min(shifted-float64((bucket-1)*salt), float64(bucket*salt)-shifted)
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%.2f\n", i, escapeChar(rythmkey[i].Char), formatMilliseconds(rythmkey[i].Timing), bucket*salt, bucket, margin)
+	}
+
+	return tw.Flush()
+}
+
+// ditherOffset spreads positions over the bucket with the golden ratio
+// sequence, which is deterministic and evenly distributed.
+func ditherOffset(i int, salt int) int {
+	_, frac := math.Modf(float64(i) * 0.6180339887498949)
+	return int(frac * float64(salt))
+}
+
+func (rythmkey Rythmkey) Hash(salt int) (string, error) {
+	return rythmkey.HashWith(HashOptions{Salt: salt})
+}
+
+// HashInput returns the canonical bytes HashWith digests: the key
+// quantized as opts says, then canonically encoded.
+func (rythmkey Rythmkey) HashInput(opts HashOptions) ([]byte, error) {
+	if opts.Salt < 1 {
+		return nil, errors.New("salt must be a positive integer")
+	}
+
+	input := canonicalEncode(rythmkey.Quantize(opts.Salt, opts.Dither), opts)
+	if opts.BindStructure {
+		input = append(canonicalStructure(rythmkey, opts.Salt), input...)
+	}
+
+	if opts.Context != "" {
+		input = append(canonicalContext(opts.Context), input...)
+	}
+
+	return input, nil
+}
+
+func (rythmkey Rythmkey) HashWith(opts HashOptions) (string, error) {
+	digest, err := rythmkey.Digest(opts)
+	if err != nil {
+		return "", err
+	}
+
+	return EncodeDigest(digest, opts.Encoding)
+}
+
+// Digest returns the raw bytes HashWith encodes.
+func (rythmkey Rythmkey) Digest(opts HashOptions) ([]byte, error) {
+	srk, err := rythmkey.HashInput(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return digestInput(srk, opts), nil
+}
+
+// digestInput digests a hashing input as opts say.
+func digestInput(input []byte, opts HashOptions) []byte {
+	h := sha256.New()
+	if key := append(append([]byte{}, opts.Key...), opts.Pepper...); len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	}
+	h.Write(input)
+
+	sum := h.Sum(nil)
+	for i := 1; i < opts.Iterations; i++ {
+		h.Reset()
+		h.Write(sum)
+		sum = h.Sum(sum[:0])
+	}
+
+	return sum
+}
+
+func (rythmkey Rythmkey) String() string {
+	str := ""
+	for _, pc := range rythmkey {
+		str += fmt.Sprintf("%c(%s)", pc.Char, formatMilliseconds(pc.Timing))
+	}
+
+	return fmt.Sprintf("%s", str)
+}
+
+// printOutput prints the key or hash a command outputs, with a trailing
+// newline on a terminal only so that $(...) captures the exact bytes.
+func printOutput(s string) {
+	writeOutput(os.Stdout, s, isTerminal(os.Stdout))
+}
+
+func writeOutput(w io.Writer, s string, terminal bool) {
+	if terminal {
+		fmt.Fprintln(w, s)
+		return
+	}
+
+	fmt.Fprint(w, s)
+}
+
+// readOutput formats a key read by the read command as its flags ask.
+func readOutput(cCtx *cli.Context, rk Rythmkey) (string, error) {
+	if cCtx.Bool("fingerprint") {
+		return rk.Fingerprint(cCtx.Int("salt"))
+	}
+
+	if cCtx.Bool("hash") {
+		opts, err := hashOptions(cCtx)
+		if err != nil {
+			return "", err
+		}
+		hrk, err := rk.HashWith(opts)
+		if err != nil {
+			return "", err
+		}
+
+		if !cCtx.Bool("bare") {
+			hrk = NewHashParams(opts, hrk).String()
+		}
+		return hrk, nil
+	}
+
+	if cCtx.Bool("checksum") {
+		return rk.EncodeChecksum(), nil
+	}
+
+	return rk.Encode(), nil
+}
+
+func hashFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:  "salt",
+			Value: defaultSalt,
+			Usage: "timing salt",
+		}, &cli.BoolFlag{
+			Name:  "skip-first-timing",
+			Value: false,
+			Usage: "leave the first character's timing out of the hash, must match between hashing and verifying",
+		}, &cli.BoolFlag{
+			Name:  "dither",
+			Value: false,
+			Usage: "experimental: offset timings by position before quantizing, must match between hashing and verifying",
+		}, &cli.StringFlag{
+			Name:  "salt-phrase",
+			Value: "",
+			Usage: "derive the salt and an HMAC key from this phrase instead of --salt",
+		}, &cli.BoolFlag{
+			Name:  "rhythm-only",
+			Value: false,
+			Usage: "hash the timings only and ignore the characters typed, must match between hashing and verifying",
+		}, &cli.IntFlag{
+			Name:  "iterations",
+			Value: 1,
+			Usage: "number of hashing rounds, more slows down brute-forcing as well as every verification",
+		}, &cli.BoolFlag{
+			Name:  "bind-structure",
+			Value: false,
+			Usage: "hash the character count and total duration along with the key, must match between hashing and verifying",
+		}, &cli.StringFlag{
+			Name:  "context",
+			Value: "",
+			Usage: "application name mixed into the hash so it can't be replayed elsewhere, must match between hashing and verifying",
+		}, &cli.BoolFlag{
+			Name:  "require-pepper",
+			Value: false,
+			Usage: "fail unless a pepper is set in " + pepperEnv,
+		}, &cli.BoolFlag{
+			Name:  "hash-modifiers",
+			Value: false,
+			Usage: "hash the modifiers held for every character, captured with --capture-modifiers, must match between hashing and verifying",
+		}, &cli.StringFlag{
+			Name:  "hash-encoding",
+			Value: "hex",
+			Usage: "encoding of the hash " + choices(hashEncodings),
+		},
+	}
+}
+
+// pepper returns the pepper of the environment, failing if it's required
+// and not set.
+func pepper(cCtx *cli.Context) ([]byte, error) {
+	pepper := os.Getenv(pepperEnv)
+	if pepper == "" && cCtx.Bool("require-pepper") {
+		return nil, fmt.Errorf("a pepper is required, set %s", pepperEnv)
+	}
+
+	return []byte(pepper), nil
+}
+
+func toleranceSlopeFlag() cli.Flag {
+	return &cli.DurationFlag{
+		Name:  "tolerance-slope",
+		Value: 0,
+		Usage: "tolerance method: widen the tolerance by this much for every character after the first, on top of --tolerance whether a duration or a percentage",
+	}
+}
+
+func matchFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "match",
+		Value: MatchFlight,
+		Usage: "what keys are matched on " + choices(matchModes) + ", the intervals between key presses, how long keys are held or both, dwell times requiring --source evdev; verify must match on what the profile was enrolled for",
+	}
+}
+
+func transpositionsFlag() cli.Flag {
+	return &cli.IntFlag{
+		Name:  "allow-transpositions",
+		Value: 0,
+		Usage: "accept up to this many swaps of two adjacent characters before comparing the timings, loosening the characters to match",
+	}
+}
+
+func randomSaltFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "random-salt",
+		Value: false,
+		Usage: "hash with a timing salt drawn at random, one of the bucket widths from 10 to 60ms rather than a cryptographic salt, kept in the hash parameters for verify to recover",
+	}
+}
+
+func hashOptions(cCtx *cli.Context) (HashOptions, error) {
+	opts := HashOptions{
+		Salt:            cCtx.Int("salt"),
+		SkipFirstTiming: cCtx.Bool("skip-first-timing"),
+		Dither:          cCtx.Bool("dither"),
+		RhythmOnly:      cCtx.Bool("rhythm-only"),
+		Iterations:      cCtx.Int("iterations"),
+		BindStructure:   cCtx.Bool("bind-structure"),
+		Context:         cCtx.String("context"),
+		Encoding:        cCtx.String("hash-encoding"),
+		Modifiers:       cCtx.Bool("hash-modifiers"),
+	}
+
+	if opts.Iterations < 1 {
+		return HashOptions{}, errors.New("iterations must be at least 1")
+	}
+
+	if !slices.Contains(hashEncodings, opts.Encoding) {
+		return HashOptions{}, fmt.Errorf("unknown hash encoding %q", opts.Encoding)
+	}
+
+	var err error
+	opts.Pepper, err = pepper(cCtx)
+	if err != nil {
+		return HashOptions{}, err
+	}
+
+	if phrase := cCtx.String("salt-phrase"); phrase != "" {
+		if cCtx.IsSet("salt") {
+			return HashOptions{}, errors.New("--salt and --salt-phrase are exclusive")
+		}
+
+		opts.Salt, opts.Key, err = DeriveSaltPhrase(phrase)
+		if err != nil {
+			return HashOptions{}, err
+		}
+	}
+
+	if cCtx.Bool("random-salt") {
+		if cCtx.IsSet("salt") || cCtx.String("salt-phrase") != "" || cCtx.Bool("bare") {
+			return HashOptions{}, errors.New("--random-salt is exclusive with --salt and --salt-phrase, and needs the hash parameters to keep the salt so it excludes --bare")
+		}
+
+		opts.Salt, err = RandomSalt()
+		if err != nil {
+			return HashOptions{}, err
+		}
+	}
+
+	return opts, nil
+}
+
+// resolveHash returns the options to verify hash with: its own parameters
+// completed by the secrets the flags provide, or the flags' options for a
+// bare digest.
+func resolveHash(cCtx *cli.Context, hash string) (HashParams, error) {
+	opts, err := hashOptions(cCtx)
+	if err != nil {
+		return HashParams{}, err
+	}
+
+	if !IsParameterizedHash(hash) {
+		if hash != "" && !cCtx.IsSet("salt") {
+			return HashParams{}, errors.New("hash has no parameters, --salt is required")
+		}
+
+		return HashParams{Options: opts, Digest: hash}, nil
+	}
+
+	hp, err := ParseHashParams(hash)
+	if err != nil {
+		return HashParams{}, err
+	}
+
+	if hp.SaltPhrase {
+		phrase := cCtx.String("salt-phrase")
+		if phrase == "" {
+			return HashParams{}, errors.New("hash was derived from a salt phrase, --salt-phrase is required")
+		}
+
+		hp.Options.Salt, hp.Options.Key, err = DeriveSaltPhrase(phrase)
+		if err != nil {
+			return HashParams{}, err
+		}
+	}
+
+	if hp.Context {
+		hp.Options.Context = cCtx.String("context")
+		if hp.Options.Context == "" {
+			return HashParams{}, errors.New("hash was made in a context, --context is required")
+		}
+	} else if cCtx.String("context") != "" {
+		return HashParams{}, errors.New("hash wasn't made in a context, drop --context")
+	}
+
+	if hp.Pepper {
+		hp.Options.Pepper = opts.Pepper
+		if len(hp.Options.Pepper) == 0 {
+			return HashParams{}, fmt.Errorf("hash was peppered, set %s", pepperEnv)
+		}
+	} else if cCtx.Bool("require-pepper") {
+		return HashParams{}, errors.New("hash wasn't peppered but a pepper is required")
+	}
+
+	return hp, nil
+}
+
+func prefixFlag() cli.Flag {
+	return &cli.IntFlag{
+		Name:  "prefix",
+		Value: 0,
+		Usage: "only use the first N characters of the rythmkey, 0 for all of them",
+	}
+}
+
+func assumeUnitFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "assume-unit",
+		Value: "ms",
+		Usage: "unit of the timings of rythmkeys without a unit header " + choices(SupportedCapabilities().Units),
+	}
+}
+
+// parseAssuming parses an encoded key flag, in the unit of --assume-unit
+// when it has no header.
+func parseAssuming(cCtx *cli.Context, name string) (ParseResult, error) {
+	unit, err := ParseUnit(cCtx.String("assume-unit"))
+	if err != nil {
+		return ParseResult{}, err
+	}
+
+	result, err := ParseDetailedAssuming(cCtx.String(name), unit)
+	if err != nil {
+		return ParseResult{}, fmt.Errorf("--%s: %w", name, err)
+	}
+
+	return result, nil
+}
+
+// checkUnits refuses to compare a key without a unit header, its timings
+// assumed in milliseconds, with one in another unit: it may well have
+// been captured in that unit and encoded by something that dropped the
+// header. Timings are compared as durations once parsed, so keys with
+// known units compare fine whatever their units. --assume-unit settles
+// the ambiguity.
+func checkUnits(cCtx *cli.Context, name string, key ParseResult, other time.Duration) error {
+	if !key.Headered && other != key.Unit && !cCtx.IsSet("assume-unit") {
+		return fmt.Errorf("--%s has no unit header but is compared with a key in %s, give its unit with --assume-unit", name, unitName(other))
+	}
+
+	return nil
+}
+
+func captureWarningFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.Float64Flag{
+			Name:  "zero-timing-threshold",
+			Value: defaultZeroTimingThreshold,
+			Usage: "warn when more than this fraction of the timings are zero",
+		}, &cli.BoolFlag{
+			Name:  "sample-rate-check",
+			Value: false,
+			Usage: "warn when the timings look like input is delivered in batches, as over a laggy SSH session",
+		},
+	}
+}
+
+// warnCapture tells the user when rk carries little of a rhythm: too many
+// timings rounded to zero, or, with --sample-rate-check, timings that look
+// like the input wasn't captured as it was typed.
+func warnCapture(cCtx *cli.Context, rk Rythmkey) {
+	fraction := rk.ZeroTimingFraction()
+	if fraction > cCtx.Float64("zero-timing-threshold") {
+		fmt.Fprintf(os.Stderr, "warning: %.0f%% of the timings are zero, the key carries almost no rhythm; try --hires\n", fraction*100)
+	}
+
+	if cCtx.Bool("sample-rate-check") {
+		if coarse, reason := rk.CoarseDelivery(); coarse {
+			fmt.Fprintf(os.Stderr, "warning: %s, input seems delivered in batches and this environment may not capture rhythm reliably\n", reason)
+		}
+	}
+}
+
+func captureFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "resolution",
+			Value: "ms",
+			Usage: "timing capture resolution " + choices(SupportedCapabilities().Units),
+		}, &cli.BoolFlag{
+			Name:  "hires",
+			Value: false,
+			Usage: "keep exact nanosecond timings, quantized only when hashing or comparing (same as --resolution ns)",
+		}, &cli.IntFlag{
+			Name:  "max-length",
+			Value: defaultMaxLength,
+			Usage: "maximum number of characters to read",
+		}, &cli.BoolFlag{
+			Name:  "mask",
+			Value: false,
+			Usage: "echo a * on stderr for every character typed",
+		}, &cli.IntFlag{
+			Name:  "smooth",
+			Value: 1,
+			Usage: "smooth timings over this odd window, must match between enrolling, hashing and verifying",
+		}, &cli.BoolFlag{
+			Name:  "require-monotonic",
+			Value: false,
+			Usage: "reject captures with a negative timing",
+		}, &cli.StringFlag{
+			Name:  "char-map",
+			Value: "",
+			Usage: "file of from:to character pairs remapped before encoding, must match between enrolling, hashing and verifying",
+		}, &cli.StringFlag{
+			Name:  "pause-key",
+			Value: "",
+			Usage: "character, or quoted character like '\\x10', toggling a pause left out of the timings",
+		}, &cli.StringFlag{
+			Name:  "terminator-key",
+			Value: "",
+			Usage: "byte sequence ending the capture instead of a newline, with Go escapes like \\x04 or \\x1b\\x1b",
+		}, &cli.BoolFlag{
+			Name:  "capture-modifiers",
+			Value: false,
+			Usage: "record whether Shift, Ctrl or Alt were held for every character, --source evdev only",
+		}, &cli.DurationFlag{
+			Name:  "timeout",
+			Value: 0,
+			Usage: "abort the capture if it isn't over after this long, 0 to wait forever",
+		}, &cli.DurationFlag{
+			Name:  "max-total-time",
+			Value: 0,
+			Usage: "abort the capture and discard the key if it takes longer than this in total, whatever the source, 0 for no cap",
+		}, &cli.DurationFlag{
+			Name:  "first-key-timeout",
+			Value: 0,
+			Usage: "abort the capture if no key is pressed this long after the prompt, 0 to wait forever",
+		}, &cli.IntFlag{
+			Name:  "control-fd",
+			Value: -1,
+			Usage: "inherited file descriptor aborting the capture when written to or closed, -1 for none",
+		}, &cli.StringFlag{
+			Name:  "input-fifo",
+			Value: "",
+			Usage: "read an encoded rythmkey line from this named pipe instead of the terminal",
+		}, &cli.BoolFlag{
+			Name:  "measure-reaction",
+			Value: false,
+			Usage: "time the first character from the prompt instead of zeroing it, changing the hash",
+		}, &cli.StringFlag{
+			Name:  "source",
+			Value: "tty",
+			Usage: "source of the timings " + choices(keySources),
+		}, &cli.StringFlag{
+			Name:  "evdev-device",
+			Value: "",
+			Usage: "evdev source: input device of the keyboard, like /dev/input/event3, which must be readable (root or the input group)",
+		}, &cli.BoolFlag{
+			Name:  "lock-memory",
+			Value: false,
+			Usage: "disable core dumps and lock the process memory so the key isn't swapped, warning if it can't",
+		}, &cli.BoolFlag{
+			Name:  "correct-read-overhead",
+			Value: false,
+			Usage: "measure the latency of the capture loop at startup and subtract it from the timings, must match between enrolling, hashing and verifying",
+		}, &cli.StringFlag{
+			Name:  "forbid",
+			Value: "",
+			Usage: "reject captures holding any of these characters, with Go escapes like \\t",
+		}, &cli.StringFlag{
+			Name:  "control-bytes",
+			Value: ControlRejectNUL,
+			Usage: "what to do with NUL and the other control bytes but tab captured " + choices(controlByteModes) + ", recorded ones are escaped when encoded",
+		}, &cli.IntSliceFlag{
+			Name:  "positions",
+			Usage: "only keep the characters at these comma separated indices, must match between enrolling, hashing and verifying",
+		},
+	}
+}
+
+func readOptions(cCtx *cli.Context) (ReadOptions, error) {
+	resolution, err := ParseUnit(cCtx.String("resolution"))
+	if err != nil {
+		return ReadOptions{}, err
+	}
+
+	if cCtx.Bool("hires") {
+		if cCtx.IsSet("resolution") && resolution != time.Nanosecond {
+			return ReadOptions{}, errors.New("--hires and --resolution conflict")
+		}
+		resolution = time.Nanosecond
+	}
+
+	smooth := cCtx.Int("smooth")
+	if smooth < 1 || smooth%2 == 0 {
+		return ReadOptions{}, errors.New("smooth window must be a positive odd number")
+	}
+
+	ro := ReadOptions{
+		Resolution: resolution,
+		MaxLength:  cCtx.Int("max-length"),
+		Smooth:     smooth,
+
+		RequireMonotonic: cCtx.Bool("require-monotonic"),
+		InputFIFO:        cCtx.String("input-fifo"),
+		MeasureReaction:  cCtx.Bool("measure-reaction"),
+		Source:           cCtx.String("source"),
+		EvdevDevice:      cCtx.String("evdev-device"),
+		LockMemory:       cCtx.Bool("lock-memory"),
+		Positions:        cCtx.IntSlice("positions"),
+		Timeout:          cCtx.Duration("timeout"),
+		FirstKeyTimeout:  cCtx.Duration("first-key-timeout"),
+		MaxTotalTime:     cCtx.Duration("max-total-time"),
+		Modifiers:        cCtx.Bool("capture-modifiers"),
+	}
+
+	if ro.Modifiers && ro.Source != "evdev" {
+		fmt.Fprintf(os.Stderr, "warning: the terminal doesn't report modifiers, use --source evdev to capture them\n")
+	}
+
+	if cCtx.Bool("correct-read-overhead") {
+		ro.ReadOverhead, err = measureReadOverhead(defaultOverheadSamples)
+		if err != nil {
+			return ReadOptions{}, fmt.Errorf("can't measure the read overhead: %w", err)
+		}
+		verbose.Printf("read overhead: %s", ro.ReadOverhead)
+	}
+
+	if fd := cCtx.Int("control-fd"); fd >= 0 {
+		ro.Control = os.NewFile(uintptr(fd), "control")
+	}
+
+	if ro.Source == "evdev" && (ro.Control != nil || ro.Timeout > 0 || ro.FirstKeyTimeout > 0) {
+		return ReadOptions{}, errors.New("--control-fd and timeouts aren't supported with --source evdev")
+	}
+
+	if !slices.Contains(keySources, ro.Source) {
+		return ReadOptions{}, fmt.Errorf("unknown source %q", ro.Source)
+	}
+
+	if ro.Source == "evdev" {
+		if ro.EvdevDevice == "" {
+			return ReadOptions{}, errors.New("--source evdev requires --evdev-device")
+		}
+
+		if ro.MeasureReaction || cCtx.String("pause-key") != "" {
+			return ReadOptions{}, errors.New("--source evdev can't be combined with --measure-reaction or --pause-key")
+		}
+	}
+
+	if ro.MeasureReaction && ro.RequireMonotonic {
+		return ReadOptions{}, errors.New("--measure-reaction and --require-monotonic conflict, the first timing isn't zero")
+	}
+
+	if cCtx.Bool("mask") {
+		ro.Echo = os.Stderr
+	}
+
+	if key := cCtx.String("pause-key"); key != "" {
+		c, rest, err := parseMapChar(key)
+		if err != nil || rest != "" {
+			return ReadOptions{}, fmt.Errorf("invalid pause key %q", key)
+		}
+
+		if c == 0 {
+			return ReadOptions{}, errors.New("pause key can't be NUL")
+		}
+		ro.PauseKey = c
+	}
+
+	if key := cCtx.String("terminator-key"); key != "" {
+		terminator, err := strconv.Unquote(`"` + key + `"`)
+		if err != nil || terminator == "" {
+			return ReadOptions{}, fmt.Errorf("invalid terminator key %q", key)
+		}
+		ro.Terminator = []byte(terminator)
+	}
+
+	ro.ControlBytes = cCtx.String("control-bytes")
+	if !slices.Contains(controlByteModes, ro.ControlBytes) {
+		return ReadOptions{}, fmt.Errorf("unknown control bytes mode %q", ro.ControlBytes)
+	}
+
+	if chars := cCtx.String("forbid"); chars != "" {
+		forbid, err := strconv.Unquote(`"` + chars + `"`)
+		if err != nil {
+			return ReadOptions{}, fmt.Errorf("invalid forbidden characters %q", chars)
+		}
+		ro.Forbid = []byte(forbid)
+	}
+
+	if ro.PauseKey != 0 && bytes.IndexByte(ro.terminator(), ro.PauseKey) >= 0 {
+		return ReadOptions{}, errors.New("pause key can't be part of the terminator")
+	}
+
+	if path := cCtx.String("char-map"); path != "" {
+		ro.CharMap, err = LoadCharMap(path)
+		if err != nil {
+			return ReadOptions{}, err
+		}
+
+		for from, to := range ro.CharMap {
+			if len(ro.terminator()) == 1 && to == ro.terminator()[0] {
+				return ReadOptions{}, fmt.Errorf("char map can't map %s to the terminator", escapeChar(from))
+			}
+		}
+	}
+
+	return ro, nil
+}
+
+// exiter wraps exit for the commands failing with an exit code, which exit
+// from within app.Run before After runs: it checks the terminal and closes
+// the log file, which would otherwise lose what it buffered, first.
+func exiter(exit func(int)) func(int) {
+	return func(code int) {
+		checkTerminal(os.Stderr)
+		closeVerbose()
+		exit(code)
+	}
+}
+
+// newApp returns the command line application.
+func newApp() *cli.App {
+	return &cli.App{
+		Name:  "rythmkey",
+		Usage: "make your password more in rythm",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "verbose",
+				Value: false,
+				Usage: "log diagnostics, including the typed key, to stderr",
+			}, &cli.StringFlag{
+				Name:  "log-file",
+				Value: "",
+				Usage: "write verbose logs to this file instead of stderr",
+			}, &cli.BoolFlag{
+				Name:    "test-mode",
+				Value:   false,
+				Usage:   "for tests only: deterministic and headless commands, with zero timings unless scripted",
+				EnvVars: []string{testModeEnv},
+			}, &cli.StringFlag{
+				Name:  "parse-strategy",
+				Value: ParseAuto,
+				Usage: "how the timings of encoded keys end, auto reading keys encoded without delimiters greedily " + choices(parseStrategies),
+			},
+		},
+		Before: func(cCtx *cli.Context) error {
+			testMode = cCtx.Bool("test-mode")
+			if !slices.Contains(parseStrategies, cCtx.String("parse-strategy")) {
+				return fmt.Errorf("unknown parse strategy %q", cCtx.String("parse-strategy"))
+			}
+			parseStrategy = cCtx.String("parse-strategy")
+			return setupVerbose(cCtx.Bool("verbose"), cCtx.String("log-file"))
+		},
+		After: func(cCtx *cli.Context) error {
+			return closeVerbose()
+		},
+		Commands: []*cli.Command{
+			{
+				Name: "read",
+				Flags: append(append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:  "hash",
+						Value: false,
+						Usage: "hash to resulting rythmkey",
+					}, &cli.BoolFlag{
+						Name:  "bare",
+						Value: false,
+						Usage: "output the bare hex digest, without the parameters needed to verify it",
+					}, &cli.BoolFlag{
+						Name:  "checksum",
+						Value: false,
+						Usage: "end the encoded rythmkey with a checksum detecting corruption",
+					}, &cli.StringFlag{
+						Name:  "tab-mode",
+						Value: "data",
+						Usage: "whether a tab is recorded as a character or separates fields output one per line " + choices(tabModes),
+					}, &cli.BoolFlag{
+						Name:    "output-hash-only",
+						Aliases: []string{"quiet"},
+						Value:   false,
+						Usage:   "hash and write exactly the hash to stdout, without a newline even on a terminal, nor any warning",
+					}, &cli.BoolFlag{
+						Name:  "fingerprint",
+						Value: false,
+						Usage: "output a short fingerprint of the hash with --salt, to recognize the key rather than verify it",
+					}, prefixFlag(), toClipboardFlag(), randomSaltFlag(),
+				}, append(hashFlags(), append(captureWarningFlags(), sessionLogFlags()...)...)...), captureFlags()...),
+				Aliases: []string{"r"},
+				Usage:   "read a rythmkey from your terminal emulator",
+				Action: func(cCtx *cli.Context) error {
+					tabMode := cCtx.String("tab-mode")
+					if !slices.Contains(tabModes, tabMode) {
+						return fmt.Errorf("unknown tab mode %q", tabMode)
+					}
+
+					quiet := cCtx.Bool("output-hash-only")
+					if quiet {
+						if cCtx.Bool("checksum") || tabMode == "separator" || cCtx.Bool("verbose") {
+							return errors.New("--output-hash-only outputs a single hash, it excludes --checksum, --tab-mode separator and --verbose")
+						}
+						cCtx.Set("hash", "true")
+					}
+
+					if cCtx.Bool("fingerprint") && (cCtx.Bool("hash") || cCtx.Bool("checksum")) {
+						return errors.New("--fingerprint excludes --hash, --output-hash-only and --checksum")
+					}
+
+					if cCtx.Bool("to-clipboard") {
+						if !cCtx.Bool("hash") || quiet || tabMode == "separator" {
+							return errors.New("--to-clipboard copies a single hash, it requires --hash and excludes --output-hash-only and --tab-mode separator")
+						}
+					}
+
+					ro, err := readOptions(cCtx)
+					if err != nil {
+						return err
+					}
+
+					if tabMode == "separator" && bytes.IndexByte(ro.terminator(), '\t') >= 0 {
+						return errors.New("--tab-mode separator can't be used with a terminator containing a tab")
+					}
+
+					rk := Rythmkey{}
+					err = rk.ReadWith(ro)
+					if err != nil {
+						return err
+					}
+					defer rk.Zero()
+					logSession(cCtx, rk, nil)
+
+					fields := []Rythmkey{rk}
+					if tabMode == "separator" {
+						fields = rk.Split('\t')
+					}
+
+					outputs := []string{}
+					for _, field := range fields {
+						defer field.Zero()
+
+						field = field.Prefix(cCtx.Int("prefix"))
+						if !quiet {
+							warnCapture(cCtx, field)
+						}
+
+						output, err := readOutput(cCtx, field)
+						if err != nil {
+							return err
+						}
+						outputs = append(outputs, output)
+					}
+
+					if quiet {
+						fmt.Print(outputs[0])
+						return nil
+					}
+
+					if cCtx.Bool("to-clipboard") {
+						return outputHash(cCtx, outputs[0])
+					}
+
+					printOutput(strings.Join(outputs, "\n"))
+					return nil
+				},
+			}, {
+				Name: "hash",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "rythmkey",
+						Value: "",
+						Usage: "ryhtmkey to hash",
+					}, &cli.BoolFlag{
+						Name:  "bare",
+						Value: false,
+						Usage: "output the bare hex digest, without the parameters needed to verify it",
+					}, &cli.BoolFlag{
+						Name:  "explain",
+						Value: false,
+						Usage: "with --verbose, log the quantized canonical input fed to the digest",
+					}, &cli.BoolFlag{
+						Name:  "explain-raw",
+						Value: false,
+						Usage: "with --explain, also log the unquantized rythmkey",
+					}, prefixFlag(), clipboardFlag(), toClipboardFlag(), randomSaltFlag(),
+				}, hashFlags()...),
+				Usage:  "hash an encoded rythmkey",
+				Before: rythmkeyFromClipboard,
+				Action: func(cCtx *cli.Context) error {
+					rk, err := ParseRythmkey(cCtx.String("rythmkey"))
+					if err != nil {
+						return err
+					}
+					defer rk.Zero()
+					rk = rk.Prefix(cCtx.Int("prefix"))
+
+					opts, err := hashOptions(cCtx)
+					if err != nil {
+						return err
+					}
+
+					if cCtx.Bool("explain") {
+						if !cCtx.Bool("verbose") {
+							return errors.New("--explain requires --verbose")
+						}
+
+						input, err := rk.HashInput(opts)
+						if err != nil {
+							return err
+						}
+
+						if cCtx.Bool("explain-raw") {
+							verbose.Printf("rythmkey: %q", rk.Encode())
+						}
+						verbose.Printf("buckets with salt %d:", opts.Salt)
+						rk.WriteBuckets(verbose.Writer(), opts.Salt, opts.Dither)
+						verbose.Printf("hash input: %q", input)
+					} else if cCtx.Bool("explain-raw") {
+						return errors.New("--explain-raw requires --explain")
+					}
+
+					hrk, err := rk.HashWith(opts)
+					if err != nil {
+						return err
+					}
+
+					if !cCtx.Bool("bare") {
+						hrk = NewHashParams(opts, hrk).String()
+					}
+					return outputHash(cCtx, hrk)
+				},
+			}, {
+				Name: "hash-multi",
+				Flags: append(append([]cli.Flag{
+					&cli.IntFlag{
+						Name:  "fields",
+						Value: 2,
+						Usage: "number of rythmkeys to combine, like a username and a password",
+					}, &cli.BoolFlag{
+						Name:  "tab-separated",
+						Value: false,
+						Usage: "type every field in a single capture, separated by tabs, instead of a prompt each",
+					}, &cli.StringFlag{
+						Name:  "hash",
+						Value: "",
+						Usage: "verify the fields against this combined hash instead of outputting it, they must be typed in the order they were hashed in",
+					}, &cli.BoolFlag{
+						Name:  "bare",
+						Value: false,
+						Usage: "output the bare digest, without the parameters needed to verify it",
+					},
+				}, append(hashFlags(), captureWarningFlags()...)...), captureFlags()...),
+				Usage: "type several rythmkeys and combine them into a single hash, in order",
+				Action: func(cCtx *cli.Context) error {
+					n := cCtx.Int("fields")
+					if n < 2 {
+						return errors.New("at least two fields are required")
+					}
+
+					hp, err := resolveHash(cCtx, cCtx.String("hash"))
+					if err != nil {
+						return err
+					}
+					if cCtx.String("hash") != "" && IsParameterizedHash(cCtx.String("hash")) && hp.Fields != n {
+						return fmt.Errorf("hash combines %d fields, not %d", max(1, hp.Fields), n)
+					}
+
+					ro, err := readOptions(cCtx)
+					if err != nil {
+						return err
+					}
+
+					fields := []Rythmkey{}
+					if cCtx.Bool("tab-separated") {
+						rk := Rythmkey{}
+						if err := rk.ReadWith(ro); err != nil {
+							return err
+						}
+						fields = rk.Split('\t')
+						rk.Zero()
+					} else {
+						for i := 0; i < n; i++ {
+							rk, err := readPrompted(fmt.Sprintf("field %d/%d: ", i+1, n), ro)
+							if err != nil {
+								return err
+							}
+							fields = append(fields, rk)
+						}
+					}
+					for _, field := range fields {
+						defer field.Zero()
+						warnCapture(cCtx, field)
+					}
+
+					if len(fields) != n {
+						return fmt.Errorf("typed %d fields, expected %d", len(fields), n)
+					}
+
+					if cCtx.String("hash") != "" {
+						start := now()
+						ok, err := VerifyFields(fields, hp.Options, hp.Digest)
+						waitVerdict(start, defaultRejectDelay)
+						if err != nil {
+							return err
+						}
+						if !ok {
+							return cli.Exit("reject", exitReject)
+						}
+
+						fmt.Println("accept")
+						return nil
+					}
+
+					digest, err := HashFields(fields, hp.Options)
+					if err != nil {
+						return err
+					}
+
+					if !cCtx.Bool("bare") {
+						params := NewHashParams(hp.Options, digest)
+						params.Fields = n
+						digest = params.String()
+					}
+					printOutput(digest)
+					return nil
+				},
+			}, {
+				Name: "compare",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "rythmkey",
+						Value: "",
+						Usage: "ryhtmkey to compare against",
+					}, &cli.StringFlag{
+						Name:  "tolerance",
+						Value: defaultTolerance,
+						Usage: "maximum timing difference of a character: a duration, a percentage of the reference timing, or max/min of them like max(50ms,10%); curve method: of the mean interval",
+					}, &cli.StringFlag{
+						Name:  "method",
+						Value: "tolerance",
+						Usage: "timing matching method " + choices(compareMethods),
+					}, &cli.Float64Flag{
+						Name:  "min-correlation",
+						Value: defaultMinCorrelation,
+						Usage: "rank method: minimum rank correlation of the intervals to match",
+					}, &cli.Float64Flag{
+						Name:  "timing-penalty",
+						Value: defaultScoringConfig.TimingMissPenalty,
+						Usage: "tolerance method: score penalty of a character typed out of tolerance, in characters",
+					}, &cli.Float64Flag{
+						Name:  "char-penalty",
+						Value: defaultScoringConfig.CharMissPenalty,
+						Usage: "tolerance method: score penalty of a character error, in characters",
+					}, &cli.StringFlag{
+						Name:  "layout",
+						Value: "",
+						Usage: "tolerance method: keyboard layout " + choices(keyboardLayouts) + " allowing far reaches between keys more tolerance, must be the same between enrolling and verifying",
+					}, &cli.IntFlag{
+						Name:  "salt",
+						Value: defaultSalt,
+						Usage: "exact method: timing salt both keys are quantized with, as for hash",
+					}, &cli.StringFlag{
+						Name:  "norm",
+						Value: "",
+						Usage: "tolerance method: match on this norm of the timing differences " + choices(norms) + ", at most one tolerance, instead of every character within tolerance",
+					}, &cli.DurationFlag{
+						Name:  "reach-per-key",
+						Value: defaultReachPerKey,
+						Usage: "layout: tolerance added for every key width from the previous character",
+					}, transpositionsFlag(), toleranceSlopeFlag(), &cli.DurationFlag{
+						Name:  "align-offset",
+						Value: 0,
+						Usage: "tolerance method: shift every typed interval by the offset within this bound either way matching the reference best, 0 to disable",
+					}, &cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "output format " + choices(reportFormats),
+					}, prefixFlag(), assumeUnitFlag(), clipboardFlag(),
+				}, append(sessionLogFlags(), captureFlags()...)...),
+				Aliases: []string{"cmp"},
+				Usage:   "read a rythmkey from your terminal emulator and compare it",
+				Before:  rythmkeyFromClipboard,
+				Action: func(cCtx *cli.Context) error {
+					if len(cCtx.String("rythmkey")) == 0 {
+						return errors.New("empty rythmkey")
+					}
+
+					parsed, err := parseAssuming(cCtx, "rythmkey")
+					if err != nil {
+						return err
+					}
+					rk := parsed.Rythmkey
+					defer rk.Zero()
+
+					tolerance, err := ParseTolerance(cCtx.String("tolerance"))
+					if err != nil {
+						return err
+					}
+
+					method := cCtx.String("method")
+					if !slices.Contains(compareMethods, method) {
+						return fmt.Errorf("unknown method %q", method)
+					}
+					if cCtx.String("norm") != "" && method != "tolerance" {
+						return errors.New("--norm only applies to the tolerance method")
+					}
+
+					scoring := ScoringConfig{
+						TimingMissPenalty: cCtx.Float64("timing-penalty"),
+						CharMissPenalty:   cCtx.Float64("char-penalty"),
+					}
+					if scoring.TimingMissPenalty < 0 || scoring.CharMissPenalty < 0 {
+						return errors.New("penalties can't be negative")
+					}
+
+					ro, err := readOptions(cCtx)
+					if err != nil {
+						return err
+					}
+
+					if err := checkUnits(cCtx, "rythmkey", parsed, ro.resolution()); err != nil {
+						return err
+					}
+
+					rrk := Rythmkey{}
+					err = rrk.ReadWith(ro)
+					if err != nil {
+						return err
+					}
+					defer rrk.Zero()
+
+					rk = rk.Prefix(cCtx.Int("prefix"))
+					rrk = rrk.Prefix(cCtx.Int("prefix"))
+					captured := rrk
+
+					if n := cCtx.Int("allow-transpositions"); n > 0 {
+						aligned, swaps := rrk.AlignTranspositions(rk.Chars(), n)
+						defer aligned.Zero()
+						verbose.Printf("undid %d transpositions", swaps)
+						rrk = aligned
+					}
+
+					compare := func(rrk Rythmkey) CompareReport {
+						return CompareSloped(rk, rrk, tolerance, cCtx.Duration("tolerance-slope"))
+					}
+					if name := cCtx.String("layout"); name != "" {
+						layout, err := LayoutByName(name)
+						if err != nil {
+							return err
+						}
+						compare = func(rrk Rythmkey) CompareReport {
+							return CompareLayout(rk, rrk, tolerance, layout, cCtx.Duration("reach-per-key"), cCtx.Duration("tolerance-slope"))
+						}
+					}
+
+					offset := time.Duration(0)
+					if bound := cCtx.Duration("align-offset"); bound > 0 {
+						if method != "tolerance" {
+							return errors.New("--align-offset only applies to the tolerance method")
+						}
+
+						offset = BestOffset(rk, rrk, bound, compare)
+						shifted := rrk.Shift(offset)
+						defer shifted.Zero()
+						rrk = shifted
+					}
+
+					var report CompareReport
+					switch method {
+					case "rank":
+						report, err = CompareRank(rk, rrk, cCtx.Float64("min-correlation"))
+					case "curve":
+						report, err = CompareCurve(rk, rrk, tolerance)
+					case "exact":
+						report, err = CompareExact(rk, rrk, HashOptions{Salt: cCtx.Int("salt")})
+					default:
+						report = compare(rrk)
+						report.Offset = milliseconds(offset)
+						if norm := cCtx.String("norm"); norm != "" {
+							report, err = report.WithNorm(norm)
+						}
+						report.Score = report.ScoreWithPenalties(scoring)
+					}
+					if err != nil {
+						return err
+					}
+
+					logSession(cCtx, captured, &report.Score)
+
+					switch cCtx.String("format") {
+					case "text":
+						fmt.Printf("compare: %+v | %+v\n", rk, rrk)
+						fmt.Printf("char distance: %d\n", report.CharDistance)
+						fmt.Println(report)
+					case "json":
+						return json.NewEncoder(os.Stdout).Encode(struct {
+							CompareReport
+							Result MatchResult `json:"result"`
+						}{report, report.Result()})
+					default:
+						return fmt.Errorf("unknown format %q", cCtx.String("format"))
+					}
+
+					return nil
+				},
+			}, {
+				Name: "enroll",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "profile",
+						Value:    "",
+						Usage:    "file to save the enrolled profile to",
+						Required: true,
+					}, &cli.IntFlag{
+						Name:  "samples",
+						Value: 3,
+						Usage: "number of samples to type",
+					}, &cli.BoolFlag{
+						Name:  "raw",
+						Value: false,
+						Usage: "save the samples themselves, the profile being computed from them whenever it's loaded",
+					}, &cli.IntFlag{
+						Name:  "salt",
+						Value: 0,
+						Usage: "timing salt keys of this rythmkey are hashed with, recorded in the profile, 0 if unknown",
+					}, matchFlag(),
+				}, append(captureWarningFlags(), append(sessionLogFlags(), captureFlags()...)...)...),
+				Aliases: []string{"e"},
+				Usage:   "type a rythmkey several times and save it as a profile",
+				Action: func(cCtx *cli.Context) error {
+					n := cCtx.Int("samples")
+					if n < 1 {
+						return errors.New("at least one sample is required")
+					}
+
+					ro, err := readOptions(cCtx)
+					if err != nil {
+						return err
+					}
+
+					match := cCtx.String("match")
+					if !slices.Contains(matchModes, match) {
+						return fmt.Errorf("unknown match mode %q", match)
+					}
+					if match != MatchFlight && cCtx.Bool("raw") {
+						return errors.New("--raw samples don't keep dwell times, it only applies to --match flight")
+					}
+
+					samples := []Rythmkey{}
+					dwells := [][]time.Duration{}
+					for i := 0; i < n; i++ {
+						dwell := []time.Duration{}
+						if match != MatchFlight {
+							ro.Dwell = &dwell
+						}
+
+						rk, err := readPrompted(fmt.Sprintf("sample %d/%d: ", i+1, n), ro)
+						if err != nil {
+							return err
+						}
+						defer rk.Zero()
+						defer clear(dwell)
+						warnCapture(cCtx, rk)
+						logSession(cCtx, rk, nil)
+
+						samples = append(samples, rk)
+						dwells = append(dwells, dwell)
+					}
+
+					p, err := NewProfile(samples, ro.Resolution)
+					if err != nil {
+						return err
+					}
+
+					if match != MatchFlight {
+						if err := p.AddDwell(dwells); err != nil {
+							return err
+						}
+						p.MatchOn = match
+					}
+
+					if cCtx.Bool("raw") {
+						if cCtx.IsSet("salt") {
+							return errors.New("--raw saves the samples only, it excludes --salt")
+						}
+						return SaveRawSamples(cCtx.String("profile"), samples)
+					}
+
+					if cCtx.Int("salt") < 0 {
+						return errors.New("salt can't be negative")
+					}
+					p.Salt = cCtx.Int("salt")
+					if ro.Smooth > 1 {
+						p.Smooth = ro.Smooth
+					}
+
+					return p.Save(cCtx.String("profile"))
+				},
+			}, {
+				Name: "setup",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "profile",
+						Value:    "",
+						Usage:    "file to save the enrolled profile to",
+						Required: true,
+					}, &cli.IntFlag{
+						Name:  "samples",
+						Value: 5,
+						Usage: "number of samples to type",
+					},
+				}, captureFlags()...),
+				Usage: "enroll step by step and get the parameters recommended to hash and verify with",
+				Action: func(cCtx *cli.Context) error {
+					n := cCtx.Int("samples")
+					if n < 2 {
+						return errors.New("at least two samples are required to recommend parameters")
+					}
+
+					ro, err := readOptions(cCtx)
+					if err != nil {
+						return err
+					}
+
+					return Setup(cCtx.String("profile"), n, ro, os.Stdout)
+				},
+			}, {
+				Name:  "profile",
+				Usage: "inspect enrolled profiles",
+				Subcommands: []*cli.Command{
+					{
+						Name: "show",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "profile",
+								Value:    "",
+								Usage:    "profile file to show",
+								Required: true,
+							}, &cli.StringFlag{
+								Name:  "format",
+								Value: "text",
+								Usage: "output format " + choices(reportFormats),
+							},
+						},
+						Usage: "print the characters, timing statistics and parameters of a profile",
+						Action: func(cCtx *cli.Context) error {
+							p, err := LoadProfile(cCtx.String("profile"))
+							if err != nil {
+								return err
+							}
+
+							switch cCtx.String("format") {
+							case "text":
+								return p.WriteText(os.Stdout)
+							case "json":
+								return json.NewEncoder(os.Stdout).Encode(p)
+							default:
+								return fmt.Errorf("unknown format %q", cCtx.String("format"))
+							}
+						},
+					}, {
+						Name: "export",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "profile",
+								Value:    "",
+								Usage:    "profile file to export",
+								Required: true,
+							}, &cli.StringFlag{
+								Name:     "password",
+								Value:    "",
+								Usage:    "password the bundle is encrypted with",
+								Required: true,
+							}, &cli.StringFlag{
+								Name:     "out",
+								Value:    "",
+								Usage:    "file to write the bundle to",
+								Required: true,
+							},
+						},
+						Usage: "encrypt a profile into a portable bundle",
+						Action: func(cCtx *cli.Context) error {
+							p, err := LoadProfile(cCtx.String("profile"))
+							if err != nil {
+								return err
+							}
+
+							bundle, err := p.Export(cCtx.String("password"))
+							if err != nil {
+								return err
+							}
+
+							return os.WriteFile(cCtx.String("out"), bundle, 0600)
+						},
+					}, {
+						Name: "import",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "bundle",
+								Value:    "",
+								Usage:    "bundle file to import",
+								Required: true,
+							}, &cli.StringFlag{
+								Name:     "password",
+								Value:    "",
+								Usage:    "password the bundle was encrypted with",
+								Required: true,
+							}, &cli.StringFlag{
+								Name:     "profile",
+								Value:    "",
+								Usage:    "file to save the decrypted profile to",
+								Required: true,
+							},
+						},
+						Usage: "decrypt a bundle made by profile export",
+						Action: func(cCtx *cli.Context) error {
+							bundle, err := os.ReadFile(cCtx.String("bundle"))
+							if err != nil {
+								return err
+							}
+
+							p, err := ImportProfile(bundle, cCtx.String("password"))
+							if err != nil {
+								return err
+							}
+
+							return p.Save(cCtx.String("profile"))
+						},
+					}, {
+						Name: "diff",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "old",
+								Value:    "",
+								Usage:    "profile enrolled first",
+								Required: true,
+							}, &cli.StringFlag{
+								Name:     "new",
+								Value:    "",
+								Usage:    "profile enrolled again with the same characters",
+								Required: true,
+							}, &cli.StringFlag{
+								Name:  "format",
+								Value: "text",
+								Usage: "output format " + choices(reportFormats),
+							},
+						},
+						Usage: "show how the timing mean and deviation of every character changed between two profiles",
+						Action: func(cCtx *cli.Context) error {
+							old, err := LoadProfile(cCtx.String("old"))
+							if err != nil {
+								return err
+							}
+
+							updated, err := LoadProfile(cCtx.String("new"))
+							if err != nil {
+								return err
+							}
+
+							deltas, err := DiffProfiles(old, updated)
+							if err != nil {
+								return err
+							}
+
+							switch cCtx.String("format") {
+							case "text":
+								return WriteProfileDiff(os.Stdout, deltas)
+							case "json":
+								return json.NewEncoder(os.Stdout).Encode(deltas)
+							default:
+								return fmt.Errorf("unknown format %q", cCtx.String("format"))
+							}
+						},
+					},
+				},
+			}, {
+				Name: "verify",
+				Flags: append(append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "hash",
+						Value: "",
+						Usage: "hashed rythmkey to verify against",
+					}, &cli.StringFlag{
+						Name:  "profiles-dir",
+						Value: "",
+						Usage: "directory of enrolled profiles to find the best match in",
+					}, &cli.Float64Flag{
+						Name:  "threshold",
+						Value: defaultThreshold,
+						Usage: "minimum profile score to accept",
+					}, &cli.BoolFlag{
+						Name:  "reject-synthetic",
+						Value: false,
+						Usage: "reject keys whose timings look generated rather than typed",
+					}, &cli.Float64Flag{
+						Name:  "min-variance",
+						Value: 0,
+						Usage: "reject keys whose intervals vary less than this many square milliseconds, too flat to be typed, 0 to disable",
+					}, &cli.BoolFlag{
+						Name:  "early-reject",
+						Value: false,
+						Usage: "tolerance method against profiles: reject as soon as the key typed so far can't match, which tells how much of it was right",
+					}, &cli.DurationFlag{
+						Name:  "reject-delay",
+						Value: defaultRejectDelay,
+						Usage: "minimum time before any verdict is returned",
+					}, &cli.StringFlag{
+						Name:  "audit-log",
+						Value: "",
+						Usage: "append a JSON line of metadata, never the key, about each verification to this file",
+					}, &cli.StringFlag{
+						Name:  "method",
+						Value: "tolerance",
+						Usage: "matching method, a registered matcher " + choices(Matchers()),
+					}, &cli.StringFlag{
+						Name:  "weights",
+						Value: defaultEnsembleWeights,
+						Usage: "ensemble method: comma separated method=weight pairs",
+					}, &cli.StringFlag{
+						Name:  "norm",
+						Value: "",
+						Usage: "tolerance method: score on this norm of the timing differences " + choices(norms) + ", 1 within one tolerance and its inverse beyond so the threshold t accepts up to 1/t tolerances",
+					}, &cli.Float64Flag{
+						Name:  "band-k",
+						Value: 0,
+						Usage: "bands method: accept timings within this many standard deviations of the mean, 0 for the enrolled range",
+					}, &cli.StringFlag{
+						Name:  "samples-dir",
+						Value: "",
+						Usage: "directory of raw enrollment samples, *.rk encoded keys, to vote with",
+					}, &cli.IntFlag{
+						Name:  "vote",
+						Value: 0,
+						Usage: "samples method: number of samples that must match, 0 for a majority",
+					}, &cli.StringFlag{
+						Name:  "tolerance",
+						Value: defaultTolerance,
+						Usage: "samples method: maximum timing difference of a character, as for compare",
+					}, &cli.DurationFlag{
+						Name:  "pair",
+						Value: 0,
+						Usage: "verify a key captured on another device: print a single use pairing token valid this long and read the capture submitted for it from stdin",
+					}, transpositionsFlag(), toleranceSlopeFlag(), matchFlag(), &cli.BoolFlag{
+						Name:  "dry-run",
+						Value: false,
+						Usage: "give the verdict but write nothing, listing the writes skipped on stderr",
+					},
+				}, append(hashFlags(), sessionLogFlags()...)...), captureFlags()...),
+				Aliases: []string{"v"},
+				Usage:   "read a rythmkey from your terminal emulator and verify it against a hash",
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.Bool("dry-run") {
+						if path := skipLogFile(); path != "" {
+							fmt.Fprintf(os.Stderr, "dry run: skipped appending the verbose logs to %s, they go to stderr\n", path)
+						}
+					}
+
+					hash := cCtx.String("hash")
+					profilesDir := cCtx.String("profiles-dir")
+					samplesDir := cCtx.String("samples-dir")
+
+					references := 0
+					for _, reference := range []string{hash, profilesDir, samplesDir} {
+						if reference != "" {
+							references++
+						}
+					}
+					if references != 1 {
+						return errors.New("exactly one of --hash, --profiles-dir or --samples-dir is required")
+					}
+
+					samples := []Rythmkey{}
+					if samplesDir != "" {
+						var err error
+						samples, err = LoadSamples(samplesDir)
+						if err != nil {
+							return err
+						}
+
+						for _, sample := range samples {
+							defer sample.Zero()
+						}
+
+						if len(samples) == 0 {
+							return fmt.Errorf("no sample found in %s", samplesDir)
+						}
+					}
+
+					tolerance, err := ParseTolerance(cCtx.String("tolerance"))
+					if err != nil {
+						return err
+					}
+
+					profiles := []NamedProfile{}
+					if profilesDir != "" {
+						var err error
+						profiles, err = LoadProfiles(profilesDir)
+						if err != nil {
+							return err
+						}
+
+						if len(profiles) == 0 {
+							return fmt.Errorf("no profile found in %s", profilesDir)
+						}
+					}
+
+					method := cCtx.String("method")
+					if hash != "" && cCtx.IsSet("method") {
+						return errors.New("--method doesn't apply to --hash, a hash only matches exactly")
+					}
+
+					matcher, known := LookupMatcher(method)
+					if !known {
+						return fmt.Errorf("unknown method %q", method)
+					}
+
+					if norm := cCtx.String("norm"); norm != "" {
+						if !slices.Contains(norms, norm) {
+							return fmt.Errorf("unknown norm %q", norm)
+						}
+						if method != "tolerance" || samplesDir != "" {
+							return errors.New("--norm only applies to the tolerance method against profiles")
+						}
+					}
+
+					if cCtx.Float64("band-k") < 0 {
+						return errors.New("band width can't be negative")
+					}
+
+					if cCtx.Duration("tolerance-slope") != 0 && (samplesDir == "" || method != "tolerance") {
+						return errors.New("--tolerance-slope only applies to the tolerance method against samples, profiles have a tolerance of their own")
+					}
+
+					if cCtx.Int("allow-transpositions") > 0 && (profilesDir == "" || cCtx.Bool("early-reject")) {
+						return errors.New("--allow-transpositions only applies against profiles, without --early-reject")
+					}
+
+					match := cCtx.String("match")
+					if !slices.Contains(matchModes, match) {
+						return fmt.Errorf("unknown match mode %q", match)
+					}
+					if match != MatchFlight && (profilesDir == "" || method != "tolerance" || cCtx.String("norm") != "" || cCtx.Bool("early-reject") || cCtx.Int("allow-transpositions") > 0) {
+						return errors.New("--match dwell and both only apply to the tolerance method against profiles, without --norm, --early-reject or --allow-transpositions")
+					}
+					if profilesDir != "" {
+						for _, np := range profiles {
+							if err := np.Profile.checkMatchMode(match); err != nil {
+								return fmt.Errorf("%s: %w", np.Name, err)
+							}
+						}
+					}
+
+					if method == "bands" {
+						for _, np := range profiles {
+							if _, err := np.Profile.Bands(cCtx.Float64("band-k")); err != nil {
+								return fmt.Errorf("%s: %w", np.Name, err)
+							}
+						}
+					}
+
+					weights, err := ParseEnsembleWeights(cCtx.String("weights"))
+					if err != nil {
+						return err
+					}
+
+					hp, err := resolveHash(cCtx, hash)
+					if err != nil {
+						return err
+					}
+					if hp.Fields > 1 {
+						return fmt.Errorf("hash combines %d fields, verify it with hash-multi --hash", hp.Fields)
+					}
+					opts, hash := hp.Options, hp.Digest
+
+					// The built-in matchers take their parameters from the
+					// flags, registered ones are used as they are.
+					switch method {
+					case "tolerance":
+						matcher = ToleranceMatcher{
+							Tolerance: tolerance,
+							Slope:     cCtx.Duration("tolerance-slope"),
+							Threshold: cCtx.Float64("threshold"),
+							Norm:      cCtx.String("norm"),
+						}
+					case "dtw":
+						matcher = DTWMatcher{Tolerance: tolerance}
+					case "exact":
+						matcher = ExactMatcher{Options: opts}
+					case "ensemble":
+						matcher = EnsembleMatcher{Weights: weights, Threshold: cCtx.Float64("threshold")}
+					case "bands":
+						matcher = BandsMatcher{K: cCtx.Float64("band-k")}
+					}
+
+					ro, err := readOptions(cCtx)
+					if err != nil {
+						return err
+					}
+
+					if ttl := cCtx.Duration("pair"); ttl != 0 {
+						if ttl < 0 {
+							return errors.New("pairing token lifetime can't be negative")
+						}
+						if ro.InputFIFO != "" || ro.Source == "evdev" {
+							return errors.New("--pair can't be combined with --input-fifo or --source evdev")
+						}
+
+						ro.Pairing, err = NewPairing(ttl)
+						if err != nil {
+							return err
+						}
+					}
+
+					var audit AuditFunc
+					if path := cCtx.String("audit-log"); path != "" && cCtx.Bool("dry-run") {
+						audit = func(AuditEvent) {
+							fmt.Fprintf(os.Stderr, "dry run: skipped appending an audit event to %s\n", path)
+						}
+					} else if path != "" {
+						f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+						if err != nil {
+							return err
+						}
+						defer f.Close()
+
+						audit = JSONLinesAudit(f)
+					}
+
+					auditMethod := "hash"
+					if profilesDir != "" {
+						auditMethod = "profile"
+					} else if samplesDir != "" {
+						auditMethod = "samples"
+					}
+
+					if cCtx.Bool("early-reject") {
+						if profilesDir == "" || method != "tolerance" || cCtx.String("norm") != "" {
+							return errors.New("--early-reject only applies to the tolerance method against profiles, without --norm")
+						}
+						if ro.Smooth > 1 || len(ro.Positions) > 0 || len(ro.CharMap) > 0 || ro.Source == "evdev" {
+							return errors.New("--early-reject can't be combined with --smooth, --positions, a char map or --source evdev")
+						}
+
+						check := EarlyReject(profiles, cCtx.Float64("threshold"))
+						ro.Check = func(rk Rythmkey) error {
+							err := check(rk)
+							if err != nil {
+								fmt.Fprintf(os.Stderr, "\nreject: %v\n", err)
+							}
+							return err
+						}
+					}
+
+					dwell := []time.Duration{}
+					if match != MatchFlight {
+						ro.Dwell = &dwell
+					}
+
+					rk := Rythmkey{}
+					err = rk.ReadWith(ro)
+					if errors.Is(err, errPrefixMismatch) || errors.Is(err, errToleranceBudget) {
+						// Already reported, without waiting for a verdict delay.
+						audit.record(AuditEvent{Method: auditMethod, Reason: err.Error()})
+						if errors.Is(err, errPrefixMismatch) {
+							return cli.Exit("", exitWrongSequence)
+						}
+						return cli.Exit("", exitReject)
+					}
+					if err != nil {
+						return err
+					}
+					defer rk.Zero()
+					defer clear(dwell)
+
+					start := now()
+					if cCtx.Bool("reject-synthetic") {
+						if human, reason := rk.LooksHuman(); !human {
+							audit.record(AuditEvent{Method: auditMethod, Length: rk.Len(), Reason: reason})
+							logSession(cCtx, rk, nil)
+							waitVerdict(start, cCtx.Duration("reject-delay"))
+							return cli.Exit("reject: "+reason, exitReject)
+						}
+					}
+
+					if minVariance := cCtx.Float64("min-variance"); minVariance > 0 {
+						if err := rk.CheckVariance(minVariance); err != nil {
+							audit.record(AuditEvent{Method: auditMethod, Length: rk.Len(), Reason: err.Error()})
+							logSession(cCtx, rk, nil)
+							waitVerdict(start, cCtx.Duration("reject-delay"))
+							return cli.Exit("reject: "+err.Error(), exitReject)
+						}
+					}
+
+					if samplesDir != "" {
+						votes, ok, err := Vote(samples, rk, matcher, cCtx.Int("vote"))
+						if err != nil {
+							waitVerdict(start, cCtx.Duration("reject-delay"))
+							return err
+						}
+						score := float64(votes) / float64(len(samples))
+						audit.record(AuditEvent{Method: auditMethod, Accepted: ok, Score: score, Length: rk.Len()})
+						logSession(cCtx, rk, &score)
+						waitVerdict(start, cCtx.Duration("reject-delay"))
+
+						verbose.Printf("matched %d of %d samples", votes, len(samples))
+						if !ok {
+							return cli.Exit("reject", exitReject)
+						}
+
+						fmt.Printf("accept (%d/%d samples)\n", votes, len(samples))
+						return nil
+					}
+
+					if profilesDir != "" {
+						np, score, ok := matchProfiles(profiles, func(p Profile) (bool, float64, error) {
+							if match != MatchFlight {
+								score, err := p.MatchScore(rk, dwell, match)
+								return score >= cCtx.Float64("threshold"), score, err
+							}
+							if n := cCtx.Int("allow-transpositions"); n > 0 {
+								aligned, _ := rk.AlignTranspositions(p.Chars, n)
+								defer aligned.Zero()
+								return MatchProfile(matcher, p, aligned)
+							}
+							return MatchProfile(matcher, p, rk)
+						})
+						// Diagnosed whatever the verdict, before waiting for
+						// it, so the time it takes doesn't tell which it is.
+						mismatch := sequenceMismatch(profiles, rk)
+						audit.record(AuditEvent{Method: auditMethod, Accepted: ok, Score: score, Length: rk.Len()})
+						logSession(cCtx, rk, &score)
+						waitVerdict(start, cCtx.Duration("reject-delay"))
+
+						if cCtx.Bool("verbose") {
+							for _, candidate := range profiles {
+								results, err := candidate.Profile.Breakdown(rk)
+								if err != nil {
+									continue
+								}
+
+								verbose.Printf("breakdown against %s:", candidate.Name)
+								WriteBreakdown(verbose.Writer(), results)
+
+								if method == "ensemble" {
+									score, components, _ := candidate.Profile.EnsembleScore(rk, weights)
+									verbose.Printf("ensemble score against %s: %.2f, components: %v", candidate.Name, score, components)
+								}
+
+								if method == "bands" {
+									bands, _ := candidate.Profile.Bands(cCtx.Float64("band-k"))
+									if within, i := rk.WithinBands(bands); !within {
+										verbose.Printf("character %d out of its band against %s", i, candidate.Name)
+									}
+								}
+							}
+						}
+						if !ok {
+							if mismatch != nil {
+								return cli.Exit("no match: "+mismatch.Error(), exitWrongSequence)
+							}
+							return cli.Exit("no match", exitReject)
+						}
+
+						fmt.Printf("%s (score: %.2f)\n", np.Name, score)
+						return nil
+					}
 
-			ct.Char = rks[i+j]
-			rk = append(rk, ct)
-			ct = nil
+					ok, err := rk.VerifyHash(opts, hash)
 
-			i += j
-		}
-	}
+					// Hashed whatever the verdict, before waiting for it, so
+					// the time it takes doesn't tell which it is.
+					inverted := rk.InvertCase()
+					defer inverted.Zero()
+					capsLock, _ := inverted.VerifyHash(opts, hash)
 
-	return rk, nil
-}
+					if err == nil {
+						audit.record(AuditEvent{Method: auditMethod, Accepted: ok, Length: rk.Len()})
+					}
+					logSession(cCtx, rk, nil)
+					waitVerdict(start, cCtx.Duration("reject-delay"))
+					if err != nil {
+						return err
+					}
 
-// 0     x    y          z
-// t<c><timing><t><c><timing>
-type Rythmkey []*CharTiming
+					if !ok {
+						if capsLock {
+							return cli.Exit("reject: "+errCapsLock.Error(), exitWrongSequence)
+						}
+						return cli.Exit("reject", exitReject)
+					}
 
-func (rk *Rythmkey) Read() error {
-	exec.Command("stty", "-f", "/dev/tty", "cbreak", "min", "1").Run()
-	exec.Command("stty", "-f", "/dev/tty", "-echo").Run()
-	defer exec.Command("stty", "-f", "/dev/tty", "echo").Run()
+					fmt.Println("accept")
+					return nil
+				},
+			}, {
+				Name: "evaluate",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "profile",
+						Value:    "",
+						Usage:    "reference profile",
+						Required: true,
+					}, &cli.StringFlag{
+						Name:     "dataset",
+						Value:    "",
+						Usage:    "file of genuine/impostor labeled rythmkeys",
+						Required: true,
+					}, &cli.Float64Flag{
+						Name:  "threshold",
+						Value: defaultThreshold,
+						Usage: "profile score threshold to evaluate",
+					}, &cli.Float64Flag{
+						Name:  "sweep",
+						Value: 0,
+						Usage: "evaluate every threshold from 0 to 1 by this step instead",
+					}, &cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "output format " + choices(reportFormats),
+					},
+				},
+				Usage: "measure false accept and false reject rates of a profile against a dataset",
+				Action: func(cCtx *cli.Context) error {
+					p, err := LoadProfile(cCtx.String("profile"))
+					if err != nil {
+						return err
+					}
 
-	buf := make([]byte, 1)
+					samples, err := LoadDataset(cCtx.String("dataset"))
+					if err != nil {
+						return err
+					}
 
-	took := time.Duration(0)
-	for {
-		now := time.Now()
+					scores := p.Scores(samples)
 
-		c, err := os.Stdin.Read(buf)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
-		}
+					rates := []Rates{ComputeRates(samples, scores, cCtx.Float64("threshold"))}
+					if step := cCtx.Float64("sweep"); step > 0 {
+						rates = SweepRates(samples, scores, step)
+					}
 
-		if buf[0] == '\n' {
-			break
-		}
+					switch cCtx.String("format") {
+					case "text":
+						w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+						fmt.Fprintln(w, "threshold\tFAR\tFRR")
+						for _, r := range rates {
+							fmt.Fprintf(w, "%.2f\t%.4f\t%.4f\n", r.Threshold, r.FAR, r.FRR)
+						}
+						return w.Flush()
+					case "json":
+						return json.NewEncoder(os.Stdout).Encode(rates)
+					default:
+						return fmt.Errorf("unknown format %q", cCtx.String("format"))
+					}
+				},
+			}, {
+				Name: "qr",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "rythmkey",
+						Value: "",
+						Usage: "ryhtmkey to render",
+					}, &cli.StringFlag{
+						Name:  "out",
+						Value: "",
+						Usage: "write a PNG image to this file instead of the terminal",
+					}, &cli.IntFlag{
+						Name:  "size",
+						Value: 256,
+						Usage: "PNG image size in pixels",
+					}, clipboardFlag(),
+				},
+				Usage:  "render a rythmkey as a QR code of its base64 form",
+				Before: rythmkeyFromClipboard,
+				Action: func(cCtx *cli.Context) error {
+					rk, err := ParseRythmkey(cCtx.String("rythmkey"))
+					if err != nil {
+						return err
+					}
 
-		if len(*rk) == 0 {
-			took = 0
-		} else {
-			took = time.Since(now)
-		}
+					qr, err := rk.QRCode()
+					if err != nil {
+						return err
+					}
 
-		log.Printf("get char [%c] %+v in %+v (micro: %d, milli:%s, dec:%d, hex:%X)", buf[0], c, took.Microseconds(), took.Milliseconds(), took, took, took)
-		*rk = append(*rk, &CharTiming{
-			Timing: time.Duration(took.Microseconds() / 1000),
-			Char:   buf[0],
-		})
-	}
+					if out := cCtx.String("out"); out != "" {
+						return qr.WriteFile(cCtx.Int("size"), out)
+					}
 
-	return nil
-}
+					fmt.Print(qr.ToSmallString(false))
+					return nil
+				},
+			}, {
+				Name: "tune",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "profile",
+						Value:    "",
+						Usage:    "reference profile",
+						Required: true,
+					}, &cli.StringFlag{
+						Name:  "dataset",
+						Value: "",
+						Usage: "file of genuine/impostor labeled rythmkeys to tune against",
+					}, &cli.BoolFlag{
+						Name:  "interactive-threshold-tuning",
+						Value: false,
+						Usage: "type samples, label each of them and tune on the answers until ctrl-d",
+					}, &cli.IntFlag{
+						Name:  "live-window",
+						Value: 0,
+						Usage: "show while typing the mean of the last live-window intervals, 0 to disable",
+					},
+				}, captureFlags()...),
+				Usage: "recommend the profile score threshold misclassifying the fewest samples",
+				Action: func(cCtx *cli.Context) error {
+					p, err := LoadProfile(cCtx.String("profile"))
+					if err != nil {
+						return err
+					}
 
-func (rythmkey Rythmkey) Encode() string {
-	encoded := ""
+					if cCtx.Bool("interactive-threshold-tuning") {
+						ro, err := readOptions(cCtx)
+						if err != nil {
+							return err
+						}
+						ro.LiveWindow = cCtx.Int("live-window")
 
-	for _, ct := range rythmkey {
-		encoded += "t" + strconv.FormatInt(int64(ct.Timing), 10) + string(ct.Char)
-	}
+						threshold, err := TuneInteractively(p, ro)
+						if err != nil {
+							return err
+						}
 
-	return encoded
-}
+						fmt.Printf("%.2f\n", threshold)
+						return nil
+					}
 
-func (rythmkey Rythmkey) Hash(salt int) (string, error) {
-	rk := Rythmkey{}
-	for _, ct := range rythmkey {
-		saltedTiming := time.Duration((((int(ct.Timing) + salt) / salt) * salt))
-		rk = append(rk, &CharTiming{Char: ct.Char, Timing: saltedTiming})
-	}
+					if cCtx.String("dataset") == "" {
+						return errors.New("--dataset or --interactive-threshold-tuning is required")
+					}
 
-	h := sha256.New()
+					samples, err := LoadDataset(cCtx.String("dataset"))
+					if err != nil {
+						return err
+					}
 
-	srk := rk.Encode()
-	log.Printf("rk: %s, salted rk: %s", rythmkey.Encode(), srk)
-	_, err := h.Write([]byte(srk))
-	if err != nil {
-		return "", err
-	}
+					fmt.Printf("%.2f\n", OptimalThreshold(samples, p.Scores(samples)))
+					return nil
+				},
+			}, {
+				Name: "watch",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "profile",
+						Value:    "",
+						Usage:    "reference profile",
+						Required: true,
+					}, &cli.DurationFlag{
+						Name:  "interval",
+						Value: defaultWatchInterval,
+						Usage: "time to wait between two samples",
+					}, &cli.Float64Flag{
+						Name:  "threshold",
+						Value: defaultThreshold,
+						Usage: "warn about samples scoring below this threshold",
+					}, &cli.IntFlag{
+						Name:  "live-window",
+						Value: 0,
+						Usage: "show while typing the mean of the last live-window intervals, 0 to disable",
+					},
+				}, captureFlags()...),
+				Usage: "type a rythmkey at intervals and report how its rhythm drifts from a profile until ctrl-d",
+				Action: func(cCtx *cli.Context) error {
+					p, err := LoadProfile(cCtx.String("profile"))
+					if err != nil {
+						return err
+					}
 
-	return hex.EncodeToString(h.Sum(nil)), nil
-}
+					ro, err := readOptions(cCtx)
+					if err != nil {
+						return err
+					}
+					ro.LiveWindow = cCtx.Int("live-window")
 
-func (rythmkey Rythmkey) String() string {
-	str := ""
-	for _, pc := range rythmkey {
-		str += fmt.Sprintf("%c(%d)", pc.Char, pc.Timing)
-	}
+					return Watch(p, ro, cCtx.Duration("interval"), cCtx.Float64("threshold"), os.Stdout)
+				},
+			}, {
+				Name: "practice",
+				Flags: append([]cli.Flag{
+					&cli.IntFlag{
+						Name:  "live-window",
+						Value: 0,
+						Usage: "show while typing the mean of the last live-window intervals, 0 to disable",
+					},
+				}, captureFlags()...),
+				Usage: "type a rythmkey repeatedly and score every attempt against the previous one until ctrl-d, storing nothing",
+				Action: func(cCtx *cli.Context) error {
+					ro, err := readOptions(cCtx)
+					if err != nil {
+						return err
+					}
+					ro.LiveWindow = cCtx.Int("live-window")
 
-	return fmt.Sprintf("%s", str)
-}
+					return Practice(ro, os.Stdout)
+				},
+			}, {
+				Name:  "doctor",
+				Usage: "check that the terminal supports capturing a rythmkey",
+				Action: func(cCtx *cli.Context) error {
+					if !WriteDiagnosis(os.Stdout, Diagnose()) {
+						return cli.Exit("", 1)
+					}
 
-func main() {
-	app := &cli.App{
-		Name:  "rythmkey",
-		Usage: "make your password more in rythm",
-		Commands: []*cli.Command{
-			{
-				Name: "read",
+					return nil
+				},
+			}, {
+				Name: "benchmark",
 				Flags: []cli.Flag{
-					&cli.BoolFlag{
-						Name:  "hash",
-						Value: false,
-						Usage: "hash to resulting rythmkey",
+					&cli.StringSliceFlag{
+						Name:  "method",
+						Value: cli.NewStringSlice(benchmarkMethods...),
+						Usage: "matching methods to benchmark " + choices(benchmarkMethods),
 					}, &cli.IntFlag{
-						Name:  "salt",
-						Value: 20,
-						Usage: "timing salt",
+						Name:  "samples",
+						Value: 1000,
+						Usage: "number of synthetic keys to verify",
+					}, &cli.IntFlag{
+						Name:  "length",
+						Value: 16,
+						Usage: "number of characters of the synthetic keys",
 					},
 				},
-				Aliases: []string{"r"},
-				Usage:   "read a rythmkey from your terminal emulator",
+				Usage: "measure verification throughput and latency on synthetic keys",
 				Action: func(cCtx *cli.Context) error {
-					rk := Rythmkey{}
+					results := []BenchmarkResult{}
+					for _, method := range cCtx.StringSlice("method") {
+						result, err := Benchmark(method, cCtx.Int("samples"), cCtx.Int("length"))
+						if err != nil {
+							return err
+						}
+						results = append(results, result)
+					}
 
-					err := rk.Read()
+					return WriteBenchmark(os.Stdout, results)
+				},
+			}, {
+				Name: "edit",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "rythmkey",
+						Value: "",
+						Usage: "rythmkey to edit",
+					}, &cli.DurationFlag{
+						Name:  "step",
+						Value: defaultEditStep,
+						Usage: "timing adjustment of every up or down key press",
+					}, clipboardFlag(),
+				},
+				Usage:  "adjust the timings of a rythmkey with the arrow keys and output it again",
+				Before: rythmkeyFromClipboard,
+				Action: func(cCtx *cli.Context) error {
+					rk, err := ParseRythmkey(cCtx.String("rythmkey"))
 					if err != nil {
 						return err
 					}
+					defer rk.Zero()
 
-					hash := cCtx.Bool("hash")
-					if hash {
-						salt := cCtx.Int("salt")
-						hrk, err := rk.Hash(salt)
-						if err != nil {
-							return err
-						}
-						fmt.Print(hrk)
-						return nil
+					e := NewEditor(rk, cCtx.Duration("step"))
+					err = editInteractively(e)
+					if err != nil {
+						return err
 					}
 
-					fmt.Print(rk.Encode())
+					printOutput(e.Rythmkey.Encode())
 					return nil
 				},
 			}, {
-				Name: "compare",
+				Name: "deltas",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
+						Name:     "reference",
+						Value:    "",
+						Usage:    "reference rythmkey",
+						Required: true,
+					}, &cli.StringFlag{
 						Name:     "rythmkey",
 						Value:    "",
-						Usage:    "ryhtmkey to compare against",
+						Usage:    "rythmkey typed with the same characters",
 						Required: true,
-					},
+					}, &cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "output format " + choices(reportFormats),
+					}, assumeUnitFlag(),
 				},
-				Aliases: []string{"cmp"},
-				Usage:   "read a rythmkey from your terminal emulator and compare it",
+				Usage: "show where a rythmkey was typed faster or slower than a reference",
 				Action: func(cCtx *cli.Context) error {
-					rks := cCtx.String("rythmkey")
-					if len(rks) == 0 {
-						return errors.New("empty rythmkey")
+					parsedReference, err := parseAssuming(cCtx, "reference")
+					if err != nil {
+						return err
 					}
+					reference := parsedReference.Rythmkey
+					defer reference.Zero()
 
-					rk, err := ParseRythmkey(rks)
+					parsed, err := parseAssuming(cCtx, "rythmkey")
 					if err != nil {
 						return err
 					}
+					rk := parsed.Rythmkey
+					defer rk.Zero()
 
-					rrk := Rythmkey{}
-					err = rrk.Read()
+					if err := checkUnits(cCtx, "reference", parsedReference, parsed.Unit); err != nil {
+						return err
+					}
+					if err := checkUnits(cCtx, "rythmkey", parsed, parsedReference.Unit); err != nil {
+						return err
+					}
+
+					deltas, err := Deltas(reference, rk)
+					if err != nil {
+						return err
+					}
+
+					switch cCtx.String("format") {
+					case "text":
+						return WriteDeltaBars(os.Stdout, deltas)
+					case "json":
+						return json.NewEncoder(os.Stdout).Encode(deltas)
+					default:
+						return fmt.Errorf("unknown format %q", cCtx.String("format"))
+					}
+				},
+			}, {
+				Name: "text",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "rythmkey",
+						Value: "",
+						Usage: "encoded rythmkey to recover the characters of",
+					}, &cli.BoolFlag{
+						Name:  "reveal",
+						Value: false,
+						Usage: "confirm printing the characters, which are the secret part of the key",
+					}, clipboardFlag(),
+				},
+				Usage:  "print the characters of a rythmkey without its timings",
+				Before: rythmkeyFromClipboard,
+				Action: func(cCtx *cli.Context) error {
+					if !cCtx.Bool("reveal") {
+						return errors.New("this prints the plaintext of the key, confirm with --reveal")
+					}
+
+					rk, err := ParseRythmkey(cCtx.String("rythmkey"))
 					if err != nil {
 						return err
 					}
+					defer rk.Zero()
 
-					fmt.Printf("compare: %+v | %+v", rk, rrk)
+					fmt.Fprintln(os.Stderr, "warning: printing the plaintext characters of the key")
+					printOutput(rk.Chars())
 					return nil
 				},
 			}, {
-				Name: "parse",
+				Name: "template",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "rythmkey",
+						Name:  "shell",
+						Value: "bash",
+						Usage: "shell to write the script for " + choices(templateShells),
+					}, &cli.IntFlag{
+						Name:  "samples",
+						Value: 3,
+						Usage: "number of samples the script enrolls",
+					},
+				},
+				Usage: "write a shell script enrolling a rythmkey once then verifying it, to wire into login scripts or hooks",
+				Action: func(cCtx *cli.Context) error {
+					if err := checkTemplateFlags(cCtx.App); err != nil {
+						return err
+					}
+
+					return WriteTemplate(os.Stdout, cCtx.String("shell"), cCtx.Int("samples"))
+				},
+			}, {
+				Name: "import",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
 						Value:    "",
-						Usage:    "ryhtmkey to parse",
+						Usage:    "dataset to import, - for stdin",
 						Required: true,
+					}, &cli.StringFlag{
+						Name:  "format",
+						Value: "cmu",
+						Usage: "dataset format " + choices(importFormats),
+					}, &cli.StringFlag{
+						Name:  "genuine",
+						Value: "",
+						Usage: "output a dataset for evaluate and tune, the keys of this subject labeled genuine and the others impostor",
+					},
+				},
+				Usage: "convert a keystroke dynamics research dataset to encoded rythmkeys, one per line",
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.String("format") != "cmu" {
+						return fmt.Errorf("unknown format %q", cCtx.String("format"))
+					}
+
+					r := io.Reader(os.Stdin)
+					if path := cCtx.String("file"); path != "-" {
+						f, err := os.Open(path)
+						if err != nil {
+							return err
+						}
+						defer f.Close()
+						r = f
+					}
+
+					keys, err := ReadCMU(r)
+					if err != nil {
+						return err
+					}
+
+					w := bufio.NewWriter(os.Stdout)
+					for _, key := range keys {
+						line := key.Rythmkey.Encode()
+						if subject := cCtx.String("genuine"); subject != "" {
+							label := "impostor"
+							if key.Subject == subject {
+								label = "genuine"
+							}
+							line = label + " " + line
+						}
+						fmt.Fprintln(w, line)
+					}
+
+					return w.Flush()
+				},
+			}, {
+				Name: "capabilities",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "output format " + choices(reportFormats),
 					},
 				},
+				Usage: "list the supported methods, algorithms, units and formats",
+				Action: func(cCtx *cli.Context) error {
+					c := SupportedCapabilities()
+
+					switch cCtx.String("format") {
+					case "text":
+						return c.WriteText(os.Stdout)
+					case "json":
+						return c.WriteJSON(os.Stdout)
+					default:
+						return fmt.Errorf("unknown format %q", cCtx.String("format"))
+					}
+				},
+			}, {
+				Name: "parse",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "rythmkey",
+						Value: "",
+						Usage: "ryhtmkey to parse, or with --input-format msgpack the file to read, - for stdin",
+					}, &cli.StringFlag{
+						Name:  "input-format",
+						Value: "encoded",
+						Usage: "input format " + choices(parseInputFormats),
+					}, &cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "output format " + choices(parseFormats),
+					}, &cli.BoolFlag{
+						Name:  "normalize",
+						Value: false,
+						Usage: "vector format: output timings as fractions of the total duration instead of milliseconds",
+					}, &cli.StringFlag{
+						Name:  "separator",
+						Value: ",",
+						Usage: "vector format: separator between timings",
+					}, &cli.BoolFlag{
+						Name:  "lenient",
+						Value: false,
+						Usage: "accept timings with leading zeros, like t007.a, reading them as the canonical t7.a",
+					}, clipboardFlag(),
+				},
 				Aliases: []string{"p"},
 				Usage:   "parse a rythmkey to test it and decompose it",
+				Before:  rythmkeyFromClipboard,
 				Action: func(cCtx *cli.Context) error {
 					rythmkey := cCtx.String("rythmkey")
 					if len(rythmkey) == 0 {
 						return errors.New("empty rythmkey")
 					}
 
-					rk, err := ParseRythmkey(rythmkey)
+					var err error
+					rk := Rythmkey{}
+					switch cCtx.String("input-format") {
+					case "encoded":
+						if cCtx.Bool("lenient") {
+							rk, err = ParseLenient(rythmkey)
+						} else {
+							rk, err = ParseRythmkey(rythmkey)
+						}
+					case "msgpack":
+						data := []byte{}
+						if rythmkey == "-" {
+							data, err = io.ReadAll(os.Stdin)
+						} else {
+							data, err = os.ReadFile(rythmkey)
+						}
+						if err == nil {
+							err = rk.UnmarshalMsgpack(data)
+						}
+					default:
+						return fmt.Errorf("unknown input format %q", cCtx.String("input-format"))
+					}
 					if err != nil {
 						return err
 					}
 
-					fmt.Printf("rythmkey: %+v", rk)
+					switch cCtx.String("format") {
+					case "text":
+						fmt.Printf("rythmkey: %+v", rk)
+					case "timeline":
+						fmt.Println(rk.Timeline(terminalWidth()))
+					case "table":
+						return rk.WriteTable(os.Stdout)
+					case "vector":
+						fmt.Println(formatVector(rk.Vector(cCtx.Bool("normalize")), cCtx.String("separator")))
+					case "msgpack":
+						_, err = os.Stdout.Write(rk.MarshalMsgpack())
+						return err
+					default:
+						return fmt.Errorf("unknown format %q", cCtx.String("format"))
+					}
+
 					return nil
 				},
 			},
 		},
 	}
+}
+
+func main() {
+	app := newApp()
+	cli.OsExiter = exiter(cli.OsExiter)
 
-	if err := app.Run(os.Args); err != nil {
+	err := app.Run(os.Args)
+	checkTerminal(os.Stderr)
+	if err != nil {
 		log.Fatal(err)
 	}
 }
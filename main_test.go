@@ -0,0 +1,1776 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// mustParse parses an encoded key, failing the test if it can't.
+func mustParse(t testing.TB, rks string) Rythmkey {
+	t.Helper()
+
+	rk, err := ParseRythmkey(rks)
+	if err != nil {
+		t.Fatalf("ParseRythmkey(%q): %v", rks, err)
+	}
+
+	return rk
+}
+
+// mustProfile enrolls a millisecond profile from encoded samples, failing
+// the test if it can't.
+func mustProfile(t testing.TB, samples ...string) Profile {
+	t.Helper()
+
+	rks := []Rythmkey{}
+	for _, sample := range samples {
+		rks = append(rks, mustParse(t, sample))
+	}
+
+	p, err := NewProfile(rks, time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewProfile(%q): %v", samples, err)
+	}
+
+	return p
+}
+
+func TestHashSkipFirstTiming(t *testing.T) {
+	rk := mustParse(t, "t0.at120.bt80.c")
+	reacted := mustParse(t, "t250.at120.bt80.c")
+
+	digest := func(rk Rythmkey, skip bool) string {
+		hash, err := rk.HashWith(HashOptions{Salt: 20, SkipFirstTiming: skip})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	if digest(rk, false) == digest(rk, true) {
+		t.Error("skipping the first timing doesn't change the digest")
+	}
+
+	if digest(rk, false) == digest(reacted, false) {
+		t.Error("the first timing isn't hashed without the flag")
+	}
+
+	if digest(rk, true) != digest(reacted, true) {
+		t.Error("the first timing is hashed with the flag")
+	}
+}
+
+// scriptedRead is a result a scriptedReader returns, a byte if n is 1,
+// after sleeping for delay.
+type scriptedRead struct {
+	n     int
+	b     byte
+	err   error
+	delay time.Duration
+}
+
+// scriptedReader returns its reads in order, then io.EOF.
+type scriptedReader struct {
+	reads []scriptedRead
+}
+
+// scriptedBytes reads every byte of s then io.EOF.
+func scriptedBytes(s string) *scriptedReader {
+	return pacedBytes(s, 0)
+}
+
+// pacedBytes reads every byte of s, each after sleeping for delay, then
+// io.EOF.
+func pacedBytes(s string, delay time.Duration) *scriptedReader {
+	r := &scriptedReader{}
+	for i := 0; i < len(s); i++ {
+		r.reads = append(r.reads, scriptedRead{n: 1, b: s[i], delay: delay})
+	}
+
+	return r
+}
+
+// spin waits for d, busily since sleeping oversleeps by more than the
+// sub-millisecond delays captures are tested with.
+func spin(d time.Duration) {
+	for start := time.Now(); time.Since(start) < d; {
+	}
+}
+
+func (r *scriptedReader) Read(p []byte) (int, error) {
+	if len(r.reads) == 0 {
+		return 0, io.EOF
+	}
+
+	read := r.reads[0]
+	r.reads = r.reads[1:]
+	spin(read.delay)
+	if read.n == 1 {
+		p[0] = read.b
+	}
+
+	return read.n, read.err
+}
+
+func TestCaptureRetriesEmptyReads(t *testing.T) {
+	r := &scriptedReader{reads: []scriptedRead{
+		{n: 1, b: 'a'},
+		{n: 0},
+		{n: 0, err: syscall.EINTR},
+		{n: 1, b: 'b'},
+		{n: 0},
+		{n: 1, b: '\n'},
+	}}
+
+	rk := Rythmkey{}
+	if err := rk.Capture(r, ReadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rk.Chars() != "ab" {
+		t.Errorf("captured %q, want %q", rk.Chars(), "ab")
+	}
+}
+
+func TestCaptureResolution(t *testing.T) {
+	coarse := Rythmkey{}
+	if err := coarse.Capture(pacedBytes("abcd", 300*time.Microsecond), ReadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	fine := Rythmkey{}
+	if err := fine.Capture(pacedBytes("abcd", 300*time.Microsecond), ReadOptions{Resolution: time.Microsecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < 4; i++ {
+		if coarse[i].Timing != 0 {
+			t.Errorf("millisecond timing %d = %s, want it collapsed to zero", i, coarse[i].Timing)
+		}
+
+		if fine[i].Timing < 300*time.Microsecond || fine[i].Timing%time.Microsecond != 0 {
+			t.Errorf("microsecond timing %d = %s, want whole microseconds of at least 300us", i, fine[i].Timing)
+		}
+	}
+
+	parsed := mustParse(t, fine.Encode())
+	if parsed.Encode() != fine.Encode() || !strings.HasPrefix(fine.Encode(), "us:") {
+		t.Errorf("round trip of %q = %q", fine.Encode(), parsed.Encode())
+	}
+}
+
+func TestCaptureMaxLength(t *testing.T) {
+	rk := Rythmkey{}
+	if err := rk.Capture(scriptedBytes("abcdef\n"), ReadOptions{MaxLength: 4}); err == nil {
+		t.Errorf("captured %q beyond the 4 characters cap", rk.Chars())
+	}
+
+	rk = Rythmkey{}
+	if err := rk.Capture(scriptedBytes("abcd\n"), ReadOptions{MaxLength: 4}); err != nil || rk.Chars() != "abcd" {
+		t.Errorf("Capture = %q, %v, want the 4 characters", rk.Chars(), err)
+	}
+}
+
+// scheduledReader delivers the bytes of s at every interval since its
+// first read, like keys typed at a steady pace whatever the reader does in
+// between, then io.EOF.
+type scheduledReader struct {
+	s        string
+	interval time.Duration
+	start    time.Time
+	i        int
+}
+
+func (r *scheduledReader) Read(p []byte) (int, error) {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+
+	if r.i == len(r.s) {
+		return 0, io.EOF
+	}
+
+	spin(time.Until(r.start.Add(time.Duration(r.i) * r.interval)))
+	p[0] = r.s[r.i]
+	r.i++
+	return 1, nil
+}
+
+// slowWriter takes delay to write anything, like a slow terminal.
+type slowWriter struct {
+	delay time.Duration
+}
+
+func (w slowWriter) Write(p []byte) (int, error) {
+	spin(w.delay)
+	return len(p), nil
+}
+
+func TestCaptureEchoDoesntInflateTimings(t *testing.T) {
+	interval := 20 * time.Millisecond
+
+	rk := Rythmkey{}
+	err := rk.Capture(&scheduledReader{s: "abcd", interval: interval}, ReadOptions{Echo: slowWriter{delay: 10 * time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, ct := range rk[1:] {
+		if ct.Timing < interval-2*time.Millisecond || ct.Timing > interval+2*time.Millisecond {
+			t.Errorf("timing %d = %s with a slow echo, want %s", i+1, ct.Timing, interval)
+		}
+	}
+}
+
+// boundaryFlips counts the positions of two keys whose buckets differ.
+func boundaryFlips(a Rythmkey, b Rythmkey, salt int, dither bool) int {
+	flips := 0
+	ba, bb := a.BucketIndices(salt, dither), b.BucketIndices(salt, dither)
+	for i := range ba {
+		if ba[i] != bb[i] {
+			flips++
+		}
+	}
+
+	return flips
+}
+
+func TestDitherReducesBoundaryFlips(t *testing.T) {
+	// Every timing a millisecond either side of the 40ms bucket bound.
+	early, late := Rythmkey{}, Rythmkey{}
+	for i := 0; i < 16; i++ {
+		early = append(early, &CharTiming{Timing: 39 * time.Millisecond, Char: 'a'})
+		late = append(late, &CharTiming{Timing: 41 * time.Millisecond, Char: 'a'})
+	}
+
+	plain := boundaryFlips(early, late, 20, false)
+	dithered := boundaryFlips(early, late, 20, true)
+
+	if plain != early.Len() {
+		t.Errorf("%d flips without dithering, want all %d timings", plain, early.Len())
+	}
+
+	if dithered*4 > plain {
+		t.Errorf("%d flips with dithering, %d without, want at most a quarter", dithered, plain)
+	}
+}
+
+func TestReadInputEnv(t *testing.T) {
+	t.Setenv(inputEnv, "pass word\nignored")
+
+	rk := Rythmkey{}
+	if err := rk.ReadWith(ReadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := rk.Encode(), "t0.pt0.at0.st0.st0. t0.wt0.ot0.rt0.d"; got != want {
+		t.Errorf("captured %q, want %q", got, want)
+	}
+}
+
+func TestReadEncodedInputEnv(t *testing.T) {
+	t.Setenv(inputEnv, "ignored")
+	t.Setenv(encodedInputEnv, "t0.at120.bt80.c")
+
+	rk := Rythmkey{}
+	if err := rk.ReadWith(ReadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := rk.Encode(), "t0.at120.bt80.c"; got != want {
+		t.Errorf("captured %q, want %q", got, want)
+	}
+
+	t.Setenv(encodedInputEnv, "t0.at1")
+	if err := rk.ReadWith(ReadOptions{}); err == nil {
+		t.Error("captured a malformed encoded key")
+	}
+}
+
+func TestEncodeWhitespaceRoundTrip(t *testing.T) {
+	for _, chars := range []string{"correct horse", " leading", "trailing ", "tab\tbed", "  \t  ", "0 1\t2"} {
+		rk := Rythmkey{}
+		for i := 0; i < len(chars); i++ {
+			rk.Add(chars[i], time.Duration(i*7)*time.Millisecond)
+		}
+
+		parsed, err := ParseRythmkey(rk.Encode())
+		if err != nil {
+			t.Errorf("ParseRythmkey(%q): %v", rk.Encode(), err)
+			continue
+		}
+
+		if parsed.Chars() != chars || parsed.Encode() != rk.Encode() {
+			t.Errorf("round trip of %q = %q, %q", chars, parsed.Chars(), parsed.Encode())
+		}
+	}
+}
+
+func TestCaptureKeepsWhitespace(t *testing.T) {
+	rk := Rythmkey{}
+	if err := rk.Capture(scriptedBytes(" a b\t \n"), ReadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rk.Chars() != " a b\t " {
+		t.Errorf("captured %q, want %q", rk.Chars(), " a b\t ")
+	}
+}
+
+func TestNeedsEscape(t *testing.T) {
+	for _, c := range []byte{0, '\n', '\r', 0x1b, 0x1f, 0x7f} {
+		if !needsEscape(c) {
+			t.Errorf("needsEscape(%#x) = false, want true", c)
+		}
+	}
+
+	for _, c := range []byte{'\t', ' ', 'a', '~', 0x80, 0xff} {
+		if needsEscape(c) {
+			t.Errorf("needsEscape(%#x) = true, want false", c)
+		}
+	}
+}
+
+func TestCheckMonotonic(t *testing.T) {
+	if err := mustParse(t, "t0.at120.bt0.c").CheckMonotonic(); err != nil {
+		t.Errorf("CheckMonotonic of a monotonic key: %v", err)
+	}
+
+	negative := mustParse(t, "t0.at120.bt80.c")
+	negative[2].Timing = -5 * time.Millisecond
+	if err := negative.CheckMonotonic(); err == nil {
+		t.Error("CheckMonotonic accepted a negative timing")
+	}
+
+	if err := mustParse(t, "t40.at120.b").CheckMonotonic(); err == nil {
+		t.Error("CheckMonotonic accepted a non-zero first timing")
+	}
+}
+
+func TestReadRequireMonotonic(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t30.at120.b")
+
+	rk := Rythmkey{}
+	if err := rk.ReadWith(ReadOptions{RequireMonotonic: true}); err == nil {
+		t.Errorf("captured %q with a non-zero first timing", rk.Encode())
+	}
+
+	if err := rk.ReadWith(ReadOptions{}); err != nil {
+		t.Errorf("capture without --require-monotonic: %v", err)
+	}
+}
+
+// Whether a check fails or a transformation replaces it, the captured key
+// is zeroed.
+func TestFinishCaptureZeroes(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		opts  ReadOptions
+		fails bool
+	}{
+		{"over the total time", ReadOptions{MaxTotalTime: 200 * time.Millisecond}, true},
+		{"not monotonic", ReadOptions{RequireMonotonic: true}, true},
+		{"forbidden", ReadOptions{Forbid: []byte("b")}, true},
+		{"control byte", ReadOptions{ControlBytes: ControlReject}, true},
+		{"remapped", ReadOptions{CharMap: CharMap{'a': 'A'}}, false},
+		{"smoothed", ReadOptions{Smooth: 3}, false},
+		{"selected", ReadOptions{Positions: []int{0, 2}}, false},
+		{"out of range", ReadOptions{Positions: []int{9}}, true},
+		{"remapped, smoothed then rejected", ReadOptions{CharMap: CharMap{'a': 'A'}, Smooth: 3, RequireMonotonic: true}, true},
+	} {
+		crk := mustParse(t, "t30.at120.bt120.ct0,01")
+
+		rk, err := finishCapture(crk, tc.opts)
+		if fails := err != nil; fails != tc.fails {
+			t.Errorf("%s: finishCapture = %q, %v, want failing %t", tc.name, rk.Encode(), err, tc.fails)
+		}
+
+		requireZeroed(t, tc.name, crk)
+	}
+}
+
+// The cap is on the timings added up, whatever the source: none of these
+// intervals is over it, their sum is.
+func TestReadMaxTotalTime(t *testing.T) {
+	for _, tc := range []struct {
+		encoded string
+		err     error
+	}{
+		{"t0.at400.bt400.c", nil},
+		{"t0.at500.bt500.c", nil},
+		{"t0.at600.bt600.c", errCaptureTooLong},
+		{"t0.at300.bt300.ct300.dt300.e", errCaptureTooLong},
+	} {
+		t.Setenv(encodedInputEnv, tc.encoded)
+
+		rk := Rythmkey{}
+		err := rk.ReadWith(ReadOptions{MaxTotalTime: time.Second})
+		if !errors.Is(err, tc.err) {
+			t.Errorf("ReadWith(%s) = %v, want %v", tc.encoded, err, tc.err)
+		}
+		if err != nil && rk.Len() != 0 {
+			t.Errorf("ReadWith(%s) kept %q", tc.encoded, rk.Encode())
+		}
+	}
+
+	t.Setenv(encodedInputEnv, "t0.at600.bt600.c")
+	if result := runApp(t, "read", "--max-total-time", "1s"); !errors.Is(result.err, errCaptureTooLong) || strings.Contains(result.stdout, "t600") {
+		t.Errorf("read --max-total-time 1s = %q, %v, want %v", result.stdout, result.err, errCaptureTooLong)
+	}
+}
+
+func TestCaptureZeroesOnReadError(t *testing.T) {
+	failure := errors.New("device gone")
+	r := &scriptedReader{reads: []scriptedRead{{n: 1, b: 'a'}, {n: 1, b: 'b'}, {n: 0, err: failure}}}
+
+	rk := Rythmkey{}
+	if err := rk.Capture(r, ReadOptions{}); !errors.Is(err, failure) {
+		t.Fatalf("Capture = %v, want %v", err, failure)
+	}
+
+	for i, ct := range rk {
+		if ct.Char != 0 || ct.Timing != 0 {
+			t.Errorf("character %d left as %q after a read error", i, ct.Char)
+		}
+	}
+
+	rk = Rythmkey{}
+	if err := rk.Capture(scriptedBytes("abcdef"), ReadOptions{MaxLength: 3}); err == nil {
+		t.Fatal("captured beyond the cap")
+	}
+	if rk.Chars() != "\x00\x00\x00" {
+		t.Errorf("capture left as %q beyond the cap", rk.Chars())
+	}
+}
+
+func TestPrefixHash(t *testing.T) {
+	full := mustParse(t, "t0.pt120.at80.st95.st140.w")
+	typed := mustParse(t, "t0.pt121.at83.s")
+
+	opts := HashOptions{Salt: 20}
+	prefixHash, err := full.Prefix(3).HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typedHash, err := typed.HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prefixHash != typedHash {
+		t.Errorf("hash of the 3 characters prefix %s, of the 3 characters typed %s", prefixHash, typedHash)
+	}
+
+	for _, n := range []int{0, 5, 10} {
+		if got := full.Prefix(n); got.Len() != full.Len() {
+			t.Errorf("Prefix(%d) has %d characters, want all %d", n, got.Len(), full.Len())
+		}
+	}
+}
+
+func TestZero(t *testing.T) {
+	rk := mustParse(t, "t0.at120.bt80.c")
+	rk[1].Modifiers = ModShift
+	cts := []*CharTiming(rk)
+
+	rk.Zero()
+
+	for i, ct := range cts {
+		if *ct != (CharTiming{}) {
+			t.Errorf("character %d left as %+v", i, *ct)
+		}
+	}
+}
+
+// flagsContext returns the context of a command with flags, parsed from
+// args.
+func flagsContext(t testing.TB, flags []cli.Flag, args ...string) *cli.Context {
+	t.Helper()
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range flags {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+// hashContext is the context of a command with the hash flags and --bare.
+func hashContext(t testing.TB, args ...string) *cli.Context {
+	t.Helper()
+
+	return flagsContext(t, append(hashFlags(), &cli.BoolFlag{Name: "bare"}, randomSaltFlag()), args...)
+}
+
+func TestResolveHashRoundTrip(t *testing.T) {
+	rk := mustParse(t, "t0.at120.bt80.c")
+
+	for _, args := range [][]string{
+		{"--salt", "25"},
+		{"--salt", "25", "--bare"},
+	} {
+		cCtx := hashContext(t, args...)
+		opts, err := hashOptions(cCtx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := rk.HashWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cCtx.Bool("bare") {
+			hash = NewHashParams(opts, hash).String()
+		}
+
+		// A parameterized hash verifies with its own salt.
+		verifyArgs := []string{}
+		if cCtx.Bool("bare") {
+			verifyArgs = []string{"--salt", "25"}
+		}
+
+		hp, err := resolveHash(hashContext(t, verifyArgs...), hash)
+		if err != nil {
+			t.Fatalf("%v: resolveHash(%q): %v", args, hash, err)
+		}
+
+		if ok, err := rk.VerifyHash(hp.Options, hp.Digest); err != nil || !ok {
+			t.Errorf("%v: VerifyHash = %t, %v, want a match", args, ok, err)
+		}
+	}
+}
+
+func TestResolveBareHashRequiresSalt(t *testing.T) {
+	if _, err := resolveHash(hashContext(t), "abcd"); err == nil {
+		t.Error("resolved a bare hash without --salt")
+	}
+}
+
+// scriptStdin makes captures read s from stdin in test mode, every timing
+// zeroed, until the test ends.
+func scriptStdin(t testing.TB, s string) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		w.WriteString(s)
+		w.Close()
+	}()
+
+	stdin := os.Stdin
+	os.Stdin, testMode = r, true
+	t.Cleanup(func() {
+		os.Stdin, testMode = stdin, false
+		r.Close()
+	})
+}
+
+// rawCapture makes ro record in raw the key captured, before it's
+// remapped, smoothed or selected, see ReadOptions.Check.
+func rawCapture(ro *ReadOptions, raw *Rythmkey) {
+	ro.Check = func(rk Rythmkey) error {
+		*raw = rk
+		return nil
+	}
+}
+
+// requireZeroed fails the test unless every character and timing of rk
+// was zeroed.
+func requireZeroed(t testing.TB, name string, rk Rythmkey) {
+	t.Helper()
+
+	if rk.Len() == 0 {
+		t.Fatalf("%s: nothing captured", name)
+	}
+
+	for i, ct := range rk {
+		if *ct != (CharTiming{}) {
+			t.Errorf("%s: character %d left as %+v", name, i, *ct)
+		}
+	}
+}
+
+func TestHashRhythmOnly(t *testing.T) {
+	opts := HashOptions{Salt: 20, RhythmOnly: true}
+
+	a, err := mustParse(t, "t0.at120.bt80.c").HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := mustParse(t, "t0.xt120.yt80.z").HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a != b {
+		t.Error("same timings with other characters hash differently with RhythmOnly")
+	}
+
+	c, err := mustParse(t, "t0.at300.bt80.c").HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a == c {
+		t.Error("other timings hash the same with RhythmOnly")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	rk := Rythmkey{}
+	rk.Add('a', 40*time.Millisecond)
+	rk.Add('b', 120*time.Millisecond)
+	rk.Add('c', -10*time.Millisecond)
+
+	if got, want := rk.Encode(), "t0.at120.bt0.c"; got != want {
+		t.Errorf("keys built with Add = %q, want %q", got, want)
+	}
+
+	if err := rk.CheckMonotonic(); err != nil {
+		t.Errorf("key built with Add isn't monotonic: %v", err)
+	}
+}
+
+func TestHashIterations(t *testing.T) {
+	rk := mustParse(t, "t0.at120.bt80.c")
+
+	digests := map[string]int{}
+	for _, iterations := range []int{0, 2, 1000} {
+		opts := HashOptions{Salt: 20, Iterations: iterations}
+		hash, err := rk.HashWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		digests[hash] = iterations
+
+		hp, err := ParseHashParams(NewHashParams(opts, hash).String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := rk.VerifyHash(hp.Options, hp.Digest); err != nil || !ok {
+			t.Errorf("%d iterations: VerifyHash = %t, %v, want a match", iterations, ok, err)
+		}
+	}
+
+	if len(digests) != 3 {
+		t.Errorf("iteration counts share digests: %v", digests)
+	}
+
+	one, _ := rk.HashWith(HashOptions{Salt: 20, Iterations: 1})
+	if _, ok := digests[one]; !ok || digests[one] != 0 {
+		t.Error("a single iteration doesn't hash like none")
+	}
+}
+
+func TestCapturePauseKey(t *testing.T) {
+	ms := time.Millisecond
+
+	for _, tc := range []struct {
+		name  string
+		reads []scriptedRead
+		want  time.Duration
+	}{
+		{"resumed by typing", []scriptedRead{
+			{n: 1, b: 'a'},
+			{n: 1, b: 0x10, delay: 20 * ms},
+			{n: 1, b: 'b', delay: 100 * ms},
+		}, 20 * ms},
+		{"resumed by the pause key", []scriptedRead{
+			{n: 1, b: 'a'},
+			{n: 1, b: 0x10, delay: 20 * ms},
+			{n: 1, b: 0x10, delay: 100 * ms},
+			{n: 1, b: 'b', delay: 10 * ms},
+		}, 30 * ms},
+	} {
+		rk := Rythmkey{}
+		if err := rk.Capture(&scriptedReader{reads: tc.reads}, ReadOptions{PauseKey: 0x10}); err != nil {
+			t.Fatal(err)
+		}
+
+		if rk.Chars() != "ab" {
+			t.Errorf("%s: captured %q, want %q", tc.name, rk.Chars(), "ab")
+			continue
+		}
+
+		if rk[1].Timing < tc.want || rk[1].Timing > tc.want+10*ms {
+			t.Errorf("%s: timing %s, want %s with the pause left out", tc.name, rk[1].Timing, tc.want)
+		}
+	}
+}
+
+func TestCaptureTerminatorKey(t *testing.T) {
+	for _, tc := range []struct {
+		terminator string
+		input      string
+		want       string
+	}{
+		{"\x04", "first\nsecond\x04ignored", "first\nsecond"},
+		{"\x1b\x1b", "a\x1bb\n\x1b\x1bc", "a\x1bb\n"},
+		{"", "line\nignored", "line"},
+	} {
+		rk := Rythmkey{}
+		if err := rk.Capture(scriptedBytes(tc.input), ReadOptions{Terminator: []byte(tc.terminator)}); err != nil {
+			t.Fatal(err)
+		}
+
+		if rk.Chars() != tc.want {
+			t.Errorf("terminator %q: captured %q, want %q", tc.terminator, rk.Chars(), tc.want)
+		}
+
+		parsed := mustParse(t, rk.Encode())
+		if parsed.Chars() != tc.want {
+			t.Errorf("terminator %q: %q parses back to %q", tc.terminator, rk.Encode(), parsed.Chars())
+		}
+	}
+}
+
+func TestEncodeNanosecondsRoundTrip(t *testing.T) {
+	rk := Rythmkey{}
+	for i, timing := range []time.Duration{0, 123456789, 1, 999999, 2 * time.Second} {
+		rk.Add(byte('a'+i), timing)
+	}
+
+	encoded := rk.Encode()
+	if !strings.HasPrefix(encoded, "ns:") {
+		t.Errorf("Encode = %q, want a ns: header", encoded)
+	}
+
+	parsed := mustParse(t, encoded)
+	for i := range rk {
+		if parsed[i].Timing != rk[i].Timing {
+			t.Errorf("timing %d = %s after a round trip, want %s", i, parsed[i].Timing, rk[i].Timing)
+		}
+	}
+}
+
+func TestCaptureHiresKeepsNanoseconds(t *testing.T) {
+	rk := Rythmkey{}
+	if err := rk.Capture(pacedBytes("abc", 150*time.Microsecond), ReadOptions{Resolution: time.Nanosecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed := mustParse(t, rk.Encode())
+	for i := 1; i < rk.Len(); i++ {
+		if parsed[i].Timing != rk[i].Timing {
+			t.Errorf("timing %d = %s after a round trip, want %s", i, parsed[i].Timing, rk[i].Timing)
+		}
+	}
+}
+
+// cliResult is what a command run by runApp wrote, and the code it exited
+// with, 0 unless it failed with a cli.Exit.
+type cliResult struct {
+	stdout string
+	stderr string
+	code   int
+	err    error
+}
+
+// runApp runs the command line in test mode with args, capturing what it
+// writes.
+func runApp(t testing.TB, args ...string) cliResult {
+	t.Helper()
+
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+
+	result := cliResult{}
+
+	savedStdout, savedStderr, savedExiter, savedErrWriter := os.Stdout, os.Stderr, cli.OsExiter, cli.ErrWriter
+	savedStrategy := parseStrategy
+	os.Stdout, os.Stderr, cli.ErrWriter = stdout, stderr, stderr
+	cli.OsExiter = func(code int) { result.code = code }
+	defer func() {
+		os.Stdout, os.Stderr, cli.OsExiter, cli.ErrWriter = savedStdout, savedStderr, savedExiter, savedErrWriter
+		testMode, parseStrategy = false, savedStrategy
+	}()
+
+	result.err = newApp().Run(append([]string{"rythmkey", "--test-mode"}, args...))
+
+	out, _ := os.ReadFile(stdout.Name())
+	errOut, _ := os.ReadFile(stderr.Name())
+	result.stdout, result.stderr = string(out), string(errOut)
+	return result
+}
+
+func TestHashExplain(t *testing.T) {
+	rk := mustParse(t, "t0.at123.bt7.c")
+	input, err := rk.HashInput(HashOptions{Salt: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logPath := filepath.Join(t.TempDir(), "verbose.log")
+	result := runApp(t, "--verbose", "--log-file", logPath, "hash", "--rythmkey", rk.Encode(), "--salt", "20", "--explain", "--bare")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	logged, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(logged), fmt.Sprintf("hash input: %q", input)) {
+		t.Errorf("explained:\n%s\nwant the hash input %q", logged, input)
+	}
+
+	digest, err := rk.HashWith(HashOptions{Salt: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(digestInput(input, HashOptions{})) != digest || result.stdout != digest {
+		t.Errorf("hash %q isn't the digest %s of the explained input", result.stdout, digest)
+	}
+}
+
+func TestHashExplainRequiresVerbose(t *testing.T) {
+	if result := runApp(t, "hash", "--rythmkey", "t0.a", "--salt", "20", "--explain"); result.err == nil {
+		t.Error("--explain without --verbose succeeded")
+	}
+}
+
+func TestReadWarnsZeroTimings(t *testing.T) {
+	const warning = "of the timings are zero"
+
+	for _, tc := range []struct {
+		rks  string
+		args []string
+		warn bool
+	}{
+		{"t0.pt0.at0.st0.s", nil, true},
+		{"t0.pt120.at80.st95.s", nil, false},
+		{"t0.pt0.at0.st95.s", nil, true},
+		{"t0.pt0.at0.st95.s", []string{"--zero-timing-threshold", "0.7"}, false},
+	} {
+		t.Setenv(encodedInputEnv, tc.rks)
+
+		result := runApp(t, append([]string{"read"}, tc.args...)...)
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+
+		if warned := strings.Contains(result.stderr, warning); warned != tc.warn {
+			t.Errorf("read %s %v warned %t, want %t: %q", tc.rks, tc.args, warned, tc.warn, result.stderr)
+		}
+	}
+}
+
+func TestConcat(t *testing.T) {
+	rk := mustParse(t, "t0.at80.b")
+	other := mustParse(t, "t0.c^1t95.d")
+
+	concat := rk.Concat(other, 240*time.Millisecond)
+	if got, want := concat.Encode(), "t0.at80.bt240.c^1t95.d"; got != want {
+		t.Errorf("Concat = %s, want %s", got, want)
+	}
+
+	parsed, err := ParseRythmkey(concat.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, concat) {
+		t.Errorf("ParseRythmkey(%s) = %s, want the concatenation back", concat.Encode(), parsed.Encode())
+	}
+
+	concat.Zero()
+	if rk.Encode() != "t0.at80.b" || other.Encode() != "t0.c^1t95.d" {
+		t.Errorf("Concat changed its operands to %s and %s", rk.Encode(), other.Encode())
+	}
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	rk := mustParse(t, "t0.zt130.qt95.xt210.jt80.k")
+	digest, err := rk.HashWith(HashOptions{Salt: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for _, typed := range []string{rk.Encode(), "t0.zt130.qt95.xt210.jt80.w"} {
+		t.Setenv(encodedInputEnv, typed)
+		runApp(t, "verify", "--hash", digest, "--salt", "20", "--audit-log", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("audit log %q, want 2 lines", data)
+	}
+
+	for i, accepted := range []bool{true, false} {
+		fields := map[string]any{}
+		if err := json.Unmarshal([]byte(lines[i]), &fields); err != nil {
+			t.Fatal(err)
+		}
+
+		for field := range fields {
+			if !slices.Contains([]string{"time", "method", "accepted", "score", "length", "reason"}, field) {
+				t.Errorf("audit event %d has the field %q", i, field)
+			}
+		}
+
+		if fields["method"] != "hash" || fields["accepted"] != accepted || fields["length"] != float64(rk.Len()) {
+			t.Errorf("audit event %d = %s, want an accepted %t hash of %d characters", i, lines[i], accepted, rk.Len())
+		}
+	}
+
+	for _, secret := range []string{"zqxjk", "zqxjw", "130", "210", digest} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("audit log %q leaks %q", data, secret)
+		}
+	}
+	for _, c := range "zqxjkw" {
+		if strings.ContainsRune(string(data), c) {
+			t.Errorf("audit log %q leaks the character %q", data, c)
+		}
+	}
+}
+
+// A dry run gives the verdict, with the same exit code, but neither the
+// audit log nor the session log is created, only named on stderr.
+func TestVerifyDryRun(t *testing.T) {
+	rk := mustParse(t, "t0.zt130.qt95.xt210.jt80.k")
+	digest, err := rk.HashWith(HashOptions{Salt: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	audit, session, logs := filepath.Join(dir, "audit.jsonl"), filepath.Join(dir, "session.jsonl"), filepath.Join(dir, "verbose.log")
+	for _, tc := range []struct {
+		typed string
+		code  int
+	}{
+		{rk.Encode(), 0},
+		{"t0.zt130.qt95.xt210.jt80.w", exitReject},
+	} {
+		t.Setenv(encodedInputEnv, tc.typed)
+
+		result := runApp(t, "--verbose", "--log-file", logs, "verify", "--hash", digest, "--salt", "20", "--reject-delay", "0", "--audit-log", audit, "--session-log", session, "--dry-run")
+		if result.code != tc.code {
+			t.Errorf("verify --dry-run of %s = %v, exit code %d, want %d", tc.typed, result.err, result.code, tc.code)
+		}
+		for _, path := range []string{audit, session, logs} {
+			if !strings.Contains(result.stderr, "dry run: skipped appending") || !strings.Contains(result.stderr, path) {
+				t.Errorf("verify --dry-run of %s told %q, want the write to %s skipped", tc.typed, result.stderr, path)
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("verify --dry-run wrote %v", entries)
+	}
+}
+
+func TestReadCookedFallback(t *testing.T) {
+	scriptStdin(t, "abc\n")
+	testMode = false
+
+	enter := enterCbreak
+	enterCbreak = func() error { return errors.New("operation not permitted") }
+	defer func() { enterCbreak = enter }()
+
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+
+	saved := os.Stderr
+	os.Stderr = stderr
+	rk, err := readInput(context.Background(), ReadOptions{Resolution: time.Millisecond})
+	os.Stderr = saved
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := rk.Encode(), "t0.at0.bt0.c"; got != want {
+		t.Errorf("readInput = %s, want the characters only, %s", got, want)
+	}
+
+	warning, _ := os.ReadFile(stderr.Name())
+	if !strings.Contains(string(warning), "timings are lost") || !strings.Contains(string(warning), "operation not permitted") {
+		t.Errorf("readInput warned %q, want the timings to be reported lost", warning)
+	}
+}
+
+// stubCbreak stands for a terminal entering cbreak mode, counting how many
+// times it's left.
+func stubCbreak(t *testing.T) *int {
+	t.Helper()
+
+	left := 0
+	enter, leave := enterCbreak, leaveCbreak
+	enterCbreak = func() error { return nil }
+	leaveCbreak = func() { left++ }
+	t.Cleanup(func() { enterCbreak, leaveCbreak = enter, leave })
+
+	return &left
+}
+
+// The terminal is restored once whether the capture succeeds or is cut
+// by --max-length.
+func TestReadRestoresCbreak(t *testing.T) {
+	for _, tc := range []struct {
+		input     string
+		maxLength int
+		fails     bool
+	}{
+		{"abc\n", 0, false},
+		{"abcdef\n", 3, true},
+	} {
+		scriptStdin(t, tc.input)
+		testMode = false
+		left := stubCbreak(t)
+
+		rk, err := readInput(context.Background(), ReadOptions{MaxLength: tc.maxLength})
+		if (err != nil) != tc.fails {
+			t.Errorf("readInput of %q up to %d = %v, want failing %t", tc.input, tc.maxLength, err, tc.fails)
+		}
+		rk.Zero()
+		if *left != 1 {
+			t.Errorf("readInput of %q up to %d left cbreak %d times, want once", tc.input, tc.maxLength, *left)
+		}
+	}
+}
+
+func TestCaptureMeasureReaction(t *testing.T) {
+	const reaction = 30 * time.Millisecond
+
+	for _, measure := range []bool{false, true} {
+		r := &scriptedReader{reads: []scriptedRead{
+			{n: 1, b: 'a', delay: reaction},
+			{n: 1, b: 'b'},
+			{n: 1, b: '\n'},
+		}}
+
+		rk := Rythmkey{}
+		if err := rk.Capture(r, ReadOptions{Resolution: time.Millisecond, MeasureReaction: measure}); err != nil {
+			t.Fatal(err)
+		}
+
+		if rk.Len() != 2 {
+			t.Fatalf("Capture = %s, want 2 characters", rk.Encode())
+		}
+
+		first := rk[0].Timing
+		if measure && (first < reaction || first > reaction+20*time.Millisecond) {
+			t.Errorf("Capture measuring the reaction timed %v, want about %v", first, reaction)
+		}
+		if !measure && first != 0 {
+			t.Errorf("Capture timed the first character %v, want 0", first)
+		}
+	}
+}
+
+func TestMeasureReactionConflictsWithMonotonic(t *testing.T) {
+	cCtx := flagsContext(t, captureFlags(), "--measure-reaction", "--require-monotonic")
+	if _, err := readOptions(cCtx); err == nil {
+		t.Error("readOptions accepted --measure-reaction with --require-monotonic")
+	}
+}
+
+func TestVerifyProfilesMismatch(t *testing.T) {
+	dir := t.TempDir()
+	saveProfile(t, dir, "secret.json", mustProfile(t, "t0.st120.et80.ct150.rt90.e", "t0.st130.et90.ct140.rt100.e"))
+
+	for _, tc := range []struct {
+		rks     string
+		code    int
+		message string
+	}{
+		{"t0.st125.et85.ct145.rt95.e", 0, ""},
+		{"t0.st125.et85.c", exitWrongSequence, "typed 3 characters, expected 5"},
+		{"t0.st125.et85.ct145.rt95.x", exitWrongSequence, "character sequence mismatch at character 4"},
+		{"t0.St125.Et85.Ct145.Rt95.E", exitWrongSequence, "Caps Lock"},
+		{"t0.st400.et300.ct20.rt400.e", exitReject, "no match"},
+	} {
+		t.Setenv(encodedInputEnv, tc.rks)
+
+		result := runApp(t, "verify", "--profiles-dir", dir)
+		if result.code != tc.code || !strings.Contains(result.stderr, tc.message) {
+			t.Errorf("verify %s exited %d with %q, want %d with %q", tc.rks, result.code, result.stderr, tc.code, tc.message)
+		}
+	}
+}
+
+func TestVerifyHashCapsLock(t *testing.T) {
+	digest, err := mustParse(t, "t0.st120.et80.Ct150.rt90.e").HashWith(HashOptions{Salt: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		rks     string
+		code    int
+		message string
+	}{
+		{"t0.st120.et80.Ct150.rt90.e", 0, ""},
+		{"t0.St120.Et80.ct150.Rt90.E", exitWrongSequence, "Caps Lock"},
+		{"t0.St400.Et80.ct150.Rt90.E", exitReject, "reject"},
+		{"t0.st400.et80.Ct150.rt90.e", exitReject, "reject"},
+	} {
+		t.Setenv(encodedInputEnv, tc.rks)
+
+		result := runApp(t, "verify", "--hash", digest, "--salt", "20")
+		if result.code != tc.code || !strings.Contains(result.stderr, tc.message) {
+			t.Errorf("verify %s exited %d with %q, want %d with %q", tc.rks, result.code, result.stderr, tc.code, tc.message)
+		}
+		if tc.code == exitReject && strings.Contains(result.stderr, "Caps Lock") {
+			t.Errorf("verify %s hinted at Caps Lock: %q", tc.rks, result.stderr)
+		}
+	}
+}
+
+func TestReadSampleRateCheck(t *testing.T) {
+	const warning = "input seems delivered in batches"
+
+	for _, tc := range []struct {
+		rks   string
+		check bool
+		warn  bool
+	}{
+		{"t0.pt50.at100.st150.st50.wt200.o", true, true},
+		{"t0.pt50.at100.st150.st50.wt200.o", false, false},
+		{"t0.pt120.at80.st95.st140.wt63.o", true, false},
+	} {
+		t.Setenv(encodedInputEnv, tc.rks)
+
+		args := []string{"read"}
+		if tc.check {
+			args = append(args, "--sample-rate-check")
+		}
+
+		result := runApp(t, args...)
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+
+		if warned := strings.Contains(result.stderr, warning); warned != tc.warn {
+			t.Errorf("read %s %v warned %t, want %t: %q", tc.rks, args, warned, tc.warn, result.stderr)
+		}
+	}
+}
+
+func TestVerifyHashContext(t *testing.T) {
+	rk := mustParse(t, "t0.at120.bt80.c")
+	t.Setenv(encodedInputEnv, rk.Encode())
+
+	hp := NewHashParams(HashOptions{Salt: 20, Context: "login"}, "")
+	digest, err := rk.HashWith(hp.Options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hp.Digest = digest
+
+	for _, tc := range []struct {
+		args []string
+		code int
+		fail bool
+	}{
+		{[]string{"--context", "login"}, 0, false},
+		{[]string{"--context", "sudo"}, exitReject, false},
+		{nil, 0, true},
+	} {
+		result := runApp(t, append([]string{"verify", "--hash", hp.String()}, tc.args...)...)
+		if failed := result.err != nil && result.code == 0; result.code != tc.code || failed != tc.fail {
+			t.Errorf("verify %v exited %d (%v), want %d failing %t", tc.args, result.code, result.err, tc.code, tc.fail)
+		}
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	for _, terminal := range []bool{false, true} {
+		buf := bytes.Buffer{}
+		writeOutput(&buf, "t0.at120.b", terminal)
+
+		want := "t0.at120.b"
+		if terminal {
+			want += "\n"
+		}
+		if buf.String() != want {
+			t.Errorf("writeOutput to a terminal %t = %q, want %q", terminal, buf.String(), want)
+		}
+	}
+}
+
+func TestReadOutputPiped(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at120.bt80.c")
+
+	result := runApp(t, "read")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if result.stdout != "t0.at120.bt80.c" {
+		t.Errorf("read piped = %q, want the key without a newline", result.stdout)
+	}
+}
+
+func TestTotalDuration(t *testing.T) {
+	for _, tc := range []struct {
+		rk    Rythmkey
+		total time.Duration
+	}{
+		{mustParse(t, "t0.at120.bt80.ct15.d"), 215 * time.Millisecond},
+		{mustParse(t, "us:t0.at1500.b"), 1500 * time.Microsecond},
+		{mustParse(t, "t0.a"), 0},
+		{Rythmkey{}, 0},
+		{nil, 0},
+	} {
+		if total := tc.rk.TotalDuration(); total != tc.total {
+			t.Errorf("TotalDuration(%s) = %v, want %v", tc.rk.Encode(), total, tc.total)
+		}
+	}
+}
+
+func TestLenRuneCount(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	rk := RythmkeyFromEvents([]KeyEvent{
+		{Char: 'a', At: start},
+		{Char: 'é', At: start.Add(100 * time.Millisecond)},
+		{Char: '€', At: start.Add(200 * time.Millisecond)},
+		{Char: 'b', At: start.Add(300 * time.Millisecond)},
+	})
+
+	// Every byte has its own timing: é is 2 of them, € 3.
+	if rk.Len() != 7 || rk.RuneCount() != 4 {
+		t.Errorf("Len, RuneCount of %q = %d, %d, want 7, 4", rk.Chars(), rk.Len(), rk.RuneCount())
+	}
+
+	if prefix := rk.Prefix(3); prefix.Len() != 3 || prefix.Chars() != "aé" {
+		t.Errorf("Prefix(3) = %q, want the 3 bytes of aé", prefix.Chars())
+	}
+
+	report := Compare(rk, rk.Prefix(3), AbsoluteTolerance(0))
+	if report.Length != 7 || report.TypedLength != 3 {
+		t.Errorf("Compare lengths = %d, %d, want 7, 3", report.Length, report.TypedLength)
+	}
+
+	if ascii := mustParse(t, "t0.at100.bt100.c"); ascii.Len() != 3 || ascii.RuneCount() != 3 {
+		t.Errorf("Len, RuneCount of abc = %d, %d, want 3, 3", ascii.Len(), ascii.RuneCount())
+	}
+}
+
+func TestCompareAcrossUnits(t *testing.T) {
+	milli := mustParse(t, "t0.at120.bt80.c")
+	micro := mustParse(t, "us:t0.at120000.bt80000.c")
+
+	if report := Compare(milli, micro, AbsoluteTolerance(0)); !report.Match {
+		t.Errorf("Compare(ms, µs) = %v, want a match", report)
+	}
+
+	if report := Compare(milli, mustParse(t, "us:t0.at120400.bt80000.c"), AbsoluteTolerance(0)); report.Match {
+		t.Errorf("Compare(ms, µs 400µs off) = %v, want a mismatch", report)
+	}
+
+	opts := HashOptions{Salt: 20}
+	hashMilli, err := milli.HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashMicro, err := micro.HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashMilli != hashMicro {
+		t.Errorf("HashWith(ms) = %s, HashWith(µs) = %s, want the same digest", hashMilli, hashMicro)
+	}
+
+	deltas, err := Deltas(milli, micro)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range deltas {
+		if d.Delta != 0 {
+			t.Errorf("Deltas(ms, µs) = %+v, want no differences", deltas)
+			break
+		}
+	}
+}
+
+func TestCheckUnits(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"--reference", "us:t0.at120000.b", "--rythmkey", "ms:t0.at120.b"}, true},
+		// Headerless, the timings could be in either unit.
+		{[]string{"--reference", "us:t0.at120000.b", "--rythmkey", "t0.at120.b"}, false},
+		{[]string{"--reference", "us:t0.at120000.b", "--rythmkey", "t0.at120000.b", "--assume-unit", "us"}, true},
+		{[]string{"--reference", "t0.at120.b", "--rythmkey", "t0.at120.b"}, true},
+	} {
+		result := runApp(t, append([]string{"deltas"}, tc.args...)...)
+		if (result.err == nil) != tc.ok {
+			t.Errorf("deltas %v = %v, want ok %t", tc.args, result.err, tc.ok)
+		}
+		if !tc.ok && (result.err == nil || !strings.Contains(result.err.Error(), "--assume-unit")) {
+			t.Errorf("deltas %v error = %v, want a hint at --assume-unit", tc.args, result.err)
+		}
+	}
+
+	t.Setenv(encodedInputEnv, "us:t0.at120000.b")
+	if result := runApp(t, "compare", "--hires", "--rythmkey", "t0.at120.b"); result.err == nil {
+		t.Error("compare of a headerless key with a µs capture succeeded")
+	}
+	if result := runApp(t, "compare", "--hires", "--rythmkey", "ms:t0.at120.b"); result.err != nil {
+		t.Errorf("compare of a ms key with a µs capture = %v", result.err)
+	}
+}
+
+func TestChars(t *testing.T) {
+	for _, tc := range []struct {
+		rks   string
+		chars string
+	}{
+		{"t0.st120.et80.ct150.rt90.et60.t", "secret"},
+		// é and € are typed as 2 and 3 bytes, each with its timing.
+		{"t0.\xc3t0.\xa9t100.\xe2t0.\x82t0.\xact90.!", "é€!"},
+		{"t0. t100.\tt50,00", " \t\x00"},
+	} {
+		if chars := mustParse(t, tc.rks).Chars(); chars != tc.chars {
+			t.Errorf("Chars(%q) = %q, want %q", tc.rks, chars, tc.chars)
+		}
+	}
+
+	if chars := (Rythmkey{}).Chars(); chars != "" {
+		t.Errorf("Chars of an empty key = %q, want none", chars)
+	}
+}
+
+func TestTextCommand(t *testing.T) {
+	rks := "t0.\xc3t0.\xa9t100.tt90.\xc3t0.\xa9"
+
+	result := runApp(t, "text", "--rythmkey", rks)
+	if result.err == nil || result.stdout != "" {
+		t.Errorf("text without --reveal = %v, printed %q, want an error and nothing printed", result.err, result.stdout)
+	}
+
+	result = runApp(t, "text", "--rythmkey", rks, "--reveal")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if result.stdout != "été" || !strings.Contains(result.stderr, "warning") {
+		t.Errorf("text --reveal printed %q, warned %q, want été and a warning", result.stdout, result.stderr)
+	}
+}
+
+func TestTabMode(t *testing.T) {
+	rk := Rythmkey{}
+	if err := rk.Capture(scriptedBytes("ab\tcd\n"), ReadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// As data, the tab is a character of the key.
+	if rk.Chars() != "ab\tcd" {
+		t.Errorf("captured %q, want the tab among the characters", rk.Chars())
+	}
+
+	// As a separator, it ends a field, the next one starting afresh.
+	fields := rk.Split('\t')
+	if len(fields) != 2 || fields[0].Chars() != "ab" || fields[1].Chars() != "cd" || fields[1][0].Timing != 0 {
+		t.Errorf("Split = %v, want fields ab and cd, cd starting at 0", fields)
+	}
+
+	for _, tc := range []struct {
+		mode  string
+		chars []string
+	}{
+		{"data", []string{"ab\tcd"}},
+		{"separator", []string{"ab", "cd"}},
+	} {
+		scriptStdin(t, "ab\tcd\n")
+
+		result := runApp(t, "read", "--tab-mode", tc.mode)
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+
+		lines := strings.Split(result.stdout, "\n")
+		if len(lines) != len(tc.chars) {
+			t.Fatalf("read --tab-mode %s output %q, want %d keys", tc.mode, result.stdout, len(tc.chars))
+		}
+		for i, line := range lines {
+			if chars := mustParse(t, line).Chars(); chars != tc.chars[i] {
+				t.Errorf("read --tab-mode %s key %d = %q, want %q", tc.mode, i, chars, tc.chars[i])
+			}
+		}
+	}
+
+	if result := runApp(t, "read", "--tab-mode", "newline"); result.err == nil {
+		t.Error("read --tab-mode newline succeeded")
+	}
+}
+
+func TestReadLockMemory(t *testing.T) {
+	locked := false
+	lock := lockMemory
+	t.Cleanup(func() { lockMemory = lock })
+
+	for _, tc := range []struct {
+		err     error
+		warning bool
+	}{
+		{nil, false},
+		{errors.New("can't lock memory: operation not permitted"), true},
+	} {
+		lockMemory = func() error {
+			locked = true
+			return tc.err
+		}
+		locked = false
+		t.Setenv(encodedInputEnv, "t0.at120.bt80.c")
+
+		result := runApp(t, "read", "--lock-memory")
+		if result.err != nil {
+			t.Fatalf("read --lock-memory failing with %v = %v, want the key read anyway", tc.err, result.err)
+		}
+
+		warned := strings.Contains(result.stderr, "warning: can't lock memory: operation not permitted")
+		if !locked || warned != tc.warning || result.stdout != "t0.at120.bt80.c" {
+			t.Errorf("read --lock-memory failing with %v: locked %t, stderr %q, stdout %q", tc.err, locked, result.stderr, result.stdout)
+		}
+	}
+
+	locked = false
+	t.Setenv(encodedInputEnv, "t0.at120.bt80.c")
+	if result := runApp(t, "read"); result.err != nil || locked {
+		t.Errorf("read without --lock-memory = %v, locked %t, want nothing locked", result.err, locked)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	rk := mustParse(t, "t0.at120.bt80.ct150.dt90.e")
+
+	selected, err := rk.Select([]int{0, 2, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each character keeps the time since the one typed before it.
+	if selected.Encode() != "t0.at80.ct90.e" {
+		t.Errorf("Select(0, 2, 4) = %s, want t0.at80.ct90.e", selected.Encode())
+	}
+
+	for _, indices := range [][]int{{0, 5}, {-1}, {1, 1}} {
+		if _, err := rk.Select(indices); err == nil {
+			t.Errorf("Select(%v) succeeded", indices)
+		}
+	}
+}
+
+func TestSelectHashDependsOnPositions(t *testing.T) {
+	opts := HashOptions{Salt: 20}
+	digest := func(rks string) string {
+		selected, err := mustParse(t, rks).Select([]int{0, 2, 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := selected.HashWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	want := digest("t0.at120.bt80.ct150.dt90.e")
+	// Characters and timings at positions 1 and 3 are left out.
+	if got := digest("t0.at400.xt80.ct10.yt90.e"); got != want {
+		t.Error("the hash of the selected positions depends on the others")
+	}
+	if got := digest("t0.at120.bt200.ct150.dt90.e"); got == want {
+		t.Error("the hash of the selected positions doesn't depend on theirs")
+	}
+	if got := digest("t0.at120.bt80.xt150.dt90.e"); got == want {
+		t.Error("the hash of the selected positions doesn't depend on their characters")
+	}
+}
+
+func TestReadPositions(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at120.bt80.ct150.dt90.e")
+
+	result := runApp(t, "read", "--positions", "0,2,4")
+	if result.err != nil || result.stdout != "t0.at80.ct90.e" {
+		t.Errorf("read --positions 0,2,4 = %q, %v, want t0.at80.ct90.e", result.stdout, result.err)
+	}
+
+	if result := runApp(t, "read", "--positions", "0,9"); result.err == nil {
+		t.Error("read --positions beyond the key succeeded")
+	}
+}
+
+func TestHashPepper(t *testing.T) {
+	rk := mustParse(t, "t0.at120.bt80.c")
+
+	digest := func(pepper string) string {
+		hash, err := rk.HashWith(HashOptions{Salt: 20, Pepper: []byte(pepper)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	if digest("") == digest("pepper") {
+		t.Error("the pepper doesn't change the digest")
+	}
+	if digest("pepper") == digest("other") {
+		t.Error("different peppers give the same digest")
+	}
+	if digest("pepper") != digest("pepper") {
+		t.Error("the same pepper gives different digests")
+	}
+}
+
+func TestHashPepperEnv(t *testing.T) {
+	const rks = "t0.at120.bt80.c"
+
+	t.Setenv(pepperEnv, "")
+	plain := runApp(t, "hash", "--rythmkey", rks, "--salt", "20")
+	if result := runApp(t, "hash", "--rythmkey", rks, "--salt", "20", "--require-pepper"); result.err == nil {
+		t.Error("hash --require-pepper without a pepper succeeded")
+	}
+
+	t.Setenv(pepperEnv, "pepper")
+	peppered := runApp(t, "hash", "--rythmkey", rks, "--salt", "20")
+	if plain.err != nil || peppered.err != nil {
+		t.Fatal(plain.err, peppered.err)
+	}
+	if plain.stdout == peppered.stdout || !strings.Contains(peppered.stdout, "$p=1$") || strings.Contains(plain.stdout, "$p=1$") {
+		t.Errorf("hash without pepper %q, with %q, want them to differ and only the latter marked peppered", plain.stdout, peppered.stdout)
+	}
+
+	t.Setenv(encodedInputEnv, rks)
+	for _, tc := range []struct {
+		pepper string
+		hash   string
+		flags  []string
+		ok     bool
+	}{
+		{"pepper", peppered.stdout, nil, true},
+		{"other", peppered.stdout, nil, false},
+		// The hash tells it was peppered, verifying it requires one.
+		{"", peppered.stdout, nil, false},
+		{"", plain.stdout, nil, true},
+		{"pepper", plain.stdout, []string{"--require-pepper"}, false},
+	} {
+		t.Setenv(pepperEnv, tc.pepper)
+
+		result := runApp(t, append([]string{"verify", "--hash", strings.TrimSpace(tc.hash)}, tc.flags...)...)
+		if (result.err == nil) != tc.ok {
+			t.Errorf("verify %s with pepper %q %v = %v, want ok %t", tc.hash, tc.pepper, tc.flags, result.err, tc.ok)
+		}
+	}
+}
+
+func TestCaptureRepeats(t *testing.T) {
+	rk := Rythmkey{}
+	if err := rk.Capture(&scheduledReader{s: "hello\n", interval: 2 * time.Millisecond}, ReadOptions{Resolution: time.Microsecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both l are captured, the second timed from the first.
+	if rk.Len() != 5 || rk.Chars() != "hello" {
+		t.Fatalf("captured %q, want both l of hello", rk.Chars())
+	}
+	if rk[3].Timing <= 0 {
+		t.Errorf("timing of the second l = %v, want the time since the first", rk[3].Timing)
+	}
+
+	parsed := mustParse(t, "t0.ht100.et100.lt40.lt100.o")
+	if parsed.Len() != 5 || parsed[2].Timing != 100*time.Millisecond || parsed[3].Timing != 40*time.Millisecond {
+		t.Errorf("parsed %v, want both l with their own timings", parsed)
+	}
+}
+
+func TestReadOutputHashOnly(t *testing.T) {
+	const rks = "t0.at120.bt80.c"
+	opts := HashOptions{Salt: 20}
+	digest, err := mustParse(t, rks).HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		flags  []string
+		stdout string
+	}{
+		{[]string{"--output-hash-only", "--bare"}, digest},
+		{[]string{"--quiet", "--bare"}, digest},
+		{[]string{"--output-hash-only"}, NewHashParams(opts, digest).String()},
+	} {
+		t.Setenv(encodedInputEnv, rks)
+
+		result := runApp(t, append([]string{"read", "--salt", "20"}, tc.flags...)...)
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+		if result.stdout != tc.stdout {
+			t.Errorf("read %v wrote %q to stdout, want exactly %q", tc.flags, result.stdout, tc.stdout)
+		}
+	}
+
+	// A capture that warns, typed too evenly, still leaves stdout clean.
+	scriptStdin(t, "abc\n")
+	result := runApp(t, "read", "--output-hash-only", "--bare", "--salt", "20")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if len(result.stdout) != len(digest) || strings.ContainsAny(result.stdout, "\n ") {
+		t.Errorf("read --output-hash-only of a typed key wrote %q, want a bare digest", result.stdout)
+	}
+
+	for _, flag := range []string{"--checksum", "--verbose"} {
+		if result := runApp(t, "read", "--output-hash-only", flag); result.err == nil {
+			t.Errorf("read --output-hash-only %s succeeded", flag)
+		}
+	}
+}
+
+func TestCheckForbidden(t *testing.T) {
+	rk := mustParse(t, "t0.pt100.at100.st100.\tt100.s")
+
+	for _, tc := range []struct {
+		forbidden string
+		err       string
+	}{
+		{"", ""},
+		{"xyz", ""},
+		{"s", "forbidden character 's' at position 2"},
+		{"\tz", `forbidden character '\t' at position 3`},
+	} {
+		err := rk.CheckForbidden([]byte(tc.forbidden))
+		if (err == nil) != (tc.err == "") || (err != nil && err.Error() != tc.err) {
+			t.Errorf("CheckForbidden(%q) = %v, want %q", tc.forbidden, err, tc.err)
+		}
+	}
+}
+
+func TestReadForbid(t *testing.T) {
+	for _, tc := range []struct {
+		rks    string
+		forbid string
+		err    string
+	}{
+		{"t0.at100.bt100.c", "xy", ""},
+		{"t0.at100.xt100.c", "xy", "forbidden character 'x' at position 1"},
+		{"t0.at100.\tt100.c", `\t`, `forbidden character '\t' at position 1`},
+	} {
+		t.Setenv(encodedInputEnv, tc.rks)
+
+		result := runApp(t, "read", "--forbid", tc.forbid)
+		if tc.err == "" {
+			if result.err != nil || result.stdout != tc.rks {
+				t.Errorf("read %q --forbid %q = %q, %v, want the key", tc.rks, tc.forbid, result.stdout, result.err)
+			}
+			continue
+		}
+
+		if result.err == nil || !strings.Contains(result.err.Error(), tc.err) || result.stdout != "" {
+			t.Errorf("read %q --forbid %q = %q, %v, want %q", tc.rks, tc.forbid, result.stdout, result.err, tc.err)
+		}
+	}
+
+	t.Setenv(encodedInputEnv, "t0.at100.xt100.c")
+	path := filepath.Join(t.TempDir(), "secret.json")
+	if result := runApp(t, "enroll", "--profile", path, "--samples", "1", "--forbid", "x"); result.err == nil {
+		t.Error("enroll of a forbidden character succeeded")
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("enroll of a forbidden character saved a profile")
+	}
+
+	if result := runApp(t, "read", "--forbid", `\q`); result.err == nil {
+		t.Error("read --forbid with an invalid escape succeeded")
+	}
+}
+
+func TestBucketIndices(t *testing.T) {
+	rk := mustParse(t, "t0.at123.bt7.ct40.dt39.e")
+
+	for _, tc := range []struct {
+		salt    int
+		dither  bool
+		buckets []int
+	}{
+		// A timing is in bucket timing/salt + 1, quantized to its top.
+		{20, false, []int{1, 7, 1, 3, 2}},
+		{50, false, []int{1, 3, 1, 1, 1}},
+		// Dithered by 0, 12, 4, 17 and 9ms.
+		{20, true, []int{1, 7, 1, 3, 3}},
+	} {
+		if buckets := rk.BucketIndices(tc.salt, tc.dither); !slices.Equal(buckets, tc.buckets) {
+			t.Errorf("BucketIndices(%d, %t) = %v, want %v", tc.salt, tc.dither, buckets, tc.buckets)
+		}
+	}
+
+	b := strings.Builder{}
+	if err := mustParse(t, "t0.at123.b").WriteBuckets(&b, 20, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "index  char  raw  quantized  bucket  margin\n" +
+		"0      'a'   0    20         1       0.00\n" +
+		"1      'b'   123  140        7       3.00\n"
+	if b.String() != want {
+		t.Errorf("WriteBuckets =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestHashExplainBuckets(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "verbose.log")
+	result := runApp(t, "--verbose", "--log-file", logPath, "hash", "--rythmkey", "t0.at123.b", "--salt", "20", "--explain")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	logged, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(logged), "bucket  margin") || !strings.Contains(string(logged), "123  140        7       3.00") {
+		t.Errorf("explained:\n%s\nwant the bucket of every character", logged)
+	}
+}
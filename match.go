@@ -0,0 +1,89 @@
+package main
+
+import "errors"
+
+// MatchResult is the outcome of any matching entry point, comparison or
+// verification, for callers to branch on without untangling a bool, a
+// score and an error.
+type MatchResult struct {
+	Matched bool `json:"matched"`
+	// Score is higher for better matches: the score of the tolerance
+	// method and of a profile, the correlation of the rank method and the
+	// negated distance of the curve one.
+	Score float64 `json:"score"`
+	// CharMismatch is set when the characters typed, or their number,
+	// differ from the reference, rather than their rhythm.
+	CharMismatch bool `json:"char_mismatch"`
+	// FailIndex is the first failing character, -1 when there is none or
+	// it isn't known, like for a hash.
+	FailIndex int `json:"fail_index"`
+	// Method matched with: a compare method, "profile" or "hash".
+	Method string `json:"method"`
+}
+
+// Result of the comparison.
+func (report CompareReport) Result() MatchResult {
+	score := report.Score
+	switch report.Method {
+	case "rank":
+		score = report.Correlation
+	case "curve":
+		score = -report.CurveDistance
+	}
+
+	return MatchResult{
+		Matched:      report.Match,
+		Score:        score,
+		CharMismatch: report.TypedLength != report.Length || report.MatchingChars != report.Length,
+		FailIndex:    report.FailIndex,
+		Method:       report.Method,
+	}
+}
+
+// Match verifies rk against p like Verify. A character mismatch isn't an
+// error but a result, other failures are.
+func (p Profile) Match(rk Rythmkey, threshold float64) (MatchResult, error) {
+	result := MatchResult{Method: "profile", FailIndex: -1}
+
+	results, err := p.Breakdown(rk)
+	var mismatch *MismatchError
+	if errors.As(err, &mismatch) {
+		result.CharMismatch = true
+		result.FailIndex = mismatch.Index
+		if mismatch.Kind == LengthMismatch {
+			result.FailIndex = min(mismatch.Typed, mismatch.Expected)
+		}
+		return result, nil
+	}
+
+	result.Matched, result.Score, err = p.Verify(rk, threshold)
+	if err != nil {
+		return MatchResult{}, err
+	}
+
+	for _, r := range results {
+		if !r.Pass {
+			result.FailIndex = r.Index
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// MatchHash verifies rk against hash like VerifyHash. A hash tells nothing
+// about which character failed nor why, the score is 1 for a match and 0
+// otherwise.
+func (rythmkey Rythmkey) MatchHash(opts HashOptions, hash string) (MatchResult, error) {
+	ok, err := rythmkey.VerifyHash(opts, hash)
+	if err != nil {
+		return MatchResult{}, err
+	}
+
+	result := MatchResult{Matched: ok, FailIndex: -1, Method: "hash"}
+	if ok {
+		result.Score = 1
+	}
+
+	return result, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCompareResult(t *testing.T) {
+	reference := mustParse(t, "t0.at100.bt100.c")
+	tolerance := AbsoluteTolerance(20 * time.Millisecond)
+
+	for _, tc := range []struct {
+		name   string
+		report func(typed Rythmkey) (CompareReport, error)
+		typed  string
+		want   MatchResult
+	}{
+		{
+			name:   "tolerance match",
+			report: func(typed Rythmkey) (CompareReport, error) { return Compare(reference, typed, tolerance), nil },
+			typed:  "t0.at110.bt100.c",
+			want:   MatchResult{Matched: true, Score: 1, FailIndex: -1, Method: "tolerance"},
+		},
+		{
+			name:   "tolerance timing",
+			report: func(typed Rythmkey) (CompareReport, error) { return Compare(reference, typed, tolerance), nil },
+			typed:  "t0.at100.bt200.c",
+			want:   MatchResult{Score: 2.0 / 3, FailIndex: 2, Method: "tolerance"},
+		},
+		{
+			name:   "tolerance character",
+			report: func(typed Rythmkey) (CompareReport, error) { return Compare(reference, typed, tolerance), nil },
+			typed:  "t0.at100.xt100.c",
+			want:   MatchResult{Score: 1.0 / 3, CharMismatch: true, FailIndex: 1, Method: "tolerance"},
+		},
+		{
+			name:   "tolerance length",
+			report: func(typed Rythmkey) (CompareReport, error) { return Compare(reference, typed, tolerance), nil },
+			typed:  "t0.at100.b",
+			want:   MatchResult{Score: 1.0 / 3, CharMismatch: true, FailIndex: 2, Method: "tolerance"},
+		},
+		{
+			name: "rank match",
+			report: func(typed Rythmkey) (CompareReport, error) {
+				return CompareRank(mustParse(t, "t0.at100.bt300.ct200.d"), typed, 0.9)
+			},
+			typed: "t0.at150.bt400.ct250.d",
+			want:  MatchResult{Matched: true, Score: 1, FailIndex: -1, Method: "rank"},
+		},
+		{
+			name: "rank character",
+			report: func(typed Rythmkey) (CompareReport, error) {
+				return CompareRank(mustParse(t, "t0.at100.bt300.ct200.d"), typed, 0.9)
+			},
+			typed: "t0.xt150.bt400.ct250.d",
+			want:  MatchResult{CharMismatch: true, FailIndex: 0, Method: "rank"},
+		},
+		{
+			name:   "curve match",
+			report: func(typed Rythmkey) (CompareReport, error) { return CompareCurve(reference, typed, tolerance) },
+			typed:  "t0.at100.bt100.c",
+			want:   MatchResult{Matched: true, FailIndex: -1, Method: "curve"},
+		},
+	} {
+		report, err := tc.report(mustParse(t, tc.typed))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		if got := report.Result(); !sameResult(got, tc.want) {
+			t.Errorf("%s: Result() = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// sameResult compares results, scores within rounding.
+func sameResult(a, b MatchResult) bool {
+	d := a.Score - b.Score
+	a.Score, b.Score = 0, 0
+	return a == b && d < 1e-9 && d > -1e-9
+}
+
+func TestProfileMatch(t *testing.T) {
+	p := mustProfile(t, "t0.at100.bt100.c", "t0.at110.bt90.c", "t0.at90.bt110.c")
+
+	for _, tc := range []struct {
+		typed string
+		want  MatchResult
+	}{
+		{"t0.at100.bt100.c", MatchResult{Matched: true, FailIndex: -1, Method: "profile"}},
+		{"t0.at100.bt300.c", MatchResult{FailIndex: 2, Method: "profile"}},
+		{"t0.at100.xt100.c", MatchResult{CharMismatch: true, FailIndex: 1, Method: "profile"}},
+		{"t0.at100.b", MatchResult{CharMismatch: true, FailIndex: 2, Method: "profile"}},
+	} {
+		rk := mustParse(t, tc.typed)
+		got, err := p.Match(rk, defaultThreshold)
+		if err != nil {
+			t.Fatalf("Match(%s) = %v", tc.typed, err)
+		}
+
+		// The score is the profile's own, see Profile.Score.
+		if !tc.want.CharMismatch {
+			score, err := p.Score(rk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.want.Score = score
+		} else if got.Score != 0 {
+			t.Errorf("Match(%s) scored %v for a character mismatch, want 0", tc.typed, got.Score)
+		}
+
+		if got != tc.want {
+			t.Errorf("Match(%s) = %+v, want %+v", tc.typed, got, tc.want)
+		}
+	}
+}
+
+func TestMatchHash(t *testing.T) {
+	opts := HashOptions{Salt: 20}
+	hash, err := mustParse(t, "t0.at123.bt7.c").HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		typed string
+		want  MatchResult
+	}{
+		{"t0.at125.bt10.c", MatchResult{Matched: true, Score: 1, FailIndex: -1, Method: "hash"}},
+		{"t0.at300.bt10.c", MatchResult{FailIndex: -1, Method: "hash"}},
+		// A hash can't tell a character mismatch apart.
+		{"t0.at125.bt10.x", MatchResult{FailIndex: -1, Method: "hash"}},
+	} {
+		got, err := mustParse(t, tc.typed).MatchHash(opts, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("MatchHash(%s) = %+v, want %+v", tc.typed, got, tc.want)
+		}
+	}
+}
+
+func TestCompareJSONResult(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at100.xt100.c")
+
+	result := runApp(t, "compare", "--rythmkey", "t0.at100.bt100.c", "--format", "json")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	out := struct {
+		Result MatchResult `json:"result"`
+	}{}
+	if err := json.Unmarshal([]byte(result.stdout), &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := (MatchResult{Score: 1.0 / 3, CharMismatch: true, FailIndex: 1, Method: "tolerance"}); !sameResult(out.Result, want) {
+		t.Errorf("compare result = %+v, want %+v", out.Result, want)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Matcher decides whether a typed sample matches a reference key, with a
+// score between 0 and 1 for how close it is.
+//
+// Programs embedding rythmkey can add their own methods by registering a
+// Matcher, usually from an init function:
+//
+//	func init() {
+//		RegisterMatcher("mine", myMatcher{})
+//	}
+//
+// verify --method mine then dispatches to it, against raw samples as well
+// as against the mean key of enrolled profiles.
+type Matcher interface {
+	Match(reference, sample Rythmkey) (bool, float64, error)
+}
+
+// ProfileMatcher is a Matcher that can also match a sample against an
+// enrolled profile as a whole, the spread of its samples included, rather
+// than against its mean key only.
+type ProfileMatcher interface {
+	Matcher
+	MatchProfile(p Profile, sample Rythmkey) (bool, float64, error)
+}
+
+// MatchProfile matches sample against p with m, as a ProfileMatcher if it
+// is one, or against the mean key of the profile.
+func MatchProfile(m Matcher, p Profile, sample Rythmkey) (bool, float64, error) {
+	if pm, ok := m.(ProfileMatcher); ok {
+		return pm.MatchProfile(p, sample)
+	}
+
+	reference := p.Reference()
+	defer reference.Zero()
+	return m.Match(reference, sample)
+}
+
+// singleSampleProfile is the profile a ProfileMatcher matches a reference
+// key as: its only sample.
+func singleSampleProfile(reference Rythmkey) (Profile, error) {
+	return NewProfile([]Rythmkey{reference}, 0)
+}
+
+// ToleranceMatcher matches samples typed with the same characters, every
+// one within Tolerance of the reference, see Compare. Its score is the
+// fraction of characters within tolerance.
+//
+// Against a profile, the tolerance of every character is the profile's
+// own instead, and samples scoring at least Threshold match, see
+// Profile.Score, or Profile.NormScore with a Norm.
+type ToleranceMatcher struct {
+	Tolerance Tolerance
+	// Slope widens the tolerance along the key, see CompareSloped.
+	Slope     time.Duration
+	Threshold float64
+	Norm      string
+}
+
+func (m ToleranceMatcher) Match(reference, sample Rythmkey) (bool, float64, error) {
+	if len(reference) == 0 {
+		return false, 0, nil
+	}
+
+	report := CompareSloped(reference, sample, m.Tolerance, m.Slope)
+	return report.Match, float64(report.WithinTolerance) / float64(len(reference)), nil
+}
+
+func (m ToleranceMatcher) MatchProfile(p Profile, sample Rythmkey) (bool, float64, error) {
+	score, err := 0.0, error(nil)
+	if m.Norm != "" {
+		score, err = p.NormScore(sample, m.Norm)
+	} else {
+		score, err = p.Score(sample)
+	}
+	if err != nil {
+		return false, 0, err
+	}
+
+	return score >= m.Threshold, score, nil
+}
+
+// RankMatcher matches samples typed with the same characters and
+// intervals ranked like the reference's, see CompareRank. Its score is the
+// rank correlation brought between 0 and 1.
+type RankMatcher struct {
+	MinCorrelation float64
+}
+
+func (m RankMatcher) Match(reference, sample Rythmkey) (bool, float64, error) {
+	report, err := CompareRank(reference, sample, m.MinCorrelation)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return report.Match, (report.Correlation + 1) / 2, nil
+}
+
+// EnsembleMatcher matches samples whose ensemble score reaches Threshold,
+// see Profile.EnsembleScore. A reference key is matched as a profile of a
+// single sample.
+type EnsembleMatcher struct {
+	Weights   EnsembleWeights
+	Threshold float64
+}
+
+func (m EnsembleMatcher) Match(reference, sample Rythmkey) (bool, float64, error) {
+	p, err := singleSampleProfile(reference)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return m.MatchProfile(p, sample)
+}
+
+func (m EnsembleMatcher) MatchProfile(p Profile, sample Rythmkey) (bool, float64, error) {
+	score, _, err := p.EnsembleScore(sample, m.Weights)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return score >= m.Threshold, score, nil
+}
+
+// BandsMatcher matches samples with every timing within its band, K
+// standard deviations around the mean, or the enrolled range when K is
+// zero, see Profile.Bands. Its score is the fraction of timings within
+// their band. A reference key is matched as a profile of a single sample,
+// whose bands only hold its own timings.
+type BandsMatcher struct {
+	K float64
+}
+
+func (m BandsMatcher) Match(reference, sample Rythmkey) (bool, float64, error) {
+	p, err := singleSampleProfile(reference)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return m.MatchProfile(p, sample)
+}
+
+func (m BandsMatcher) MatchProfile(p Profile, sample Rythmkey) (bool, float64, error) {
+	score, err := p.BandScore(sample, m.K)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return score == 1, score, nil
+}
+
+// ExactMatcher matches samples that quantize to the same hashing input as
+// the reference with Options, see CompareExact. It only needs the verdict
+// so takes its fast path, without a report. Its score is 1 for a match, 0
+// otherwise.
+type ExactMatcher struct {
+	Options HashOptions
+}
+
+func (m ExactMatcher) Match(reference, sample Rythmkey) (bool, float64, error) {
+	match, err := matchExact(reference, sample, m.Options)
+	if err != nil || !match {
+		return false, 0, err
+	}
+
+	return true, 1, nil
+}
+
+var (
+	matchersMu sync.RWMutex
+	matchers   = map[string]Matcher{}
+)
+
+func init() {
+	tolerance, err := ParseTolerance(defaultTolerance)
+	if err != nil {
+		panic(err)
+	}
+
+	weights, err := ParseEnsembleWeights(defaultEnsembleWeights)
+	if err != nil {
+		panic(err)
+	}
+
+	RegisterMatcher("tolerance", ToleranceMatcher{Tolerance: tolerance, Threshold: defaultThreshold})
+	RegisterMatcher("rank", RankMatcher{MinCorrelation: defaultMinCorrelation})
+	RegisterMatcher("exact", ExactMatcher{Options: HashOptions{Salt: defaultSalt}})
+	RegisterMatcher("dtw", DTWMatcher{Tolerance: tolerance})
+	RegisterMatcher("ensemble", EnsembleMatcher{Weights: weights, Threshold: defaultThreshold})
+	RegisterMatcher("bands", BandsMatcher{})
+}
+
+// RegisterMatcher makes m available under name. It panics if a matcher is
+// already registered with that name.
+func RegisterMatcher(name string, m Matcher) {
+	matchersMu.Lock()
+	defer matchersMu.Unlock()
+
+	if _, ok := matchers[name]; ok {
+		panic(fmt.Sprintf("matcher %q registered twice", name))
+	}
+	matchers[name] = m
+}
+
+func LookupMatcher(name string) (Matcher, bool) {
+	matchersMu.RLock()
+	defer matchersMu.RUnlock()
+
+	m, ok := matchers[name]
+	return m, ok
+}
+
+// Matchers returns the names of the registered matchers, sorted.
+func Matchers() []string {
+	matchersMu.RLock()
+	defer matchersMu.RUnlock()
+
+	names := make([]string, 0, len(matchers))
+	for name := range matchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
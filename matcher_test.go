@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// lengthMatcher matches samples as long as the reference, whatever their
+// characters and timings, so its verdicts can't be mistaken for a built-in
+// method's.
+type lengthMatcher struct{}
+
+func (lengthMatcher) Match(reference, sample Rythmkey) (bool, float64, error) {
+	return len(reference) == len(sample), 0.5, nil
+}
+
+// registerTestMatcher registers m under name for the duration of the test.
+func registerTestMatcher(t *testing.T, name string, m Matcher) {
+	t.Helper()
+
+	RegisterMatcher(name, m)
+	t.Cleanup(func() {
+		matchersMu.Lock()
+		defer matchersMu.Unlock()
+		delete(matchers, name)
+	})
+}
+
+func TestBuiltinMatchers(t *testing.T) {
+	for _, name := range []string{"tolerance", "rank", "exact", "dtw", "ensemble", "bands"} {
+		if _, ok := LookupMatcher(name); !ok {
+			t.Errorf("LookupMatcher(%q) found nothing", name)
+		}
+	}
+
+	if !slices.IsSorted(Matchers()) {
+		t.Errorf("Matchers() = %v, want sorted names", Matchers())
+	}
+}
+
+func TestRegisteredMatcher(t *testing.T) {
+	registerTestMatcher(t, "test-length", lengthMatcher{})
+
+	m, ok := LookupMatcher("test-length")
+	if !ok || !slices.Contains(Matchers(), "test-length") {
+		t.Fatalf("test-length isn't registered: %v", Matchers())
+	}
+
+	rk := mustParse(t, "t0.xt900.y")
+	if match, score, _ := MatchProfile(m, mustProfile(t, "t0.at100.b"), rk); !match || score != 0.5 {
+		t.Errorf("MatchProfile = %t, %v, want a match scoring 0.5", match, score)
+	}
+
+	profiles := []NamedProfile{
+		{Name: "short", Profile: mustProfile(t, "t0.a")},
+		{Name: "long", Profile: mustProfile(t, "t0.at100.b")},
+	}
+	if np, _, ok := MatchProfiles(profiles, rk, m); !ok || np.Name != "long" {
+		t.Errorf("MatchProfiles = %q, %t, want long", np.Name, ok)
+	}
+
+	samples := []Rythmkey{mustParse(t, "t0.at100.b"), mustParse(t, "t0.a"), mustParse(t, "t0.ct5.d")}
+	if votes, ok, _ := Vote(samples, rk, m, 0); votes != 2 || !ok {
+		t.Errorf("Vote = %d, %t, want 2 votes accepted", votes, ok)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering test-length twice didn't panic")
+		}
+	}()
+	RegisterMatcher("test-length", lengthMatcher{})
+}
+
+func TestVerifyRegisteredMatcher(t *testing.T) {
+	registerTestMatcher(t, "test-length", lengthMatcher{})
+
+	profilesDir := t.TempDir()
+	saveProfile(t, profilesDir, "secret.json", mustProfile(t, "t0.at100.bt100.c"))
+	samplesDir := writeSamples(t, "t0.at100.bt100.c", "t0.at100.bt100.c")
+
+	for _, tc := range []struct {
+		rks    string
+		accept bool
+	}{
+		// Nothing alike but the length, which is all test-length checks.
+		{"t0.xt900.yt5.z", true},
+		{"t0.at100.b", false},
+	} {
+		t.Setenv(encodedInputEnv, tc.rks)
+
+		for _, source := range [][]string{{"--profiles-dir", profilesDir}, {"--samples-dir", samplesDir}} {
+			result := runApp(t, append([]string{"verify", "--method", "test-length"}, source...)...)
+			if accepted := result.err == nil; accepted != tc.accept || (!accepted && result.code == 0) {
+				t.Errorf("verify --method test-length %s with %s exited %d: %v, want accepted %t", source[0], tc.rks, result.code, result.err, tc.accept)
+			}
+		}
+	}
+
+	result := runApp(t, "capabilities", "--format", "json")
+	capabilities := Capabilities{}
+	if err := json.Unmarshal([]byte(result.stdout), &capabilities); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(capabilities.VerifyMethods, "test-length") {
+		t.Errorf("capabilities lists verify methods %v, want test-length among them", capabilities.VerifyMethods)
+	}
+}
+
+func TestVerifyMethodHash(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at100.b")
+
+	result := runApp(t, "verify", "--hash", "00", "--salt", "20", "--method", "dtw")
+	if result.err == nil || !strings.Contains(result.err.Error(), "--method") {
+		t.Errorf("verify --hash --method = %v, want an error about --method", result.err)
+	}
+
+	dir := t.TempDir()
+	saveProfile(t, dir, "secret.json", mustProfile(t, "t0.at100.b"))
+	result = runApp(t, "verify", "--profiles-dir", dir, "--method", "nonsense")
+	if result.err == nil || !strings.Contains(result.err.Error(), "unknown method") {
+		t.Errorf("verify --method nonsense = %v, want an unknown method error", result.err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MismatchKind tells why a typed key doesn't match its reference.
+type MismatchKind int
+
+const (
+	// LengthMismatch is a key with more or less characters than expected.
+	LengthMismatch MismatchKind = iota
+	// CharMismatch is a key of the expected length with other characters.
+	CharMismatch
+	// TimingMismatch is the expected characters typed with another
+	// rhythm.
+	TimingMismatch
+)
+
+// MismatchError reports a typed key that doesn't match its reference.
+type MismatchError struct {
+	Kind MismatchKind
+	// Typed and Expected are the lengths of the keys.
+	Typed    int
+	Expected int
+	// Index of the first differing character for a CharMismatch, -1 when
+	// unknown.
+	Index int
+}
+
+func (err *MismatchError) Error() string {
+	switch err.Kind {
+	case LengthMismatch:
+		return fmt.Sprintf("typed %d characters, expected %d", err.Typed, err.Expected)
+	case CharMismatch:
+		if err.Index < 0 {
+			return "character sequence mismatch"
+		}
+		return fmt.Sprintf("character sequence mismatch at character %d", err.Index)
+	}
+
+	return "timing mismatch"
+}
+
+// checkChars reports a typed character sequence that differs from the
+// expected one, its length first.
+func checkChars(expected string, typed string) error {
+	if len(typed) != len(expected) {
+		return &MismatchError{Kind: LengthMismatch, Typed: len(typed), Expected: len(expected), Index: -1}
+	}
+
+	for i := range expected {
+		if typed[i] != expected[i] {
+			return &MismatchError{Kind: CharMismatch, Typed: len(typed), Expected: len(expected), Index: i}
+		}
+	}
+
+	return nil
+}
+
+var errCapsLock = errors.New("character sequence has its case inverted, did you leave Caps Lock on?")
+
+// InvertCase returns a copy of the key with its ASCII letters switched to
+// the other case, as typed with Caps Lock toggled.
+func (rythmkey Rythmkey) InvertCase() Rythmkey {
+	rk := make(Rythmkey, 0, len(rythmkey))
+	for _, ct := range rythmkey {
+		c := ct.Char
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z':
+			c += 'a' - 'A'
+		}
+		rk = append(rk, &CharTiming{Timing: ct.Timing, Char: c, Modifiers: ct.Modifiers})
+	}
+
+	return rk
+}
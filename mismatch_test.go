@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckChars(t *testing.T) {
+	for _, tc := range []struct {
+		typed string
+		err   *MismatchError
+	}{
+		{"secret", nil},
+		{"sec", &MismatchError{Kind: LengthMismatch, Typed: 3, Expected: 6, Index: -1}},
+		{"secrets", &MismatchError{Kind: LengthMismatch, Typed: 7, Expected: 6, Index: -1}},
+		{"secrat", &MismatchError{Kind: CharMismatch, Typed: 6, Expected: 6, Index: 4}},
+	} {
+		err := checkChars("secret", tc.typed)
+		if tc.err == nil {
+			if err != nil {
+				t.Errorf("checkChars(%q) = %v, want nil", tc.typed, err)
+			}
+			continue
+		}
+
+		mismatch := &MismatchError{}
+		if !errors.As(err, &mismatch) || *mismatch != *tc.err {
+			t.Errorf("checkChars(%q) = %#v, want %#v", tc.typed, err, tc.err)
+		}
+	}
+}
+
+func TestMismatchError(t *testing.T) {
+	for _, tc := range []struct {
+		err  MismatchError
+		want string
+	}{
+		{MismatchError{Kind: LengthMismatch, Typed: 5, Expected: 8, Index: -1}, "typed 5 characters, expected 8"},
+		{MismatchError{Kind: CharMismatch, Typed: 8, Expected: 8, Index: 3}, "character sequence mismatch at character 3"},
+		{MismatchError{Kind: CharMismatch, Typed: 8, Expected: 8, Index: -1}, "character sequence mismatch"},
+		{MismatchError{Kind: TimingMismatch, Typed: 8, Expected: 8, Index: -1}, "timing mismatch"},
+	} {
+		if got := tc.err.Error(); got != tc.want {
+			t.Errorf("%+v.Error() = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+// mismatchKind is the kind of the *MismatchError err wraps, -1 for none.
+func mismatchKind(err error) MismatchKind {
+	mismatch := &MismatchError{}
+	if !errors.As(err, &mismatch) {
+		return -1
+	}
+
+	return mismatch.Kind
+}
+
+func TestCompareReportErr(t *testing.T) {
+	reference := mustParse(t, "t0.at120.bt80.c")
+
+	for _, tc := range []struct {
+		rks  string
+		kind MismatchKind
+	}{
+		{"t0.at120.bt80.c", -1},
+		{"t0.at120.b", LengthMismatch},
+		{"t0.at120.bt80.ct10.d", LengthMismatch},
+		{"t0.at120.bt80.x", CharMismatch},
+		{"t0.at400.bt80.c", TimingMismatch},
+	} {
+		if kind := mismatchKind(Compare(reference, mustParse(t, tc.rks), AbsoluteTolerance(50*time.Millisecond)).Err()); kind != tc.kind {
+			t.Errorf("Compare(%s).Err() is a %v mismatch, want %v", tc.rks, kind, tc.kind)
+		}
+	}
+}
+
+func TestProfileScoreMismatch(t *testing.T) {
+	p := mustProfile(t, "t0.at120.bt80.c", "t0.at130.bt90.c")
+
+	for _, tc := range []struct {
+		rks  string
+		kind MismatchKind
+	}{
+		{"t0.at120.b", LengthMismatch},
+		{"t0.at120.bt80.x", CharMismatch},
+	} {
+		if _, err := p.Score(mustParse(t, tc.rks)); mismatchKind(err) != tc.kind {
+			t.Errorf("Score(%s) = %v, want a %v mismatch", tc.rks, err, tc.kind)
+		}
+	}
+
+	if _, err := mustParse(t, "t0.at120.bt80.c").RankCorrelation(mustParse(t, "t0.at120.b")); mismatchKind(err) != LengthMismatch {
+		t.Errorf("RankCorrelation of different lengths = %v, want a length mismatch", err)
+	}
+}
+
+func TestSequenceMismatch(t *testing.T) {
+	one := []NamedProfile{{Name: "one", Profile: mustProfile(t, "t0.at120.bt80.c")}}
+	two := append(one, NamedProfile{Name: "two", Profile: mustProfile(t, "t0.xt120.yt80.z")})
+
+	for _, tc := range []struct {
+		profiles []NamedProfile
+		rks      string
+		kind     MismatchKind
+		err      error
+	}{
+		{one, "t0.at400.bt10.c", -1, nil},
+		{one, "t0.at120.b", LengthMismatch, nil},
+		{one, "t0.at120.bt80.x", CharMismatch, nil},
+		{one, "t0.At120.Bt80.C", -1, errCapsLock},
+		{two, "t0.xt400.yt10.z", -1, nil},
+		{two, "t0.x", -1, errors.New("character sequence matches no profile")},
+	} {
+		err := sequenceMismatch(tc.profiles, mustParse(t, tc.rks))
+		if kind := mismatchKind(err); kind != tc.kind {
+			t.Errorf("sequenceMismatch(%s) = %v, want a %v mismatch", tc.rks, err, tc.kind)
+		}
+
+		if tc.kind == -1 && (err == nil) != (tc.err == nil) || tc.err != nil && (err == nil || err.Error() != tc.err.Error()) {
+			t.Errorf("sequenceMismatch(%s) = %v, want %v", tc.rks, err, tc.err)
+		}
+	}
+}
+
+func TestInvertCase(t *testing.T) {
+	rk := mustParse(t, "t0.at10.Bt20.1^1t30.z")
+
+	inverted := rk.InvertCase()
+	if got, want := inverted.Encode(), "t0.At10.bt20.1^1t30.Z"; got != want {
+		t.Errorf("InvertCase = %s, want %s", got, want)
+	}
+
+	if rk.Encode() != "t0.at10.Bt20.1^1t30.z" {
+		t.Errorf("InvertCase changed its key to %s", rk.Encode())
+	}
+}
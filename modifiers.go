@@ -0,0 +1,35 @@
+package main
+
+import "strings"
+
+// Modifiers is the bitmask of the modifier keys held while a character was
+// typed. Only the evdev source sees them, see ReadOptions.Modifiers: keys
+// captured from a terminal have none. Hashed with HashOptions.Modifiers,
+// they must match between hashing and verifying.
+type Modifiers uint8
+
+const (
+	ModShift Modifiers = 1 << iota
+	ModCtrl
+	ModAlt
+)
+
+// modifiersMark starts the modifiers of a character in the encoded form,
+// "^3t120A" being an 'A' typed with Shift and Ctrl held. Like checksumMark
+// it stands where a 't' is expected so it can't be mistaken for a
+// character, and it is left out for characters without modifiers.
+const modifiersMark = '^'
+
+func (m Modifiers) String() string {
+	names := []string{}
+	for _, mod := range []struct {
+		bit  Modifiers
+		name string
+	}{{ModShift, "shift"}, {ModCtrl, "ctrl"}, {ModAlt, "alt"}} {
+		if m&mod.bit != 0 {
+			names = append(names, mod.name)
+		}
+	}
+
+	return strings.Join(names, "+")
+}
@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+func TestModifiersString(t *testing.T) {
+	for _, tc := range []struct {
+		m    Modifiers
+		want string
+	}{
+		{0, ""},
+		{ModShift, "shift"},
+		{ModCtrl | ModAlt, "ctrl+alt"},
+		{ModShift | ModCtrl | ModAlt, "shift+ctrl+alt"},
+	} {
+		if got := tc.m.String(); got != tc.want {
+			t.Errorf("Modifiers(%d).String() = %q, want %q", tc.m, got, tc.want)
+		}
+	}
+}
+
+func TestModifiersEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		rks       string
+		modifiers []Modifiers
+	}{
+		{"t0.at120.b", []Modifiers{0, 0}},
+		{"^1t0.At120.b", []Modifiers{ModShift, 0}},
+		{"t0.a^2t120.b", []Modifiers{0, ModCtrl}},
+		{"^5t0.A^3t120,01", []Modifiers{ModShift | ModAlt, ModShift | ModCtrl}},
+		// Masks are kept whole, bits no source sets yet included.
+		{"^255t0.a", []Modifiers{255}},
+	} {
+		rk, err := ParseRythmkey(tc.rks)
+		if err != nil {
+			t.Errorf("ParseRythmkey(%s) = %v", tc.rks, err)
+			continue
+		}
+
+		for i, m := range tc.modifiers {
+			if rk[i].Modifiers != m {
+				t.Errorf("ParseRythmkey(%s)[%d].Modifiers = %d, want %d", tc.rks, i, rk[i].Modifiers, m)
+			}
+		}
+		if got := rk.Encode(); got != tc.rks {
+			t.Errorf("Encode(ParseRythmkey(%s)) = %s", tc.rks, got)
+		}
+	}
+}
+
+func TestModifiersParseInvalid(t *testing.T) {
+	for _, rks := range []string{
+		"^0t0.a",
+		"^01t0.a",
+		"^256t0.a",
+		"^t0.a",
+		"^1",
+		"^1.a",
+		"t0.a^1^2t120.b",
+		"t0.a^-1t120.b",
+	} {
+		if rk, err := ParseRythmkey(rks); err == nil {
+			t.Errorf("ParseRythmkey(%s) = %s, want an error", rks, rk.Encode())
+		}
+	}
+
+	// Lenient parsing normalizes the masks a strict one rejects.
+	for rks, want := range map[string]string{
+		"^01t0.a": "^1t0.a",
+		"^0t0.a":  "t0.a",
+	} {
+		rk, err := ParseLenient(rks)
+		if err != nil {
+			t.Errorf("ParseLenient(%s) = %v", rks, err)
+			continue
+		}
+		if got := rk.Encode(); got != want {
+			t.Errorf("ParseLenient(%s) = %s, want %s", rks, got, want)
+		}
+	}
+}
+
+func TestModifiersHash(t *testing.T) {
+	plain, shifted := mustParse(t, "t0.at120.b"), mustParse(t, "^1t0.at120.b")
+
+	for _, modifiers := range []bool{false, true} {
+		opts := HashOptions{Salt: 20, Modifiers: modifiers}
+
+		a, err := plain.HashWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := shifted.HashWith(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if differ := a != b; differ != modifiers {
+			t.Errorf("with Modifiers %t, hashes differ %t, want %t", modifiers, differ, modifiers)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// MarshalMsgpack serializes the key as a MessagePack array with a map per
+// character: "t", its timing as an integer count of nanoseconds, "c", the
+// character as a 1 byte bin so it never goes through a string encoding,
+// and "m", its Modifiers, only for characters typed with some.
+func (rythmkey Rythmkey) MarshalMsgpack() []byte {
+	data := []byte{}
+
+	switch n := len(rythmkey); {
+	case n < 16:
+		data = append(data, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		data = binary.BigEndian.AppendUint16(append(data, 0xdc), uint16(n))
+	default:
+		data = binary.BigEndian.AppendUint32(append(data, 0xdd), uint32(n))
+	}
+
+	for _, ct := range rythmkey {
+		fields := byte(0x82)
+		if ct.Modifiers != 0 {
+			fields++
+		}
+
+		data = append(data, fields, 0xa1, 't', 0xd3)
+		data = binary.BigEndian.AppendUint64(data, uint64(ct.Timing))
+		data = append(data, 0xa1, 'c', 0xc4, 1, ct.Char)
+		if ct.Modifiers != 0 {
+			data = append(data, 0xa1, 'm', 0xcc, byte(ct.Modifiers))
+		}
+	}
+
+	return data
+}
+
+// UnmarshalMsgpack decodes a key serialized by MarshalMsgpack, accepting
+// any MessagePack integer for timings and modifiers and a 1 byte bin or
+// str for characters so other encoders interoperate. Modifiers are
+// optional, none when left out.
+func (rk *Rythmkey) UnmarshalMsgpack(data []byte) error {
+	d := msgpackDecoder{data: data}
+
+	n, err := d.length(0x90, 0xdc, 0xdd)
+	if err != nil {
+		return fmt.Errorf("rythmkey: %w", err)
+	}
+
+	decoded := Rythmkey{}
+	for i := 0; i < n; i++ {
+		fields, err := d.length(0x80, 0xde, 0xdf)
+		if err != nil {
+			return fmt.Errorf("character %d: %w", i, err)
+		}
+
+		ct := &CharTiming{}
+		seen := 0
+		for j := 0; j < fields; j++ {
+			key, err := d.bytes()
+			if err != nil {
+				return fmt.Errorf("character %d: %w", i, err)
+			}
+
+			switch string(key) {
+			case "t":
+				timing, err := d.int()
+				if err != nil {
+					return fmt.Errorf("character %d timing: %w", i, err)
+				}
+				ct.Timing = time.Duration(timing)
+				seen |= 1
+			case "c":
+				c, err := d.bytes()
+				if err != nil {
+					return fmt.Errorf("character %d: %w", i, err)
+				}
+				if len(c) != 1 {
+					return fmt.Errorf("character %d is %d bytes long", i, len(c))
+				}
+				ct.Char = c[0]
+				seen |= 2
+			case "m":
+				m, err := d.int()
+				if err != nil {
+					return fmt.Errorf("character %d modifiers: %w", i, err)
+				}
+				if m < 0 || m > math.MaxUint8 {
+					return fmt.Errorf("character %d: modifiers %d out of range", i, m)
+				}
+				ct.Modifiers = Modifiers(m)
+			default:
+				return fmt.Errorf("character %d: unknown field %q", i, key)
+			}
+		}
+
+		if seen != 3 {
+			return fmt.Errorf("character %d: missing field", i)
+		}
+		decoded = append(decoded, ct)
+	}
+
+	if d.off != len(d.data) {
+		return errors.New("trailing data after rythmkey")
+	}
+
+	*rk = decoded
+	return nil
+}
+
+var errMsgpackShort = errors.New("unexpected end of data")
+
+// msgpackDecoder reads the subset of MessagePack rythmkeys are made of.
+type msgpackDecoder struct {
+	data []byte
+	off  int
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if len(d.data)-d.off < n {
+		return nil, errMsgpackShort
+	}
+
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+// length reads the size of an array or map given its fix, 16 and 32 bits
+// type bytes.
+func (d *msgpackDecoder) length(fix, b16, b32 byte) (int, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case b[0]&0xf0 == fix:
+		return int(b[0] & 0x0f), nil
+	case b[0] == b16:
+		b, err := d.next(2)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.BigEndian.Uint16(b)), nil
+	case b[0] == b32:
+		b, err := d.next(4)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+
+	return 0, fmt.Errorf("unexpected type 0x%02x", b[0])
+}
+
+// bytes reads a str or a bin.
+func (d *msgpackDecoder) bytes() ([]byte, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+	switch t := b[0]; {
+	case t&0xe0 == 0xa0:
+		n = int(t & 0x1f)
+	case t == 0xd9 || t == 0xc4:
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		n = int(b[0])
+	case t == 0xda || t == 0xc5:
+		b, err := d.next(2)
+		if err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint16(b))
+	default:
+		return nil, fmt.Errorf("expected a string, got type 0x%02x", t)
+	}
+
+	return d.next(n)
+}
+
+func (d *msgpackDecoder) int() (int64, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+
+	t := b[0]
+	switch {
+	case t <= 0x7f:
+		return int64(t), nil
+	case t >= 0xe0:
+		return int64(int8(t)), nil
+	}
+
+	size := map[byte]int{0xcc: 1, 0xcd: 2, 0xce: 4, 0xcf: 8, 0xd0: 1, 0xd1: 2, 0xd2: 4, 0xd3: 8}[t]
+	if size == 0 {
+		return 0, fmt.Errorf("expected an integer, got type 0x%02x", t)
+	}
+
+	b, err = d.next(size)
+	if err != nil {
+		return 0, err
+	}
+
+	u := uint64(0)
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+
+	if t >= 0xd0 {
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, nil
+	}
+
+	if u > math.MaxInt64 {
+		return 0, errors.New("integer overflows a timing")
+	}
+	return int64(u), nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	for _, rks := range []string{
+		"t0.a",
+		"t0.at120.bt80.c",
+		"t0,00t120,0at80.\"",
+		"us:t0.at120500.b",
+		"^1t0.At120.b^7t80.C",
+		"t0.at1.bt2.ct3.dt4.et5.ft6.gt7.ht8.it9.jt10.kt11.lt12.mt13.nt14.ot15.pt16.q",
+	} {
+		rk := mustParse(t, rks)
+
+		decoded := Rythmkey{}
+		if err := decoded.UnmarshalMsgpack(rk.MarshalMsgpack()); err != nil {
+			t.Errorf("UnmarshalMsgpack(MarshalMsgpack(%s)) failed: %v", rks, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(decoded, rk) {
+			t.Errorf("UnmarshalMsgpack(MarshalMsgpack(%s)) = %s", rks, decoded.Encode())
+		}
+	}
+}
+
+func TestMsgpackMatchesJSON(t *testing.T) {
+	rk := mustParse(t, "t0.at120.bt80.c")
+
+	data, err := json.Marshal(rk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromJSON := Rythmkey{}
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	fromMsgpack := Rythmkey{}
+	if err := fromMsgpack.UnmarshalMsgpack(rk.MarshalMsgpack()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fromMsgpack, fromJSON) {
+		t.Errorf("msgpack decodes to %s, JSON to %s", fromMsgpack.Encode(), fromJSON.Encode())
+	}
+}
+
+// Other encoders pick the smallest integer and may write characters as
+// str.
+func TestUnmarshalMsgpackInterop(t *testing.T) {
+	data := []byte{
+		0x92,
+		0x82, 0xa1, 't', 0x00, 0xa1, 'c', 0xa1, 'a',
+		0x82, 0xa1, 'c', 0xc4, 0x01, 'b', 0xa1, 't', 0xce, 0x07, 0x27, 0x0e, 0x00,
+	}
+
+	fromJSON := Rythmkey{}
+	if err := json.Unmarshal([]byte(`[{"Timing":0,"Char":97},{"Timing":120000000,"Char":98}]`), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	rk := Rythmkey{}
+	if err := rk.UnmarshalMsgpack(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(rk, fromJSON) {
+		t.Errorf("UnmarshalMsgpack = %s, want %s", rk.Encode(), fromJSON.Encode())
+	}
+}
+
+func TestUnmarshalMsgpackInvalid(t *testing.T) {
+	valid := mustParse(t, "t0.at120.b").MarshalMsgpack()
+
+	for name, data := range map[string][]byte{
+		"empty":              {},
+		"truncated":          valid[:len(valid)-1],
+		"trailing data":      append(append([]byte{}, valid...), 0xc0),
+		"not an array":       {0x82},
+		"missing field":      {0x91, 0x81, 0xa1, 't', 0x00},
+		"unknown field":      {0x91, 0x82, 0xa1, 't', 0x00, 0xa1, 'x', 0x00},
+		"long character":     {0x91, 0x82, 0xa1, 't', 0x00, 0xa1, 'c', 0xa2, 'a', 'b'},
+		"string timing":      {0x91, 0x82, 0xa1, 't', 0xa1, '0', 0xa1, 'c', 0xa1, 'a'},
+		"overflow timing":    {0x91, 0x82, 0xa1, 't', 0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xa1, 'c', 0xa1, 'a'},
+		"large modifiers":    {0x91, 0x83, 0xa1, 't', 0x00, 0xa1, 'c', 0xa1, 'a', 0xa1, 'm', 0xcd, 0x01, 0x00},
+		"negative modifiers": {0x91, 0x83, 0xa1, 't', 0x00, 0xa1, 'c', 0xa1, 'a', 0xa1, 'm', 0xff},
+		"string modifiers":   {0x91, 0x83, 0xa1, 't', 0x00, 0xa1, 'c', 0xa1, 'a', 0xa1, 'm', 0xa1, '1'},
+		"only modifiers":     {0x91, 0x82, 0xa1, 't', 0x00, 0xa1, 'm', 0x01},
+	} {
+		rk := Rythmkey{}
+		if err := rk.UnmarshalMsgpack(data); err == nil {
+			t.Errorf("UnmarshalMsgpack(%s) = %s, want an error", name, rk.Encode())
+		}
+	}
+}
+
+func TestParseMsgpack(t *testing.T) {
+	rk := mustParse(t, "t0.at120.bt80.c")
+	scriptStdin(t, string(rk.MarshalMsgpack()))
+
+	result := runApp(t, "parse", "--rythmkey", "-", "--input-format", "msgpack", "--format", "msgpack")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	if result.stdout != string(rk.MarshalMsgpack()) {
+		t.Errorf("parse msgpack to msgpack = %x, want %x", result.stdout, rk.MarshalMsgpack())
+	}
+}
+
+func TestMsgpackModifiers(t *testing.T) {
+	rk := mustParse(t, "t0.a^1t120.B")
+
+	// Characters without modifiers are encoded as before, without "m".
+	want := []byte{
+		0x92,
+		0x82, 0xa1, 't', 0xd3, 0, 0, 0, 0, 0, 0, 0, 0, 0xa1, 'c', 0xc4, 0x01, 'a',
+		0x83, 0xa1, 't', 0xd3, 0, 0, 0, 0, 0x07, 0x27, 0x0e, 0x00, 0xa1, 'c', 0xc4, 0x01, 'B', 0xa1, 'm', 0xcc, 0x01,
+	}
+	if got := rk.MarshalMsgpack(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MarshalMsgpack(%s) = %x, want %x", rk.Encode(), got, want)
+	}
+
+	// Another encoder's smallest integer, and the field left out.
+	decoded := Rythmkey{}
+	if err := decoded.UnmarshalMsgpack([]byte{
+		0x92,
+		0x82, 0xa1, 't', 0x00, 0xa1, 'c', 0xa1, 'a',
+		0x83, 0xa1, 'm', 0x01, 0xa1, 't', 0xce, 0x07, 0x27, 0x0e, 0x00, 0xa1, 'c', 0xa1, 'B',
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, rk) {
+		t.Errorf("UnmarshalMsgpack = %s, want %s", decoded.Encode(), rk.Encode())
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+)
+
+// FieldsInput returns the canonical bytes HashFields digests: each field's
+// HashInput, without the context, prefixed by an 'f', its length and a
+// ':' so no field can be read as the end of another, then the context
+// prefix once over all of them. The order of the fields is part of the
+// input: verifying takes them in the order they were hashed in.
+func FieldsInput(fields []Rythmkey, opts HashOptions) ([]byte, error) {
+	if len(fields) < 2 {
+		return nil, errors.New("at least two fields are required")
+	}
+
+	fieldOpts := opts
+	fieldOpts.Context = ""
+
+	input := []byte{}
+	for _, field := range fields {
+		fi, err := field.HashInput(fieldOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		input = strconv.AppendInt(append(input, 'f'), int64(len(fi)), 10)
+		input = append(append(input, ':'), fi...)
+	}
+
+	if opts.Context != "" {
+		input = append(canonicalContext(opts.Context), input...)
+	}
+
+	return input, nil
+}
+
+// HashFields combines several keys, like a username and a password, into
+// a single digest matching only if every one of them does, see
+// FieldsInput.
+func HashFields(fields []Rythmkey, opts HashOptions) (string, error) {
+	digest, err := FieldsDigest(fields, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return EncodeDigest(digest, opts.Encoding)
+}
+
+// FieldsDigest returns the raw bytes HashFields encodes.
+func FieldsDigest(fields []Rythmkey, opts HashOptions) ([]byte, error) {
+	input, err := FieldsInput(fields, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return digestInput(input, opts), nil
+}
+
+// VerifyFields reports whether fields, in order, hash to hash, a digest in
+// opts.Encoding.
+func VerifyFields(fields []Rythmkey, opts HashOptions, hash string) (bool, error) {
+	digest, err := FieldsDigest(fields, opts)
+	if err != nil {
+		return false, err
+	}
+
+	return matchDigest(digest, hash, opts.Encoding)
+}
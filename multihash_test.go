@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFieldsInputPinned(t *testing.T) {
+	fields := []Rythmkey{mustParse(t, "t0.at123.b"), mustParse(t, "t0.ct7.d")}
+
+	input, err := FieldsInput(fields, HashOptions{Salt: 20, Context: "login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := string(input), "c5:loginf9:t20at140bf8:t20ct20d"; got != want {
+		t.Errorf("FieldsInput = %q, want %q", got, want)
+	}
+}
+
+func TestHashFields(t *testing.T) {
+	user, password := mustParse(t, "t0.at123.b"), mustParse(t, "t0.ct7.d")
+	opts := HashOptions{Salt: 20}
+
+	digest, err := HashFields([]Rythmkey{user, password}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stable across runs and captures within the buckets.
+	if got, want := digest, "c001d77e9a7503228633c15cc90a666ce962de1919fbf264152a481a1ec55665"; got != want {
+		t.Errorf("HashFields = %s, want %s", got, want)
+	}
+	again, err := HashFields([]Rythmkey{mustParse(t, "t0.at127.b"), mustParse(t, "t0.ct12.d")}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != digest {
+		t.Errorf("HashFields within the buckets = %s, want %s", again, digest)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		fields []Rythmkey
+	}{
+		{"swapped", []Rythmkey{password, user}},
+		{"other rhythm", []Rythmkey{user, mustParse(t, "t0.ct70.d")}},
+		// The same characters cut elsewhere.
+		{"other boundary", []Rythmkey{mustParse(t, "t0.at123.bt7.c"), mustParse(t, "t0.d")}},
+		{"extra field", []Rythmkey{mustParse(t, "t0.at123.b"), mustParse(t, "t0.ct7.d"), mustParse(t, "t0.e")}},
+	} {
+		other, err := HashFields(tc.fields, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other == digest {
+			t.Errorf("HashFields %s = %s, same as in order", tc.name, other)
+		}
+
+		ok, err := VerifyFields(tc.fields, opts, digest)
+		if err != nil || ok {
+			t.Errorf("VerifyFields %s = %v, %v, want a reject", tc.name, ok, err)
+		}
+	}
+
+	if ok, err := VerifyFields([]Rythmkey{user, password}, opts, digest); err != nil || !ok {
+		t.Errorf("VerifyFields in order = %v, %v, want an accept", ok, err)
+	}
+
+	if _, err := HashFields([]Rythmkey{user}, opts); err == nil {
+		t.Error("HashFields of a single field succeeded")
+	}
+}
+
+func TestHashMultiCommand(t *testing.T) {
+	scriptStdin(t, "ab\ncd\n")
+	result := runApp(t, "hash-multi")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	hash := strings.TrimSpace(result.stdout)
+
+	hp, err := ParseHashParams(hash)
+	if err != nil {
+		t.Fatalf("ParseHashParams(%s) = %v", hash, err)
+	}
+	if hp.Fields != 2 {
+		t.Errorf("hash-multi hash has %d fields, want 2", hp.Fields)
+	}
+
+	// A single tab separated capture combines like separate prompts.
+	scriptStdin(t, "ab\tcd\n")
+	if result := runApp(t, "hash-multi", "--tab-separated"); strings.TrimSpace(result.stdout) != hash {
+		t.Errorf("hash-multi --tab-separated = %q, %v, want %s", result.stdout, result.err, hash)
+	}
+
+	for _, tc := range []struct {
+		input string
+		code  int
+	}{
+		{"ab\ncd\n", 0},
+		{"cd\nab\n", exitReject},
+	} {
+		scriptStdin(t, tc.input)
+		result := runApp(t, "hash-multi", "--hash", hash)
+		if result.code != tc.code {
+			t.Errorf("hash-multi --hash with %q exited %d, want %d", tc.input, result.code, tc.code)
+		}
+	}
+
+	scriptStdin(t, "ab\ncd\ne\n")
+	if result := runApp(t, "hash-multi", "--fields", "3", "--hash", hash); result.err == nil {
+		t.Error("hash-multi --fields 3 verified a hash of 2 fields")
+	}
+}
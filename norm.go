@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Norm aggregates the timing differences of every character, each in
+// tolerances, into a single distance: 1 is a key typed on the edge of its
+// tolerance. "linf" is the largest difference, so a single character too
+// far off fails the key, while "l1", their mean, and "l2", their root mean
+// square, let precise characters make up for a sloppy one, l2 less so.
+func Norm(norm string, distances []float64) (float64, error) {
+	if len(distances) == 0 {
+		return 0, errors.New("no timing to aggregate")
+	}
+
+	total := 0.0
+	for _, d := range distances {
+		d = math.Abs(d)
+		switch norm {
+		case "l1":
+			total += d
+		case "l2":
+			total += d * d
+		case "linf":
+			total = max(total, d)
+		default:
+			return 0, fmt.Errorf("unknown norm %q", norm)
+		}
+	}
+
+	switch norm {
+	case "l1":
+		return total / float64(len(distances)), nil
+	case "l2":
+		return math.Sqrt(total / float64(len(distances))), nil
+	}
+
+	return total, nil
+}
+
+// normScore turns a norm distance into a score: 1 within tolerance, and
+// the inverse of the distance beyond it, so a threshold t accepts keys up
+// to 1/t tolerances away.
+func normScore(distance float64) float64 {
+	if distance <= 1 {
+		return 1
+	}
+
+	return 1 / distance
+}
+
+// NormScore scores rk against p on the norm of its timing differences
+// rather than on the fraction of characters within tolerance, see Norm
+// and normScore. The character sequences must be identical.
+func (p Profile) NormScore(rk Rythmkey, norm string) (float64, error) {
+	results, err := p.Breakdown(rk)
+	if err != nil {
+		return 0, err
+	}
+
+	distances := make([]float64, len(results))
+	for i, r := range results {
+		distances[i] = r.Diff / r.Tolerance
+	}
+
+	distance, err := Norm(norm, distances)
+	if err != nil {
+		return 0, err
+	}
+
+	return normScore(distance), nil
+}
+
+// WithNorm matches a tolerance comparison on the norm of its timing
+// differences instead of requiring every character within tolerance: the
+// keys match if their characters do and the norm is at most 1. linf gives
+// the same verdict as the comparison itself.
+func (report CompareReport) WithNorm(norm string) (CompareReport, error) {
+	if report.Method != "tolerance" {
+		return CompareReport{}, fmt.Errorf("the %s method has no norm", report.Method)
+	}
+
+	report.Norm = norm
+	if report.TypedLength != report.Length || report.MatchingChars != report.Length {
+		report.Match = false
+		return report, nil
+	}
+
+	distance, err := Norm(norm, report.distances)
+	if err != nil {
+		return CompareReport{}, err
+	}
+
+	report.NormDistance = distance
+	report.Match = distance <= 1
+	return report, nil
+}
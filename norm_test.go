@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNorm(t *testing.T) {
+	distances := []float64{0, 0.5, -0.5, 2}
+
+	for _, tc := range []struct {
+		norm     string
+		distance float64
+	}{
+		{"l1", 0.75},
+		{"l2", math.Sqrt(4.5 / 4)},
+		{"linf", 2},
+	} {
+		d, err := Norm(tc.norm, distances)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(d-tc.distance) > 1e-9 {
+			t.Errorf("Norm(%s, %v) = %v, want %v", tc.norm, distances, d, tc.distance)
+		}
+	}
+
+	if _, err := Norm("l3", distances); err == nil {
+		t.Error("Norm(l3) succeeded")
+	}
+	if _, err := Norm("l1", nil); err == nil {
+		t.Error("Norm of no distance succeeded")
+	}
+}
+
+// The same pair of keys, one character 2.4 tolerances off: its mean over
+// four characters is within tolerance, its root mean square and its
+// maximum aren't.
+func TestWithNormVerdicts(t *testing.T) {
+	reference, typed := mustParse(t, "t0.at100.bt100.ct100.d"), mustParse(t, "t0.at100.bt220.ct100.d")
+	report := Compare(reference, typed, AbsoluteTolerance(50*time.Millisecond))
+	if report.Match {
+		t.Fatal("Compare matched a character out of tolerance")
+	}
+
+	for _, tc := range []struct {
+		norm     string
+		distance float64
+		match    bool
+	}{
+		{"l1", 0.6, true},
+		{"l2", 1.2, false},
+		{"linf", 2.4, false},
+	} {
+		normed, err := report.WithNorm(tc.norm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(normed.NormDistance-tc.distance) > 1e-9 || normed.Match != tc.match {
+			t.Errorf("WithNorm(%s) = %v, %t, want %v, %t", tc.norm, normed.NormDistance, normed.Match, tc.distance, tc.match)
+		}
+	}
+
+	rank, err := CompareRank(typed, mustParse(t, "t0.at100.bt300.ct200.d"), 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rank.WithNorm("l1"); err == nil {
+		t.Error("WithNorm of a rank comparison succeeded")
+	}
+}
+
+func TestNormScoreVerdicts(t *testing.T) {
+	p := mustProfile(t, "t0.at100.bt100.ct100.d", "t0.at110.bt90.ct110.d", "t0.at90.bt110.ct90.d")
+
+	typed := mustParse(t, "t0.at100.bt100.ct100.d")
+	typed[2].Timing = time.Duration((p.Mean[2] + 2.4*p.Tolerance(2)) * float64(time.Millisecond))
+
+	for _, tc := range []struct {
+		norm  string
+		score float64
+	}{
+		{"l1", 1},
+		{"l2", 1 / 1.2},
+		{"linf", 1 / 2.4},
+	} {
+		score, err := p.NormScore(typed, tc.norm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(score-tc.score) > 1e-3 {
+			t.Errorf("NormScore(%s) = %v, want %v", tc.norm, score, tc.score)
+		}
+	}
+}
+
+func TestCompareNormFlag(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at100.bt220.ct100.d")
+
+	for _, tc := range []struct {
+		norm  string
+		match bool
+	}{
+		{"", false},
+		{"l1", true},
+		{"l2", false},
+		{"linf", false},
+	} {
+		result := runApp(t, "compare", "--rythmkey", "t0.at100.bt100.ct100.d", "--tolerance", "50ms", "--norm", tc.norm, "--format", "json")
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+
+		report := CompareReport{}
+		if err := json.Unmarshal([]byte(result.stdout), &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.Match != tc.match || report.Norm != tc.norm {
+			t.Errorf("compare --norm %q matched %t with norm %q, want %t", tc.norm, report.Match, report.Norm, tc.match)
+		}
+	}
+
+	// Rank, curve and exact comparisons have no norm to apply.
+	for _, method := range []string{"rank", "curve", "exact"} {
+		if result := runApp(t, "compare", "--rythmkey", "t0.at100.bt100.ct100.d", "--method", method, "--norm", "l1"); result.err == nil {
+			t.Errorf("compare --method %s --norm l1 succeeded", method)
+		}
+	}
+}
+
+func TestVerifyNormFlag(t *testing.T) {
+	p := mustProfile(t, "t0.at100.bt100.ct100.d", "t0.at110.bt90.ct110.d", "t0.at90.bt110.ct90.d")
+	dir := t.TempDir()
+	saveProfile(t, dir, "user.json", p)
+
+	typed := fmt.Sprintf("t0.at100.bt%d.ct100.d", int(math.Round(p.Mean[2]+2.4*p.Tolerance(2))))
+	t.Setenv(encodedInputEnv, typed)
+
+	for _, tc := range []struct {
+		norm   string
+		accept bool
+	}{
+		{"l1", true},
+		{"l2", false},
+		{"linf", false},
+	} {
+		result := runApp(t, "verify", "--profiles-dir", dir, "--norm", tc.norm, "--threshold", "0.9")
+		if accept := result.err == nil && result.code == 0; accept != tc.accept {
+			t.Errorf("verify --norm %s of %s accepted %t (%v), want %t", tc.norm, typed, accept, result.err, tc.accept)
+		}
+	}
+}
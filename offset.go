@@ -0,0 +1,51 @@
+package main
+
+import (
+	"time"
+)
+
+// Shift returns a copy of the key with offset added to every interval but
+// the first, which stays zero, an interval never going below zero.
+func (rythmkey Rythmkey) Shift(offset time.Duration) Rythmkey {
+	rk := make(Rythmkey, len(rythmkey))
+	for i, ct := range rythmkey {
+		timing := ct.Timing
+		if i > 0 {
+			timing = max(0, timing+offset)
+		}
+		rk[i] = &CharTiming{Timing: timing, Char: ct.Char, Modifiers: ct.Modifiers}
+	}
+
+	return rk
+}
+
+// BestOffset searches the global offset, within bound either way, that
+// shifted onto every interval of rk lines it up best with the reference,
+// so a key typed uniformly slower or faster still matches. It tries no
+// offset and the difference of every pair of intervals with the same
+// character, clamped to the bound, comparing rk shifted by each with
+// compare: the offset with the most characters within tolerance wins, then
+// the smallest one. That's a comparison per character, so the cost is
+// quadratic in the length of the key. Unlike a local alignment every
+// interval is shifted by the same offset.
+func BestOffset(reference Rythmkey, rk Rythmkey, bound time.Duration, compare func(Rythmkey) CompareReport) time.Duration {
+	candidates := []time.Duration{0}
+	for i := 1; i < len(reference) && i < len(rk); i++ {
+		if reference[i].Char == rk[i].Char {
+			candidates = append(candidates, max(-bound, min(bound, reference[i].Timing-rk[i].Timing)))
+		}
+	}
+
+	best, bestWithin := time.Duration(0), -1
+	for _, offset := range candidates {
+		shifted := rk.Shift(offset)
+		within := compare(shifted).WithinTolerance
+		shifted.Zero()
+
+		if within > bestWithin || (within == bestWithin && offset.Abs() < best.Abs()) {
+			best, bestWithin = offset, within
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestShift(t *testing.T) {
+	rk := mustParse(t, "t0.a^1t100.bt5.c")
+
+	if got, want := rk.Shift(-10*time.Millisecond).Encode(), "t0.a^1t90.bt0.c"; got != want {
+		t.Errorf("Shift(-10ms) = %s, want %s", got, want)
+	}
+	if got, want := rk.Shift(20*time.Millisecond).Encode(), "t0.a^1t120.bt25.c"; got != want {
+		t.Errorf("Shift(20ms) = %s, want %s", got, want)
+	}
+	if rk.Encode() != "t0.a^1t100.bt5.c" {
+		t.Errorf("Shift changed its key to %s", rk.Encode())
+	}
+}
+
+// A key typed uniformly 30ms slower than the reference.
+func TestBestOffset(t *testing.T) {
+	reference := mustParse(t, "t0.at100.bt200.ct150.d")
+	typed := mustParse(t, "t0.at130.bt230.ct180.d")
+	compare := func(rk Rythmkey) CompareReport {
+		return Compare(reference, rk, AbsoluteTolerance(20*time.Millisecond))
+	}
+
+	for _, tc := range []struct {
+		bound  time.Duration
+		offset time.Duration
+	}{
+		{100 * time.Millisecond, -30 * time.Millisecond},
+		// Clamped, close enough.
+		{20 * time.Millisecond, -20 * time.Millisecond},
+		// Too far either way, no offset beats none.
+		{5 * time.Millisecond, 0},
+	} {
+		if offset := BestOffset(reference, typed, tc.bound, compare); offset != tc.offset {
+			t.Errorf("BestOffset within %v = %v, want %v", tc.bound, offset, tc.offset)
+		}
+	}
+
+	// The intervals of other characters aren't candidates.
+	if offset := BestOffset(reference, mustParse(t, "t0.xt130.yt230.zt180.w"), 100*time.Millisecond, compare); offset != 0 {
+		t.Errorf("BestOffset of other characters = %v, want 0", offset)
+	}
+}
+
+func TestCompareAlignOffset(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at130.bt230.ct180.d")
+
+	for _, tc := range []struct {
+		bound  string
+		match  bool
+		offset float64
+	}{
+		{"0", false, 0},
+		{"100ms", true, -30},
+	} {
+		result := runApp(t, "compare", "--rythmkey", "t0.at100.bt200.ct150.d", "--tolerance", "20ms", "--align-offset", tc.bound, "--format", "json")
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+
+		report := CompareReport{}
+		if err := json.Unmarshal([]byte(result.stdout), &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.Match != tc.match || report.Offset != tc.offset {
+			t.Errorf("compare --align-offset %s matched %t at %vms, want %t at %vms", tc.bound, report.Match, report.Offset, tc.match, tc.offset)
+		}
+	}
+
+	for _, method := range []string{"rank", "curve", "exact"} {
+		if result := runApp(t, "compare", "--rythmkey", "t0.at100.bt200.ct150.d", "--method", method, "--align-offset", "100ms"); result.err == nil {
+			t.Errorf("compare --method %s --align-offset 100ms succeeded", method)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"time"
+)
+
+const defaultOverheadSamples = 64
+
+// measureReadOverhead estimates the latency a timing includes when the key
+// was typed before the capture loop got to read it: the median time a loop
+// iteration takes to read a byte that is already available, from one read
+// return to the next. It reads from a pipe filled beforehand so no read
+// ever blocks.
+func measureReadOverhead(samples int) (time.Duration, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if _, err := w.Write(make([]byte, samples+1)); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 1)
+	if _, err := r.Read(buf); err != nil {
+		return 0, err
+	}
+	last := time.Now()
+
+	latencies := make([]time.Duration, samples)
+	for i := range latencies {
+		if _, err := r.Read(buf); err != nil {
+			return 0, err
+		}
+		now := time.Now()
+
+		latencies[i] = now.Sub(last)
+		last = now
+	}
+
+	slices.Sort(latencies)
+	return latencies[len(latencies)/2], nil
+}
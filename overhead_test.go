@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeasureReadOverhead(t *testing.T) {
+	overhead, err := measureReadOverhead(defaultOverheadSamples)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading a byte already in a pipe takes microseconds.
+	if overhead < 0 || overhead > 10*time.Millisecond {
+		t.Errorf("measureReadOverhead = %v, want a small latency", overhead)
+	}
+}
+
+func TestCaptureSubtractsReadOverhead(t *testing.T) {
+	const interval = 10 * time.Millisecond
+
+	// The keys are read on schedule, so the total of the timings only
+	// depends on when the first and last ones are, however late the reads
+	// in between run.
+	for _, tc := range []struct {
+		overhead time.Duration
+		total    time.Duration
+	}{
+		{0, 4 * interval},
+		{5 * time.Millisecond, 4 * (interval - 5*time.Millisecond)},
+		// Never below zero.
+		{time.Hour, 0},
+	} {
+		rk := Rythmkey{}
+		opts := ReadOptions{Resolution: time.Microsecond, ReadOverhead: tc.overhead}
+		if err := rk.Capture(&scheduledReader{s: "abcde\n", interval: interval}, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		if total := rk.TotalDuration(); total < tc.total-time.Millisecond || total > tc.total+5*time.Millisecond {
+			t.Errorf("total timing with a %v overhead = %v, want %v", tc.overhead, total, tc.total)
+		}
+		if rk[0].Timing != 0 {
+			t.Errorf("first timing with a %v overhead = %v, want 0", tc.overhead, rk[0].Timing)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A pairing token lets a key captured on another device be submitted to
+// the one verifying it: the verifier generates a token with a secret of
+// its own and hands it to the capturing device, which sends the capture
+// back tied to it. The transport is up to the caller.
+//
+// A token is a random nonce, its expiry and an HMAC of both under the
+// verifier's secret, in base64 URL encoding. The nonce and expiry identify
+// the token, its HMAC is only known to the verifier and to the device it
+// was handed to, which MACs its capture with it: a capture can't be
+// forged, altered or tied to another token by whoever only sees it in
+// transit.
+
+const (
+	pairingNonceSize = 16
+	pairingIDSize    = pairingNonceSize + 8
+	pairingTokenSize = pairingIDSize + sha256.Size
+)
+
+var (
+	errBadToken     = errors.New("invalid pairing token")
+	errExpiredToken = errors.New("expired pairing token")
+	errUsedToken    = errors.New("pairing token already used")
+	errBadCapture   = errors.New("capture doesn't match its pairing token")
+)
+
+// GenerateToken returns a token valid for ttl, signed with secret.
+func GenerateToken(secret []byte, ttl time.Duration) (string, error) {
+	return generateToken(secret, time.Now().Add(ttl))
+}
+
+func generateToken(secret []byte, expiry time.Time) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("a secret is required")
+	}
+
+	id := make([]byte, pairingNonceSize, pairingTokenSize)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	id = binary.BigEndian.AppendUint64(id, uint64(expiry.Unix()))
+
+	return base64.RawURLEncoding.EncodeToString(append(id, signToken(secret, id)...)), nil
+}
+
+func signToken(secret []byte, id []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(id)
+	return mac.Sum(nil)
+}
+
+// ValidateToken checks that token was generated with secret and hasn't
+// expired yet. It can't tell whether the token was used already, see
+// Pairing for single use tokens.
+func ValidateToken(secret []byte, token string) error {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != pairingTokenSize {
+		return errBadToken
+	}
+
+	if !hmac.Equal(signToken(secret, raw[:pairingIDSize]), raw[pairingIDSize:]) {
+		return errBadToken
+	}
+
+	return checkExpiry(raw[:pairingIDSize], time.Now())
+}
+
+func checkExpiry(id []byte, at time.Time) error {
+	expiry := time.Unix(int64(binary.BigEndian.Uint64(id[pairingNonceSize:])), 0)
+	if !at.Before(expiry) {
+		return errExpiredToken
+	}
+
+	return nil
+}
+
+// SubmitCapture ties a captured key to a pairing token, returning what the
+// capturing device sends to the verifier: the token identifier, the key in
+// base64 and an HMAC of both keyed by the token signature, dot separated.
+// The signature itself isn't part of it.
+func SubmitCapture(token string, rk Rythmkey) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != pairingTokenSize {
+		return "", errBadToken
+	}
+
+	message := base64.RawURLEncoding.EncodeToString(raw[:pairingIDSize]) + "." + rk.EncodeBase64()
+	return message + "." + base64.RawURLEncoding.EncodeToString(macCapture(raw[pairingIDSize:], message)), nil
+}
+
+func macCapture(signature []byte, message string) []byte {
+	mac := hmac.New(sha256.New, signature)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// Pairing generates tokens and accepts the capture submitted for each of
+// them once, so a capture can't be replayed before its token expires. It
+// is safe for concurrent use.
+type Pairing struct {
+	secret []byte
+	ttl    time.Duration
+	// now is the clock tokens expire by.
+	now func() time.Time
+
+	mu sync.Mutex
+	// used holds the nonces of the tokens redeemed and not expired yet,
+	// with their expiry.
+	used map[string]time.Time
+}
+
+// NewPairing returns a Pairing with a random secret, its tokens valid for
+// ttl.
+func NewPairing(ttl time.Duration) (*Pairing, error) {
+	secret := make([]byte, sha256.Size)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return &Pairing{secret: secret, ttl: ttl, now: time.Now, used: map[string]time.Time{}}, nil
+}
+
+// GenerateToken returns a new token for a device to submit a capture with.
+func (p *Pairing) GenerateToken() (string, error) {
+	return generateToken(p.secret, p.now().Add(p.ttl))
+}
+
+// Accept returns the key of a capture made by SubmitCapture, if its token
+// was generated by p, hasn't expired and wasn't used yet, and the capture
+// is the one the device MACed. The token is used up once the capture is
+// accepted, a capture that fails to verify doesn't use it.
+func (p *Pairing) Accept(capture string) (Rythmkey, error) {
+	fields := strings.Split(capture, ".")
+	if len(fields) != 3 {
+		return nil, errBadCapture
+	}
+
+	id, err := base64.RawURLEncoding.DecodeString(fields[0])
+	if err != nil || len(id) != pairingIDSize {
+		return nil, errBadToken
+	}
+
+	sum, err := base64.RawURLEncoding.DecodeString(fields[2])
+	if err != nil || !hmac.Equal(macCapture(signToken(p.secret, id), fields[0]+"."+fields[1]), sum) {
+		return nil, errBadCapture
+	}
+
+	at := p.now()
+	if err := checkExpiry(id, at); err != nil {
+		return nil, err
+	}
+
+	rk, err := ParseRythmkeyBase64(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for nonce, expiry := range p.used {
+		if !at.Before(expiry) {
+			delete(p.used, nonce)
+		}
+	}
+
+	nonce := string(id[:pairingNonceSize])
+	if _, ok := p.used[nonce]; ok {
+		rk.Zero()
+		return nil, errUsedToken
+	}
+	p.used[nonce] = time.Unix(int64(binary.BigEndian.Uint64(id[pairingNonceSize:])), 0)
+
+	return rk, nil
+}
+
+// readPaired generates a token, writes it to w for the user to hand to the
+// capturing device, and accepts the capture submitted for it as a line of
+// r.
+func readPaired(p *Pairing, r io.Reader, w io.Writer) (Rythmkey, error) {
+	token, err := p.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(w, "pairing token: %s\n", token)
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	if line == "" {
+		return nil, errors.New("no capture submitted before end of input")
+	}
+
+	return p.Accept(line)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPairing returns a Pairing on a clock the test moves forward.
+func testPairing(t *testing.T, ttl time.Duration) (*Pairing, *time.Time) {
+	t.Helper()
+
+	p, err := NewPairing(ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return clock }
+	return p, &clock
+}
+
+func mustSubmit(t *testing.T, token string, rks string) string {
+	t.Helper()
+
+	capture, err := SubmitCapture(token, mustParse(t, rks))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return capture
+}
+
+func TestPairingLifecycle(t *testing.T) {
+	p, clock := testPairing(t, time.Minute)
+
+	token, err := p.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	capture := mustSubmit(t, token, "t0.at120.bt80.c")
+
+	rk, err := p.Accept(capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rk.Encode() != "t0.at120.bt80.c" {
+		t.Errorf("Accept = %s, want the key submitted", rk.Encode())
+	}
+
+	if _, err := p.Accept(capture); !errors.Is(err, errUsedToken) {
+		t.Errorf("Accept of a replayed capture = %v, want %v", err, errUsedToken)
+	}
+	if _, err := p.Accept(mustSubmit(t, token, "t0.xt10.y")); !errors.Is(err, errUsedToken) {
+		t.Errorf("Accept of another capture for a used token = %v, want %v", err, errUsedToken)
+	}
+
+	// Used tokens are forgotten once expired, as they can't be accepted
+	// anymore.
+	*clock = clock.Add(time.Minute)
+	if _, err := p.Accept(capture); !errors.Is(err, errExpiredToken) {
+		t.Errorf("Accept after expiry = %v, want %v", err, errExpiredToken)
+	}
+
+	fresh, err := p.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Accept(mustSubmit(t, fresh, "t0.a")); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.used) != 1 {
+		t.Errorf("%d used tokens remembered, want only the unexpired one", len(p.used))
+	}
+}
+
+func TestPairingExpiry(t *testing.T) {
+	p, clock := testPairing(t, time.Minute)
+
+	token, err := p.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	capture := mustSubmit(t, token, "t0.at120.b")
+
+	*clock = clock.Add(59 * time.Second)
+	if _, err := p.Accept(capture); err != nil {
+		t.Errorf("Accept before expiry = %v", err)
+	}
+
+	token, err = p.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	capture = mustSubmit(t, token, "t0.at120.b")
+
+	*clock = clock.Add(time.Minute)
+	if _, err := p.Accept(capture); !errors.Is(err, errExpiredToken) {
+		t.Errorf("Accept at expiry = %v, want %v", err, errExpiredToken)
+	}
+}
+
+func TestPairingRejectsForgeries(t *testing.T) {
+	p, _ := testPairing(t, time.Minute)
+	other, _ := testPairing(t, time.Minute)
+
+	token, err := p.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, err := other.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capture := mustSubmit(t, token, "t0.at120.bt80.c")
+	fields := strings.Split(capture, ".")
+	otherFields := strings.Split(mustSubmit(t, otherToken, "t0.at120.bt80.c"), ".")
+	altered := strings.Split(mustSubmit(t, token, "t0.at400.bt80.c"), ".")
+
+	for name, forged := range map[string]string{
+		"key altered":          fields[0] + "." + altered[1] + "." + fields[2],
+		"tied to other token":  otherFields[0] + "." + fields[1] + "." + fields[2],
+		"token of another":     strings.Join(otherFields, "."),
+		"MAC truncated":        fields[0] + "." + fields[1] + "." + fields[2][:8],
+		"missing MAC":          fields[0] + "." + fields[1],
+		"token instead of MAC": fields[0] + "." + fields[1] + "." + token,
+		"garbage":              "not.a.capture",
+	} {
+		if rk, err := p.Accept(forged); err == nil {
+			t.Errorf("Accept(%s) = %s, want an error", name, rk.Encode())
+		}
+	}
+
+	// None of them used the token up.
+	if _, err := p.Accept(capture); err != nil {
+		t.Errorf("Accept after forgeries = %v", err)
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	secret := []byte("verifier secret")
+
+	token, err := GenerateToken(secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateToken(secret, token); err != nil {
+		t.Errorf("ValidateToken = %v", err)
+	}
+
+	if err := ValidateToken([]byte("another secret"), token); !errors.Is(err, errBadToken) {
+		t.Errorf("ValidateToken with another secret = %v, want %v", err, errBadToken)
+	}
+
+	expired, err := GenerateToken(secret, -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateToken(secret, expired); !errors.Is(err, errExpiredToken) {
+		t.Errorf("ValidateToken of an expired token = %v, want %v", err, errExpiredToken)
+	}
+
+	for _, bad := range []string{"", "!!", token[:len(token)-2]} {
+		if err := ValidateToken(secret, bad); !errors.Is(err, errBadToken) {
+			t.Errorf("ValidateToken(%q) = %v, want %v", bad, err, errBadToken)
+		}
+	}
+
+	if _, err := GenerateToken(nil, time.Minute); err == nil {
+		t.Error("GenerateToken without a secret succeeded")
+	}
+	if _, err := SubmitCapture("garbage", mustParse(t, "t0.a")); !errors.Is(err, errBadToken) {
+		t.Errorf("SubmitCapture to a bad token = %v, want %v", err, errBadToken)
+	}
+}
+
+// The device is handed the token readInput writes to stderr and submits
+// its capture on stdin.
+func TestReadInputPaired(t *testing.T) {
+	p, _ := testPairing(t, time.Minute)
+
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdinR.Close()
+
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderrR.Close()
+
+	go func() {
+		defer stdinW.Close()
+
+		line, err := bufio.NewReader(stderrR).ReadString('\n')
+		token, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), "pairing token: ")
+		if err != nil || !ok {
+			return
+		}
+
+		capture, err := SubmitCapture(token, Rythmkey{{Char: 'a'}, {Timing: 120 * time.Millisecond, Char: 'b'}})
+		if err != nil {
+			return
+		}
+		stdinW.WriteString(capture + "\n")
+	}()
+
+	stdin, stderr := os.Stdin, os.Stderr
+	os.Stdin, os.Stderr = stdinR, stderrW
+	rk, err := readInput(context.Background(), ReadOptions{Pairing: p})
+	os.Stdin, os.Stderr = stdin, stderr
+	stderrW.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rk.Encode() != "t0.at120.b" {
+		t.Errorf("readInput = %s, want the key submitted", rk.Encode())
+	}
+}
+
+func TestVerifyPairConflicts(t *testing.T) {
+	for _, args := range [][]string{
+		{"--pair", "-1m"},
+		{"--pair", "1m", "--input-fifo", "fifo"},
+	} {
+		result := runApp(t, append([]string{"verify", "--hash", "00", "--salt", "20"}, args...)...)
+		if result.err == nil {
+			t.Errorf("verify %v succeeded", args)
+		} else if !strings.Contains(result.err.Error(), "pair") {
+			t.Errorf("verify %v failed with %v, want the pairing flags rejected", args, result.err)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TokenKind int
+
+const (
+	// TokenUnit is the unit header, its trailing ':' included.
+	TokenUnit TokenKind = iota
+	// TokenT is the 't' starting a character timing.
+	TokenT
+	// TokenTiming is the digits of a timing.
+	TokenTiming
+	// TokenChar is the character byte.
+	TokenChar
+	// TokenChecksum is the checksum suffix, its leading '!' included.
+	TokenChecksum
+	// TokenModifiers is the modifiers of a character, its leading '^'
+	// included.
+	TokenModifiers
+	// TokenDelimiter is the '.' ending a timing, or the ',' of an escaped
+	// character.
+	TokenDelimiter
+)
+
+func (kind TokenKind) String() string {
+	switch kind {
+	case TokenUnit:
+		return "unit"
+	case TokenT:
+		return "t"
+	case TokenTiming:
+		return "timing"
+	case TokenChar:
+		return "char"
+	case TokenChecksum:
+		return "checksum"
+	case TokenModifiers:
+		return "modifiers"
+	case TokenDelimiter:
+		return "delimiter"
+	}
+
+	return fmt.Sprintf("TokenKind(%d)", int(kind))
+}
+
+// Token is a span of an encoded key, Start and End being byte offsets as
+// in a slice expression.
+type Token struct {
+	Kind  TokenKind
+	Start int
+	End   int
+}
+
+// ParseResult is an encoded key both as the tokens of its grammar and as
+// the key they mean.
+type ParseResult struct {
+	// Rythmkey with its timings normalized to durations, whatever unit
+	// they were encoded in.
+	Rythmkey Rythmkey
+	Unit     time.Duration
+	// Headered tells whether the unit came from a header rather than
+	// being assumed.
+	Headered bool
+	Tokens   []Token
+}
+
+// ParseError locates where an encoded key stops making sense.
+type ParseError struct {
+	Offset int
+	Err    error
+}
+
+func (err *ParseError) Error() string {
+	return fmt.Sprintf("offset %d: %s", err.Offset, err.Err)
+}
+
+func (err *ParseError) Unwrap() error {
+	return err.Err
+}
+
+// delimiterMark ends every timing, so the character after it is never
+// read as a digit of the timing, even when it is a digit itself.
+const delimiterMark = '.'
+
+// Parse strategies resolve where a timing ends and its character starts,
+// which only keys encoded without delimiters leave open:
+//
+//   - ParseAuto reads keys delimited and, when that fails, greedily, so the
+//     keys of .rk, dataset and .samples files written before delimiters
+//     existed still parse. A key that fails both reports the error of the
+//     delimited read. It never reads a valid delimited key otherwise than
+//     ParseDelimited, and only misreads the undelimited keys ParseGreedy
+//     does.
+//   - ParseDelimited requires the delimiter after every timing. It is
+//     unambiguous, and how keys are encoded, but it rejects keys encoded
+//     before delimiters existed. Only it reads escaped characters.
+//   - ParseGreedy reads every digit after the 't' as the timing, the
+//     character being the byte after them: keys without delimiters parse
+//     as they always have, and a key holding a digit fails to parse.
+//   - ParseMinimal reads like ParseGreedy, except that when the digits run
+//     to the end of the key or up to a 't' followed by a digit, neither of
+//     which a greedy read accepts, their last one is the character instead.
+//     It reads every key ParseGreedy does the same way, and those holding
+//     digits but for a digit right before a '^' or '!', which are then
+//     read as the character rather than as modifiers or a checksum.
+const (
+	ParseAuto      = "auto"
+	ParseDelimited = "delimited"
+	ParseGreedy    = "greedy"
+	ParseMinimal   = "minimal"
+)
+
+// parseStrategy is how encoded keys are parsed, set by the global
+// --parse-strategy flag.
+var parseStrategy = ParseAuto
+
+// ParseDetailed parses an encoded key like ParseRythmkey, also returning
+// the position of every token and reporting errors as a *ParseError. A key
+// ending in a checksum, see EncodeChecksum, must match it.
+func ParseDetailed(rks string) (ParseResult, error) {
+	return ParseDetailedAssuming(rks, time.Millisecond)
+}
+
+// ParseDetailedAssuming parses like ParseDetailed, but the timings of a
+// key without a unit header are in unit rather than milliseconds.
+func ParseDetailedAssuming(rks string, unit time.Duration) (ParseResult, error) {
+	return parseDetailed(rks, unit, false)
+}
+
+// parseDetailed rejects timings with leading zeros unless lenient, which
+// has them normalized, see Rythmkey.
+func parseDetailed(rks string, unit time.Duration, lenient bool) (ParseResult, error) {
+	if parseStrategy != ParseAuto {
+		return parseWith(rks, unit, lenient, parseStrategy)
+	}
+
+	result, err := parseWith(rks, unit, lenient, ParseDelimited)
+	if err != nil {
+		if greedy, greedyErr := parseWith(rks, unit, lenient, ParseGreedy); greedyErr == nil {
+			return greedy, nil
+		}
+	}
+
+	return result, err
+}
+
+// parseWith parses rks with strategy, which can't be ParseAuto.
+func parseWith(rks string, unit time.Duration, lenient bool, strategy string) (ParseResult, error) {
+	if len(rks) == 0 {
+		return ParseResult{}, &ParseError{0, errors.New("empty rythmkey")}
+	}
+
+	result := ParseResult{Rythmkey: Rythmkey{}, Unit: unit}
+
+	i := 0
+	if header, _, ok := strings.Cut(rks, ":"); ok {
+		if u, err := ParseUnit(header); err == nil {
+			result.Unit = u
+			result.Headered = true
+			i = len(header) + 1
+			result.Tokens = append(result.Tokens, Token{TokenUnit, 0, i})
+		}
+	}
+
+	if i >= len(rks) || (rks[i] != 't' && rks[i] != modifiersMark) {
+		return ParseResult{}, &ParseError{i, errors.New("rythmkey char timing must start with a t")}
+	}
+
+	for i < len(rks) {
+		if rks[i] == checksumMark {
+			if err := verifyChecksum(rks[:i], rks[i+1:]); err != nil {
+				return ParseResult{}, &ParseError{i, err}
+			}
+			result.Tokens = append(result.Tokens, Token{TokenChecksum, i, len(rks)})
+			break
+		}
+
+		modifiers := Modifiers(0)
+		if rks[i] == modifiersMark {
+			j := i + 1
+			for j < len(rks) && rks[j] >= '0' && rks[j] <= '9' {
+				j++
+			}
+
+			mask, err := strconv.ParseUint(rks[i+1:j], 10, 8)
+			if err != nil {
+				return ParseResult{}, &ParseError{i + 1, fmt.Errorf("bad modifiers: %w", err)}
+			}
+			if !lenient && (mask == 0 || rks[i+1] == '0') {
+				return ParseResult{}, &ParseError{i + 1, errors.New("modifiers must be a non zero mask without leading zeros")}
+			}
+			modifiers = Modifiers(mask)
+			result.Tokens = append(result.Tokens, Token{TokenModifiers, i, j})
+			i = j
+
+			if i >= len(rks) || rks[i] != 't' {
+				return ParseResult{}, &ParseError{i, errors.New("modifiers must be followed by a char timing")}
+			}
+		}
+
+		if rks[i] != 't' {
+			return ParseResult{}, &ParseError{i, errors.New("bad chartiming start")}
+		}
+		result.Tokens = append(result.Tokens, Token{TokenT, i, i + 1})
+		i++
+
+		j := i
+		for j < len(rks) && isDigit(rks[j]) {
+			j++
+		}
+
+		if strategy == ParseMinimal && j-i > 1 && (j == len(rks) || rks[j] == 't' && j+1 < len(rks) && isDigit(rks[j+1])) {
+			j--
+		}
+
+		if j >= len(rks) {
+			return ParseResult{}, &ParseError{j, errors.New("missing data after timing")}
+		}
+
+		if j-i > 1 && rks[i] == '0' && !lenient {
+			return ParseResult{}, &ParseError{i, errors.New("timing has leading zeros")}
+		}
+
+		timing, err := strconv.ParseInt(rks[i:j], 10, 64)
+		if err != nil {
+			return ParseResult{}, &ParseError{i, err}
+		}
+		result.Tokens = append(result.Tokens, Token{TokenTiming, i, j})
+
+		c := j
+		escaped := false
+		if strategy == ParseDelimited {
+			if rks[j] != delimiterMark && rks[j] != escapeMark {
+				return ParseResult{}, &ParseError{j, errors.New("timing must end with a '.', parse keys encoded without delimiters with --parse-strategy greedy or minimal")}
+			}
+			escaped = rks[j] == escapeMark
+			result.Tokens = append(result.Tokens, Token{TokenDelimiter, j, j + 1})
+			c++
+
+			if c >= len(rks) {
+				return ParseResult{}, &ParseError{c, errors.New("missing data after timing")}
+			}
+		}
+
+		char, end := rks[c], c+1
+		if escaped {
+			char, end, err = parseEscaped(rks, c, lenient)
+			if err != nil {
+				return ParseResult{}, &ParseError{c, err}
+			}
+		}
+		result.Tokens = append(result.Tokens, Token{TokenChar, c, end})
+
+		result.Rythmkey = append(result.Rythmkey, &CharTiming{
+			Timing:    time.Duration(timing) * result.Unit,
+			Char:      char,
+			Modifiers: modifiers,
+		})
+		i = end
+	}
+
+	return result, nil
+}
+
+// parseEscaped parses the two hex digits of an escaped character at
+// rks[i:], returning it and where it ends. Unless lenient, they must be
+// lowercase and escape a character Encode escapes, so the key encodes back
+// to the same text.
+func parseEscaped(rks string, i int, lenient bool) (byte, int, error) {
+	if i+2 > len(rks) {
+		return 0, 0, errors.New("escaped character must be two hex digits")
+	}
+
+	c, err := strconv.ParseUint(rks[i:i+2], 16, 8)
+	if err != nil {
+		return 0, 0, errors.New("escaped character must be two hex digits")
+	}
+
+	if !lenient && (strings.ToLower(rks[i:i+2]) != rks[i:i+2] || !needsEscape(byte(c))) {
+		return 0, 0, fmt.Errorf("%q isn't escaped as encoded", rks[i:i+2])
+	}
+
+	return byte(c), i + 2, nil
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// checksumMark starts the checksum suffix. It can't be mistaken for a
+// character as it stands where a 't' is expected.
+const checksumMark = '!'
+
+// EncodeChecksum is Encode followed by a '!' and the CRC32 of the encoded
+// key in hexadecimal, so corruption is detected when parsing it back.
+func (rythmkey Rythmkey) EncodeChecksum() string {
+	encoded := rythmkey.Encode()
+	return fmt.Sprintf("%s%c%08x", encoded, checksumMark, crc32.ChecksumIEEE([]byte(encoded)))
+}
+
+func verifyChecksum(encoded string, checksum string) error {
+	sum, err := strconv.ParseUint(checksum, 16, 32)
+	if err != nil || len(checksum) != 8 {
+		return fmt.Errorf("malformed checksum %q", checksum)
+	}
+
+	if uint32(sum) != crc32.ChecksumIEEE([]byte(encoded)) {
+		return errors.New("checksum mismatch")
+	}
+
+	return nil
+}
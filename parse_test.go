@@ -0,0 +1,399 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDetailedTokens(t *testing.T) {
+	result, err := ParseDetailed("us:^1t0.at120,0at7.b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Token{
+		{TokenUnit, 0, 3},
+		{TokenModifiers, 3, 5},
+		{TokenT, 5, 6},
+		{TokenTiming, 6, 7},
+		{TokenDelimiter, 7, 8},
+		{TokenChar, 8, 9},
+		{TokenT, 9, 10},
+		{TokenTiming, 10, 13},
+		{TokenDelimiter, 13, 14},
+		{TokenChar, 14, 16},
+		{TokenT, 16, 17},
+		{TokenTiming, 17, 18},
+		{TokenDelimiter, 18, 19},
+		{TokenChar, 19, 20},
+	}
+	if !reflect.DeepEqual(result.Tokens, want) {
+		t.Errorf("ParseDetailed tokens = %v, want %v", result.Tokens, want)
+	}
+
+	if result.Unit != time.Microsecond || !result.Headered {
+		t.Errorf("ParseDetailed unit = %v (headered %t), want a µs header", result.Unit, result.Headered)
+	}
+
+	if got, want := result.Rythmkey.Encode(), "us:^1t0.at120,0at7.b"; got != want {
+		t.Errorf("ParseDetailed key = %s, want %s", got, want)
+	}
+}
+
+func TestParseDetailedChecksumToken(t *testing.T) {
+	rks := mustParse(t, "t0.at12.b").EncodeChecksum()
+
+	result, err := ParseDetailed(rks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := result.Tokens[len(result.Tokens)-1]
+	if want := (Token{TokenChecksum, len("t0.at12.b"), len(rks)}); last != want {
+		t.Errorf("ParseDetailed(%s) last token = %v, want %v", rks, last, want)
+	}
+}
+
+func TestParseDetailedErrorOffset(t *testing.T) {
+	for _, tc := range []struct {
+		rks    string
+		offset int
+	}{
+		{"", 0},
+		{"a", 0},
+		{"t0.at12", 7},
+		{"t0.at012.b", 5},
+		{"t0.at12b", 7},
+		{"t0.a^1", 6},
+	} {
+		_, err := ParseDetailed(tc.rks)
+
+		parseErr := &ParseError{}
+		if !errors.As(err, &parseErr) {
+			t.Errorf("ParseDetailed(%q) error = %v, want a *ParseError", tc.rks, err)
+			continue
+		}
+
+		if parseErr.Offset != tc.offset {
+			t.Errorf("ParseDetailed(%q) failed at offset %d, want %d: %v", tc.rks, parseErr.Offset, tc.offset, err)
+		}
+	}
+}
+
+func TestChecksumRoundTrip(t *testing.T) {
+	rk := mustParse(t, "us:t0.at120500.b^1t80.c")
+
+	parsed, err := ParseRythmkey(rk.EncodeChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, rk) {
+		t.Errorf("ParseRythmkey(%s) = %s", rk.EncodeChecksum(), parsed.Encode())
+	}
+
+	// Keys without a checksum parse as before.
+	if _, err := ParseRythmkey(rk.Encode()); err != nil {
+		t.Errorf("ParseRythmkey(%s) = %v", rk.Encode(), err)
+	}
+}
+
+func TestChecksumDetectsCorruption(t *testing.T) {
+	rks := mustParse(t, "t0.at120.bt80.c").EncodeChecksum()
+	sum := strings.IndexByte(rks, checksumMark)
+
+	for i := 0; i < len(rks); i++ {
+		for _, c := range []byte("0159abt.") {
+			if rks[i] == c || i == sum {
+				continue
+			}
+			corrupted := rks[:i] + string(c) + rks[i+1:]
+
+			if rk, err := ParseRythmkey(corrupted); err == nil {
+				t.Errorf("ParseRythmkey(%s) = %s, want the corruption detected", corrupted, rk.Encode())
+			}
+		}
+	}
+
+	// A flipped timing digit still parses, only the checksum catches it.
+	corrupted := strings.Replace(rks, "t120", "t170", 1)
+	if _, err := ParseRythmkey(corrupted); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("ParseRythmkey(%s) = %v, want a checksum mismatch", corrupted, err)
+	}
+
+	for _, malformed := range []string{rks[:len(rks)-1], rks + "0", rks[:sum+1] + "zzzzzzzz"} {
+		if _, err := ParseRythmkey(malformed); err == nil {
+			t.Errorf("ParseRythmkey(%s) accepted a malformed checksum", malformed)
+		}
+	}
+}
+
+func TestReadChecksum(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at120.bt80.c")
+
+	result := runApp(t, "read", "--checksum")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	if want := mustParse(t, "t0.at120.bt80.c").EncodeChecksum(); result.stdout != want {
+		t.Errorf("read --checksum = %q, want %q", result.stdout, want)
+	}
+}
+
+func TestParseLeadingZeros(t *testing.T) {
+	for _, tc := range []struct {
+		rks       string
+		canonical string
+	}{
+		{"t007.at120.b", "t7.at120.b"},
+		{"t0.at0120.b", "t0.at120.b"},
+		{"t0.at00.b", "t0.at0.b"},
+		{"us:t0.at0120500.b", "us:t0.at120500.b"},
+	} {
+		// Strict parsing rejects them, at the timing.
+		_, err := ParseRythmkey(tc.rks)
+		perr := &ParseError{}
+		if !errors.As(err, &perr) || !strings.Contains(err.Error(), "leading zeros") {
+			t.Errorf("ParseRythmkey(%s) = %v, want a leading zeros error", tc.rks, err)
+		}
+
+		// Lenient parsing normalizes them, the key encoding to its
+		// canonical form, which round trips.
+		rk, err := ParseLenient(tc.rks)
+		if err != nil {
+			t.Fatalf("ParseLenient(%s): %v", tc.rks, err)
+		}
+		if rk.Encode() != tc.canonical {
+			t.Errorf("ParseLenient(%s) encodes to %s, want %s", tc.rks, rk.Encode(), tc.canonical)
+		}
+		if parsed := mustParse(t, rk.Encode()); !reflect.DeepEqual(parsed, rk) {
+			t.Errorf("canonical %s doesn't round trip: %s", rk.Encode(), parsed.Encode())
+		}
+	}
+
+	// A lone zero isn't a leading one.
+	if _, err := ParseRythmkey("t0.at0.b"); err != nil {
+		t.Errorf("ParseRythmkey(t0.at0.b) = %v", err)
+	}
+}
+
+func TestChecksumLeadingZeros(t *testing.T) {
+	// A checksum computed over the text with leading zeros guards that
+	// text: lenient parsing verifies it, then the key gets a checksum of
+	// its canonical form.
+	const text = "t007.at120.b"
+	withSum := fmt.Sprintf("%s!%08x", text, crc32.ChecksumIEEE([]byte(text)))
+
+	if _, err := ParseRythmkey(withSum); err == nil {
+		t.Errorf("ParseRythmkey(%s) succeeded", withSum)
+	}
+
+	rk, err := ParseLenient(withSum)
+	if err != nil {
+		t.Fatalf("ParseLenient(%s): %v", withSum, err)
+	}
+	if rk.EncodeChecksum() == withSum {
+		t.Errorf("EncodeChecksum = %s, want the checksum of the canonical form", rk.EncodeChecksum())
+	}
+	if _, err := ParseRythmkey(rk.EncodeChecksum()); err != nil {
+		t.Errorf("ParseRythmkey(%s) = %v", rk.EncodeChecksum(), err)
+	}
+
+	// The checksum of the canonical form doesn't match the text with
+	// leading zeros, which isn't the text it was computed over.
+	corrupt := "t007.at120.b" + rk.EncodeChecksum()[len(rk.Encode()):]
+	if _, err := ParseLenient(corrupt); err == nil {
+		t.Errorf("ParseLenient(%s) succeeded", corrupt)
+	}
+}
+
+func TestParseCommandLenient(t *testing.T) {
+	if result := runApp(t, "parse", "--rythmkey", "t007.at120.b"); result.err == nil {
+		t.Error("parse of leading zeros succeeded")
+	}
+
+	result := runApp(t, "parse", "--rythmkey", "t007.at120.b", "--lenient", "--format", "vector")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if !strings.Contains(result.stdout, "7,120") {
+		t.Errorf("parse --lenient = %q, want the timings 7 and 120", result.stdout)
+	}
+}
+
+// withParseStrategy parses keys with strategy until the test ends.
+func withParseStrategy(t *testing.T, strategy string) {
+	t.Helper()
+
+	saved := parseStrategy
+	parseStrategy = strategy
+	t.Cleanup(func() { parseStrategy = saved })
+}
+
+func TestParseStrategies(t *testing.T) {
+	// The keys each strategy reads, "" when it fails to.
+	for _, tc := range []struct {
+		rks                              string
+		auto, delimited, greedy, minimal string
+	}{
+		// Delimited, a digit typed.
+		{"t0.at120.1t80.b", "t0.at120.1t80.b", "t0.at120.1t80.b", "", ""},
+		{"t0.1t5.2", "t0.1t5.2", "t0.1t5.2", "", ""},
+		// Undelimited, without digits: the same key whichever way it's read.
+		{"t0at120bt80c", "t0.at120.bt80.c", "", "t0.at120.bt80.c", "t0.at120.bt80.c"},
+		// Undelimited and a digit typed: a greedy read swallows it in the
+		// timing, a minimal one gives it back.
+		{"t0at1201t80b", "", "", "", "t0.at120.1t80.b"},
+		{"t0at1205", "", "", "", "t0.at120.5"},
+		// Both encodings mixed parse neither way.
+		{"t0.at1201t80b", "", "", "", ""},
+	} {
+		for _, s := range []struct {
+			strategy, want string
+		}{
+			{ParseAuto, tc.auto},
+			{ParseDelimited, tc.delimited},
+			{ParseGreedy, tc.greedy},
+			{ParseMinimal, tc.minimal},
+		} {
+			withParseStrategy(t, s.strategy)
+
+			rk, err := ParseRythmkey(tc.rks)
+			switch {
+			case s.want == "" && err == nil:
+				t.Errorf("%s: ParseRythmkey(%s) = %s, want an error", s.strategy, tc.rks, rk.Encode())
+			case s.want != "" && err != nil:
+				t.Errorf("%s: ParseRythmkey(%s) = %v, want %s", s.strategy, tc.rks, err, s.want)
+			case s.want != "" && rk.Encode() != s.want:
+				t.Errorf("%s: ParseRythmkey(%s) = %s, want %s", s.strategy, tc.rks, rk.Encode(), s.want)
+			}
+		}
+	}
+}
+
+// A key of 't', the mark starting every character, or of the other marks
+// of the encoding, reads back character by character: the character is
+// the byte after the timing and its delimiter, never taken for the start
+// of the next one.
+func TestParseMarksAsCharacters(t *testing.T) {
+	timings := []time.Duration{0, 120 * time.Millisecond, 7 * time.Millisecond, 0, 1500 * time.Microsecond}
+
+	for _, chars := range []string{"ttttt", "t.t.t", "t^!t.", "tt:tt"} {
+		rk := Rythmkey{}
+		for i := range chars {
+			rk.Add(chars[i], timings[i])
+		}
+		rk[3].Modifiers = ModShift
+
+		for _, encoded := range []string{rk.Encode(), rk.EncodeChecksum()} {
+			for _, strategy := range []string{ParseAuto, ParseDelimited} {
+				withParseStrategy(t, strategy)
+
+				parsed, err := ParseRythmkey(encoded)
+				if err != nil {
+					t.Errorf("%s: ParseRythmkey(%s) = %v", strategy, encoded, err)
+					continue
+				}
+				if parsed.Chars() != chars || parsed.Encode() != rk.Encode() || parsed[3].Modifiers != ModShift {
+					t.Errorf("%s: ParseRythmkey(%s) = %s, want %s", strategy, encoded, parsed.Encode(), rk.Encode())
+				}
+			}
+		}
+	}
+
+	// Undelimited too, as keys were encoded before delimiters.
+	for _, strategy := range []string{ParseAuto, ParseGreedy, ParseMinimal} {
+		withParseStrategy(t, strategy)
+
+		rk, err := ParseRythmkey("t0tt120tt7tt0tt300t")
+		if err != nil || rk.Encode() != "t0.tt120.tt7.tt0.tt300.t" {
+			t.Errorf("%s: ParseRythmkey of an undelimited ttttt = %s, %v", strategy, rk.Encode(), err)
+		}
+	}
+
+	withParseStrategy(t, ParseDelimited)
+	result, err := ParseDetailed("t0.tt120.t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Token{
+		{TokenT, 0, 1},
+		{TokenTiming, 1, 2},
+		{TokenDelimiter, 2, 3},
+		{TokenChar, 3, 4},
+		{TokenT, 4, 5},
+		{TokenTiming, 5, 8},
+		{TokenDelimiter, 8, 9},
+		{TokenChar, 9, 10},
+	}
+	if !reflect.DeepEqual(result.Tokens, want) {
+		t.Errorf("ParseDetailed(t0.tt120.t) tokens = %v, want %v", result.Tokens, want)
+	}
+}
+
+func TestReadMarksAsCharacters(t *testing.T) {
+	scriptStdin(t, "ttttt\n")
+	result := runApp(t, "read")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	encoded := strings.TrimSpace(result.stdout)
+	rk, err := ParseRythmkey(encoded)
+	if err != nil || rk.Chars() != "ttttt" {
+		t.Errorf("ParseRythmkey(%s) of a read ttttt = %q, %v", encoded, rk.Chars(), err)
+	}
+}
+
+// Keys encoded before delimiters existed still parse with the default
+// strategy, the error of a key that parses neither way being the delimited
+// one.
+func TestParseAutoFallback(t *testing.T) {
+	withParseStrategy(t, ParseAuto)
+
+	dir := writeSamples(t, "t0at120bt80c", "t0.at130.bt90.c")
+	samples, err := LoadSamples(dir)
+	if err != nil {
+		t.Fatalf("LoadSamples of legacy samples = %v", err)
+	}
+	if len(samples) != 2 || samples[0].Encode() != "t0.at120.bt80.c" || samples[1].Encode() != "t0.at130.bt90.c" {
+		t.Errorf("LoadSamples = %v, want both keys", samples)
+	}
+
+	_, err = ParseRythmkey("t0.at120bt80.c")
+	if err == nil || !strings.Contains(err.Error(), "must end with a '.'") {
+		t.Errorf("ParseRythmkey of a key mixing both encodings = %v, want the delimiter error", err)
+	}
+}
+
+func TestParseStrategyFlag(t *testing.T) {
+	for _, tc := range []struct {
+		strategy string
+		ok       bool
+	}{
+		{"", true},
+		{ParseAuto, true},
+		{ParseDelimited, false},
+		{ParseGreedy, true},
+		{ParseMinimal, true},
+	} {
+		args := []string{"parse", "--rythmkey", "t0at120bt80c", "--format", "vector"}
+		if tc.strategy != "" {
+			args = append([]string{"--parse-strategy", tc.strategy}, args...)
+		}
+
+		result := runApp(t, args...)
+		if ok := result.err == nil && strings.Contains(result.stdout, "0,120,80"); ok != tc.ok {
+			t.Errorf("--parse-strategy %q parse of an undelimited key = %q, %v, want ok %t", tc.strategy, result.stdout, result.err, tc.ok)
+		}
+	}
+
+	if result := runApp(t, "--parse-strategy", "lazy", "parse", "--rythmkey", "t0.a"); result.err == nil {
+		t.Error("--parse-strategy lazy succeeded")
+	}
+}
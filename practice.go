@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// PracticeTracker scores every attempt against the previous one, the
+// consistency of a rhythm before enrolling it. Only the last attempt is
+// kept, in memory.
+type PracticeTracker struct {
+	previous Rythmkey
+}
+
+// Add an attempt and return its score against the previous one, as if the
+// previous attempt were a profile of a single sample. ok is false for the
+// first attempt, or when the characters differ from the previous attempt,
+// which then is replaced by this one.
+func (t *PracticeTracker) Add(rk Rythmkey) (score float64, ok bool, err error) {
+	previous := t.previous
+	t.previous = rk
+	if previous == nil {
+		return 0, false, nil
+	}
+	defer previous.Zero()
+
+	p, err := NewProfile([]Rythmkey{previous}, 0)
+	if err != nil {
+		return 0, false, err
+	}
+
+	score, err = p.Score(rk)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return score, true, nil
+}
+
+// Zero overwrites the last attempt, see Rythmkey.Zero.
+func (t *PracticeTracker) Zero() {
+	t.previous.Zero()
+	t.previous = nil
+}
+
+// Practice captures attempts until ctrl-d and reports on w the score of
+// each of them against the previous one. Nothing is stored.
+func Practice(ro ReadOptions, w io.Writer) error {
+	defer stty("-cbreak", "echo").Run()
+
+	tracker := PracticeTracker{}
+	defer tracker.Zero()
+
+	for n := 1; ; n++ {
+		rk, err := readPrompted(fmt.Sprintf("attempt %d: ", n), ro)
+		if err != nil {
+			return err
+		}
+
+		if endOfInput(rk) {
+			rk.Zero()
+			return nil
+		}
+
+		score, ok, err := tracker.Add(rk)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: attempt %d not scored: %v\n", n, err)
+			continue
+		}
+
+		if ok {
+			fmt.Fprintf(w, "attempt %d: score %.2f against attempt %d\n", n, score, n-1)
+		}
+	}
+}
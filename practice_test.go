@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPracticeTracker(t *testing.T) {
+	tracker := PracticeTracker{}
+
+	for i, tc := range []struct {
+		rks   string
+		ok    bool
+		err   bool
+		score float64
+	}{
+		// Nothing to score the first attempt against.
+		{"t0.at100.bt100.c", false, false, 0},
+		{"t0.at100.bt100.c", true, false, 1},
+		{"t0.at100.bt300.c", true, false, 2.0 / 3},
+		// Other characters aren't scored, but start over.
+		{"t0.at100.bt100.x", false, true, 0},
+		{"t0.at100.bt110.x", true, false, 1},
+	} {
+		previous := tracker.previous
+		score, ok, err := tracker.Add(mustParse(t, tc.rks))
+		if (err != nil) != tc.err || ok != tc.ok || math.Abs(score-tc.score) > 1e-9 {
+			t.Errorf("attempt %d, Add(%s) = %v, %t, %v, want %v, %t, error %t", i+1, tc.rks, score, ok, err, tc.score, tc.ok, tc.err)
+		}
+
+		// Only the last attempt is kept, the one before is zeroed.
+		if tracker.previous.Encode() != tc.rks {
+			t.Errorf("attempt %d, kept %s, want %s", i+1, tracker.previous.Encode(), tc.rks)
+		}
+		if previous != nil {
+			requireZeroed(t, "previous attempt", previous)
+		}
+	}
+
+	last := tracker.previous
+	tracker.Zero()
+	requireZeroed(t, "last attempt", last)
+	if tracker.previous != nil {
+		t.Error("Zero kept the last attempt")
+	}
+}
+
+func TestPracticeCommand(t *testing.T) {
+	scriptStdin(t, "abc\nabc\nabd\nabd\n")
+	result := runApp(t, "practice")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	for _, want := range []string{"attempt 2: score 1.00 against attempt 1", "attempt 4: score 1.00 against attempt 3"} {
+		if !strings.Contains(result.stdout, want) {
+			t.Errorf("practice wrote %q, want %q", result.stdout, want)
+		}
+	}
+	if strings.Contains(result.stdout, "attempt 3: score") || !strings.Contains(result.stderr, "attempt 3 not scored") {
+		t.Errorf("practice scored attempt 3 with other characters: %q, %q", result.stdout, result.stderr)
+	}
+}
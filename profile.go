@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	defaultThreshold       = 0.8
+	defaultToleranceFactor = 2.0
+	minTolerance           = 30.0
+)
+
+// Profile is the enrolled reference of a rythmkey: the typed characters
+// and, for each of them, the mean and standard deviation of its timing in
+// milliseconds.
+type Profile struct {
+	Chars  string    `json:"chars"`
+	Mean   []float64 `json:"mean"`
+	Stddev []float64 `json:"stddev"`
+	// Min and Max are the range of the timings enrolled, for bands. Older
+	// profiles don't have them.
+	Min []float64 `json:"min,omitempty"`
+	Max []float64 `json:"max,omitempty"`
+	// Unit the samples were captured at.
+	Unit string `json:"unit,omitempty"`
+	// Salt keys of this rythmkey are hashed with, as recommended by setup
+	// or given to enroll, 0 when unknown.
+	Salt int `json:"salt,omitempty"`
+	// Smooth is the window the timings were normalized over with a moving
+	// average when captured, see ReadOptions.Smooth. Keys verified
+	// against the profile must be smoothed the same. 0 or 1 for none.
+	Smooth int `json:"smooth,omitempty"`
+	// Dwell and DwellStddev are the mean and standard deviation of how
+	// long every character was held, in milliseconds, for profiles
+	// enrolled to match on them, see AddDwell.
+	Dwell       []float64 `json:"dwell,omitempty"`
+	DwellStddev []float64 `json:"dwell_stddev,omitempty"`
+	// MatchOn is what keys are matched on against the profile, MatchFlight
+	// when empty. Verify must match on the same, see MatchScore.
+	MatchOn string `json:"match,omitempty"`
+}
+
+type NamedProfile struct {
+	Name    string
+	Profile Profile
+}
+
+func NewProfile(samples []Rythmkey, unit time.Duration) (Profile, error) {
+	if len(samples) == 0 {
+		return Profile{}, errors.New("no samples to build a profile from")
+	}
+
+	chars := samples[0].Chars()
+	for _, sample := range samples[1:] {
+		if sample.Chars() != chars {
+			return Profile{}, errors.New("samples don't share the same characters")
+		}
+	}
+
+	p := Profile{
+		Chars:  chars,
+		Mean:   make([]float64, len(chars)),
+		Stddev: make([]float64, len(chars)),
+		Min:    make([]float64, len(chars)),
+		Max:    make([]float64, len(chars)),
+		Unit:   unitName(unit),
+	}
+
+	for i := range chars {
+		p.Min[i], p.Max[i] = math.Inf(1), math.Inf(-1)
+		for _, sample := range samples {
+			p.Mean[i] += milliseconds(sample[i].Timing)
+			p.Min[i] = math.Min(p.Min[i], milliseconds(sample[i].Timing))
+			p.Max[i] = math.Max(p.Max[i], milliseconds(sample[i].Timing))
+		}
+		p.Mean[i] /= float64(len(samples))
+
+		for _, sample := range samples {
+			d := milliseconds(sample[i].Timing) - p.Mean[i]
+			p.Stddev[i] += d * d
+		}
+		p.Stddev[i] = math.Sqrt(p.Stddev[i] / float64(len(samples)))
+	}
+
+	return p, nil
+}
+
+// LoadProfile loads a profile, or computes it from a raw samples file, see
+// SaveRawSamples.
+func LoadProfile(path string) (Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	p := Profile{}
+	if isRawSamples(data) {
+		p, err = profileFromRawSamples(data)
+		if err != nil {
+			return Profile{}, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if err := json.Unmarshal(data, &p); err != nil {
+		return Profile{}, err
+	}
+
+	if err := p.Validate(); err != nil {
+		return Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return p, nil
+}
+
+// Validate checks the invariants of a profile a corrupt or hand edited
+// file could break: a mean and a standard deviation per character, a
+// range per character if any, finite and non-negative timings, and a
+// known unit.
+func (p Profile) Validate() error {
+	if len(p.Chars) == 0 {
+		return errors.New("profile has no characters")
+	}
+
+	if len(p.Mean) != len(p.Chars) || len(p.Stddev) != len(p.Chars) {
+		return fmt.Errorf("profile has %d characters but %d means and %d standard deviations", len(p.Chars), len(p.Mean), len(p.Stddev))
+	}
+
+	if (len(p.Min) > 0 || len(p.Max) > 0) && (len(p.Min) != len(p.Chars) || len(p.Max) != len(p.Chars)) {
+		return fmt.Errorf("profile has %d characters but %d minimums and %d maximums", len(p.Chars), len(p.Min), len(p.Max))
+	}
+
+	if (len(p.Dwell) > 0 || len(p.DwellStddev) > 0) && (len(p.Dwell) != len(p.Chars) || len(p.DwellStddev) != len(p.Chars)) {
+		return fmt.Errorf("profile has %d characters but %d dwell means and %d dwell standard deviations", len(p.Chars), len(p.Dwell), len(p.DwellStddev))
+	}
+
+	for _, field := range []struct {
+		name   string
+		values []float64
+	}{{"mean", p.Mean}, {"stddev", p.Stddev}, {"min", p.Min}, {"max", p.Max}, {"dwell", p.Dwell}, {"dwell stddev", p.DwellStddev}} {
+		for i, v := range field.values {
+			if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+				return fmt.Errorf("invalid %s %v of character %d", field.name, v, i)
+			}
+		}
+	}
+
+	for i := range p.Min {
+		if p.Min[i] > p.Max[i] {
+			return fmt.Errorf("minimum %v above maximum %v of character %d", p.Min[i], p.Max[i], i)
+		}
+	}
+
+	if p.Unit != "" {
+		if _, err := ParseUnit(p.Unit); err != nil {
+			return err
+		}
+	}
+
+	if p.MatchOn != "" && !slices.Contains(matchModes, p.MatchOn) {
+		return fmt.Errorf("unknown match mode %q", p.MatchOn)
+	}
+
+	if (p.MatchOn == MatchDwell || p.MatchOn == MatchBoth) && len(p.Dwell) == 0 {
+		return fmt.Errorf("profile matches on %s without dwell times", p.MatchOn)
+	}
+
+	if p.Salt < 0 {
+		return fmt.Errorf("invalid salt %d", p.Salt)
+	}
+
+	if p.Smooth < 0 || p.Smooth > 1 && p.Smooth%2 == 0 {
+		return fmt.Errorf("invalid smoothing window %d", p.Smooth)
+	}
+
+	return nil
+}
+
+func (p Profile) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// LoadProfiles loads every profile of dir, named after its file, see
+// FileProfileStore.
+func LoadProfiles(dir string) ([]NamedProfile, error) {
+	return LoadStoredProfiles(FileProfileStore{Dir: dir})
+}
+
+func (p Profile) Tolerance(i int) float64 {
+	return math.Max(minTolerance, defaultToleranceFactor*p.Stddev[i])
+}
+
+// CharResult is how a typed character compares to its profile, timings
+// being in milliseconds.
+type CharResult struct {
+	Index     int
+	Char      byte
+	Reference float64
+	Typed     float64
+	Diff      float64
+	Tolerance float64
+	Pass      bool
+}
+
+// Breakdown compares each character of rk to the profile. The character
+// sequences must be identical.
+func (p Profile) Breakdown(rk Rythmkey) ([]CharResult, error) {
+	if err := checkChars(p.Chars, rk.Chars()); err != nil {
+		return nil, err
+	}
+
+	results := make([]CharResult, len(rk))
+	for i, ct := range rk {
+		typed := milliseconds(ct.Timing)
+		results[i] = CharResult{
+			Index:     i,
+			Char:      ct.Char,
+			Reference: p.Mean[i],
+			Typed:     typed,
+			Diff:      typed - p.Mean[i],
+			Tolerance: p.Tolerance(i),
+		}
+		results[i].Pass = math.Abs(results[i].Diff) <= results[i].Tolerance
+	}
+
+	return results, nil
+}
+
+func WriteBreakdown(w io.Writer, results []CharResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "index\tchar\treference\ttyped\tdiff\ttolerance\tpass")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d\t%s\t%.2f\t%.2f\t%+.2f\t%.2f\t%t\n", r.Index, escapeChar(r.Char), r.Reference, r.Typed, r.Diff, r.Tolerance, r.Pass)
+	}
+
+	return tw.Flush()
+}
+
+// Score returns the fraction of characters typed within the tolerance of
+// the profile. The character sequences must be identical.
+func (p Profile) Score(rk Rythmkey) (float64, error) {
+	if len(rk) == 0 {
+		return 0, errors.New("empty rythmkey")
+	}
+
+	results, err := p.Breakdown(rk)
+	if err != nil {
+		return 0, err
+	}
+
+	within := 0
+	for _, r := range results {
+		if r.Pass {
+			within++
+		}
+	}
+
+	return float64(within) / float64(len(rk)), nil
+}
+
+// BestMatch returns the profile scoring the highest for rk, ignoring the
+// ones typed with other characters. ok is false when no profile reaches
+// the threshold.
+func BestMatch(profiles []NamedProfile, rk Rythmkey, threshold float64) (NamedProfile, float64, bool) {
+	return bestMatch(profiles, threshold, func(p Profile) (float64, error) {
+		return p.Score(rk)
+	})
+}
+
+func bestMatch(profiles []NamedProfile, threshold float64, scoreOf func(Profile) (float64, error)) (NamedProfile, float64, bool) {
+	best := NamedProfile{}
+	bestScore := -1.0
+
+	for _, np := range profiles {
+		score, err := scoreOf(np.Profile)
+		if err != nil {
+			continue
+		}
+
+		if score > bestScore {
+			best, bestScore = np, score
+		}
+	}
+
+	if bestScore < threshold {
+		return NamedProfile{}, 0, false
+	}
+
+	return best, bestScore, true
+}
+
+// MatchProfiles matches rk against every profile with m, see
+// MatchProfile, and returns the best scoring profile it matches. Profiles
+// it can't be matched against, like ones typed with other characters, are
+// skipped. ok is false when it matches none.
+func MatchProfiles(profiles []NamedProfile, rk Rythmkey, m Matcher) (NamedProfile, float64, bool) {
+	return matchProfiles(profiles, func(p Profile) (bool, float64, error) {
+		return MatchProfile(m, p, rk)
+	})
+}
+
+func matchProfiles(profiles []NamedProfile, match func(Profile) (bool, float64, error)) (NamedProfile, float64, bool) {
+	best := NamedProfile{}
+	bestScore := -1.0
+
+	for _, np := range profiles {
+		ok, score, err := match(np.Profile)
+		if err != nil || !ok {
+			continue
+		}
+
+		if score > bestScore {
+			best, bestScore = np, score
+		}
+	}
+
+	if bestScore < 0 {
+		return NamedProfile{}, 0, false
+	}
+
+	return best, bestScore, true
+}
+
+func (p Profile) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "characters: %d\n", len(p.Chars))
+	fmt.Fprintf(w, "sequence: %s\n", strconv.Quote(p.Chars))
+
+	unit := p.Unit
+	if unit == "" {
+		unit = "ms"
+	}
+	fmt.Fprintf(w, "unit: %s\n", unit)
+
+	if p.Salt > 0 {
+		fmt.Fprintf(w, "salt: %d\n", p.Salt)
+	} else {
+		fmt.Fprintln(w, "salt: unknown")
+	}
+
+	if p.Smooth > 1 {
+		fmt.Fprintf(w, "normalization: moving average over %d timings\n", p.Smooth)
+	} else {
+		fmt.Fprintln(w, "normalization: none")
+	}
+	if p.MatchOn != "" {
+		fmt.Fprintf(w, "match: %s\n", p.MatchOn)
+	} else {
+		fmt.Fprintf(w, "match: %s\n", MatchFlight)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(p.Dwell) > 0 {
+		fmt.Fprintln(tw, "index\tchar\tmean\tstddev\ttolerance\tdwell\tdwell stddev\tdwell tolerance")
+		for i := range p.Mean {
+			fmt.Fprintf(tw, "%d\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\n", i, escapeChar(p.Chars[i]), p.Mean[i], p.Stddev[i], p.Tolerance(i), p.Dwell[i], p.DwellStddev[i], p.DwellTolerance(i))
+		}
+		return tw.Flush()
+	}
+
+	fmt.Fprintln(tw, "index\tchar\tmean\tstddev\ttolerance")
+	for i := range p.Mean {
+		fmt.Fprintf(tw, "%d\t%s\t%.2f\t%.2f\t%.2f\n", i, escapeChar(p.Chars[i]), p.Mean[i], p.Stddev[i], p.Tolerance(i))
+	}
+
+	return tw.Flush()
+}
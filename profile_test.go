@@ -0,0 +1,188 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBestMatch(t *testing.T) {
+	profiles := []NamedProfile{
+		{Name: "other-chars", Profile: mustProfile(t, "t0.xt100.yt100.z")},
+		{Name: "slow", Profile: mustProfile(t, "t0.at400.bt400.c", "t0.at420.bt380.c")},
+		{Name: "fast", Profile: mustProfile(t, "t0.at100.bt90.c", "t0.at110.bt100.c")},
+	}
+
+	np, score, ok := BestMatch(profiles, mustParse(t, "t0.at105.bt95.c"), defaultThreshold)
+	if !ok || np.Name != "fast" || score != 1 {
+		t.Errorf("BestMatch = %s, %v, %t, want fast, 1, true", np.Name, score, ok)
+	}
+
+	if np, _, ok := BestMatch(profiles, mustParse(t, "t0.at250.bt250.c"), defaultThreshold); ok {
+		t.Errorf("BestMatch of an unenrolled rhythm = %s, want no match", np.Name)
+	}
+
+	if np, _, ok := BestMatch(profiles, mustParse(t, "t0.qt100.rt100.s"), 0); ok {
+		t.Errorf("BestMatch of unenrolled characters = %s, want no match", np.Name)
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, p := range map[string]Profile{
+		"alice": mustProfile(t, "t0.at100.bt90.c"),
+		"bob":   mustProfile(t, "t0.at400.bt400.c"),
+	} {
+		if err := p.Save(filepath.Join(dir, name+".json")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	profiles, err := LoadProfiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(profiles) != 2 || profiles[0].Name != "alice" || profiles[1].Name != "bob" {
+		t.Fatalf("LoadProfiles = %v, want alice and bob", profiles)
+	}
+
+	np, _, ok := BestMatch(profiles, mustParse(t, "t0.at390.bt410.c"), defaultThreshold)
+	if !ok || np.Name != "bob" {
+		t.Errorf("BestMatch = %s, %t, want bob", np.Name, ok)
+	}
+}
+
+func TestProfileShowParameters(t *testing.T) {
+	p := mustProfile(t, "t0.at100.bt90.c", "t0.at110.bt100.c")
+	p.Salt, p.Smooth = 25, 3
+
+	text := &strings.Builder{}
+	if err := p.WriteText(text); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{`sequence: "abc"`, "unit: ms", "salt: 25", "normalization: moving average over 3 timings"} {
+		if !strings.Contains(text.String(), line+"\n") {
+			t.Errorf("profile text lacks %q:\n%s", line, text)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "p.json")
+	if err := p.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, p) {
+		t.Errorf("LoadProfile = %+v, want %+v", loaded, p)
+	}
+}
+
+func TestProfileShowUnknownParameters(t *testing.T) {
+	text := &strings.Builder{}
+	if err := mustProfile(t, "t0.at100.b").WriteText(text); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"salt: unknown", "normalization: none"} {
+		if !strings.Contains(text.String(), line+"\n") {
+			t.Errorf("profile text lacks %q:\n%s", line, text)
+		}
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	p := mustProfile(t, "t0.at100.bt200.c", "t0.at120.bt200.c")
+
+	results, err := p.Breakdown(mustParse(t, "t0.at110.bt300.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := &strings.Builder{}
+	if err := WriteBreakdown(text, results); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `index  char  reference  typed   diff     tolerance  pass
+0      'a'   0.00       0.00    +0.00    30.00      true
+1      'b'   110.00     110.00  +0.00    30.00      true
+2      'c'   200.00     300.00  +100.00  30.00      false
+`
+	if text.String() != want {
+		t.Errorf("breakdown:\n%s\nwant:\n%s", text, want)
+	}
+
+	if _, err := p.Breakdown(mustParse(t, "t0.at110.b")); err == nil {
+		t.Error("Breakdown of a key with other characters succeeded")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Profile {
+		return Profile{Chars: "abc", Mean: []float64{0, 120, 80}, Stddev: []float64{0, 10, 5}, Min: []float64{0, 110, 75}, Max: []float64{0, 130, 85}}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Validate of a valid profile = %v", err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		corrupt func(p *Profile)
+	}{
+		{"no characters", func(p *Profile) { *p = Profile{} }},
+		{"fewer means", func(p *Profile) { p.Mean = p.Mean[:2] }},
+		{"more stddevs", func(p *Profile) { p.Stddev = append(p.Stddev, 1) }},
+		{"fewer characters", func(p *Profile) { p.Chars = "ab" }},
+		{"partial range", func(p *Profile) { p.Max = nil }},
+		{"negative stddev", func(p *Profile) { p.Stddev[1] = -1 }},
+		{"NaN stddev", func(p *Profile) { p.Stddev[2] = math.NaN() }},
+		{"NaN mean", func(p *Profile) { p.Mean[1] = math.NaN() }},
+		{"infinite mean", func(p *Profile) { p.Mean[1] = math.Inf(1) }},
+		{"negative min", func(p *Profile) { p.Min[1] = -5 }},
+		{"min above max", func(p *Profile) { p.Min[1] = 140 }},
+		{"unknown unit", func(p *Profile) { p.Unit = "fortnights" }},
+		{"negative salt", func(p *Profile) { p.Salt = -20 }},
+		{"even smoothing", func(p *Profile) { p.Smooth = 4 }},
+	} {
+		p := valid()
+		tc.corrupt(&p)
+		if err := p.Validate(); err == nil {
+			t.Errorf("Validate of a profile with %s succeeded", tc.name)
+		}
+	}
+
+	// Older profiles have no range.
+	p := valid()
+	p.Min, p.Max = nil, nil
+	if err := p.Validate(); err != nil {
+		t.Errorf("Validate of a profile without range = %v", err)
+	}
+}
+
+func TestLoadCorruptProfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(path, []byte(`{"chars":"abc","mean":[0,120],"stddev":[0,10,-5]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadProfile(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("LoadProfile of a corrupt profile = %v, want an error naming it", err)
+	}
+
+	if result := runApp(t, "profile", "show", "--profile", path); result.err == nil {
+		t.Error("profile show of a corrupt profile succeeded")
+	}
+
+	t.Setenv(encodedInputEnv, "t0.at120.bt80.c")
+	if result := runApp(t, "verify", "--profiles-dir", dir); result.err == nil || result.code != 0 {
+		t.Errorf("verify against a corrupt profile exited %d: %v, want it to fail loading", result.code, result.err)
+	}
+}
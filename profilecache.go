@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// ProfileCache keeps parsed profiles in memory for long-running callers, so
+// a profile file is parsed once and reused until it changes. Entries are
+// invalidated when the file's modification time or size changes, or once
+// they are older than TTL. It is safe for concurrent use.
+type ProfileCache struct {
+	// MaxSize bounds the number of cached profiles, unbounded when 0. The
+	// oldest entry is evicted first.
+	MaxSize int
+	// TTL bounds how long an entry is trusted without looking at its file
+	// again, 0 meaning the file is stat'ed on every Load.
+	TTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*profileCacheEntry
+}
+
+type profileCacheEntry struct {
+	profile Profile
+	modTime time.Time
+	size    int64
+	loaded  time.Time
+}
+
+// Load returns the profile at path, from the cache when its file didn't
+// change since it was last parsed.
+func (c *ProfileCache) Load(path string) (Profile, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	c.mu.Unlock()
+
+	if ok && c.TTL > 0 && now.Sub(entry.loaded) < c.TTL {
+		return entry.profile, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		c.Invalidate(path)
+		return Profile{}, err
+	}
+
+	if ok && info.ModTime().Equal(entry.modTime) && info.Size() == entry.size {
+		return entry.profile, nil
+	}
+
+	p, err := LoadProfile(path)
+	if err != nil {
+		c.Invalidate(path)
+		return Profile{}, err
+	}
+
+	c.store(path, &profileCacheEntry{profile: p, modTime: info.ModTime(), size: info.Size(), loaded: now})
+
+	return p, nil
+}
+
+// Invalidate drops the cached profile for path, if any.
+func (c *ProfileCache) Invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, path)
+}
+
+func (c *ProfileCache) store(path string, entry *profileCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]*profileCacheEntry{}
+	}
+
+	if _, ok := c.entries[path]; !ok && c.MaxSize > 0 {
+		for len(c.entries) >= c.MaxSize {
+			oldest := ""
+			for p, e := range c.entries {
+				if oldest == "" || e.loaded.Before(c.entries[oldest].loaded) {
+					oldest = p
+				}
+			}
+			delete(c.entries, oldest)
+		}
+	}
+
+	c.entries[path] = entry
+}
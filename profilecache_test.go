@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// saveProfile saves p in dir under name and returns its path.
+func saveProfile(t testing.TB, dir string, name string, p Profile) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := p.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestProfileCacheInvalidation(t *testing.T) {
+	path := saveProfile(t, t.TempDir(), "p.json", mustProfile(t, "t0.at100.b"))
+	cache := &ProfileCache{}
+
+	p, err := cache.Load(path)
+	if err != nil || p.Chars != "ab" {
+		t.Fatalf("Load = %q, %v", p.Chars, err)
+	}
+
+	// Changed on disk, with another size and modification time.
+	saveProfile(t, filepath.Dir(path), "p.json", mustProfile(t, "t0.xt100.yt100.z"))
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if p, err := cache.Load(path); err != nil || p.Chars != "xyz" {
+		t.Errorf("Load after a change = %q, %v, want the new profile", p.Chars, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Load(path); err == nil {
+		t.Error("Load of a removed profile succeeded")
+	}
+}
+
+func TestProfileCacheTTL(t *testing.T) {
+	path := saveProfile(t, t.TempDir(), "p.json", mustProfile(t, "t0.at100.b"))
+	cache := &ProfileCache{TTL: time.Hour}
+
+	if _, err := cache.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Trusted without looking at the file again.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := cache.Load(path); err != nil || p.Chars != "ab" {
+		t.Errorf("Load within the TTL = %q, %v, want the cached profile", p.Chars, err)
+	}
+
+	cache.Invalidate(path)
+	if _, err := cache.Load(path); err == nil {
+		t.Error("Load after Invalidate used the cache")
+	}
+}
+
+func TestProfileCacheMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	cache := &ProfileCache{MaxSize: 2}
+
+	for _, name := range []string{"a.json", "b.json", "c.json"} {
+		if _, err := cache.Load(saveProfile(t, dir, name, mustProfile(t, "t0.at100.b"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(cache.entries) != 2 {
+		t.Errorf("%d cached profiles, want at most 2", len(cache.entries))
+	}
+	if _, ok := cache.entries[filepath.Join(dir, "a.json")]; ok {
+		t.Error("the oldest profile wasn't evicted")
+	}
+}
+
+func BenchmarkProfileLoad(b *testing.B) {
+	path := saveProfile(b, b.TempDir(), "p.json", mustProfile(b, "t0.pt120.at80.st95.st140.wt90.ot110.rt70.d"))
+	rk := mustParse(b, "t0.pt120.at80.st95.st140.wt90.ot110.rt70.d")
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p, err := LoadProfile(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			p.Verify(rk, defaultThreshold)
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		cache := &ProfileCache{}
+		for i := 0; i < b.N; i++ {
+			p, err := cache.Load(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			p.Verify(rk, defaultThreshold)
+		}
+	})
+
+	b.Run("cached-ttl", func(b *testing.B) {
+		cache := &ProfileCache{TTL: time.Minute}
+		for i := 0; i < b.N; i++ {
+			p, err := cache.Load(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			p.Verify(rk, defaultThreshold)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// ProfileDelta is how a character changed from an old profile to a new
+// one, in milliseconds: positive mean deltas are slower, positive stddev
+// deltas less consistent.
+type ProfileDelta struct {
+	Index       int     `json:"index"`
+	Char        string  `json:"char"`
+	MeanDelta   float64 `json:"mean_delta_ms"`
+	StddevDelta float64 `json:"stddev_delta_ms"`
+}
+
+// DiffProfiles returns the change of every character from old to updated,
+// which must share the same characters.
+func DiffProfiles(old Profile, updated Profile) ([]ProfileDelta, error) {
+	if err := checkChars(old.Chars, updated.Chars); err != nil {
+		return nil, err
+	}
+
+	deltas := make([]ProfileDelta, len(updated.Chars))
+	for i := range deltas {
+		deltas[i] = ProfileDelta{
+			Index:       i,
+			Char:        updated.Chars[i : i+1],
+			MeanDelta:   updated.Mean[i] - old.Mean[i],
+			StddevDelta: updated.Stddev[i] - old.Stddev[i],
+		}
+	}
+
+	return deltas, nil
+}
+
+func WriteProfileDiff(w io.Writer, deltas []ProfileDelta) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "index\tchar\tmean\tstddev")
+	for _, d := range deltas {
+		fmt.Fprintf(tw, "%d\t%s\t%+.2f\t%+.2f\n", d.Index, escapeChar(d.Char[0]), d.MeanDelta, d.StddevDelta)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffProfiles(t *testing.T) {
+	old := Profile{Chars: "abc", Mean: []float64{0, 120, 80}, Stddev: []float64{0, 10, 5}}
+	updated := Profile{Chars: "abc", Mean: []float64{0, 100, 95}, Stddev: []float64{0, 4, 12.5}}
+
+	deltas, err := DiffProfiles(old, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ProfileDelta{
+		{Index: 0, Char: "a", MeanDelta: 0, StddevDelta: 0},
+		{Index: 1, Char: "b", MeanDelta: -20, StddevDelta: -6},
+		{Index: 2, Char: "c", MeanDelta: 15, StddevDelta: 7.5},
+	}
+	if !slices.Equal(deltas, want) {
+		t.Errorf("DiffProfiles = %+v, want %+v", deltas, want)
+	}
+
+	if _, err := DiffProfiles(old, Profile{Chars: "abd", Mean: []float64{0, 0, 0}, Stddev: []float64{0, 0, 0}}); err == nil {
+		t.Error("DiffProfiles of profiles of other characters succeeded")
+	}
+}
+
+func TestWriteProfileDiff(t *testing.T) {
+	b := strings.Builder{}
+	if err := WriteProfileDiff(&b, []ProfileDelta{{Index: 0, Char: "\t", MeanDelta: -20, StddevDelta: 7.5}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := b.String(), "index  char  mean    stddev\n0      '\\t'  -20.00  +7.50\n"; got != want {
+		t.Errorf("WriteProfileDiff = %q, want %q", got, want)
+	}
+}
+
+func TestProfileDiffCommand(t *testing.T) {
+	dir := t.TempDir()
+	old := saveProfile(t, dir, "old.json", Profile{Chars: "ab", Mean: []float64{0, 120}, Stddev: []float64{0, 10}})
+	updated := saveProfile(t, dir, "new.json", Profile{Chars: "ab", Mean: []float64{0, 100}, Stddev: []float64{0, 15}})
+
+	result := runApp(t, "profile", "diff", "--old", old, "--new", updated, "--format", "json")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	deltas := []ProfileDelta{}
+	if err := json.Unmarshal([]byte(result.stdout), &deltas); err != nil {
+		t.Fatal(err)
+	}
+	if len(deltas) != 2 || deltas[1].MeanDelta != -20 || deltas[1].StddevDelta != 5 {
+		t.Errorf("profile diff = %+v, want b 20ms faster and 5ms less consistent", deltas)
+	}
+
+	other := saveProfile(t, dir, "other.json", Profile{Chars: "xy", Mean: []float64{0, 100}, Stddev: []float64{0, 15}})
+	if result := runApp(t, "profile", "diff", "--old", old, "--new", other); result.err == nil {
+		t.Error("profile diff of other characters succeeded")
+	}
+	if result := runApp(t, "profile", "diff", "--old", old, "--new", filepath.Join(dir, "missing.json")); result.err == nil {
+		t.Error("profile diff of a missing profile succeeded")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// promptLine is the prompt of an interactive capture along with the mask
+// echoed so far, so it can be redrawn when the terminal gets resized.
+type promptLine struct {
+	mu     sync.Mutex
+	prompt string
+	echo   io.Writer
+	masked int
+	// live is shown after the mask, see ReadOptions.LiveWindow.
+	live string
+}
+
+func newPromptLine(prompt string, echo io.Writer) *promptLine {
+	pl := &promptLine{prompt: prompt, echo: echo}
+	fmt.Fprint(os.Stderr, prompt)
+	return pl
+}
+
+// Write forwards the mask to the echo writer, keeping count of it.
+func (pl *promptLine) Write(p []byte) (int, error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	pl.masked += bytes.Count(p, []byte("*"))
+	return pl.echo.Write(p)
+}
+
+func (pl *promptLine) redraw() {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	pl.draw()
+}
+
+func (pl *promptLine) draw() {
+	fmt.Fprint(os.Stderr, "\r\033[K"+pl.prompt+strings.Repeat("*", pl.masked)+pl.live)
+}
+
+// setLive replaces what is shown after the mask.
+func (pl *promptLine) setLive(live string) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	pl.live = live
+	pl.draw()
+}
+
+// endOfInput tells whether a prompted capture was ended by ctrl-d rather
+// than typed: an empty capture, or one starting with ctrl-d which a
+// terminal in cbreak mode delivers as a character.
+func endOfInput(rk Rythmkey) bool {
+	return len(rk) == 0 || rk[0].Char == '\x04'
+}
+
+// readPrompted captures a key after displaying prompt, redrawing it on
+// terminal resizes for the duration of the capture only.
+func readPrompted(prompt string, ro ReadOptions) (Rythmkey, error) {
+	pl := newPromptLine(prompt, ro.Echo)
+	if ro.Echo != nil {
+		ro.Echo = pl
+	}
+
+	if ro.LiveWindow > 0 {
+		smoother := TrailingSmoother{Window: ro.LiveWindow}
+		onTiming, typed := ro.OnTiming, 0
+		ro.OnTiming = func(timing time.Duration) {
+			if onTiming != nil {
+				onTiming(timing)
+			}
+
+			// The first timing isn't an interval.
+			if typed++; typed > 1 {
+				pl.setLive(fmt.Sprintf("  ~%dms", smoother.Add(timing).Milliseconds()))
+			}
+		}
+	}
+
+	if !testMode {
+		stop := onResize(pl.redraw)
+		defer stop()
+	}
+
+	rk := Rythmkey{}
+	err := rk.ReadWith(ro)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	return rk, nil
+}
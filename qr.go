@@ -0,0 +1,25 @@
+package main
+
+import (
+	"encoding/base64"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// EncodeBase64 is the compact, transport friendly form of Encode.
+func (rythmkey Rythmkey) EncodeBase64() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(rythmkey.Encode()))
+}
+
+func ParseRythmkeyBase64(b64 string) (Rythmkey, error) {
+	rks, err := base64.RawURLEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseRythmkey(string(rks))
+}
+
+func (rythmkey Rythmkey) QRCode() (*qrcode.QRCode, error) {
+	return qrcode.New(rythmkey.EncodeBase64(), qrcode.Medium)
+}
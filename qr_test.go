@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBase64RoundTrip(t *testing.T) {
+	escaped := Rythmkey{}
+	escaped.Add(0x01, 0)
+	escaped.Add('\n', 120*time.Millisecond)
+	escaped.Add('.', 80*time.Millisecond)
+	escaped.Add('t', 0)
+	escaped.Add(0xe9, 300*time.Millisecond)
+
+	for _, rk := range []Rythmkey{
+		mustParse(t, "t0.at120.bt80.c"),
+		mustParse(t, "t0.h^1t95.e^5t110.y"),
+		mustParse(t, "us:t0.at120500.b"),
+		escaped,
+	} {
+		b64 := rk.EncodeBase64()
+		if strings.ContainsAny(b64, "+/=") {
+			t.Errorf("EncodeBase64(%s) = %s, want unpadded URL safe base64", rk.Encode(), b64)
+		}
+
+		parsed, err := ParseRythmkeyBase64(b64)
+		if err != nil {
+			t.Fatalf("ParseRythmkeyBase64(%s) = %v", b64, err)
+		}
+		if !reflect.DeepEqual(parsed, rk) {
+			t.Errorf("ParseRythmkeyBase64(EncodeBase64(%s)) = %s", rk.Encode(), parsed.Encode())
+		}
+	}
+
+	for _, b64 := range []string{
+		"dDAuYQ==", // padded
+		"dDAu+2E",  // not URL safe
+		"!!!",
+		"aGVsbG8", // hello, not a key
+	} {
+		if _, err := ParseRythmkeyBase64(b64); err == nil {
+			t.Errorf("ParseRythmkeyBase64(%q) succeeded", b64)
+		}
+	}
+}
+
+func TestQRCommand(t *testing.T) {
+	const rks = "t0.h^1t95.e"
+
+	qr, err := mustParse(t, rks).QRCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mustParse(t, rks).EncodeBase64(); qr.Content != want {
+		t.Errorf("QRCode holds %q, want the base64 form %q", qr.Content, want)
+	}
+
+	result := runApp(t, "qr", "--rythmkey", rks)
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if result.stdout != qr.ToSmallString(false) {
+		t.Errorf("qr printed %q, want the QR code of %s", result.stdout, rks)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.png")
+	if result := runApp(t, "qr", "--rythmkey", rks, "--out", path, "--size", "64"); result.err != nil || result.stdout != "" {
+		t.Fatalf("qr --out = %v, printed %q", result.err, result.stdout)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Errorf("qr --out wrote %q..., want a PNG image", data[:min(len(data), 8)])
+	}
+
+	if result := runApp(t, "qr", "--rythmkey", "t0.at"); result.err == nil {
+		t.Error("qr of a malformed key succeeded")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+const defaultMinCorrelation = 0.8
+
+// ranks returns the rank of every value, 1 for the smallest, tied values
+// sharing the average of the ranks they span.
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case values[a] < values[b]:
+			return -1
+		case values[a] > values[b]:
+			return 1
+		}
+		return 0
+	})
+
+	r := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			r[order[k]] = rank
+		}
+		i = j + 1
+	}
+
+	return r
+}
+
+// RankCorrelation is the Spearman rank correlation of the intervals of
+// both keys, the first timing left aside: 1 when every character is typed
+// relatively faster or slower than the others in both, whatever the
+// overall tempo. It needs keys of the same length, with at least two
+// intervals that aren't all the same in either key.
+func (rythmkey Rythmkey) RankCorrelation(other Rythmkey) (float64, error) {
+	if len(rythmkey) != len(other) {
+		return 0, &MismatchError{Kind: LengthMismatch, Typed: other.Len(), Expected: rythmkey.Len(), Index: -1}
+	}
+
+	if len(rythmkey) < 3 {
+		return 0, errors.New("at least two intervals are needed to rank them")
+	}
+
+	a := make([]float64, 0, len(rythmkey)-1)
+	b := make([]float64, 0, len(other)-1)
+	for i := 1; i < len(rythmkey); i++ {
+		a = append(a, float64(rythmkey[i].Timing))
+		b = append(b, float64(other[i].Timing))
+	}
+
+	ra, rb := ranks(a), ranks(b)
+
+	mean := float64(len(ra)+1) / 2
+	cov, va, vb := 0.0, 0.0, 0.0
+	for i := range ra {
+		da, db := ra[i]-mean, rb[i]-mean
+		cov += da * db
+		va += da * da
+		vb += db * db
+	}
+
+	if va == 0 || vb == 0 {
+		return 0, errors.New("all intervals are the same, they can't be ranked")
+	}
+
+	return cov / math.Sqrt(va*vb), nil
+}
+
+// CompareRank compares the characters of both keys like Compare, but
+// matches their timings on the rank correlation of their intervals rather
+// than on a tolerance.
+func CompareRank(reference Rythmkey, rk Rythmkey, minCorrelation float64) (CompareReport, error) {
+	report := CompareReport{
+		Method:       "rank",
+		Length:       reference.Len(),
+		TypedLength:  rk.Len(),
+		CharDistance: reference.CharDistance(rk),
+		FailIndex:    -1,
+	}
+
+	for i := 0; i < len(reference) && i < len(rk); i++ {
+		if reference[i].Char == rk[i].Char {
+			report.MatchingChars++
+		} else {
+			report.fail(i)
+		}
+	}
+	report.failLength()
+
+	if len(reference) != len(rk) || report.MatchingChars != len(reference) {
+		return report, nil
+	}
+
+	correlation, err := reference.RankCorrelation(rk)
+	if err != nil {
+		return CompareReport{}, err
+	}
+
+	report.Correlation = correlation
+	report.Match = correlation >= minCorrelation
+	return report, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRanks(t *testing.T) {
+	for _, tc := range []struct {
+		values []float64
+		ranks  []float64
+	}{
+		{[]float64{30, 10, 20}, []float64{3, 1, 2}},
+		{[]float64{10, 20, 20, 5}, []float64{2, 3.5, 3.5, 1}},
+		{[]float64{7, 7, 7}, []float64{2, 2, 2}},
+	} {
+		if r := ranks(tc.values); !reflect.DeepEqual(r, tc.ranks) {
+			t.Errorf("ranks(%v) = %v, want %v", tc.values, r, tc.ranks)
+		}
+	}
+}
+
+func TestRankCorrelation(t *testing.T) {
+	reference := mustParse(t, "t0.pt120.at80.st95.st140.wt60.o")
+
+	for _, tc := range []struct {
+		rks         string
+		correlation float64
+	}{
+		// Twice as slow, and much faster, the pattern intact.
+		{"t0.pt240.at160.st190.st280.wt120.o", 1},
+		{"t0.pt36.at24.st29.st42.wt18.o", 1},
+		// Every interval outranked in reverse.
+		{"t0.pt80.at140.st95.st60.wt120.o", -0.9},
+		// Ties share their ranks.
+		{"t0.pt120.at80.st80.st140.wt60.o", 0.9746794344808963},
+	} {
+		correlation, err := reference.RankCorrelation(mustParse(t, tc.rks))
+		if err != nil {
+			t.Errorf("RankCorrelation(%s) failed: %v", tc.rks, err)
+			continue
+		}
+
+		if math.Abs(correlation-tc.correlation) > 1e-9 {
+			t.Errorf("RankCorrelation(%s) = %v, want %v", tc.rks, correlation, tc.correlation)
+		}
+	}
+}
+
+func TestRankCorrelationInvalid(t *testing.T) {
+	for _, tc := range [][2]string{
+		{"t0.at10.bt20.c", "t0.at10.b"},
+		{"t0.at10.b", "t0.at20.b"},
+		{"t0.at10.bt10.c", "t0.at20.bt10.c"},
+	} {
+		if _, err := mustParse(t, tc[0]).RankCorrelation(mustParse(t, tc[1])); err == nil {
+			t.Errorf("RankCorrelation(%s, %s) succeeded", tc[0], tc[1])
+		}
+	}
+}
+
+func TestCompareRankScaled(t *testing.T) {
+	reference := mustParse(t, "t0.pt120.at80.st95.st140.wt60.o")
+
+	for _, tc := range []struct {
+		rks   string
+		match bool
+	}{
+		{"t0.pt360.at240.st285.st420.wt180.o", true},
+		{"t0.pt80.at140.st95.st60.wt120.o", false},
+		{"t0.pt120.at80.st95.st140.wt60.x", false},
+	} {
+		report, err := CompareRank(reference, mustParse(t, tc.rks), defaultMinCorrelation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if report.Match != tc.match {
+			t.Errorf("CompareRank(%s) = %+v, want a match %t", tc.rks, report, tc.match)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// A raw samples file keeps the samples of an enrollment rather than the
+// profile computed from them, so profiles can be computed again as
+// matching changes without typing the key again. Its first line is
+// rawSamplesHeader, then every line is a sample encoded as by Encode, a
+// line starting with a '#' being a comment. The profile is computed when
+// the file is loaded, in the unit of its first sample.
+const rawSamplesHeader = "# rythmkey raw samples v1"
+
+// rawSamplesExt names raw samples files in a profiles directory, next to
+// the *.json profiles.
+const rawSamplesExt = ".samples"
+
+func SaveRawSamples(path string, samples []Rythmkey) error {
+	if len(samples) == 0 {
+		return errors.New("no samples to save")
+	}
+
+	lines := []string{rawSamplesHeader}
+	for _, sample := range samples {
+		lines = append(lines, sample.Encode())
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+// isRawSamples tells whether data is a raw samples file rather than a
+// profile.
+func isRawSamples(data []byte) bool {
+	return strings.HasPrefix(string(data), rawSamplesHeader+"\n")
+}
+
+// parseRawSamples returns the samples of a raw samples file and the unit
+// of the first of them.
+func parseRawSamples(data []byte) ([]Rythmkey, time.Duration, error) {
+	if !isRawSamples(data) {
+		return nil, 0, errors.New("not a raw samples file")
+	}
+
+	samples := []Rythmkey{}
+	unit := time.Millisecond
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	scanner.Scan()
+	for n := 2; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		result, err := ParseDetailed(line)
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		if len(samples) == 0 {
+			unit = result.Unit
+		}
+		samples = append(samples, result.Rythmkey)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return samples, unit, nil
+}
+
+// profileFromRawSamples computes the profile of a raw samples file.
+func profileFromRawSamples(data []byte) (Profile, error) {
+	samples, unit, err := parseRawSamples(data)
+	if err != nil {
+		return Profile{}, err
+	}
+	defer func() {
+		for _, sample := range samples {
+			sample.Zero()
+		}
+	}()
+
+	return NewProfile(samples, unit)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRawSamplesFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user"+rawSamplesExt)
+	samples := []Rythmkey{mustParse(t, "t0.at100.bt200.c"), mustParse(t, "t0.at110.bt190.c")}
+	if err := SaveRawSamples(path, samples); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := rawSamplesHeader + "\nt0.at100.bt200.c\nt0.at110.bt190.c\n"; string(data) != want {
+		t.Errorf("raw samples file = %q, want %q", data, want)
+	}
+
+	if err := SaveRawSamples(path, nil); err == nil {
+		t.Error("SaveRawSamples of no sample succeeded")
+	}
+}
+
+// The profile of a raw samples file is the one of its samples, computed
+// when it's loaded, in the unit of the first.
+func TestLoadProfileFromRawSamples(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name    string
+		content string
+		samples []string
+	}{
+		{"plain", "t0.at100.bt200.c\nt0.at110.bt190.c\n", []string{"t0.at100.bt200.c", "t0.at110.bt190.c"}},
+		{"comments", "# first day\nt0.at100.bt200.c\n\n# second day\nt0.at110.bt190.c\n", []string{"t0.at100.bt200.c", "t0.at110.bt190.c"}},
+		{"microseconds", "us:t0.at100500.bt200000.c\n", []string{"us:t0.at100500.bt200000.c"}},
+	} {
+		path := filepath.Join(dir, tc.name+rawSamplesExt)
+		if err := os.WriteFile(path, []byte(rawSamplesHeader+"\n"+tc.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := LoadProfile(path)
+		if err != nil {
+			t.Fatalf("%s: LoadProfile = %v", tc.name, err)
+		}
+
+		samples := []Rythmkey{}
+		for _, s := range tc.samples {
+			samples = append(samples, mustParse(t, s))
+		}
+		result, err := ParseDetailed(tc.samples[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := NewProfile(samples, result.Unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: LoadProfile = %+v, want %+v", tc.name, got, want)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"other chars":     rawSamplesHeader + "\nt0.at100.bt200.c\nt0.at100.bt200.x\n",
+		"no sample":       rawSamplesHeader + "\n# nothing yet\n",
+		"no header":       "t0.at100.bt200.c\n",
+		"another version": "# rythmkey raw samples v2\nt0.at100.bt200.c\n",
+	} {
+		path := filepath.Join(dir, "invalid"+rawSamplesExt)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadProfile(path); err == nil {
+			t.Errorf("LoadProfile of %s succeeded", name)
+		}
+	}
+
+	path := filepath.Join(dir, "bad"+rawSamplesExt)
+	if err := os.WriteFile(path, []byte(rawSamplesHeader+"\nt0.at100.bt200.c\nt0.a!\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadProfile(path); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("LoadProfile of a bad third line = %v, want its line number", err)
+	}
+}
+
+func TestEnrollRawVerifies(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "user"+rawSamplesExt)
+	t.Setenv(encodedInputEnv, "t0.at100.bt200.c")
+
+	if result := runApp(t, "enroll", "--profile", path, "--raw"); result.err != nil {
+		t.Fatal(result.err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isRawSamples(data) || strings.Count(string(data), "t0.at100.bt200.c\n") != 3 {
+		t.Errorf("enroll --raw saved %q, want the 3 samples", data)
+	}
+
+	for _, tc := range []struct {
+		typed string
+		code  int
+	}{
+		{"t0.at105.bt195.c", 0},
+		{"t0.at300.bt50.c", exitReject},
+	} {
+		t.Setenv(encodedInputEnv, tc.typed)
+		result := runApp(t, "verify", "--profiles-dir", dir, "--reject-delay", "0")
+		if result.code != tc.code {
+			t.Errorf("verify of %s against raw samples exited %d (%v), want %d", tc.typed, result.code, result.err, tc.code)
+		}
+	}
+
+	if result := runApp(t, "enroll", "--profile", path, "--raw", "--salt", "20"); result.err == nil {
+		t.Error("enroll --raw --salt succeeded")
+	}
+}
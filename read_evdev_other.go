@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+type evdevRecorder struct{}
+
+func openEvdev(path string) (*evdevRecorder, error) {
+	return nil, errors.New("evdev is only available on linux")
+}
+
+func (r *evdevRecorder) stop() []keyPress {
+	return nil
+}
@@ -0,0 +1,141 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Event type and key codes of linux/input-event-codes.h.
+const (
+	evKey       = 0x01
+	keyReleased = 0
+	keyPressed  = 1
+)
+
+// evdevModifiers are the keys pressed along with a character rather than
+// typing one, and the modifier they hold if it's recorded.
+var evdevModifiers = map[uint16]Modifiers{
+	29:  ModCtrl,  // KEY_LEFTCTRL
+	42:  ModShift, // KEY_LEFTSHIFT
+	54:  ModShift, // KEY_RIGHTSHIFT
+	56:  ModAlt,   // KEY_LEFTALT
+	58:  0,        // KEY_CAPSLOCK
+	97:  ModCtrl,  // KEY_RIGHTCTRL
+	100: ModAlt,   // KEY_RIGHTALT
+	125: 0,        // KEY_LEFTMETA
+	126: 0,        // KEY_RIGHTMETA
+}
+
+// evdevRecorder collects the key presses of an input device until
+// stopped.
+type evdevRecorder struct {
+	f       *os.File
+	mu      sync.Mutex
+	presses []keyPress
+	done    chan struct{}
+}
+
+// openEvdev records the key presses of the input device at path, like
+// /dev/input/event3. Reading it requires the read permission on the
+// device, usually being root or in the input group, and grants reading
+// every key typed on it, in any application.
+func openEvdev(path string) (*evdevRecorder, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &evdevRecorder{f: f, done: make(chan struct{})}
+	go r.record()
+	return r, nil
+}
+
+// record reads struct input_event: a struct timeval of two longs, then
+// the type, code and value.
+func (r *evdevRecorder) record() {
+	defer close(r.done)
+
+	long := int(unsafe.Sizeof(syscall.Timeval{}.Sec))
+	event := make([]byte, 2*long+8)
+	held := map[uint16]bool{}
+	for {
+		if _, err := r.f.Read(event); err != nil {
+			return
+		}
+
+		typ := binary.NativeEndian.Uint16(event[2*long:])
+		code := binary.NativeEndian.Uint16(event[2*long+2:])
+		value := int32(binary.NativeEndian.Uint32(event[2*long+4:]))
+		if typ != evKey {
+			continue
+		}
+
+		if _, ok := evdevModifiers[code]; ok {
+			held[code] = value != keyReleased
+			continue
+		}
+
+		if value == keyReleased {
+			r.release(code, eventTime(event, long))
+			continue
+		}
+		if value != keyPressed {
+			continue
+		}
+
+		modifiers := Modifiers(0)
+		for code, down := range held {
+			if down {
+				modifiers |= evdevModifiers[code]
+			}
+		}
+
+		r.mu.Lock()
+		r.presses = append(r.presses, keyPress{At: eventTime(event, long), Modifiers: modifiers, Code: code})
+		r.mu.Unlock()
+	}
+}
+
+// eventTime returns the struct timeval an event starts with, of longs of
+// long bytes.
+func eventTime(event []byte, long int) time.Time {
+	sec, usec := int64(0), int64(0)
+	if long == 8 {
+		sec, usec = int64(binary.NativeEndian.Uint64(event)), int64(binary.NativeEndian.Uint64(event[8:]))
+	} else {
+		sec, usec = int64(int32(binary.NativeEndian.Uint32(event))), int64(int32(binary.NativeEndian.Uint32(event[4:])))
+	}
+
+	return time.Unix(sec, usec*1000)
+}
+
+// release records at as the release of the last press of the key code
+// still held. Releases of keys pressed before recording started match
+// none.
+func (r *evdevRecorder) release(code uint16, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := len(r.presses) - 1; i >= 0; i-- {
+		if r.presses[i].Code == code && r.presses[i].Released.IsZero() {
+			r.presses[i].Released = at
+			return
+		}
+	}
+}
+
+// stop closes the device and returns the presses recorded.
+func (r *evdevRecorder) stop() []keyPress {
+	r.f.Close()
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.presses
+}
@@ -0,0 +1,116 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// inputEvent encodes a struct input_event like the kernel does.
+func inputEvent(at time.Time, typ, code uint16, value int32) []byte {
+	event := []byte{}
+	if unsafe.Sizeof(syscall.Timeval{}.Sec) == 8 {
+		event = binary.NativeEndian.AppendUint64(event, uint64(at.Unix()))
+		event = binary.NativeEndian.AppendUint64(event, uint64(at.Nanosecond()/1000))
+	} else {
+		event = binary.NativeEndian.AppendUint32(event, uint32(at.Unix()))
+		event = binary.NativeEndian.AppendUint32(event, uint32(at.Nanosecond()/1000))
+	}
+	event = binary.NativeEndian.AppendUint16(event, typ)
+	event = binary.NativeEndian.AppendUint16(event, code)
+	return binary.NativeEndian.AppendUint32(event, uint32(value))
+}
+
+func TestEvdevRecorder(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	start := time.Unix(1700000000, 0)
+	for _, event := range [][]byte{
+		inputEvent(start, evKey, 30, keyPressed),
+		inputEvent(start.Add(50*time.Millisecond), evKey, 30, keyReleased),
+		// A sync event, ignored.
+		inputEvent(start.Add(60*time.Millisecond), 0, 0, 0),
+		inputEvent(start.Add(100*time.Millisecond), evKey, 42, keyPressed),
+		inputEvent(start.Add(120*time.Millisecond+500*time.Microsecond), evKey, 48, keyPressed),
+		// Autorepeat, not a press.
+		inputEvent(start.Add(130*time.Millisecond), evKey, 48, 2),
+		inputEvent(start.Add(140*time.Millisecond), evKey, 42, keyReleased),
+		inputEvent(start.Add(200*time.Millisecond), evKey, 46, keyPressed),
+	} {
+		if _, err := w.Write(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := &evdevRecorder{f: r, done: make(chan struct{})}
+	go recorder.record()
+	// Stopping closes the device, wait for every event to be read first.
+	w.Close()
+	<-recorder.done
+
+	presses := recorder.stop()
+	if len(presses) != 3 {
+		t.Fatalf("recorded %d presses, want 3: %v", len(presses), presses)
+	}
+
+	if presses[1].Modifiers != ModShift || presses[0].Modifiers != 0 || presses[2].Modifiers != 0 {
+		t.Errorf("modifiers = %v, %v, %v, want shift on the second press only", presses[0].Modifiers, presses[1].Modifiers, presses[2].Modifiers)
+	}
+
+	timings := pressTimings(presses, time.Microsecond)
+	if timings[1] != 120500*time.Microsecond || timings[2] != 79500*time.Microsecond {
+		t.Errorf("timings = %v, want 120.5ms and 79.5ms", timings)
+	}
+}
+
+// Releases pair with the last press of their key still held, and
+// modifiers aren't characters held.
+func TestEvdevRecorderReleases(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	start := time.Unix(1700000000, 0)
+	for _, event := range [][]byte{
+		// Released before recording, of no press.
+		inputEvent(start, evKey, 31, keyReleased),
+		inputEvent(start.Add(10*time.Millisecond), evKey, 30, keyPressed),
+		inputEvent(start.Add(50*time.Millisecond), evKey, 42, keyPressed),
+		// Rolled over: b pressed before a is released.
+		inputEvent(start.Add(60*time.Millisecond), evKey, 48, keyPressed),
+		inputEvent(start.Add(90*time.Millisecond), evKey, 30, keyReleased),
+		inputEvent(start.Add(100*time.Millisecond), evKey, 42, keyReleased),
+		inputEvent(start.Add(150*time.Millisecond), evKey, 48, keyReleased),
+		inputEvent(start.Add(200*time.Millisecond), evKey, 30, keyPressed),
+		inputEvent(start.Add(270*time.Millisecond), evKey, 30, keyReleased),
+		// Held past the end of the capture.
+		inputEvent(start.Add(300*time.Millisecond), evKey, 46, keyPressed),
+	} {
+		if _, err := w.Write(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := &evdevRecorder{f: r, done: make(chan struct{})}
+	go recorder.record()
+	w.Close()
+	<-recorder.done
+
+	presses := recorder.stop()
+	want := []time.Duration{80 * time.Millisecond, 90 * time.Millisecond, 70 * time.Millisecond, 0}
+	if dwells := pressDwells(presses, time.Millisecond); !slices.Equal(dwells, want) {
+		t.Errorf("dwells = %v, want %v", dwells, want)
+	}
+}
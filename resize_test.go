@@ -0,0 +1,78 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// redraws counts the prompt redraws written to stderr, in path.
+func redraws(t *testing.T, path string) int {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Count(string(b), "\r\033[K")
+}
+
+// A resize redraws the prompt while a key is captured, and nothing once
+// the capture is over.
+func TestReadPromptedRedrawsOnResize(t *testing.T) {
+	scriptStdin(t, "ab\n")
+	testMode = false
+	stubCbreak(t)
+
+	stderr, err := os.Create(t.TempDir() + "/stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	savedStderr := os.Stderr
+	os.Stderr = stderr
+	t.Cleanup(func() { os.Stderr = savedStderr })
+
+	redrawn := false
+	resize := func() {
+		if redrawn {
+			return
+		}
+
+		syscall.Kill(os.Getpid(), syscall.SIGWINCH)
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if redraws(t, stderr.Name()) > 0 {
+				redrawn = true
+				return
+			}
+		}
+	}
+
+	rk, err := readPrompted("key: ", ReadOptions{OnTiming: func(time.Duration) { resize() }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rk.Zero()
+	if !redrawn {
+		t.Fatal("the prompt wasn't redrawn on a resize during the capture")
+	}
+
+	// Wait for the signal to be delivered before checking nothing else
+	// was redrawn.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+	defer signal.Stop(sigs)
+	syscall.Kill(os.Getpid(), syscall.SIGWINCH)
+	<-sigs
+	time.Sleep(10 * time.Millisecond)
+
+	if n := redraws(t, stderr.Name()); n != 1 {
+		t.Errorf("prompt redrawn %d times, want once, during the capture only", n)
+	}
+}
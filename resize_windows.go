@@ -0,0 +1,5 @@
+package main
+
+func onResize(redraw func()) (stop func()) {
+	return func() {}
+}
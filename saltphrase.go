@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Salts derived from a phrase or drawn at random fall in this range of
+// milliseconds, wide enough to tolerate typing jitter without flattening
+// the rhythm.
+const (
+	minDerivedSalt = 10
+	maxDerivedSalt = 60
+)
+
+// saltPhraseSalt is the fixed scrypt salt of the derivation: a given phrase
+// must always derive the same parameters, so the phrase itself is the only
+// secret and must not be guessable.
+var saltPhraseSalt = []byte("rythmkey salt-phrase v1")
+
+// DeriveSaltPhrase stretches phrase with scrypt into a quantization salt
+// and a 32 bytes key the digest is then an HMAC of. Knowing the hash isn't
+// enough to brute-force the rythmkey offline without also guessing the
+// phrase, which costs a scrypt derivation per guess.
+func DeriveSaltPhrase(phrase string) (int, []byte, error) {
+	derived, err := scrypt.Key([]byte(phrase), saltPhraseSalt, 1<<15, 8, 1, 36)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	salt := minDerivedSalt + int(binary.BigEndian.Uint32(derived[:4])%(maxDerivedSalt-minDerivedSalt+1))
+	return salt, derived[4:], nil
+}
+
+// RandomSalt draws a timing salt, the width of the buckets timings are
+// quantized to, uniformly from the range of derived salts with a
+// cryptographic random source. It's only one of the 51 widths of the
+// range, a choice of quantization per key rather than a salt in the
+// cryptographic sense: the same key hashed twice is quantized the same one
+// time in 51, and a guess costs as much whatever the width. It isn't a
+// secret either, verifying needs it back from the parameters of the hash.
+func RandomSalt() (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(maxDerivedSalt-minDerivedSalt+1))
+	if err != nil {
+		return 0, err
+	}
+
+	return minDerivedSalt + int(n.Int64()), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// The derivation is frozen: if it changes, every hash made with a salt
+// phrase breaks.
+func TestDeriveSaltPhrasePinned(t *testing.T) {
+	salt, key, err := DeriveSaltPhrase("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 39; salt != want {
+		t.Errorf("salt = %d, want %d", salt, want)
+	}
+
+	if got, want := hex.EncodeToString(key), "fed52f5d7303eececf56a3ba642cb3e6a829eed8b8348b1c47a4bb33781c16f4"; got != want {
+		t.Errorf("key = %s, want %s", got, want)
+	}
+}
+
+func TestDeriveSaltPhraseDiffers(t *testing.T) {
+	salt, key, err := DeriveSaltPhrase("correct horse battery stapler")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if salt < minDerivedSalt || salt > maxDerivedSalt {
+		t.Errorf("salt %d out of [%d, %d]", salt, minDerivedSalt, maxDerivedSalt)
+	}
+
+	_, pinned, _ := DeriveSaltPhrase("correct horse battery staple")
+	if bytes.Equal(key, pinned) {
+		t.Error("two phrases derive the same key")
+	}
+}
+
+func TestRandomSalt(t *testing.T) {
+	seen := map[int]bool{}
+	for i := 0; i < 500; i++ {
+		salt, err := RandomSalt()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if salt < minDerivedSalt || salt > maxDerivedSalt {
+			t.Fatalf("salt %d out of [%d, %d]", salt, minDerivedSalt, maxDerivedSalt)
+		}
+		seen[salt] = true
+	}
+
+	// 500 draws of 51 widths all but surely cover half of them.
+	if len(seen) < (maxDerivedSalt-minDerivedSalt+1)/2 {
+		t.Errorf("500 draws gave only %d salts", len(seen))
+	}
+}
+
+// A hash made with a random salt keeps it in its parameters, so it
+// verifies against the same key without the salt being given again.
+func TestRandomSaltVerifies(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at123.bt7.c")
+
+	for _, command := range [][]string{
+		{"read", "--hash", "--random-salt"},
+		{"hash", "--rythmkey", "t0.at123.bt7.c", "--random-salt"},
+	} {
+		result := runApp(t, command...)
+		if result.err != nil {
+			t.Fatalf("%v = %v", command, result.err)
+		}
+		hash := strings.TrimSpace(result.stdout)
+
+		hp, err := ParseHashParams(hash)
+		if err != nil {
+			t.Fatalf("ParseHashParams(%s) = %v", hash, err)
+		}
+		if salt := hp.Options.Salt; salt < minDerivedSalt || salt > maxDerivedSalt {
+			t.Errorf("%v salt = %d, out of [%d, %d]", command, salt, minDerivedSalt, maxDerivedSalt)
+		}
+
+		if result := runApp(t, "verify", "--hash", hash, "--reject-delay", "0"); result.err != nil || result.code != 0 {
+			t.Errorf("verify of the same key against %s = %v, exit %d", hash, result.err, result.code)
+		}
+
+		// Other characters, whatever the width.
+		t.Setenv(encodedInputEnv, "t0.at123.bt7.x")
+		if result := runApp(t, "verify", "--hash", hash, "--reject-delay", "0"); result.code != exitReject {
+			t.Errorf("verify of another key against %s exited %d, want %d", hash, result.code, exitReject)
+		}
+		t.Setenv(encodedInputEnv, "t0.at123.bt7.c")
+	}
+
+	for _, args := range [][]string{
+		{"read", "--hash", "--random-salt", "--salt", "20"},
+		{"read", "--hash", "--random-salt", "--salt-phrase", "correct horse battery staple"},
+		{"read", "--hash", "--random-salt", "--bare"},
+	} {
+		if result := runApp(t, args...); result.err == nil {
+			t.Errorf("%v succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// SessionEntry is a capture logged for later study of how one's typing
+// evolves. Unlike an AuditEvent it holds the key itself, its timings only
+// when logged privately.
+type SessionEntry struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	// Rythmkey is the encoded key, left out by a timings only log.
+	Rythmkey string `json:"rythmkey,omitempty"`
+	// Timings in milliseconds, by position.
+	Timings []float64 `json:"timings_ms"`
+	// Score of the key when it was compared or verified.
+	Score *float64 `json:"score,omitempty"`
+}
+
+// NewSessionEntry records rk, its characters too unless timingsOnly.
+func NewSessionEntry(command string, rk Rythmkey, timingsOnly bool) SessionEntry {
+	entry := SessionEntry{Time: now(), Command: command, Timings: rk.Vector(false)}
+	if !timingsOnly {
+		entry.Rythmkey = rk.Encode()
+	}
+
+	return entry
+}
+
+// AppendSession appends entry to the file at path as a line of JSON,
+// creating it readable by its owner only. The line is written at once on a
+// file opened for appending, so concurrent writers don't interleave, and
+// synced before returning.
+func AppendSession(path string, entry SessionEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+func sessionLogFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "session-log",
+			Value: "",
+			Usage: "append every capture, as a JSON line with its score when there is one, to this file created with 0600 permissions",
+		}, &cli.BoolFlag{
+			Name:  "session-log-timings-only",
+			Value: false,
+			Usage: "leave the characters out of the session log, only logging the timings",
+		},
+	}
+}
+
+// logSession logs rk to the --session-log, if any, with score unless nil,
+// only telling it would with --dry-run. A failure is only warned about, so
+// logging never fails a capture.
+func logSession(cCtx *cli.Context, rk Rythmkey, score *float64) {
+	path := cCtx.String("session-log")
+	if path == "" {
+		return
+	}
+
+	if cCtx.Bool("dry-run") {
+		fmt.Fprintf(os.Stderr, "dry run: skipped appending the capture to the session log %s\n", path)
+		return
+	}
+
+	entry := NewSessionEntry(cCtx.Command.Name, rk, cCtx.Bool("session-log-timings-only"))
+	entry.Score = score
+	if err := AppendSession(path, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: can't write the session log: %v\n", err)
+	}
+}
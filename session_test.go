@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sessionLines reads the session log at path as JSON objects, one per
+// line.
+func sessionLines(t *testing.T, path string) []map[string]any {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "\n") {
+		t.Errorf("session log %q doesn't end a line", data)
+	}
+
+	lines := []map[string]any{}
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		fields := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatalf("session log line %q: %v", line, err)
+		}
+		lines = append(lines, fields)
+	}
+
+	return lines
+}
+
+// fieldNames returns the sorted names of the fields of a JSON object.
+func fieldNames(fields map[string]any) []string {
+	names := []string{}
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+func TestAppendSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	rk := mustParse(t, "t0.xt120.yt80.z")
+	score := 0.75
+
+	entry := NewSessionEntry("verify", rk, false)
+	entry.Score = &score
+	for _, entry := range []SessionEntry{entry, NewSessionEntry("read", rk, true)} {
+		if err := AppendSession(path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("session log created with %v, want 0600", perm)
+	}
+
+	lines := sessionLines(t, path)
+	if len(lines) != 2 {
+		t.Fatalf("%d lines logged, want 2", len(lines))
+	}
+
+	if names, want := fieldNames(lines[0]), []string{"command", "rythmkey", "score", "time", "timings_ms"}; !slices.Equal(names, want) {
+		t.Errorf("entry fields = %v, want %v", names, want)
+	}
+	if lines[0]["command"] != "verify" || lines[0]["rythmkey"] != "t0.xt120.yt80.z" || lines[0]["score"] != 0.75 {
+		t.Errorf("entry = %v", lines[0])
+	}
+	if timings := lines[0]["timings_ms"]; !reflect.DeepEqual(timings, []any{0.0, 120.0, 80.0}) {
+		t.Errorf("timings = %v, want 0, 120 and 80", timings)
+	}
+
+	// Timings only: no characters at all, no score when there's none.
+	if names, want := fieldNames(lines[1]), []string{"command", "time", "timings_ms"}; !slices.Equal(names, want) {
+		t.Errorf("timings only entry fields = %v, want %v", names, want)
+	}
+	data, _ := os.ReadFile(path)
+	if second := strings.Split(string(data), "\n")[1]; strings.ContainsAny(second, "xyz") {
+		t.Errorf("timings only entry %q holds characters", second)
+	}
+}
+
+func TestSessionLogFlags(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "session.jsonl")
+	saveProfile(t, dir, "user.json", mustProfile(t, "t0.qt0.wt0.z"))
+
+	scriptStdin(t, "qwz\n")
+	if result := runApp(t, "read", "--session-log", path); result.err != nil {
+		t.Fatal(result.err)
+	}
+	scriptStdin(t, "qwz\n")
+	if result := runApp(t, "verify", "--profiles-dir", dir, "--session-log", path, "--session-log-timings-only", "--reject-delay", "0"); result.err != nil {
+		t.Fatal(result.err)
+	}
+	scriptStdin(t, "qwz\n")
+	if result := runApp(t, "verify", "--profiles-dir", dir, "--session-log", path, "--dry-run", "--reject-delay", "0"); result.err != nil || !strings.Contains(result.stderr, "dry run") {
+		t.Errorf("verify --dry-run = %v, %q", result.err, result.stderr)
+	}
+
+	lines := sessionLines(t, path)
+	if len(lines) != 2 {
+		t.Fatalf("%d lines logged, want 2, the dry run logging none", len(lines))
+	}
+	if lines[0]["command"] != "read" || lines[0]["rythmkey"] != "t0.qt0.wt0.z" || lines[0]["score"] != nil {
+		t.Errorf("read logged %v", lines[0])
+	}
+	if lines[1]["command"] != "verify" || lines[1]["rythmkey"] != nil || lines[1]["score"] != 1.0 {
+		t.Errorf("verify logged %v", lines[1])
+	}
+}
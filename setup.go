@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maxRecommendedSalt bounds the salts Recommend tries: coarser ones
+// flatten the rhythm too much for the hash to be worth anything.
+const maxRecommendedSalt = 200
+
+var errSetupCanceled = errors.New("setup canceled")
+
+// Recommendation is what setup suggests for a set of enrollment samples.
+type Recommendation struct {
+	// Salt is the finest one, from minDerivedSalt, all samples hash the
+	// same with, 0 if none up to maxRecommendedSalt does.
+	Salt int `json:"salt"`
+	// Threshold is the lowest score of the samples against their profile,
+	// capped to defaultThreshold, so every enrolled sample would verify.
+	Threshold float64 `json:"threshold"`
+}
+
+// Recommend derives the hashing and verification parameters fitting the
+// samples p was enrolled from.
+func Recommend(samples []Rythmkey, p Profile) (Recommendation, error) {
+	r := Recommendation{Threshold: defaultThreshold}
+	for _, sample := range samples {
+		score, err := p.Score(sample)
+		if err != nil {
+			return Recommendation{}, err
+		}
+		r.Threshold = min(r.Threshold, score)
+	}
+
+	for salt := minDerivedSalt; salt <= maxRecommendedSalt && r.Salt == 0; salt++ {
+		if quantizeTheSame(samples, salt) {
+			r.Salt = salt
+		}
+	}
+
+	return r, nil
+}
+
+func quantizeTheSame(samples []Rythmkey, salt int) bool {
+	reference := samples[0].Quantize(salt, false)
+	for _, sample := range samples[1:] {
+		for i, ct := range sample.Quantize(salt, false) {
+			if ct.Timing != reference[i].Timing {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Setup guides through enrolling n samples, saves their profile to path
+// and prints on w the flags recommended to hash and verify with. Ctrl-d at
+// any prompt cancels it.
+func Setup(path string, n int, ro ReadOptions, w io.Writer) error {
+	defer stty("-cbreak", "echo").Run()
+
+	fmt.Fprintf(os.Stderr, "Type your passphrase %d times, the way you usually do, and press enter after each.\n", n)
+	fmt.Fprintln(os.Stderr, "Both the characters and the rhythm you type them with make up your rythmkey. Press ctrl-d to cancel.")
+
+	samples := []Rythmkey{}
+	defer func() {
+		for _, sample := range samples {
+			sample.Zero()
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		rk, err := readPrompted(fmt.Sprintf("sample %d/%d: ", i+1, n), ro)
+		if err != nil {
+			return err
+		}
+
+		if endOfInput(rk) {
+			rk.Zero()
+			return errSetupCanceled
+		}
+
+		if len(samples) > 0 && rk.Chars() != samples[0].Chars() {
+			rk.Zero()
+			fmt.Fprintln(os.Stderr, "this sample's characters differ from the first one's, type it again")
+			i--
+			continue
+		}
+
+		samples = append(samples, rk)
+	}
+
+	p, err := NewProfile(samples, ro.resolution())
+	if err != nil {
+		return err
+	}
+
+	r, err := Recommend(samples, p)
+	if err != nil {
+		return err
+	}
+
+	p.Salt = r.Salt
+	if ro.Smooth > 1 {
+		p.Smooth = ro.Smooth
+	}
+	if err := p.Save(path); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "profile saved to %s\n", path)
+	fmt.Fprintf(w, "verify against it with: rythmkey verify --profiles-dir <its directory> --threshold %.2f\n", r.Threshold)
+	if r.Salt == 0 {
+		fmt.Fprintf(w, "your samples vary too much to hash consistently, even with a %dms salt: verify against the profile\n", maxRecommendedSalt)
+		return nil
+	}
+
+	fmt.Fprintf(w, "or hash it with: rythmkey hash --salt %d\n", r.Salt)
+	if r.Salt > 100 {
+		fmt.Fprintln(w, "that salt is coarse and leaves little of your rhythm in the hash, consider enrolling again more consistently")
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecommend(t *testing.T) {
+	for _, tc := range []struct {
+		samples   []string
+		salt      int
+		threshold float64
+	}{
+		// Consistent samples hash the same from the finest salt on.
+		{[]string{"t0.at120.bt80.c", "t0.at125.bt85.c", "t0.at128.bt82.c"}, 10, defaultThreshold},
+		// 118 and 123 first fall in the same bucket with a 13ms salt.
+		{[]string{"t0.at118.bt80.c", "t0.at123.bt80.c"}, 13, defaultThreshold},
+		// No salt up to maxRecommendedSalt flattens them enough.
+		{[]string{"t0.at100.b", "t0.at1000.b"}, 0, defaultThreshold},
+		// An outlier scores half against the profile, which becomes the
+		// threshold so it verifies too.
+		{[]string{"t0.at100.b", "t0.at100.b", "t0.at100.b", "t0.at100.b", "t0.at100.b", "t0.at400.b"}, 0, 0.5},
+	} {
+		samples := []Rythmkey{}
+		for _, rks := range tc.samples {
+			samples = append(samples, mustParse(t, rks))
+		}
+
+		r, err := Recommend(samples, mustProfile(t, tc.samples...))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if r.Salt != tc.salt || r.Threshold != tc.threshold {
+			t.Errorf("Recommend(%v) = %+v, want salt %d, threshold %v", tc.samples, r, tc.salt, tc.threshold)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.json")
+
+	// The sample typed with other characters is asked again.
+	scriptStdin(t, "abc\nabd\nabc\nabc\n")
+	result := runApp(t, "setup", "--profile", path, "--samples", "3")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	if !strings.Contains(result.stderr, "differ from the first one's") {
+		t.Errorf("setup stderr = %q, want the differing sample asked again", result.stderr)
+	}
+	if !strings.Contains(result.stdout, "profile saved to "+path) || !strings.Contains(result.stdout, "rythmkey hash --salt") {
+		t.Errorf("setup stdout = %q, want the profile saved and a salt recommended", result.stdout)
+	}
+
+	profiles, err := LoadProfiles(filepath.Dir(path))
+	if err != nil || len(profiles) != 1 || profiles[0].Profile.Chars != "abc" || profiles[0].Profile.Salt == 0 {
+		t.Errorf("saved profiles = %+v, %v, want one of abc with the salt recommended", profiles, err)
+	}
+}
+
+func TestSetupCanceled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.json")
+
+	// Input ends after the first sample, as ctrl-d does.
+	scriptStdin(t, "abc\n")
+	result := runApp(t, "setup", "--profile", path, "--samples", "3")
+	if !errors.Is(result.err, errSetupCanceled) {
+		t.Errorf("setup ended early = %v, want %v", result.err, errSetupCanceled)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("canceled setup saved a profile: %v", err)
+	}
+
+	if result := runApp(t, "setup", "--profile", path, "--samples", "1"); result.err == nil {
+		t.Error("setup of a single sample succeeded")
+	}
+}
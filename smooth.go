@@ -0,0 +1,50 @@
+package main
+
+import "time"
+
+// Smooth returns a copy of the key with each interval replaced by the
+// centered moving average of the window intervals around it, the window
+// shrinking at both ends. The first timing, which isn't an interval, stays
+// zero and characters are unchanged.
+func (rythmkey Rythmkey) Smooth(window int) Rythmkey {
+	rk := make(Rythmkey, len(rythmkey))
+	for i, ct := range rythmkey {
+		rk[i] = &CharTiming{Timing: ct.Timing, Char: ct.Char, Modifiers: ct.Modifiers}
+	}
+
+	half := window / 2
+	for i := 1; i < len(rythmkey); i++ {
+		lo, hi := max(1, i-half), min(len(rythmkey)-1, i+half)
+
+		sum := time.Duration(0)
+		for j := lo; j <= hi; j++ {
+			sum += rythmkey[j].Timing
+		}
+		rk[i].Timing = sum / time.Duration(hi-lo+1)
+	}
+
+	return rk
+}
+
+// TrailingSmoother averages a stream of timings over the last Window of
+// them, to display a steady rhythm while a key is typed. It never touches
+// the captured timings.
+type TrailingSmoother struct {
+	Window int
+	recent []time.Duration
+}
+
+// Add a timing and return the mean of the trailing window.
+func (s *TrailingSmoother) Add(timing time.Duration) time.Duration {
+	s.recent = append(s.recent, timing)
+	if len(s.recent) > max(1, s.Window) {
+		s.recent = s.recent[1:]
+	}
+
+	sum := time.Duration(0)
+	for _, t := range s.recent {
+		sum += t
+	}
+
+	return sum / time.Duration(len(s.recent))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSmooth(t *testing.T) {
+	rk := mustParse(t, "t0.at100.bt200.ct300.dt400.e")
+
+	for _, tc := range []struct {
+		window int
+		want   string
+	}{
+		{1, "t0.at100.bt200.ct300.dt400.e"},
+		// The window shrinks at both ends and never takes in the first
+		// timing: (100+200)/2, (100+200+300)/3 ... (300+400)/2.
+		{3, "t0.at150.bt200.ct300.dt350.e"},
+		{5, "t0.at200.bt250.ct250.dt300.e"},
+		{99, "t0.at250.bt250.ct250.dt250.e"},
+	} {
+		if got := rk.Smooth(tc.window).Encode(); got != tc.want {
+			t.Errorf("Smooth(%d) = %q, want %q", tc.window, got, tc.want)
+		}
+	}
+
+	if rk.Encode() != "t0.at100.bt200.ct300.dt400.e" {
+		t.Errorf("Smooth changed the key to %q", rk.Encode())
+	}
+}
+
+func TestSmoothShortKeys(t *testing.T) {
+	for _, rks := range []string{"t0.a", "t0.at120.b"} {
+		if got := mustParse(t, rks).Smooth(3).Encode(); got != rks {
+			t.Errorf("Smooth(3) of %q = %q, want it unchanged", rks, got)
+		}
+	}
+
+	if rk := (Rythmkey{}).Smooth(3); rk.Len() != 0 {
+		t.Errorf("Smooth(3) of an empty key = %v", rk)
+	}
+}
+
+func TestReadSmoothsCapture(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.at100.bt200.ct300.d")
+
+	rk := Rythmkey{}
+	if err := rk.ReadWith(ReadOptions{Smooth: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := rk.Encode(), "t0.at150.bt200.ct250.d"; got != want {
+		t.Errorf("captured %q, want %q", got, want)
+	}
+}
+
+func TestReadSmoothZeroesOriginal(t *testing.T) {
+	scriptStdin(t, "abcd\n")
+
+	raw := Rythmkey{}
+	ro := ReadOptions{Smooth: 3}
+	rawCapture(&ro, &raw)
+
+	rk := Rythmkey{}
+	if err := rk.ReadWith(ro); err != nil {
+		t.Fatal(err)
+	}
+
+	if rk.Chars() != "abcd" {
+		t.Errorf("captured %q, want %q", rk.Chars(), "abcd")
+	}
+	requireZeroed(t, "key before smoothing", raw)
+}
+
+func TestTrailingSmoother(t *testing.T) {
+	const ms = time.Millisecond
+	stream := []time.Duration{100 * ms, 200 * ms, 300 * ms, 0, 600 * ms}
+
+	for _, tc := range []struct {
+		window int
+		means  []time.Duration
+	}{
+		{3, []time.Duration{100 * ms, 150 * ms, 200 * ms, 500 * ms / 3, 300 * ms}},
+		{2, []time.Duration{100 * ms, 150 * ms, 250 * ms, 150 * ms, 300 * ms}},
+		// A window of one or less shows every timing as is.
+		{1, stream},
+		{0, stream},
+	} {
+		s := TrailingSmoother{Window: tc.window}
+		for i, timing := range stream {
+			if mean := s.Add(timing); mean != tc.means[i] {
+				t.Errorf("window %d: Add(%v) at %d = %v, want %v", tc.window, timing, i, mean, tc.means[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ProfileStore keeps enrolled profiles by id, letting programs importing
+// the package keep them in a database or a key value store rather than in
+// files. Load of an unknown id fails with a *ProfileNotFoundError, List
+// returns every id Load accepts, sorted, and an id saved then loaded
+// returns an equal profile. Stores needn't be safe for concurrent use.
+type ProfileStore interface {
+	Load(id string) (Profile, error)
+	Save(id string, p Profile) error
+	List() ([]string, error)
+}
+
+type ProfileNotFoundError struct {
+	ID string
+}
+
+func (err *ProfileNotFoundError) Error() string {
+	return fmt.Sprintf("profile %q not found", err.ID)
+}
+
+// FileProfileStore is the ProfileStore of a directory, the one the
+// commands use: profile id is the file id.json, or the raw samples file
+// id.samples when there is no such profile, see SaveRawSamples. With both,
+// id.json wins and id.samples is ignored, so saving a profile enrolled
+// from raw samples takes precedence over them. Save always writes id.json.
+// Files whose name makes no valid id, like .json or .hidden.samples, are
+// neither listed nor loaded.
+type FileProfileStore struct {
+	Dir string
+}
+
+// path returns the file of id with ext, refusing ids that aren't a plain
+// file name.
+func (s FileProfileStore) path(id string, ext string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid profile id %q", id)
+	}
+
+	return filepath.Join(s.Dir, id+ext), nil
+}
+
+func (s FileProfileStore) Load(id string) (Profile, error) {
+	for _, ext := range []string{".json", rawSamplesExt} {
+		path, err := s.path(id, ext)
+		if err != nil {
+			return Profile{}, err
+		}
+
+		p, err := LoadProfile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return p, err
+	}
+
+	return Profile{}, &ProfileNotFoundError{ID: id}
+}
+
+func (s FileProfileStore) Save(id string, p Profile) error {
+	path, err := s.path(id, ".json")
+	if err != nil {
+		return err
+	}
+
+	return p.Save(path)
+}
+
+func (s FileProfileStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".json" && ext != rawSamplesExt) {
+			continue
+		}
+
+		id := strings.TrimSuffix(entry.Name(), ext)
+		if _, err := s.path(id, ext); err != nil || slices.Contains(ids, id) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// LoadStoredProfiles loads every profile of store, named after its id.
+func LoadStoredProfiles(store ProfileStore) ([]NamedProfile, error) {
+	ids, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []NamedProfile{}
+	for _, id := range ids {
+		p, err := store.Load(id)
+		if err != nil {
+			return nil, err
+		}
+
+		profiles = append(profiles, NamedProfile{Name: id, Profile: p})
+	}
+
+	return profiles, nil
+}
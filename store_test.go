@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// memoryProfileStore is the ProfileStore a program keeping profiles
+// elsewhere than in files would plug in.
+type memoryProfileStore map[string]Profile
+
+func (s memoryProfileStore) Load(id string) (Profile, error) {
+	p, ok := s[id]
+	if !ok {
+		return Profile{}, &ProfileNotFoundError{ID: id}
+	}
+
+	return p, nil
+}
+
+func (s memoryProfileStore) Save(id string, p Profile) error {
+	s[id] = p
+	return nil
+}
+
+func (s memoryProfileStore) List() ([]string, error) {
+	ids := []string{}
+	for id := range s {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// testProfileStore checks store, empty, keeps the ProfileStore contract.
+func testProfileStore(t *testing.T, store ProfileStore) {
+	t.Helper()
+
+	notFound := &ProfileNotFoundError{}
+	if _, err := store.Load("alice"); !errors.As(err, &notFound) || notFound.ID != "alice" {
+		t.Errorf("Load of an unknown id = %v, want a *ProfileNotFoundError", err)
+	}
+
+	profiles := map[string]Profile{
+		"bob":   mustProfile(t, "t0.at100.bt200.c", "t0.at110.bt190.c"),
+		"alice": mustProfile(t, "t0.xt80.y"),
+	}
+	for id, p := range profiles {
+		if err := store.Save(id, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"alice", "bob"}; !slices.Equal(ids, want) {
+		t.Errorf("List = %v, want %v", ids, want)
+	}
+
+	for id, p := range profiles {
+		loaded, err := store.Load(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loaded, p) {
+			t.Errorf("Load(%s) = %+v, want %+v", id, loaded, p)
+		}
+	}
+
+	named, err := LoadStoredProfiles(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(named) != 2 || named[0].Name != "alice" || named[1].Name != "bob" || !reflect.DeepEqual(named[1].Profile, profiles["bob"]) {
+		t.Errorf("LoadStoredProfiles = %+v, want alice then bob", named)
+	}
+}
+
+func TestFileProfileStore(t *testing.T) {
+	testProfileStore(t, FileProfileStore{Dir: t.TempDir()})
+}
+
+func TestMemoryProfileStore(t *testing.T) {
+	testProfileStore(t, memoryProfileStore{})
+}
+
+// With both files of an id, the profile wins over the raw samples.
+func TestFileProfileStorePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	store := FileProfileStore{Dir: dir}
+
+	if err := SaveRawSamples(filepath.Join(dir, "user"+rawSamplesExt), []Rythmkey{mustParse(t, "t0.at300.b")}); err != nil {
+		t.Fatal(err)
+	}
+	samples, err := store.Load("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(samples.Mean, []float64{0, 300}) {
+		t.Errorf("Load of raw samples = %+v, want their mean", samples)
+	}
+
+	if err := store.Save("user", mustProfile(t, "t0.at100.b")); err != nil {
+		t.Fatal(err)
+	}
+	p, err := store.Load("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(p.Mean, []float64{0, 100}) {
+		t.Errorf("Load of a profile next to raw samples = %+v, want the profile", p)
+	}
+
+	if ids, err := store.List(); err != nil || !slices.Equal(ids, []string{"user"}) {
+		t.Errorf("List = %v, %v, want user once", ids, err)
+	}
+}
+
+func TestFileProfileStoreInvalidIDs(t *testing.T) {
+	dir := t.TempDir()
+	store := FileProfileStore{Dir: dir}
+	saveProfile(t, dir, "user.json", mustProfile(t, "t0.at100.b"))
+
+	for _, name := range []string{".json", rawSamplesExt, ".hidden.json", ".hidden" + rawSamplesExt, "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.json"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids, err := store.List(); err != nil || !slices.Equal(ids, []string{"user"}) {
+		t.Errorf("List = %v, %v, want only user", ids, err)
+	}
+	if _, err := LoadStoredProfiles(store); err != nil {
+		t.Errorf("LoadStoredProfiles = %v", err)
+	}
+
+	for _, id := range []string{"", ".hidden", "../user", "sub/user", "."} {
+		notFound := &ProfileNotFoundError{}
+		if _, err := store.Load(id); err == nil || errors.As(err, &notFound) {
+			t.Errorf("Load(%q) = %v, want an invalid id error", id, err)
+		}
+		if err := store.Save(id, mustProfile(t, "t0.at100.b")); err == nil {
+			t.Errorf("Save(%q) succeeded", id)
+		}
+	}
+
+	if _, err := (FileProfileStore{Dir: filepath.Join(dir, "missing")}).List(); err == nil {
+		t.Error("List of a missing directory succeeded")
+	}
+}
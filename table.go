@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+func escapeChar(c byte) string {
+	if c >= 0x80 {
+		return fmt.Sprintf(`'\x%02x'`, c)
+	}
+
+	return strconv.QuoteRune(rune(c))
+}
+
+// WriteTable writes one aligned row per character with its index, its
+// escaped value, its timing and the cumulative time since the first one,
+// both in milliseconds.
+func (rythmkey Rythmkey) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "index\tchar\ttiming\tcumulative")
+
+	cumulative := time.Duration(0)
+	for i, ct := range rythmkey {
+		cumulative += ct.Timing
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", i, escapeChar(ct.Char), formatMilliseconds(ct.Timing), formatMilliseconds(cumulative))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteTable(t *testing.T) {
+	rk := Rythmkey{}
+	rk.Add('a', 0)
+	rk.Add('\t', 120*time.Millisecond)
+	rk.Add(0x01, 80500*time.Microsecond)
+	rk.Add('\'', 0)
+	rk.Add(0xe9, 1000*time.Millisecond)
+
+	buf := bytes.Buffer{}
+	if err := rk.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `index  char    timing  cumulative
+0      'a'     0       0
+1      '\t'    120     120
+2      '\x01'  80.5    200.5
+3      '\''    0       200.5
+4      '\xe9'  1000    1200.5
+`
+	if buf.String() != want {
+		t.Errorf("WriteTable =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestParseTableFormat(t *testing.T) {
+	result := runApp(t, "parse", "--rythmkey", "t0.at120.bt80.c", "--format", "table")
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+
+	want := `index  char  timing  cumulative
+0      'a'   0       0
+1      'b'   120     120
+2      'c'   80      200
+`
+	if result.stdout != want {
+		t.Errorf("parse --format table printed\n%s\nwant\n%s", result.stdout, want)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"text/template"
+
+	"github.com/urfave/cli/v2"
+)
+
+// workflowTemplate enrolls a profile once then verifies in a loop, telling
+// the exit codes of verify apart.
+var workflowTemplate = template.Must(template.New("workflow").Parse(`#!/usr/bin/env {{.Shell}}
+# Enroll a rythmkey once, then verify it before running a command.
+# Generated by rythmkey template, edit to taste.
+
+PROFILES_DIR="${RYTHMKEY_PROFILES_DIR:-$HOME/.rythmkey}"
+PROFILE="$PROFILES_DIR/$USER.json"
+THRESHOLD={{printf "%.2f" .Threshold}}
+ATTEMPTS=3
+
+if [ ! -f "$PROFILE" ]; then
+	mkdir -p "$PROFILES_DIR" || exit 1
+	echo "enroll: type your rythmkey {{.Samples}} times" >&2
+	rythmkey enroll --profile "$PROFILE" --samples {{.Samples}} || exit 1
+fi
+
+attempt=1
+while [ "$attempt" -le "$ATTEMPTS" ]; do
+	rythmkey verify --profiles-dir "$PROFILES_DIR" --threshold "$THRESHOLD"
+	case $? in
+	0)
+		exit 0
+		;;
+	{{.ExitReject}})
+		echo "rejected, type it again" >&2
+		;;
+	{{.ExitWrongSequence}})
+		echo "wrong characters, is Caps Lock on?" >&2
+		;;
+	*)
+		echo "rythmkey failed" >&2
+		exit 1
+		;;
+	esac
+	attempt=$((attempt + 1))
+done
+
+exit {{.ExitReject}}
+`))
+
+// templateFlags are the flags workflowTemplate passes, by command, checked
+// against the application so the script never drifts from it.
+var templateFlags = []struct {
+	command string
+	flags   []string
+}{
+	{"enroll", []string{"profile", "samples"}},
+	{"verify", []string{"profiles-dir", "threshold"}},
+}
+
+// checkTemplateFlags fails if a command or flag workflowTemplate uses is
+// missing from app.
+func checkTemplateFlags(app *cli.App) error {
+	for _, tf := range templateFlags {
+		cmd := app.Command(tf.command)
+		if cmd == nil {
+			return fmt.Errorf("template uses unknown command %q", tf.command)
+		}
+
+		names := []string{}
+		for _, flag := range cmd.Flags {
+			names = append(names, flag.Names()...)
+		}
+		for _, flag := range tf.flags {
+			if !slices.Contains(names, flag) {
+				return fmt.Errorf("template uses unknown flag --%s of %s", flag, tf.command)
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteTemplate writes the enroll then verify workflow for shell to w.
+func WriteTemplate(w io.Writer, shell string, samples int) error {
+	if !slices.Contains(templateShells, shell) {
+		return fmt.Errorf("unknown shell %q", shell)
+	}
+
+	return workflowTemplate.Execute(w, struct {
+		Shell             string
+		Samples           int
+		Threshold         float64
+		ExitReject        int
+		ExitWrongSequence int
+	}{shell, samples, defaultThreshold, exitReject, exitWrongSequence})
+}
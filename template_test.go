@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckTemplateFlags(t *testing.T) {
+	if err := checkTemplateFlags(newApp()); err != nil {
+		t.Fatalf("checkTemplateFlags(app) = %v, want nil", err)
+	}
+
+	saved := templateFlags
+	t.Cleanup(func() { templateFlags = saved })
+
+	unknownFlag := saved[0]
+	unknownFlag.flags = []string{"no-such-flag"}
+	templateFlags = append(slices.Clone(saved), unknownFlag)
+	if err := checkTemplateFlags(newApp()); err == nil {
+		t.Errorf("checkTemplateFlags(app) with an unknown flag = nil, want an error")
+	}
+
+	unknownCommand := saved[0]
+	unknownCommand.command = "no-such-command"
+	templateFlags = append(slices.Clone(saved), unknownCommand)
+	if err := checkTemplateFlags(newApp()); err == nil {
+		t.Errorf("checkTemplateFlags(app) with an unknown command = nil, want an error")
+	}
+}
+
+// templateInvocations returns the flags of every rythmkey command the
+// script runs, by command.
+func templateInvocations(script string) map[string][]string {
+	invocations := map[string][]string{}
+	for _, line := range strings.Split(script, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "rythmkey" {
+			continue
+		}
+
+		flags := []string{}
+		for _, field := range fields[2:] {
+			if flag, ok := strings.CutPrefix(field, "--"); ok {
+				flags = append(flags, flag)
+			}
+		}
+		invocations[fields[1]] = append(invocations[fields[1]], flags...)
+	}
+
+	return invocations
+}
+
+func TestWriteTemplate(t *testing.T) {
+	app := newApp()
+	declared := map[string][]string{}
+	for _, tf := range templateFlags {
+		declared[tf.command] = tf.flags
+	}
+
+	for _, shell := range templateShells {
+		t.Run(shell, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteTemplate(&b, shell, 5); err != nil {
+				t.Fatalf("WriteTemplate(%q) = %v", shell, err)
+			}
+			script := b.String()
+
+			if want := "#!/usr/bin/env " + shell + "\n"; !strings.HasPrefix(script, want) {
+				t.Errorf("script starts with %q, want %q", strings.SplitN(script, "\n", 2)[0], want)
+			}
+			for _, want := range []string{"--samples 5", fmt.Sprintf("\t%d)", exitReject), fmt.Sprintf("\t%d)", exitWrongSequence)} {
+				if !strings.Contains(script, want) {
+					t.Errorf("script doesn't contain %q", want)
+				}
+			}
+
+			// Every command and flag the script runs is checked by
+			// checkTemplateFlags, so exists in the application.
+			invocations := templateInvocations(script)
+			if len(invocations) != len(templateFlags) {
+				t.Errorf("script runs %v, want the commands of templateFlags", invocations)
+			}
+			for command, flags := range invocations {
+				want, ok := declared[command]
+				if !ok {
+					t.Errorf("script runs %s, missing from templateFlags", command)
+					continue
+				}
+				if app.Command(command) == nil {
+					t.Errorf("script runs unknown command %s", command)
+				}
+				for _, flag := range flags {
+					if !slices.Contains(want, flag) {
+						t.Errorf("script passes --%s to %s, missing from templateFlags", flag, command)
+					}
+				}
+			}
+
+			if path, err := exec.LookPath(shell); err == nil {
+				cmd := exec.Command(path, "-n")
+				cmd.Stdin = strings.NewReader(script)
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Errorf("%s -n: %v: %s", shell, err, out)
+				}
+			}
+		})
+	}
+
+	if err := WriteTemplate(&bytes.Buffer{}, "fish", 3); err == nil {
+		t.Errorf("WriteTemplate(fish) = nil, want an error")
+	}
+}
+
+func TestTemplateCommand(t *testing.T) {
+	result := runApp(t, "template", "--shell", "zsh")
+	if result.err != nil {
+		t.Fatalf("template --shell zsh: %v", result.err)
+	}
+	if !strings.HasPrefix(result.stdout, "#!/usr/bin/env zsh\n") {
+		t.Errorf("template --shell zsh wrote %q", result.stdout)
+	}
+
+	if result := runApp(t, "template", "--shell", "fish"); result.err == nil {
+		t.Errorf("template --shell fish succeeded, want an error")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// testModeEnv, like the --test-mode flag, makes every command
+// deterministic and headless for end to end tests of the CLI. It is meant
+// for tests only, never for actual keys:
+//   - captures come from RYTHMKEY_INPUT, RYTHMKEY_INPUT_ENCODED or
+//     --input-fifo as usual, or else from stdin with every timing zeroed,
+//   - /dev/tty is never touched, stty always fails and neither stdin nor
+//     stdout are considered terminals,
+//   - no signal handler is installed,
+//   - the clock is frozen at testClock and verdicts aren't delayed.
+const testModeEnv = "RYTHMKEY_TEST_MODE"
+
+var testMode bool
+
+var (
+	testClock   = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	errTestMode = errors.New("no terminal in test mode")
+)
+
+// now is time.Now, frozen in test mode.
+func now() time.Time {
+	if testMode {
+		return testClock
+	}
+
+	return time.Now()
+}
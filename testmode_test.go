@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+// Every command runs headless in test mode, from stdin with zeroed
+// timings, printing the same whatever the machine.
+func TestTestModeEndToEnd(t *testing.T) {
+	const hash = "$rk$sha256$v=1$s=20$u=ms$8cb57626697c3704489632e275a50366878e9a6bfd081a403e81169cb0becb17"
+
+	for _, tc := range []struct {
+		name       string
+		stdin      string
+		args       []string
+		wantStdout string
+		wantStderr string
+		wantCode   int
+	}{
+		{
+			name:       "read",
+			stdin:      "abc\n",
+			args:       []string{"read", "--hash"},
+			wantStdout: hash,
+			wantStderr: "warning: 100% of the timings are zero, the key carries almost no rhythm; try --hires\n",
+		}, {
+			name:       "hash",
+			args:       []string{"hash", "--rythmkey", "t0.at0.bt0.c"},
+			wantStdout: hash,
+		}, {
+			name:       "same key",
+			stdin:      "abc\n",
+			args:       []string{"verify", "--hash", hash},
+			wantStdout: "accept\n",
+		}, {
+			name:       "case inverted",
+			stdin:      "ABC\n",
+			args:       []string{"verify", "--hash", hash},
+			wantStderr: "reject: " + errCapsLock.Error() + "\n",
+			wantCode:   exitWrongSequence,
+		}, {
+			name:       "other key",
+			stdin:      "abd\n",
+			args:       []string{"verify", "--hash", hash},
+			wantStderr: "reject\n",
+			wantCode:   exitReject,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scriptStdin(t, tc.stdin)
+
+			result := runApp(t, tc.args...)
+			if result.stdout != tc.wantStdout {
+				t.Errorf("stdout = %q, want %q", result.stdout, tc.wantStdout)
+			}
+			if result.stderr != tc.wantStderr {
+				t.Errorf("stderr = %q, want %q", result.stderr, tc.wantStderr)
+			}
+			if result.code != tc.wantCode {
+				t.Errorf("exit code = %d, want %d", result.code, tc.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultTimelineWidth = 80
+
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+
+	out, err := stty("size").Output()
+	if err != nil {
+		return defaultTimelineWidth
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return defaultTimelineWidth
+	}
+
+	columns, err := strconv.Atoi(fields[1])
+	if err != nil || columns <= 0 {
+		return defaultTimelineWidth
+	}
+
+	return columns
+}
+
+// Timeline lays the characters out on a horizontal line, spaced
+// proportionally to their cumulative timing and scaled to width columns.
+// Every character keeps at least one column, keys that don't fit are wrapped.
+func (rythmkey Rythmkey) Timeline(width int) string {
+	if len(rythmkey) == 0 {
+		return ""
+	}
+
+	if width < 1 {
+		width = defaultTimelineWidth
+	}
+
+	total := int64(rythmkey.TotalDuration())
+
+	scale := 0.0
+	if total > 0 {
+		scale = float64(width-1) / float64(total)
+	}
+
+	positions := make([]int, len(rythmkey))
+	cumulative := int64(0)
+	for i, ct := range rythmkey {
+		cumulative += int64(ct.Timing)
+
+		pos := int(float64(cumulative)*scale + 0.5)
+		if i > 0 && pos <= positions[i-1] {
+			pos = positions[i-1] + 1
+		}
+		positions[i] = pos
+	}
+
+	rows := positions[len(positions)-1]/width + 1
+	lines := make([][]byte, rows)
+	for i := range lines {
+		lines[i] = []byte(strings.Repeat(" ", width))
+	}
+
+	for i, ct := range rythmkey {
+		c := ct.Char
+		if c < ' ' || c > '~' {
+			c = '?'
+		}
+		lines[positions[i]/width][positions[i]%width] = c
+	}
+
+	timeline := make([]string, rows)
+	for i, line := range lines {
+		timeline[i] = strings.TrimRight(string(line), " ")
+	}
+
+	return strings.Join(timeline, "\n")
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rk       string
+		width    int
+		timeline string
+	}{
+		// 40 columns for 400ms, b at 100ms and c at 400ms.
+		{"proportional", "t0.at100.bt300.c", 41, "a" + strings.Repeat(" ", 9) + "b" + strings.Repeat(" ", 29) + "c"},
+		// b and c scale to the column of a but each keeps one of its own.
+		{"minimum column", "t0.at1.bt1.ct1000.d", 11, "abc       d"},
+		{"all zero", "t0.at0.bt0.c", 80, "abc"},
+		{"wrapped", "t0.at0.bt0.ct0.dt0.et0.ft0.gt0.ht0.it0.j", 4, "abcd\nefgh\nij"},
+		{"default width", "t0.at100.b", 0, "a" + strings.Repeat(" ", defaultTimelineWidth-2) + "b"},
+	} {
+		if timeline := mustParse(t, tc.rk).Timeline(tc.width); timeline != tc.timeline {
+			t.Errorf("%s: Timeline(%d) of %s = %q, want %q", tc.name, tc.width, tc.rk, timeline, tc.timeline)
+		}
+	}
+
+	rk := Rythmkey{}
+	rk.Add(0x01, 0)
+	rk.Add('a', 100*time.Millisecond)
+	if timeline := rk.Timeline(3); timeline != "? a" {
+		t.Errorf("Timeline of a control character = %q, want it shown as ?", timeline)
+	}
+
+	if timeline := (Rythmkey{}).Timeline(80); timeline != "" {
+		t.Errorf("Timeline of no key = %q", timeline)
+	}
+}
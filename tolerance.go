@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tolerance is the maximum timing difference allowed for a character given
+// its reference timing.
+type Tolerance func(reference time.Duration) time.Duration
+
+// AbsoluteTolerance allows the same difference whatever the reference.
+func AbsoluteTolerance(d time.Duration) Tolerance {
+	return func(time.Duration) time.Duration { return d }
+}
+
+// RelativeTolerance allows a fraction of the reference timing.
+func RelativeTolerance(fraction float64) Tolerance {
+	return func(reference time.Duration) time.Duration {
+		return time.Duration(float64(reference.Abs()) * fraction)
+	}
+}
+
+// ParseTolerance parses a tolerance expression: a duration like "50ms", a
+// percentage of the reference timing like "10%", or the max or min of
+// other expressions like "max(50ms,10%)".
+func ParseTolerance(s string) (Tolerance, error) {
+	tolerance, err := parseTolerance(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tolerance %q: %w", s, err)
+	}
+
+	return tolerance, nil
+}
+
+func parseTolerance(s string) (Tolerance, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	for _, name := range []string{"max", "min"} {
+		args, ok := strings.CutPrefix(s, name+"(")
+		if !ok {
+			continue
+		}
+
+		args, ok = strings.CutSuffix(args, ")")
+		if !ok {
+			return nil, fmt.Errorf("missing ) in %s", s)
+		}
+
+		tolerances := []Tolerance{}
+		for _, arg := range splitArgs(args) {
+			tolerance, err := parseTolerance(strings.TrimSpace(arg))
+			if err != nil {
+				return nil, err
+			}
+			tolerances = append(tolerances, tolerance)
+		}
+
+		if len(tolerances) < 2 {
+			return nil, fmt.Errorf("%s needs at least two arguments", name)
+		}
+
+		isMax := name == "max"
+		return func(reference time.Duration) time.Duration {
+			d := tolerances[0](reference)
+			for _, tolerance := range tolerances[1:] {
+				if isMax {
+					d = max(d, tolerance(reference))
+				} else {
+					d = min(d, tolerance(reference))
+				}
+			}
+			return d
+		}, nil
+	}
+
+	if percent, ok := strings.CutSuffix(s, "%"); ok {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || p < 0 {
+			return nil, fmt.Errorf("bad percentage %s", s)
+		}
+
+		return RelativeTolerance(p / 100), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return nil, fmt.Errorf("bad duration %s", s)
+	}
+
+	return AbsoluteTolerance(d), nil
+}
+
+// splitArgs splits s on the commas that aren't nested in parentheses.
+func splitArgs(s string) []string {
+	args := []string{}
+
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(args, s[start:])
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTolerance(t *testing.T) {
+	for _, tc := range []struct {
+		s         string
+		reference time.Duration
+		tolerance time.Duration
+	}{
+		{"50ms", 300 * time.Millisecond, 50 * time.Millisecond},
+		{"50ms", 0, 50 * time.Millisecond},
+		{"10%", 300 * time.Millisecond, 30 * time.Millisecond},
+		{"10%", -300 * time.Millisecond, 30 * time.Millisecond},
+		{" 10% ", 300 * time.Millisecond, 30 * time.Millisecond},
+		{"max(50ms,10%)", 300 * time.Millisecond, 50 * time.Millisecond},
+		{"max(50ms,10%)", 800 * time.Millisecond, 80 * time.Millisecond},
+		{"min(50ms, 10%)", 300 * time.Millisecond, 30 * time.Millisecond},
+		{"min(50ms,10%)", 800 * time.Millisecond, 50 * time.Millisecond},
+		{"max(20ms,min(50ms,10%),5%)", 800 * time.Millisecond, 50 * time.Millisecond},
+	} {
+		tolerance, err := ParseTolerance(tc.s)
+		if err != nil {
+			t.Errorf("ParseTolerance(%q) failed: %v", tc.s, err)
+			continue
+		}
+
+		if d := tolerance(tc.reference); d != tc.tolerance {
+			t.Errorf("ParseTolerance(%q)(%v) = %v, want %v", tc.s, tc.reference, d, tc.tolerance)
+		}
+	}
+}
+
+func TestParseToleranceMalformed(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"50",
+		"-50ms",
+		"-10%",
+		"ten%",
+		"max(50ms)",
+		"max(50ms,10%",
+		"min(50ms,)",
+		"avg(50ms,10%)",
+	} {
+		if _, err := ParseTolerance(s); err == nil {
+			t.Errorf("ParseTolerance(%q) succeeded", s)
+		}
+	}
+}
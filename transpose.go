@@ -0,0 +1,30 @@
+package main
+
+// AlignTranspositions returns a copy of the key where up to n adjacent
+// transpositions of characters against reference are undone, "hte" typed
+// for "the" becoming "the", and how many were. Only the characters are
+// swapped: the timings stay where they were typed, so the rhythm is still
+// compared position by position. It loosens the character sequence a key
+// must match, a key with a transposition matching once aligned.
+func (rythmkey Rythmkey) AlignTranspositions(reference string, n int) (Rythmkey, int) {
+	rk := make(Rythmkey, len(rythmkey))
+	for i, ct := range rythmkey {
+		rk[i] = &CharTiming{Timing: ct.Timing, Char: ct.Char, Modifiers: ct.Modifiers}
+	}
+
+	swaps := 0
+	for i := 0; i+1 < len(rk) && i+1 < len(reference) && swaps < n; i++ {
+		if rk[i].Char == reference[i] {
+			continue
+		}
+
+		if rk[i].Char == reference[i+1] && rk[i+1].Char == reference[i] {
+			rk[i].Char, rk[i+1].Char = rk[i+1].Char, rk[i].Char
+			rk[i].Modifiers, rk[i+1].Modifiers = rk[i+1].Modifiers, rk[i].Modifiers
+			swaps++
+			i++
+		}
+	}
+
+	return rk, swaps
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAlignTranspositions(t *testing.T) {
+	for _, tc := range []struct {
+		typed, reference string
+		n                int
+		aligned          string
+		swaps            int
+	}{
+		{"t0.ht120.tt80.e", "the", 0, "t0.ht120.tt80.e", 0},
+		{"t0.ht120.tt80.e", "the", 1, "t0.tt120.ht80.e", 1},
+		{"t0.bt100.at100.dt100.c", "abcd", 1, "t0.at100.bt100.dt100.c", 1},
+		{"t0.bt100.at100.dt100.c", "abcd", 2, "t0.at100.bt100.ct100.d", 2},
+		// Not adjacent, not a transposition.
+		{"t0.ct100.bt100.a", "abc", 1, "t0.ct100.bt100.a", 0},
+		{"t0.tt120.ht80.e", "the", 1, "t0.tt120.ht80.e", 0},
+	} {
+		rk := mustParse(t, tc.typed)
+		aligned, swaps := rk.AlignTranspositions(tc.reference, tc.n)
+		if aligned.Encode() != tc.aligned || swaps != tc.swaps {
+			t.Errorf("AlignTranspositions(%s, %s, %d) = %s, %d, want %s, %d", tc.typed, tc.reference, tc.n, aligned.Encode(), swaps, tc.aligned, tc.swaps)
+		}
+		if rk.Encode() != tc.typed {
+			t.Errorf("AlignTranspositions changed the key to %s", rk.Encode())
+		}
+	}
+
+	// The modifiers go with their characters.
+	rk := mustParse(t, "^1t0.ht120.tt80.e")
+	if aligned, _ := rk.AlignTranspositions("the", 1); aligned[0].Modifiers != 0 || aligned[1].Modifiers != ModShift {
+		t.Errorf("AlignTranspositions = %s, want the shift on the h", aligned.Encode())
+	}
+}
+
+// "hte" typed in the rhythm of "the" is accepted with one transposition
+// allowed and rejected without.
+func TestAllowTranspositionsFlag(t *testing.T) {
+	t.Setenv(encodedInputEnv, "t0.ht120.tt80.e")
+
+	for _, tc := range []struct {
+		n     string
+		match bool
+	}{
+		{"0", false},
+		{"1", true},
+	} {
+		result := runApp(t, "compare", "--rythmkey", "t0.tt120.ht80.e", "--allow-transpositions", tc.n, "--format", "json")
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+
+		report := CompareReport{}
+		if err := json.Unmarshal([]byte(result.stdout), &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.Match != tc.match {
+			t.Errorf("compare --allow-transpositions %s matched %t, want %t", tc.n, report.Match, tc.match)
+		}
+	}
+
+	dir := t.TempDir()
+	saveProfile(t, dir, "user.json", mustProfile(t, "t0.tt120.ht80.e", "t0.tt125.ht85.e"))
+	for _, tc := range []struct {
+		n    string
+		code int
+	}{
+		{"0", exitWrongSequence},
+		{"1", 0},
+	} {
+		result := runApp(t, "verify", "--profiles-dir", dir, "--allow-transpositions", tc.n, "--reject-delay", "0")
+		if result.code != tc.code {
+			t.Errorf("verify --allow-transpositions %s exited %d (%v), want %d", tc.n, result.code, result.err, tc.code)
+		}
+	}
+
+	if result := runApp(t, "verify", "--profiles-dir", dir, "--allow-transpositions", "1", "--early-reject"); result.err == nil {
+		t.Error("verify --allow-transpositions --early-reject succeeded")
+	}
+}
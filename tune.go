@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readAnswer reads a line from stdin a byte at a time, so nothing meant for
+// the next capture gets buffered away.
+func readAnswer() (string, error) {
+	answer := []byte{}
+	buf := make([]byte, 1)
+
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 1 {
+			if buf[0] == '\n' {
+				return strings.TrimSpace(string(answer)), nil
+			}
+			answer = append(answer, buf[0])
+		}
+
+		if err != nil {
+			if err == io.EOF && len(answer) > 0 {
+				return strings.TrimSpace(string(answer)), nil
+			}
+			return "", err
+		}
+	}
+}
+
+// TuneInteractively captures samples until EOF, asking after each of them
+// whether it was typed by the enrolled user, and returns the threshold
+// best separating the answers so far.
+func TuneInteractively(p Profile, ro ReadOptions) (float64, error) {
+	samples := []LabeledSample{}
+	scores := []float64{}
+	threshold := defaultThreshold
+
+	for n := 1; ; n++ {
+		rk, err := readPrompted(fmt.Sprintf("sample %d: ", n), ro)
+		if err != nil {
+			return 0, err
+		}
+
+		if len(rk) == 0 {
+			break
+		}
+
+		score, err := p.Score(rk)
+		if err != nil {
+			score = -1
+		}
+		rk.Zero()
+
+		fmt.Fprintf(os.Stderr, "score: %.2f, was this you? (y/n) ", score)
+		answer, err := readAnswer()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			samples = append(samples, LabeledSample{Genuine: true})
+		case "n", "no":
+			samples = append(samples, LabeledSample{Genuine: false})
+		default:
+			fmt.Fprintln(os.Stderr, "answer y or n, sample ignored")
+			continue
+		}
+		scores = append(scores, score)
+
+		threshold = OptimalThreshold(samples, scores)
+		fmt.Fprintf(os.Stderr, "threshold: %.2f\n", threshold)
+	}
+
+	if len(samples) == 0 {
+		return 0, errors.New("no labeled sample")
+	}
+
+	return threshold, nil
+}
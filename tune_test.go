@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptimalThreshold(t *testing.T) {
+	samples, err := ReadDataset(strings.NewReader(evaluateDataset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := mustProfile(t, evaluateProfile).Scores(samples)
+
+	for _, tc := range []struct {
+		name          string
+		samples       []LabeledSample
+		scores        []float64
+		wantThreshold float64
+	}{
+		// 0.75 and 0.5 both misclassify one sample, the highest wins.
+		{"mixed", samples, scores, 0.75},
+		{"genuine only", samples[:3], scores[:3], 0.5},
+		// Nothing to accept, every impostor is rejected.
+		{"impostors only", samples[3:], scores[3:], 1},
+		{"no sample", nil, nil, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if threshold := OptimalThreshold(tc.samples, tc.scores); threshold != tc.wantThreshold {
+				t.Errorf("OptimalThreshold = %v, want %v", threshold, tc.wantThreshold)
+			}
+		})
+	}
+}
+
+func TestTuneInteractively(t *testing.T) {
+	// Captures from stdin have every timing zeroed in test mode, so abc
+	// scores 2/3 and xyz, typed with other characters, -1.
+	p := mustProfile(t, "t0.at100.bt0.c")
+	genuine := 2.0 / 3
+
+	for _, tc := range []struct {
+		name          string
+		answers       string
+		wantThreshold float64
+		wantErr       bool
+	}{
+		{"mixed", "abc\ny\nxyz\nn\nabc\nyes\n", genuine, false},
+		{"genuine only", "abc\ny\nabc\nY\n", genuine, false},
+		{"impostors only", "xyz\nn\nxyz\nNO\n", 1, false},
+		// Rejecting everything misclassifies one sample out of two, like
+		// any lower threshold.
+		{"mislabeled", "xyz\ny\nabc\nn\n", 1, false},
+		{"ignored answers", "abc\nmaybe\nabc\n\n", 0, true},
+		// An unanswered sample isn't labeled.
+		{"unanswered", "abc\ny\nxyz\n", genuine, false},
+		{"no sample", "", 0, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scriptStdin(t, tc.answers)
+
+			threshold, err := TuneInteractively(p, ReadOptions{})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("TuneInteractively: %v, want error %v", err, tc.wantErr)
+			}
+			if threshold != tc.wantThreshold {
+				t.Errorf("TuneInteractively = %v, want %v", threshold, tc.wantThreshold)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// units a timing can be captured and encoded in, from the coarsest. Keys
+// encoded in anything but milliseconds carry a "<unit>:" header.
+var units = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"ms", time.Millisecond},
+	{"us", time.Microsecond},
+	{"ns", time.Nanosecond},
+}
+
+func ParseUnit(name string) (time.Duration, error) {
+	for _, u := range units {
+		if u.Name == name {
+			return u.Duration, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown unit %q", name)
+}
+
+func unitName(unit time.Duration) string {
+	for _, u := range units {
+		if u.Duration == unit {
+			return u.Name
+		}
+	}
+
+	return unit.String()
+}
+
+// unit returns the coarsest unit all the timings of the key are a whole
+// multiple of.
+func (rythmkey Rythmkey) unit() time.Duration {
+	for _, u := range units {
+		exact := true
+		for _, ct := range rythmkey {
+			if ct.Timing%u.Duration != 0 {
+				exact = false
+				break
+			}
+		}
+
+		if exact {
+			return u.Duration
+		}
+	}
+
+	return time.Nanosecond
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func formatMilliseconds(d time.Duration) string {
+	return strconv.FormatFloat(milliseconds(d), 'f', -1, 64)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Vector returns the timings in milliseconds, or as fractions of the total
+// duration of the key when normalized.
+func (rythmkey Rythmkey) Vector(normalize bool) []float64 {
+	vector := make([]float64, len(rythmkey))
+	for i, ct := range rythmkey {
+		vector[i] = milliseconds(ct.Timing)
+	}
+	total := milliseconds(rythmkey.TotalDuration())
+
+	if normalize && total > 0 {
+		for i := range vector {
+			vector[i] /= total
+		}
+	}
+
+	return vector
+}
+
+func formatVector(vector []float64, separator string) string {
+	values := make([]string, len(vector))
+	for i, v := range vector {
+		values[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	return strings.Join(values, separator)
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestVector(t *testing.T) {
+	rk := mustParse(t, "us:t0.at120500.bt79500.c")
+
+	if got, want := formatVector(rk.Vector(false), ","), "0,120.5,79.5"; got != want {
+		t.Errorf("vector = %q, want %q", got, want)
+	}
+
+	if got, want := formatVector(rk.Vector(true), " "), "0 0.6025 0.3975"; got != want {
+		t.Errorf("normalized vector = %q, want %q", got, want)
+	}
+
+	if got, want := formatVector(mustParse(t, "t0.at0.b").Vector(true), ","), "0,0"; got != want {
+		t.Errorf("normalized vector of a zero duration key = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// verbose logs the diagnostics of a run, discarded unless --verbose is set.
+var verbose = log.New(io.Discard, "", log.LstdFlags)
+
+// logPath is the --log-file the verbose logs go to, only opened as
+// logFile by the first of them so a run skipping it leaves no file.
+var (
+	logPath string
+	logFile *os.File
+)
+
+// setupVerbose sends verbose logs to stderr, or to path when set. The file
+// is only readable by its owner as the logs may reveal the typed key.
+func setupVerbose(enabled bool, path string) error {
+	if !enabled {
+		return nil
+	}
+
+	if path == "" {
+		verbose.SetOutput(os.Stderr)
+		return nil
+	}
+
+	logPath = path
+	verbose.SetOutput(&logWriter{})
+	return nil
+}
+
+// logWriter writes to logPath, opening it on the first write. The logger
+// drops write errors, so failing to open it is warned about once.
+type logWriter struct {
+	err error
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	if logFile == nil && w.err == nil {
+		logFile, w.err = os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if w.err != nil {
+			fmt.Fprintf(os.Stderr, "warning: can't write verbose logs: %v\n", w.err)
+		}
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	return logFile.Write(p)
+}
+
+// skipLogFile sends the verbose logs meant for the --log-file to stderr
+// instead, returning its path, empty when there is none.
+func skipLogFile() string {
+	path := logPath
+	if path != "" {
+		closeVerbose()
+		verbose.SetOutput(os.Stderr)
+	}
+
+	return path
+}
+
+func closeVerbose() error {
+	if logPath == "" {
+		return nil
+	}
+
+	verbose.SetOutput(io.Discard)
+	logPath = ""
+	if logFile == nil {
+		return nil
+	}
+
+	err := logFile.Close()
+	logFile = nil
+	return err
+}
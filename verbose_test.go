@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExiterClosesLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "verbose.log")
+	if err := setupVerbose(true, path); err != nil {
+		t.Fatal(err)
+	}
+	defer closeVerbose()
+
+	verbose.Print("before exiting")
+
+	code := -1
+	exiter(func(c int) { code = c })(exitReject)
+
+	if code != exitReject {
+		t.Errorf("exited with %d, want %d", code, exitReject)
+	}
+
+	if logFile != nil {
+		t.Error("log file still open after exiting")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) == 0 {
+		t.Error("log file empty after exiting")
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("log file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+}
+
+// The log file is only created by the first log.
+func TestLogFileOpenedOnFirstLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "verbose.log")
+	if err := setupVerbose(true, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := closeVerbose(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("log file created without logging anything: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultRejectDelay slows down online guessing. Every verdict waits for at
+// least this long so an accept can't be told apart from a reject by timing,
+// at the cost of making every verification this much slower for the user.
+const defaultRejectDelay = 500 * time.Millisecond
+
+// Exit codes of verify when the key isn't accepted. A wrong sequence is
+// told apart from a rejected rhythm since it's usually a typo.
+const (
+	exitReject        = 1
+	exitWrongSequence = 2
+)
+
+// The verification path — ParseRythmkey, HashWith, VerifyHash, LoadProfile,
+// Profile.Score, Profile.Verify and BestMatch — works on its arguments only
+// and is safe for concurrent use, a loaded Profile being shared read-only
+// between goroutines. The only package state it touches is the verbose
+// logger, which serializes its writes. Capturing (Read, ReadWith) drives
+// the process' terminal and must not run concurrently.
+
+// VerifyHash reports whether rythmkey hashes to hash, a digest in
+// opts.Encoding.
+func (rythmkey Rythmkey) VerifyHash(opts HashOptions, hash string) (bool, error) {
+	digest, err := rythmkey.Digest(opts)
+	if err != nil {
+		return false, err
+	}
+
+	return matchDigest(digest, hash, opts.Encoding)
+}
+
+// matchDigest compares digest to hash, rendered in encoding, in constant
+// time.
+func matchDigest(digest []byte, hash string, encoding string) (bool, error) {
+	expected, err := DecodeDigest(hash, encoding)
+	if err != nil {
+		return false, fmt.Errorf("malformed digest: %w", err)
+	}
+
+	return subtle.ConstantTimeCompare(digest, expected) == 1, nil
+}
+
+// Verify reports whether rk scores at least threshold against p.
+func (p Profile) Verify(rk Rythmkey, threshold float64) (bool, float64, error) {
+	score, err := p.Score(rk)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return score >= threshold, score, nil
+}
+
+// VerifyContext verifies like Verify unless ctx is already done, in which
+// case it returns ctx.Err(). Scoring is too quick to be worth interrupting
+// once started.
+func (p Profile) VerifyContext(ctx context.Context, rk Rythmkey, threshold float64) (bool, float64, error) {
+	if err := ctx.Err(); err != nil {
+		return false, 0, err
+	}
+
+	return p.Verify(rk, threshold)
+}
+
+func waitVerdict(start time.Time, delay time.Duration) {
+	if testMode {
+		return
+	}
+
+	if remaining := delay - time.Since(start); remaining > 0 {
+		time.Sleep(remaining)
+	}
+}
+
+// sequenceMismatch returns why rk matches the characters of none of the
+// profiles: Caps Lock if they'd match with the case inverted, the
+// mismatch itself against a single profile, or a generic one against
+// several. It is nil if the characters of a profile match.
+func sequenceMismatch(profiles []NamedProfile, rk Rythmkey) error {
+	var mismatch *MismatchError
+	for _, np := range profiles {
+		err := checkChars(np.Profile.Chars, rk.Chars())
+		if !errors.As(err, &mismatch) {
+			return nil
+		}
+	}
+
+	inverted := rk.InvertCase()
+	defer inverted.Zero()
+	for _, np := range profiles {
+		if checkChars(np.Profile.Chars, inverted.Chars()) == nil {
+			return errCapsLock
+		}
+	}
+
+	if len(profiles) == 1 {
+		return mismatch
+	}
+
+	return errors.New("character sequence matches no profile")
+}
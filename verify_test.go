@@ -0,0 +1,102 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWaitVerdictSameDelay(t *testing.T) {
+	opts := HashOptions{Salt: 20}
+	hash, err := mustParse(t, "t0.at120.bt80.c").HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delay := 50 * time.Millisecond
+	for rks, accept := range map[string]bool{"t0.at120.bt80.c": true, "t0.at400.bt80.c": false} {
+		start := now()
+		ok, err := mustParse(t, rks).VerifyHash(opts, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitVerdict(start, delay)
+
+		if ok != accept {
+			t.Errorf("%s: accepted %t, want %t", rks, ok, accept)
+		}
+		if elapsed := time.Since(start); elapsed < delay {
+			t.Errorf("%s: verdict after %s, before %s", rks, elapsed, delay)
+		}
+	}
+}
+
+func TestWaitVerdictTestMode(t *testing.T) {
+	testMode = true
+	defer func() { testMode = false }()
+
+	start := time.Now()
+	waitVerdict(start, time.Hour)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("verdict delayed %s in test mode", elapsed)
+	}
+}
+
+// Run with -race: verifications share the loaded profile read-only.
+func TestVerifyConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "p.json")
+	if err := mustProfile(t, "t0.at100.bt90.c", "t0.at110.bt100.c").Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles := []NamedProfile{{Name: "p", Profile: p}}
+
+	opts := HashOptions{Salt: 20}
+	hash, err := mustParse(t, "t0.at105.bt95.c").HashWith(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			rks, accept := "t0.at105.bt95.c", true
+			if i%2 == 1 {
+				rks, accept = "t0.at300.bt300.c", false
+			}
+
+			for j := 0; j < 50; j++ {
+				rk, err := ParseRythmkey(rks)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				ok, _, err := p.Verify(rk, defaultThreshold)
+				if err != nil || ok != accept {
+					t.Errorf("Verify(%s) = %t, %v, want %t", rks, ok, err, accept)
+					return
+				}
+
+				if _, _, ok := BestMatch(profiles, rk, defaultThreshold); ok != accept {
+					t.Errorf("BestMatch(%s) = %t, want %t", rks, ok, accept)
+					return
+				}
+
+				if ok, err := rk.VerifyHash(opts, hash); err != nil || ok != accept {
+					t.Errorf("VerifyHash(%s) = %t, %v, want %t", rks, ok, err, accept)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadSamples loads the raw enrollment samples of dir, every *.rk file
+// holding an encoded key.
+func LoadSamples(dir string) ([]Rythmkey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.rk"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	samples := []Rythmkey{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		rk, err := ParseRythmkey(strings.TrimSuffix(string(data), "\n"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		samples = append(samples, rk)
+	}
+
+	return samples, nil
+}
+
+// Vote matches rk against every sample and returns how many it matches.
+// It is accepted when at least k of them match, a majority of the samples
+// when k is zero.
+func Vote(samples []Rythmkey, rk Rythmkey, m Matcher, k int) (int, bool, error) {
+	if k <= 0 {
+		k = len(samples)/2 + 1
+	}
+
+	votes := 0
+	for _, sample := range samples {
+		ok, _, err := m.Match(sample, rk)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if ok {
+			votes++
+		}
+	}
+
+	return votes, votes >= k, nil
+}
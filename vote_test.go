@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSamples writes every key as a sample of a new samples directory.
+func writeSamples(t *testing.T, samples ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for i, rks := range samples {
+		path := filepath.Join(dir, string(rune('a'+i))+".rk")
+		if err := os.WriteFile(path, []byte(rks+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestVote(t *testing.T) {
+	samples := []Rythmkey{
+		mustParse(t, "t0.at120.bt80.c"),
+		mustParse(t, "t0.at130.bt90.c"),
+		mustParse(t, "t0.at400.bt80.c"),
+		mustParse(t, "t0.at120.bt80.x"),
+		mustParse(t, "t0.at125.bt85.c"),
+	}
+	m := ToleranceMatcher{Tolerance: AbsoluteTolerance(50 * time.Millisecond)}
+	rk := mustParse(t, "t0.at125.bt85.c")
+
+	for _, tc := range []struct {
+		k      int
+		accept bool
+	}{
+		{0, true},
+		{3, true},
+		{4, false},
+		{5, false},
+	} {
+		votes, ok, err := Vote(samples, rk, m, tc.k)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if votes != 3 || ok != tc.accept {
+			t.Errorf("Vote(k=%d) = %d, %t, want 3 votes, %t", tc.k, votes, ok, tc.accept)
+		}
+	}
+
+	// A majority of two of the four samples isn't reached.
+	if votes, ok, _ := Vote(samples[1:], rk, m, 0); votes != 2 || ok {
+		t.Errorf("Vote over 4 samples = %d, %t, want 2 votes rejected", votes, ok)
+	}
+}
+
+func TestLoadSamples(t *testing.T) {
+	dir := writeSamples(t, "t0.at130.bt90.c", "t0.at120.bt80.c")
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a sample"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := LoadSamples(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != 2 || samples[0].Encode() != "t0.at130.bt90.c" || samples[1].Encode() != "t0.at120.bt80.c" {
+		t.Errorf("LoadSamples = %v, want both samples in name order", samples)
+	}
+
+	if _, err := LoadSamples(writeSamples(t, "t0.at130.bt90.c", "garbage")); err == nil {
+		t.Error("LoadSamples of an invalid sample succeeded")
+	}
+}
+
+func TestVerifySamplesVote(t *testing.T) {
+	dir := writeSamples(t, "t0.at120.bt80.c", "t0.at130.bt90.c", "t0.at400.bt80.c", "t0.at120.bt80.x", "t0.at125.bt85.c")
+	t.Setenv(encodedInputEnv, "t0.at125.bt85.c")
+
+	for _, tc := range []struct {
+		vote string
+		code int
+	}{
+		{"0", 0},
+		{"3", 0},
+		{"4", exitReject},
+	} {
+		result := runApp(t, "verify", "--samples-dir", dir, "--vote", tc.vote)
+		if result.code != tc.code {
+			t.Errorf("verify --vote %s exited %d, want %d: %v", tc.vote, result.code, tc.code, result.err)
+		}
+	}
+}
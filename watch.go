@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+const defaultWatchInterval = time.Minute
+
+// Drift is how far the timings of rk stray from p: the mean over its
+// characters of their distance to the profile mean, in tolerances. 0 is
+// typed exactly like the mean, 1 on the edge of the tolerance. The
+// character sequences must be identical.
+func (p Profile) Drift(rk Rythmkey) (float64, error) {
+	results, err := p.Breakdown(rk)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(results) == 0 {
+		return 0, nil
+	}
+
+	drift := 0.0
+	for _, r := range results {
+		drift += math.Abs(r.Diff) / r.Tolerance
+	}
+
+	return drift / float64(len(results)), nil
+}
+
+// DriftTracker keeps the running drift of a session, the mean drift of
+// its samples.
+type DriftTracker struct {
+	Samples int
+	Total   float64
+}
+
+// Add a sample's drift and return the running drift.
+func (d *DriftTracker) Add(drift float64) float64 {
+	d.Samples++
+	d.Total += drift
+	return d.Mean()
+}
+
+func (d DriftTracker) Mean() float64 {
+	if d.Samples == 0 {
+		return 0
+	}
+
+	return d.Total / float64(d.Samples)
+}
+
+// Watch captures a sample every interval and reports on w its score and
+// drift against p, warning when the profile wouldn't accept it at
+// threshold. It stops at ctrl-d, see endOfInput.
+func Watch(p Profile, ro ReadOptions, interval time.Duration, threshold float64, w io.Writer) error {
+	defer stty("-cbreak", "echo").Run()
+
+	tracker := DriftTracker{}
+	for n := 1; ; n++ {
+		if n > 1 {
+			time.Sleep(interval)
+		}
+
+		rk, err := readPrompted(fmt.Sprintf("sample %d: ", n), ro)
+		if err != nil {
+			return err
+		}
+
+		if endOfInput(rk) {
+			rk.Zero()
+			return nil
+		}
+
+		score, err := p.Score(rk)
+		if err != nil {
+			rk.Zero()
+			fmt.Fprintf(os.Stderr, "warning: sample %d ignored: %v\n", n, err)
+			continue
+		}
+
+		drift, err := p.Drift(rk)
+		rk.Zero()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "sample %d: score %.2f, drift %.2f, running drift %.2f\n", n, score, drift, tracker.Add(drift))
+		if score < threshold {
+			fmt.Fprintf(os.Stderr, "warning: sample %d would be rejected at threshold %.2f, consider enrolling again\n", n, threshold)
+		}
+	}
+}
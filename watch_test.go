@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDrift(t *testing.T) {
+	// Every character has the minimum tolerance, 30ms, the first one
+	// counting too.
+	p := mustProfile(t, "t0.at100.bt200.c", "t0.at100.bt200.c")
+
+	for _, tc := range []struct {
+		rks   string
+		drift float64
+	}{
+		{"t0.at100.bt200.c", 0},
+		{"t0.at130.bt200.c", 1.0 / 3},
+		{"t0.at70.bt230.c", 2.0 / 3},
+		{"t0.at160.bt140.c", 4.0 / 3},
+	} {
+		drift, err := p.Drift(mustParse(t, tc.rks))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if math.Abs(drift-tc.drift) > 1e-9 {
+			t.Errorf("Drift(%s) = %v, want %v", tc.rks, drift, tc.drift)
+		}
+	}
+
+	if _, err := p.Drift(mustParse(t, "t0.at100.bt200.x")); err == nil {
+		t.Error("Drift of other characters succeeded")
+	}
+}
+
+func TestDriftTracker(t *testing.T) {
+	p := mustProfile(t, "t0.at100.bt200.c", "t0.at100.bt200.c")
+	tracker := DriftTracker{}
+
+	if mean := tracker.Mean(); mean != 0 {
+		t.Errorf("Mean of no sample = %v, want 0", mean)
+	}
+
+	// A session drifting further with every sample.
+	for i, tc := range []struct {
+		rks     string
+		running float64
+	}{
+		{"t0.at100.bt200.c", 0},
+		{"t0.at130.bt230.c", 1.0 / 3},
+		{"t0.at160.bt260.c", 2.0 / 3},
+		{"t0.at190.bt290.c", 1},
+	} {
+		drift, err := p.Drift(mustParse(t, tc.rks))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if running := tracker.Add(drift); math.Abs(running-tc.running) > 1e-9 {
+			t.Errorf("running drift after sample %d = %v, want %v", i+1, running, tc.running)
+		}
+	}
+
+	if tracker.Samples != 4 {
+		t.Errorf("Samples = %d, want 4", tracker.Samples)
+	}
+}
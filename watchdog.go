@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// savedTerminal is the state of the terminal before rythmkey first changed
+// it, empty while it hasn't.
+var savedTerminal string
+
+// terminalState returns the settings of the terminal, as stty -g prints
+// them, and setTerminal restores them. They are variables so tests can
+// simulate a terminal that fails to be restored.
+var (
+	terminalState = func() (string, error) {
+		out, err := stty("-g").Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	setTerminal = func(state string) error {
+		return stty(state).Run()
+	}
+)
+
+// saveTerminal records the state of the terminal before a capture or the
+// editor changes it, once per run, for checkTerminal.
+func saveTerminal() {
+	if savedTerminal != "" {
+		return
+	}
+
+	if state, err := terminalState(); err == nil {
+		savedTerminal = state
+	}
+}
+
+// checkTerminal is a safety net run right before exiting: restoring the
+// terminal may have failed silently, so it compares the terminal to its
+// saved state, restores it a second time if they differ and writes a
+// remediation to w if that failed too.
+func checkTerminal(w io.Writer) {
+	if savedTerminal == "" {
+		return
+	}
+
+	if state, err := terminalState(); err == nil && state == savedTerminal {
+		return
+	}
+
+	setTerminal(savedTerminal)
+	if state, err := terminalState(); err == nil && state == savedTerminal {
+		return
+	}
+
+	fmt.Fprintln(w, "warning: the terminal may not have been restored, run 'stty sane' or 'reset'")
+}
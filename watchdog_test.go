@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeTerminal replaces the terminal with state until the test ends. Every
+// restore sets it to the state given, unless fail says it doesn't take.
+func fakeTerminal(t *testing.T, state *string, fail func(attempt int) bool) *[]string {
+	t.Helper()
+
+	restores := []string{}
+	savedState, savedSet, saved := terminalState, setTerminal, savedTerminal
+	terminalState = func() (string, error) { return *state, nil }
+	setTerminal = func(s string) error {
+		restores = append(restores, s)
+		if fail(len(restores)) {
+			return errors.New("stty: can't set the terminal")
+		}
+		*state = s
+		return nil
+	}
+	savedTerminal = ""
+	t.Cleanup(func() { terminalState, setTerminal, savedTerminal = savedState, savedSet, saved })
+
+	return &restores
+}
+
+func TestCheckTerminal(t *testing.T) {
+	never := func(int) bool { return false }
+	always := func(int) bool { return true }
+
+	for _, tc := range []struct {
+		name     string
+		after    string
+		fail     func(int) bool
+		restores int
+		warning  bool
+	}{
+		{"restored", "cooked", never, 0, false},
+		{"first restore failed", "cbreak", never, 1, false},
+		{"both restores failed", "cbreak", always, 1, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			state := "cooked"
+			restores := fakeTerminal(t, &state, tc.fail)
+
+			saveTerminal()
+			state = "cbreak"
+			// Only the first state is saved.
+			saveTerminal()
+			state = tc.after
+
+			var w strings.Builder
+			checkTerminal(&w)
+
+			if len(*restores) != tc.restores {
+				t.Errorf("restored %d times, want %d", len(*restores), tc.restores)
+			}
+			for _, s := range *restores {
+				if s != "cooked" {
+					t.Errorf("restored to %q, want the saved state", s)
+				}
+			}
+			if warned := strings.Contains(w.String(), "stty sane"); warned != tc.warning {
+				t.Errorf("warned %t (%q), want %t", warned, w.String(), tc.warning)
+			}
+		})
+	}
+}
+
+func TestCheckTerminalUnsaved(t *testing.T) {
+	state := "cbreak"
+	restores := fakeTerminal(t, &state, func(int) bool { return false })
+
+	var w strings.Builder
+	checkTerminal(&w)
+	if len(*restores) != 0 || w.Len() != 0 {
+		t.Errorf("checkTerminal of a terminal never changed restored %v and wrote %q", *restores, w.String())
+	}
+}